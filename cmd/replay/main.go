@@ -0,0 +1,104 @@
+// Command replay runs a to_buy/to_sell sheet snapshot against historical prices with the
+// paper broker and writes a fill report.
+//
+//	go run ./cmd/replay -date 2024-01-15 -buy to_buy.csv -sell to_sell.csv -prices bars.csv
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/replay"
+)
+
+func main() {
+	var (
+		date   = flag.String("date", "", "trading day to replay (YYYY-MM-DD, IST)")
+		buy    = flag.String("buy", "", "CSV snapshot of the to_buy sheet range (column B onwards)")
+		sell   = flag.String("sell", "", "CSV snapshot of the to_sell sheet range (column B onwards)")
+		prices = flag.String("prices", "", "comma-separated tick or OHLC price CSV files")
+		out    = flag.String("out", "", "output directory (default ./replay/<date>)")
+		seed   = flag.Int64("seed", 0, "paper broker seed (default PAPER_SEED)")
+		cash   = flag.Float64("cash", 0, "starting cash (default PAPER_INITIAL_CASH)")
+	)
+	flag.Parse()
+
+	if err := run(*date, *buy, *sell, *prices, *out, *seed, *cash); err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(date, buy, sell, prices, out string, seed int64, cash float64) error {
+	if date == "" {
+		return fmt.Errorf("-date is required")
+	}
+	if buy == "" && sell == "" {
+		return fmt.Errorf("at least one of -buy or -sell is required")
+	}
+	if prices == "" {
+		return fmt.Errorf("-prices is required")
+	}
+
+	istLocation, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		istLocation = time.UTC
+	}
+	day, err := time.ParseInLocation("2006-01-02", date, istLocation)
+	if err != nil {
+		return fmt.Errorf("invalid -date: %w", err)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if seed != 0 {
+		cfg.Broker.Paper.Seed = seed
+	}
+	if cash > 0 {
+		cfg.Broker.Paper.InitialCash = cash
+	}
+
+	if out == "" {
+		out = filepath.Join(".", "replay", date)
+	}
+	log, err := logger.NewLogger(cfg.Logging.Level, filepath.Join(out, "replay.log"))
+	if err != nil {
+		return fmt.Errorf("failed to create logger: %w", err)
+	}
+	defer log.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	runner := replay.NewRunner(cfg, replay.Options{
+		Date:       day,
+		BuyPath:    buy,
+		SellPath:   sell,
+		PricePaths: strings.Split(prices, ","),
+		OutputDir:  out,
+	}, log)
+
+	report, err := runner.Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Replay %s: %d orders, %d filled, %d unfilled, %d rejected, %d expired, %d missed\n",
+		date, len(report.Orders), report.Count(replay.StatusFilled), report.Count(replay.StatusUnfilled),
+		report.Count(replay.StatusRejected), report.Count(replay.StatusExpired), report.Count(replay.StatusMissed))
+	fmt.Printf("P&L: %.2f (realised %.2f, unrealised %.2f), avg slippage %.2f bps\n",
+		report.TotalPnL(), report.RealisedPnL, report.UnrealisedPnL, report.AverageSlippageBps())
+	fmt.Printf("Report: %s\n", filepath.Join(out, "report.md"))
+	return nil
+}
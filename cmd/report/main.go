@@ -0,0 +1,109 @@
+// Command report builds the end-of-day execution report from the execution journal and
+// writes it as Markdown, CSV and HTML. With -notify it also posts the summary through the
+// configured alert sinks.
+//
+//	go run ./cmd/report -date 2024-01-15 -format md,html -notify
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/notify"
+	"github.com/mach_five/trading-system/internal/report"
+)
+
+func main() {
+	var (
+		date        = flag.String("date", "", "trading day to report (YYYY-MM-DD, IST; default today)")
+		journalPath = flag.String("journal", "", "execution journal to read (default JOURNAL_PATH)")
+		out         = flag.String("out", "", "output directory (default ./reports/<date>)")
+		formats     = flag.String("format", "md,csv,html", "comma-separated output formats: md, csv, html")
+		post        = flag.Bool("notify", false, "post the summary through the configured alert sinks")
+	)
+	flag.Parse()
+
+	if err := run(*date, *journalPath, *out, *formats, *post); err != nil {
+		fmt.Fprintf(os.Stderr, "report: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(date, journalPath, out, formats string, post bool) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if journalPath == "" {
+		journalPath = cfg.Journal.Path
+	}
+
+	day := report.StartOfDay(time.Now())
+	if date != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", date, day.Location())
+		if err != nil {
+			return fmt.Errorf("invalid -date: %w", err)
+		}
+		day = parsed
+	}
+	if out == "" {
+		out = filepath.Join(".", "reports", day.Format("2006-01-02"))
+	}
+
+	rep, err := report.Load(journalPath, day)
+	if err != nil {
+		return err
+	}
+	paths, err := rep.WriteFiles(out, splitFormats(formats))
+	if err != nil {
+		return err
+	}
+
+	amo, regular := rep.SubmittedCounts()
+	fmt.Printf("Report %s: %d read, %d executed, %d failed, %d expired, %d abandoned, %d not submitted\n",
+		day.Format("2006-01-02"), rep.ReadCount(), rep.Count(report.StatusExecuted), rep.Count(report.StatusFailed),
+		rep.Count(report.StatusExpired), rep.Count(report.StatusAbandoned), rep.Count(report.StatusNotSubmitted))
+	fmt.Printf("Submitted: %d AMO, %d regular; scheduler delay p50 %v, p99 %v\n",
+		amo, regular, rep.SchedulerDelay.P50, rep.SchedulerDelay.P99)
+	for _, path := range paths {
+		fmt.Printf("Wrote %s\n", path)
+	}
+
+	if post {
+		log, err := logger.NewLogger(cfg.Logging.Level, filepath.Join(out, "report.log"))
+		if err != nil {
+			return fmt.Errorf("failed to create logger: %w", err)
+		}
+		defer log.Close()
+
+		notifier := notify.NewNotifier(cfg, log)
+		if !notifier.Enabled() {
+			return fmt.Errorf("-notify given but no alert sink is configured")
+		}
+		event := rep.Event()
+		event.Time = time.Now()
+		if err := notifier.Deliver(context.Background(), event); err != nil {
+			return fmt.Errorf("failed to post report: %w", err)
+		}
+		fmt.Println("Posted summary to the configured alert sinks")
+	}
+	return nil
+}
+
+// splitFormats splits the -format flag
+func splitFormats(value string) []string {
+	var formats []string
+	for _, format := range strings.Split(value, ",") {
+		if format = strings.TrimSpace(format); format != "" {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
@@ -2,14 +2,22 @@ package broker
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"time"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/mach_five/trading-system/internal/clock"
 	"github.com/mach_five/trading-system/internal/config"
 	"github.com/mach_five/trading-system/internal/logger"
 	"github.com/mach_five/trading-system/internal/models"
 )
 
+// DefaultAlpacaAPIURL is the Alpaca trading API host used when no base_url is configured
+const DefaultAlpacaAPIURL = "https://api.alpaca.markets"
+
 // AlpacaBroker implements broker interface for Alpaca API
 // This is a placeholder implementation - actual implementation would use Alpaca SDK
 type AlpacaBroker struct {
@@ -18,6 +26,7 @@ type AlpacaBroker struct {
 	apiKey    string
 	apiSecret string
 	baseURL   string
+	clock     clock.Clock
 }
 
 // NewAlpacaBroker creates a new Alpaca broker instance
@@ -31,7 +40,8 @@ func NewAlpacaBroker(cfg *config.Config, log *logger.Logger) (*AlpacaBroker, err
 		logger:    log,
 		apiKey:    cfg.Broker.APIKey,
 		apiSecret: cfg.Broker.APISecret,
-		baseURL:   cfg.Broker.BaseURL,
+		baseURL:   alpacaURL(cfg.Broker.BaseURL),
+		clock:     clock.Real{},
 	}, nil
 }
 
@@ -46,21 +56,119 @@ func (a *AlpacaBroker) ExecuteOrder(ctx context.Context, order models.Order) (mo
 	return models.ExecutionResult{
 		OrderID:     order.ID,
 		Success:     false,
-		ExecutedAt:  time.Now(),
+		ExecutedAt:  a.clock.Now(),
 		ErrorMessage: "Alpaca broker not fully implemented",
 	}, fmt.Errorf("Alpaca broker implementation pending")
 }
 
+// SetClock replaces the clock used to timestamp execution results
+func (a *AlpacaBroker) SetClock(c clock.Clock) {
+	a.clock = c
+}
+
 // HealthCheck checks Alpaca API health
 func (a *AlpacaBroker) HealthCheck(ctx context.Context) error {
 	// TODO: Implement health check via Alpaca API
 	return nil
 }
 
-// ValidateSymbol validates symbol via Alpaca API
-func (a *AlpacaBroker) ValidateSymbol(ctx context.Context, exchange, symbol string) (bool, error) {
-	// TODO: Implement symbol validation via Alpaca API
-	return true, nil
+// Funds fetches the account's buying power from Alpaca
+func (a *AlpacaBroker) Funds(ctx context.Context) (models.Funds, error) {
+	var account struct {
+		BuyingPower string `json:"buying_power"`
+	}
+	if err := a.get(ctx, "/v2/account", &account); err != nil {
+		return models.Funds{}, fmt.Errorf("failed to fetch account: %w", err)
+	}
+	buyingPower, err := strconv.ParseFloat(account.BuyingPower, 64)
+	if err != nil {
+		return models.Funds{}, fmt.Errorf("invalid buying_power %q: %w", account.BuyingPower, err)
+	}
+	return models.Funds{Available: buyingPower, FetchedAt: a.clock.Now()}, nil
+}
+
+// alpacaPosition is one entry of the Alpaca /v2/positions response; quantities are decimal strings
+type alpacaPosition struct {
+	Symbol        string `json:"symbol"`
+	Exchange      string `json:"exchange"`
+	Qty           string `json:"qty"`
+	QtyAvailable  string `json:"qty_available"` // Not held for open orders
+	AvgEntryPrice string `json:"avg_entry_price"`
+	CurrentPrice  string `json:"current_price"`
+}
+
+// Portfolio fetches open positions from Alpaca. Alpaca has no separate holdings, so long
+// positions are reported as holdings and short ones as positions.
+func (a *AlpacaBroker) Portfolio(ctx context.Context) (models.Portfolio, error) {
+	var positions []alpacaPosition
+	if err := a.get(ctx, "/v2/positions", &positions); err != nil {
+		return models.Portfolio{}, fmt.Errorf("failed to fetch positions: %w", err)
+	}
+
+	portfolio := models.Portfolio{FetchedAt: a.clock.Now()}
+	for _, p := range positions {
+		qty := parseAlpacaQuantity(p.Qty)
+		average, _ := strconv.ParseFloat(p.AvgEntryPrice, 64)
+		last, _ := strconv.ParseFloat(p.CurrentPrice, 64)
+		if qty < 0 {
+			portfolio.Positions = append(portfolio.Positions, models.Position{
+				Exchange: p.Exchange, Symbol: p.Symbol, Quantity: qty, AveragePrice: average, LastPrice: last,
+			})
+			continue
+		}
+		used := 0
+		if p.QtyAvailable != "" {
+			used = qty - parseAlpacaQuantity(p.QtyAvailable)
+		}
+		portfolio.Holdings = append(portfolio.Holdings, models.Holding{
+			Exchange: p.Exchange, Symbol: p.Symbol, Quantity: qty, UsedQuantity: used, AveragePrice: average, LastPrice: last,
+		})
+	}
+	return portfolio, nil
+}
+
+// get performs an authenticated GET against the Alpaca API and decodes the JSON response into out
+func (a *AlpacaBroker) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", a.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("APCA-API-KEY-ID", a.apiKey)
+	req.Header.Set("APCA-API-SECRET-KEY", a.apiSecret)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("alpaca API returned status %d for %s: %s", resp.StatusCode, path, string(body))
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			err = fmt.Errorf("%w: %v", ErrTokenRejected, err)
+		}
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", path, err)
+	}
+	return nil
 }
 
+// parseAlpacaQuantity parses a decimal quantity string, dropping fractional shares
+func parseAlpacaQuantity(value string) int {
+	qty, _ := strconv.ParseFloat(value, 64)
+	return int(qty)
+}
 
+// alpacaURL returns the configured API host without a trailing slash, or the default host
+func alpacaURL(baseURL string) string {
+	if baseURL = strings.TrimRight(baseURL, "/"); baseURL == "" {
+		return DefaultAlpacaAPIURL
+	}
+	return baseURL
+}
@@ -2,28 +2,72 @@ package broker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
+	"github.com/mach_five/trading-system/internal/clock"
 	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/instruments"
 	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/metrics"
 	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/time/rate"
 )
 
+// ErrTokenRejected is wrapped by broker errors caused by an expired or invalid access token (HTTP 401/403)
+var ErrTokenRejected = errors.New("access token rejected")
+
+// ErrOrderRejected matches errors for orders held back by a local check before reaching the broker
+var ErrOrderRejected = errors.New("order rejected before reaching the broker")
+
+// rejection marks an error from a local check as an ErrOrderRejected, keeping its message
+type rejection struct {
+	error
+}
+
+func (r rejection) Is(target error) bool { return target == ErrOrderRejected }
+
+func (r rejection) Unwrap() error { return r.error }
+
+// rejected wraps err as a local rejection
+func rejected(err error) error {
+	return rejection{err}
+}
+
 // Broker interface for executing orders
 type Broker interface {
 	ExecuteOrder(ctx context.Context, order models.Order) (models.ExecutionResult, error)
 	HealthCheck(ctx context.Context) error
 }
 
+// clockSetter is implemented by brokers whose timestamps follow an injectable clock
+type clockSetter interface {
+	SetClock(c clock.Clock)
+}
+
+// FillNotifier is implemented by brokers that report fills as they happen, including orders
+// that rest before filling
+type FillNotifier interface {
+	OnFill(handler func(models.Fill))
+}
+
 // BrokerManager manages broker instances and rate limiting
 type BrokerManager struct {
-	broker    Broker
-	config    *config.Config
-	logger    *logger.Logger
-	rateLimit *rate.Limiter
-	mu        sync.RWMutex
+	broker      Broker
+	config      *config.Config
+	logger      *logger.Logger
+	rateLimit   *rate.Limiter
+	portfolio   *PortfolioView        // Nil when the broker cannot report holdings and positions
+	funds       *FundsView            // Nil when the broker cannot report available funds
+	instruments *instruments.Store    // Tick sizes for limit prices; nil when the instrument master is disabled
+	bands       map[string]cachedBand // Circuit bands per EXCHANGE:SYMBOL, fetched once a day
+	clock       clock.Clock
+	mu          sync.RWMutex
 }
 
 // NewBrokerManager creates a new broker manager
@@ -43,6 +87,12 @@ func NewBrokerManager(cfg *config.Config, log *logger.Logger) (*BrokerManager, e
 		if err != nil {
 			return nil, fmt.Errorf("failed to create Alpaca broker: %w", err)
 		}
+	case "paper":
+		log.Warn("📝 Using PAPER broker - orders are simulated, no real trades will be executed")
+		broker, err = NewPaperBroker(cfg, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create paper broker: %w", err)
+		}
 	case "kite":
 		log.Info("🪁 Initializing Kite (Zerodha) broker")
 		broker, err = NewKiteBroker(cfg, log)
@@ -52,8 +102,8 @@ func NewBrokerManager(cfg *config.Config, log *logger.Logger) (*BrokerManager, e
 		log.Success("✅ Kite broker initialized successfully")
 	default:
 		log.Error("❌ Unknown broker type: %s, falling back to mock", cfg.Broker.Type)
-		log.Warn("⚠️  Supported types: mock, alpaca, kite")
-		return nil, fmt.Errorf("unknown broker type: %s (supported: mock, alpaca, kite)", cfg.Broker.Type)
+		log.Warn("⚠️  Supported types: mock, paper, alpaca, kite")
+		return nil, fmt.Errorf("unknown broker type: %s (supported: mock, paper, alpaca, kite)", cfg.Broker.Type)
 	}
 
 	// Create rate limiter
@@ -62,32 +112,135 @@ func NewBrokerManager(cfg *config.Config, log *logger.Logger) (*BrokerManager, e
 		cfg.Broker.RateLimit.BurstSize,
 	)
 
-	return &BrokerManager{
+	bm := &BrokerManager{
 		broker:    broker,
 		config:    cfg,
 		logger:    log,
 		rateLimit: rateLimiter,
-	}, nil
+		bands:     make(map[string]cachedBand),
+		clock:     clock.Real{},
+	}
+	if cfg.Instruments.Enabled {
+		bm.instruments = instruments.NewStore(cfg, nil, log)
+	}
+	if provider, ok := broker.(PortfolioProvider); ok {
+		bm.portfolio = NewPortfolioView(provider, cfg.Broker.Portfolio.RefreshInterval)
+		log.Info("💼 Sell orders are checked against holdings and positions (policy: %s)", cfg.Broker.Portfolio.SellPolicy)
+	}
+	if provider, ok := broker.(FundsProvider); ok {
+		bm.funds = NewFundsView(provider, cfg.Broker.Funds.RefreshInterval)
+		log.Info("💰 Buy orders are checked against available funds (policy: %s)", cfg.Broker.Funds.BuyPolicy)
+	}
+	return bm, nil
 }
 
 // ExecuteOrder executes an order without retries
 func (bm *BrokerManager) ExecuteOrder(ctx context.Context, order models.Order) (models.ExecutionResult, error) {
-	// Wait for rate limit
+	ctx, span := tracing.Tracer().Start(ctx, "broker.execute_order",
+		trace.WithAttributes(attribute.String("broker.type", bm.config.Broker.Type)))
+	defer span.End()
+
+	// Hold back futures and options orders the exchange would refuse, then put limit prices
+	// where the exchange accepts them
+	adjusted := len(order.PriceAdjustments)
+	err := bm.checkContract(order)
+	if err == nil {
+		order, err = bm.normalizePrice(ctx, order)
+	}
+	if err != nil {
+		if bm.funds != nil {
+			bm.funds.Release(order.ID)
+		}
+		tracing.RecordError(span, err)
+		bm.logger.Error("Order %s not sent: %v", order.ID, err)
+		return models.ExecutionResult{
+			OrderID:      order.ID,
+			Success:      false,
+			ExecutedAt:   bm.clock.Now(),
+			ErrorMessage: err.Error(),
+		}, err
+	}
+
+	// Validate or cap sells against the portfolio before they reach the broker
+	requested := order.Quantity
+	order, reserved, err := bm.checkSell(ctx, order)
+	if err != nil {
+		tracing.RecordError(span, err)
+		bm.logger.Error("Order %s not sent: %v", order.ID, err)
+		return models.ExecutionResult{
+			OrderID:      order.ID,
+			Success:      false,
+			ExecutedAt:   bm.clock.Now(),
+			ErrorMessage: err.Error(),
+		}, err
+	}
+
+	// Wait for rate limit only once the order is going to the broker, so local rejections
+	// do not use up tokens valid orders need
 	if err := bm.rateLimit.Wait(ctx); err != nil {
+		if reserved > 0 {
+			bm.portfolio.Release(order.Symbol, reserved)
+		}
+		if bm.funds != nil {
+			bm.funds.Release(order.ID)
+		}
+		tracing.RecordError(span, err)
 		return models.ExecutionResult{}, fmt.Errorf("rate limit wait failed: %w", err)
 	}
+	span.AddEvent("rate limit acquired")
 
 	// Execute order (single attempt, no retries)
+	start := time.Now()
 	execResult, err := bm.broker.ExecuteOrder(ctx, order)
+	metrics.BrokerLatency.WithLabelValues(bm.config.Broker.Type).Observe(time.Since(start).Seconds())
+	if order.Quantity != requested {
+		execResult.CappedQuantity = order.Quantity
+	}
+	if len(order.PriceAdjustments) > adjusted {
+		execResult.PriceAdjustments = order.PriceAdjustments[adjusted:]
+	}
+	if err != nil || !execResult.Success {
+		if reserved > 0 {
+			bm.portfolio.Release(order.Symbol, reserved)
+		}
+		if bm.funds != nil {
+			bm.funds.Release(order.ID)
+		}
+	}
 	if err != nil {
+		tracing.RecordError(span, err)
 		bm.logger.Error("Order %s execution failed: %v", order.ID, err)
 		return execResult, err
 	}
 
+	span.SetAttributes(attribute.String("broker.execution_id", execResult.ExecutionID))
 	bm.logger.Info("Order %s executed successfully: %+v", order.ID, execResult)
 	return execResult, nil
 }
 
+// Broker returns the underlying broker implementation
+func (bm *BrokerManager) Broker() Broker {
+	return bm.broker
+}
+
+// SetClock replaces the clock used by the manager, its portfolio and funds views, its
+// instrument store and the underlying broker
+func (bm *BrokerManager) SetClock(c clock.Clock) {
+	bm.clock = c
+	if bm.instruments != nil {
+		bm.instruments.SetClock(c)
+	}
+	if bm.portfolio != nil {
+		bm.portfolio.SetClock(c)
+	}
+	if bm.funds != nil {
+		bm.funds.SetClock(c)
+	}
+	if setter, ok := bm.broker.(clockSetter); ok {
+		setter.SetClock(c)
+	}
+}
+
 // HealthCheck checks broker health
 func (bm *BrokerManager) HealthCheck(ctx context.Context) error {
 	return bm.broker.HealthCheck(ctx)
@@ -0,0 +1,29 @@
+package broker
+
+import (
+	"fmt"
+
+	"github.com/mach_five/trading-system/internal/derivatives"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// checkContract checks an order's product suits its instrument and, for futures and
+// options, that its quantity is a whole number of lots and its contract can still be
+// traded under the expiry-day rules. Failures are local rejections.
+func (bm *BrokerManager) checkContract(order models.Order) error {
+	if order.Product != "" {
+		if err := derivatives.CheckProduct(order.Product, order.InstrumentType); err != nil {
+			return rejected(err)
+		}
+	}
+	if order.LotSize > 1 && order.Quantity%order.LotSize != 0 {
+		return rejected(fmt.Errorf("quantity %d of %s is not a multiple of its lot size %d", order.Quantity, order.Symbol, order.LotSize))
+	}
+	if !derivatives.IsDerivative(order.InstrumentType) {
+		return nil
+	}
+	if err := derivatives.CheckExpiry(order.Expiry, bm.clock.Now(), bm.config.Broker.Derivatives.ExpiryDayCutoff); err != nil {
+		return rejected(err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/broker/kitetest"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/derivatives"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+func TestBrokerManagerPlacesFuturesOrders(t *testing.T) {
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{}
+	server.Configure(cfg)
+	cfg.Broker.RateLimit = config.RateLimitConfig{RequestsPerSecond: 100, BurstSize: 100}
+	cfg.Broker.Portfolio.SellPolicy = config.SellPolicyReject
+	cfg.Broker.Derivatives.ExpiryDayCutoff = "15:00"
+	manager, err := NewBrokerManager(cfg, newTestLogger(t))
+	if err != nil {
+		t.Fatalf("NewBrokerManager: %v", err)
+	}
+	ist, _ := time.LoadLocation("Asia/Kolkata")
+	sim := clock.NewSimulated(time.Date(2024, 1, 25, 10, 0, 0, 0, ist))
+	manager.SetClock(sim)
+	ctx := context.Background()
+
+	future := func(id, side string, quantity int) models.Order {
+		return models.Order{ID: id, Symbol: "NIFTY24JANFUT", Exchange: "NFO", Price: 21500, Quantity: quantity,
+			OrderType: "LIMIT", Side: side, Product: "NRML", InstrumentType: "FUT", LotSize: 50,
+			Expiry: time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC)}
+	}
+
+	// A short sell opens a position, so it is not checked against holdings
+	if _, err := manager.ExecuteOrder(ctx, future("FUT-1", "Sell", 100)); err != nil {
+		t.Fatalf("ExecuteOrder(future): %v", err)
+	}
+	if _, err := manager.ExecuteOrder(ctx, future("FUT-2", "Buy", 75)); !errors.Is(err, ErrOrderRejected) {
+		t.Errorf("ExecuteOrder(75 of a 50 lot) error = %v, want a lot size rejection", err)
+	}
+	cnc := future("FUT-3", "Buy", 50)
+	cnc.Product = "CNC"
+	if _, err := manager.ExecuteOrder(ctx, cnc); !errors.Is(err, derivatives.ErrInvalidProduct) || !errors.Is(err, ErrOrderRejected) {
+		t.Errorf("ExecuteOrder(CNC future) error = %v, want ErrInvalidProduct", err)
+	}
+	sim.Set(time.Date(2024, 1, 25, 15, 5, 0, 0, ist))
+	if _, err := manager.ExecuteOrder(ctx, future("FUT-4", "Buy", 50)); !errors.Is(err, derivatives.ErrExpiryDayCutoff) || !errors.Is(err, ErrOrderRejected) {
+		t.Errorf("ExecuteOrder(after expiry-day cutoff) error = %v, want ErrExpiryDayCutoff", err)
+	}
+
+	orders := server.Orders()
+	if len(orders) != 1 {
+		t.Fatalf("placed %d orders, want only FUT-1", len(orders))
+	}
+	form := orders[0].Form
+	if form.Get("exchange") != "NFO" || form.Get("product") != "NRML" || form.Get("quantity") != "100" {
+		t.Errorf("future placed as %s %s x%s, want NFO NRML x100", form.Get("exchange"), form.Get("product"), form.Get("quantity"))
+	}
+}
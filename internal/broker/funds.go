@@ -0,0 +1,345 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/metrics"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// ErrInsufficientFunds is wrapped by the reasons given for buy orders held back by the funds check
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// ErrMarginUnknown is wrapped by the reasons given for buy orders whose margin could not be estimated
+var ErrMarginUnknown = errors.New("margin unknown")
+
+// ErrFundsUnsupported is returned when the broker cannot report available funds
+var ErrFundsUnsupported = errors.New("broker does not report available funds")
+
+// FundsProvider is implemented by brokers that can report the margin available for new orders
+type FundsProvider interface {
+	Funds(ctx context.Context) (models.Funds, error)
+}
+
+// MarginCalculator is implemented by brokers that can calculate the margin orders would block.
+// Without one, an order's margin is estimated as its price times its quantity.
+type MarginCalculator interface {
+	OrderMargins(ctx context.Context, orders []models.Order) ([]float64, error)
+}
+
+// FundsRejection is a buy order the funds check held back, with the reason
+type FundsRejection struct {
+	Order  models.Order
+	Reason string
+}
+
+// FundsView caches a broker's funds and tracks the margin reserved by buys approved
+// since they were fetched, keyed by order ID so a failed placement can give it back
+type FundsView struct {
+	provider        FundsProvider
+	refreshInterval time.Duration
+	clock           clock.Clock
+	mu              sync.Mutex
+	funds           models.Funds
+	fetchedAt       time.Time
+	reserved        map[string]float64
+}
+
+// NewFundsView creates a view that fetches from provider at most once per refreshInterval
+func NewFundsView(provider FundsProvider, refreshInterval time.Duration) *FundsView {
+	return &FundsView{
+		provider:        provider,
+		refreshInterval: refreshInterval,
+		clock:           clock.Real{},
+		reserved:        make(map[string]float64),
+	}
+}
+
+// SetClock replaces the clock used to age the cached funds
+func (v *FundsView) SetClock(c clock.Clock) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.clock = c
+}
+
+// Get returns the cached funds, fetching them first if they are stale or refresh is set
+func (v *FundsView) Get(ctx context.Context, refresh bool) (models.Funds, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.ensureFresh(ctx, refresh); err != nil {
+		return models.Funds{}, err
+	}
+	return v.funds, nil
+}
+
+// Release returns the margin reserved for an order that was not placed
+func (v *FundsView) Release(orderID string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.reserved, orderID)
+}
+
+// allocate fetches funds if needed and runs decide with what is still available, reserving
+// the margin of every order decide approves
+func (v *FundsView) allocate(ctx context.Context, decide func(available float64) map[string]float64) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.ensureFresh(ctx, false); err != nil {
+		return err
+	}
+
+	available := v.funds.Available
+	for _, margin := range v.reserved {
+		available -= margin
+	}
+	for orderID, margin := range decide(available) {
+		v.reserved[orderID] = margin
+	}
+	return nil
+}
+
+// ensureFresh fetches funds if needed; callers must hold v.mu. A fetch replaces the
+// reservations because the broker's available margin now accounts for the placed buys.
+func (v *FundsView) ensureFresh(ctx context.Context, force bool) error {
+	now := v.clock.Now()
+	if !force && !v.fetchedAt.IsZero() && now.Sub(v.fetchedAt) < v.refreshInterval {
+		return nil
+	}
+	funds, err := v.provider.Funds(ctx)
+	if err != nil {
+		return err
+	}
+	v.funds = funds
+	v.fetchedAt = now
+	v.reserved = make(map[string]float64)
+	metrics.AvailableFunds.Set(funds.Available)
+	return nil
+}
+
+// Funds returns the broker's cached funds, fetching them if stale or refresh is set
+func (bm *BrokerManager) Funds(ctx context.Context, refresh bool) (models.Funds, error) {
+	if bm.funds == nil {
+		return models.Funds{}, ErrFundsUnsupported
+	}
+	return bm.funds.Get(ctx, refresh)
+}
+
+// CheckBuys applies the buy funds policy to a batch of due orders. It returns the orders to
+// execute, in their original order and with scaled quantities where the policy scaled them,
+// and the buys held back. Buys go out unchecked when no policy is configured, the broker
+// cannot report funds or they cannot be fetched.
+func (bm *BrokerManager) CheckBuys(ctx context.Context, orders []models.Order) ([]models.Order, []FundsRejection) {
+	policy := bm.config.Broker.Funds.BuyPolicy
+	if policy == "" || policy == config.FundsPolicyOff {
+		return orders, nil
+	}
+
+	var buys []int
+	for i, order := range orders {
+		if strings.EqualFold(order.Side, "BUY") {
+			buys = append(buys, i)
+		}
+	}
+	if len(buys) == 0 {
+		return orders, nil
+	}
+	if bm.funds == nil {
+		metrics.BuyOrdersChecked.WithLabelValues("unchecked").Add(float64(len(buys)))
+		return orders, nil
+	}
+
+	batch := make([]models.Order, len(buys))
+	for i, index := range buys {
+		batch[i] = orders[index]
+	}
+	margins := bm.orderMargins(ctx, batch)
+
+	var decisions []fundsDecision
+	err := bm.funds.allocate(ctx, func(available float64) map[string]float64 {
+		decisions = decideBuys(policy, batch, margins, available)
+		reserved := make(map[string]float64)
+		for _, d := range decisions {
+			if d.reason == "" {
+				reserved[d.order.ID] = d.margin
+			}
+		}
+		return reserved
+	})
+	if err != nil {
+		metrics.BuyOrdersChecked.WithLabelValues("unchecked").Add(float64(len(buys)))
+		bm.logger.Warn("⚠️  Could not fetch funds, sending %d buy orders unchecked: %v", len(buys), err)
+		return orders, nil
+	}
+
+	approved := append([]models.Order(nil), orders...)
+	dropped := make(map[int]bool)
+	var rejections []FundsRejection
+	for i, d := range decisions {
+		index := buys[i]
+		switch {
+		case d.reason != "":
+			dropped[index] = true
+			rejections = append(rejections, FundsRejection{Order: d.order, Reason: d.reason})
+			metrics.BuyOrdersChecked.WithLabelValues("rejected").Inc()
+		case d.order.Quantity != orders[index].Quantity:
+			bm.logger.Warn("📉 Scaling buy order %s from %d to %d %s to fit available funds",
+				d.order.ID, orders[index].Quantity, d.order.Quantity, d.order.Symbol)
+			approved[index] = d.order
+			metrics.BuyOrdersChecked.WithLabelValues("scaled").Inc()
+		default:
+			metrics.BuyOrdersChecked.WithLabelValues("allowed").Inc()
+		}
+	}
+
+	result := approved[:0]
+	for i, order := range approved {
+		if !dropped[i] {
+			result = append(result, order)
+		}
+	}
+	return result, rejections
+}
+
+// orderMargins asks the broker for the margin each order would block, falling back to
+// price times quantity when it cannot say. Orders still without a margin, such as market
+// orders, are estimated from their last price; those that cannot be are left at 0.
+func (bm *BrokerManager) orderMargins(ctx context.Context, orders []models.Order) []float64 {
+	var margins []float64
+	if calculator, ok := bm.broker.(MarginCalculator); ok {
+		var err error
+		if margins, err = calculator.OrderMargins(ctx, orders); err != nil {
+			bm.logger.Warn("⚠️  Order margin calculation failed, estimating from price × quantity: %v", err)
+			margins = nil
+		}
+	}
+	if margins == nil {
+		margins = make([]float64, len(orders))
+		for i, order := range orders {
+			margins[i] = order.Price * float64(order.Quantity)
+		}
+	}
+
+	var unpriced []string
+	for i, order := range orders {
+		if margins[i] <= 0 {
+			unpriced = append(unpriced, QuoteKey(order))
+		}
+	}
+	if len(unpriced) == 0 {
+		return margins
+	}
+	lastPrices, err := bm.Quotes(ctx, unpriced)
+	if err != nil {
+		bm.logger.Warn("⚠️  Could not fetch last prices for %d buy orders without a price: %v", len(unpriced), err)
+	}
+	for i, order := range orders {
+		if margins[i] > 0 {
+			continue
+		}
+		if quote, ok := lastPrices[QuoteKey(order)]; ok && quote.LastPrice > 0 {
+			margins[i] = quote.LastPrice * float64(order.Quantity)
+			bm.logger.Info("💹 Estimating margin of buy order %s from last price %.2f: %.2f", order.ID, quote.LastPrice, margins[i])
+		} else {
+			bm.logger.Warn("💸 Buy order %s has no price or last price, holding it back", order.ID)
+		}
+	}
+	return margins
+}
+
+// fundsDecision is the outcome of the funds check for one buy
+type fundsDecision struct {
+	order  models.Order // With its quantity scaled, if it was
+	margin float64      // Margin reserved for the order as placed
+	reason string       // Why the order was held back; empty if approved
+}
+
+// decideBuys applies policy to buys given the funds available. Buys without a margin cannot
+// be checked and are held back under every policy.
+func decideBuys(policy string, buys []models.Order, margins []float64, available float64) []fundsDecision {
+	decisions := make([]fundsDecision, len(buys))
+	total := 0.0
+	for i, order := range buys {
+		decisions[i] = fundsDecision{order: order, margin: margins[i]}
+		if margins[i] <= 0 {
+			decisions[i].reason = fmt.Sprintf("%v: no price or last price for %s", ErrMarginUnknown, order.Symbol)
+			continue
+		}
+		total += margins[i]
+	}
+	if total <= available {
+		return decisions
+	}
+
+	switch policy {
+	case config.FundsPolicyReject:
+		for i := range decisions {
+			if decisions[i].reason == "" {
+				decisions[i].reason = fmt.Sprintf("%v: %d buy orders due together need %.2f, available %.2f",
+					ErrInsufficientFunds, len(buys), total, math.Max(available, 0))
+			}
+		}
+
+	case config.FundsPolicyScale:
+		factor := math.Max(available, 0) / total
+		for i := range decisions {
+			d := &decisions[i]
+			if d.reason != "" {
+				continue
+			}
+			quantity := int(float64(d.order.Quantity) * factor)
+			if d.order.LotSize > 1 {
+				quantity = quantity / d.order.LotSize * d.order.LotSize
+			}
+			if quantity <= 0 {
+				d.reason = fmt.Sprintf("%v: scaled to zero to fit %.2f available for %.2f of buys",
+					ErrInsufficientFunds, math.Max(available, 0), total)
+				continue
+			}
+			d.margin = d.margin * float64(quantity) / float64(d.order.Quantity)
+			d.order.Quantity = quantity
+		}
+
+	case config.FundsPolicyPriority:
+		order := make([]int, len(buys))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			x, y := buys[order[a]], buys[order[b]]
+			if !x.ScheduledTime.Equal(y.ScheduledTime) {
+				return x.ScheduledTime.Before(y.ScheduledTime)
+			}
+			return sourceRank(x) < sourceRank(y)
+		})
+		remaining := available
+		for _, i := range order {
+			d := &decisions[i]
+			if d.reason != "" {
+				continue
+			}
+			if d.margin <= remaining {
+				remaining -= d.margin
+				continue
+			}
+			d.reason = fmt.Sprintf("%v: needs %.2f, %.2f left after earlier rows", ErrInsufficientFunds, d.margin, math.Max(remaining, 0))
+		}
+	}
+	return decisions
+}
+
+// sourceRank orders buys by source row; orders without one (manual orders) come last
+func sourceRank(order models.Order) int {
+	if order.SourceRow <= 0 {
+		return math.MaxInt
+	}
+	return order.SourceRow
+}
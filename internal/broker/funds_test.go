@@ -0,0 +1,157 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/broker/kitetest"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+func TestDecideBuys(t *testing.T) {
+	at := time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)
+	buys := []models.Order{
+		{ID: "ROW-5", Quantity: 10, ScheduledTime: at, SourceRow: 5},
+		{ID: "ROW-3", Quantity: 10, ScheduledTime: at, SourceRow: 3},
+		{ID: "MANUAL", Quantity: 4, ScheduledTime: at},
+	}
+	margins := []float64{1000, 1000, 400}
+
+	tests := []struct {
+		policy     string
+		available  float64
+		quantities []int // 0 = held back
+	}{
+		{config.FundsPolicyReject, 5000, []int{10, 10, 4}},
+		{config.FundsPolicyReject, 2000, []int{0, 0, 0}},
+		{config.FundsPolicyScale, 1200, []int{5, 5, 2}},
+		{config.FundsPolicyScale, 50, []int{0, 0, 0}},
+		{config.FundsPolicyPriority, 1500, []int{0, 10, 4}},
+		{config.FundsPolicyPriority, 2100, []int{10, 10, 0}},
+	}
+	for _, tt := range tests {
+		decisions := decideBuys(tt.policy, buys, margins, tt.available)
+		for i, d := range decisions {
+			got := d.order.Quantity
+			if d.reason != "" {
+				got = 0
+				if !strings.Contains(d.reason, ErrInsufficientFunds.Error()) {
+					t.Errorf("%s/%.0f: reason %q does not mention insufficient funds", tt.policy, tt.available, d.reason)
+				}
+			}
+			if got != tt.quantities[i] {
+				t.Errorf("%s/%.0f: %s quantity = %d, want %d (%s)", tt.policy, tt.available, d.order.ID, got, tt.quantities[i], d.reason)
+			}
+		}
+	}
+}
+
+func TestBrokerManagerCheckBuysWithKiteMargins(t *testing.T) {
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+	server.SetMargin(25000)
+
+	cfg := &config.Config{}
+	server.Configure(cfg)
+	cfg.Broker.RateLimit = config.RateLimitConfig{RequestsPerSecond: 100, BurstSize: 100}
+	cfg.Broker.Funds = config.FundsConfig{BuyPolicy: config.FundsPolicyPriority, RefreshInterval: time.Minute}
+	manager, err := NewBrokerManager(cfg, newTestLogger(t))
+	if err != nil {
+		t.Fatalf("NewBrokerManager: %v", err)
+	}
+	ctx := context.Background()
+
+	funds, err := manager.Funds(ctx, false)
+	if err != nil || funds.Available != 25000 {
+		t.Fatalf("Funds = %+v, %v; want 25000 available", funds, err)
+	}
+
+	// Each INFY buy needs 10 × 1500.50; only the first fits
+	first, second := kiteOrder(false), kiteOrder(false)
+	first.ID, first.SourceRow = "B-1", 3
+	second.ID, second.SourceRow = "B-2", 4
+	sell := sellOrder("S-1", 5)
+	approved, rejected := manager.CheckBuys(ctx, []models.Order{second, sell, first})
+	if len(approved) != 2 || approved[0].ID != "S-1" || approved[1].ID != "B-1" {
+		t.Fatalf("approved = %+v, want the sell and B-1 in batch order", approved)
+	}
+	if len(rejected) != 1 || rejected[0].Order.ID != "B-2" {
+		t.Fatalf("rejected = %+v, want B-2", rejected)
+	}
+	if got := server.Requests(kitetest.PathOrderMargins); got != 1 {
+		t.Errorf("order margin requests = %d, want 1 per batch", got)
+	}
+
+	// B-1 keeps its margin reserved until funds are fetched again, unless it fails to place
+	if _, rejected := manager.CheckBuys(ctx, []models.Order{second}); len(rejected) != 1 {
+		t.Fatalf("B-2 approved while B-1 still holds its margin")
+	}
+	server.FailNext(kitetest.PathRegularOrder, kitetest.Failure{Status: 500, ErrorType: "GeneralException", Message: "down"})
+	if _, err := manager.ExecuteOrder(ctx, first); err == nil {
+		t.Fatal("expected the queued broker failure")
+	}
+	if approved, rejected := manager.CheckBuys(ctx, []models.Order{second}); len(approved) != 1 || len(rejected) != 0 {
+		t.Errorf("B-2 = %v approved, %v rejected; want approved once B-1's margin was released", approved, rejected)
+	}
+	if got := server.Requests(kitetest.PathMargins); got != 1 {
+		t.Errorf("margins fetched %d times, want 1 within the refresh interval", got)
+	}
+}
+
+func TestBrokerManagerCheckBuysEstimatesMarketOrders(t *testing.T) {
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+	server.SetMargin(25000)
+	server.SetQuote("NSE", "INFY", 1500)
+
+	cfg := &config.Config{}
+	server.Configure(cfg)
+	cfg.Broker.RateLimit = config.RateLimitConfig{RequestsPerSecond: 100, BurstSize: 100}
+	cfg.Broker.Funds = config.FundsConfig{BuyPolicy: config.FundsPolicyPriority, RefreshInterval: time.Minute}
+	manager, err := NewBrokerManager(cfg, newTestLogger(t))
+	if err != nil {
+		t.Fatalf("NewBrokerManager: %v", err)
+	}
+
+	// With the margin calculation down, market buys fall back to their last price
+	server.FailNext(kitetest.PathOrderMargins, kitetest.Failure{Status: 500, ErrorType: "GeneralException", Message: "down"})
+	market := func(id, symbol string, row int) models.Order {
+		order := kiteOrder(false)
+		order.ID, order.Symbol, order.SourceRow = id, symbol, row
+		order.OrderType, order.Price = "MARKET", 0
+		return order
+	}
+	approved, rejected := manager.CheckBuys(context.Background(), []models.Order{
+		market("M-1", "INFY", 3), market("M-2", "INFY", 4), market("M-3", "TCS", 5),
+	})
+	if len(approved) != 1 || approved[0].ID != "M-1" {
+		t.Fatalf("approved = %+v, want only M-1 within 25000 at the last price of 1500", approved)
+	}
+	reasons := make(map[string]string)
+	for _, r := range rejected {
+		reasons[r.Order.ID] = r.Reason
+	}
+	if !strings.Contains(reasons["M-2"], ErrInsufficientFunds.Error()) || !strings.Contains(reasons["M-3"], ErrMarginUnknown.Error()) {
+		t.Errorf("rejections = %v, want M-2 unfunded and M-3 held back without a last price", reasons)
+	}
+}
+
+func TestBrokerManagerCheckBuysUncheckedWithoutFunds(t *testing.T) {
+	cfg := &config.Config{Broker: config.BrokerConfig{Type: "mock"}}
+	cfg.Broker.Funds.BuyPolicy = config.FundsPolicyReject
+	manager, err := NewBrokerManager(cfg, newTestLogger(t))
+	if err != nil {
+		t.Fatalf("NewBrokerManager: %v", err)
+	}
+	approved, rejected := manager.CheckBuys(context.Background(), []models.Order{kiteOrder(false)})
+	if len(approved) != 1 || len(rejected) != 0 {
+		t.Errorf("CheckBuys on a broker without funds = %v, %v; want the buy passed through", approved, rejected)
+	}
+	if _, err := manager.Funds(context.Background(), false); !errors.Is(err, ErrFundsUnsupported) {
+		t.Errorf("Funds error = %v, want ErrFundsUnsupported", err)
+	}
+}
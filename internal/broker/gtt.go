@@ -0,0 +1,250 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// ErrGTTUnsupported is returned when the broker cannot hold GTT triggers
+var ErrGTTUnsupported = errors.New("broker does not support GTT triggers")
+
+// GTTProvider is implemented by brokers that can hold Good Till Triggered orders. Place and
+// Modify take the order to send when the trigger fires; its GTT gives the trigger prices.
+type GTTProvider interface {
+	PlaceGTT(ctx context.Context, order models.Order) (int, error)
+	ModifyGTT(ctx context.Context, triggerID int, order models.Order) error
+	DeleteGTT(ctx context.Context, triggerID int) error
+	GTTs(ctx context.Context) ([]models.GTTTrigger, error)
+}
+
+// kiteGTTCondition is the condition field of a Kite GTT
+type kiteGTTCondition struct {
+	Exchange      string    `json:"exchange"`
+	Tradingsymbol string    `json:"tradingsymbol"`
+	TriggerValues []float64 `json:"trigger_values"`
+	LastPrice     float64   `json:"last_price"`
+}
+
+// kiteGTTOrder is one leg of a Kite GTT
+type kiteGTTOrder struct {
+	Exchange        string  `json:"exchange"`
+	Tradingsymbol   string  `json:"tradingsymbol"`
+	TransactionType string  `json:"transaction_type"`
+	Quantity        int     `json:"quantity"`
+	OrderType       string  `json:"order_type"`
+	Product         string  `json:"product"`
+	Price           float64 `json:"price"`
+	Result          *struct {
+		OrderResult struct {
+			OrderID         string `json:"order_id"`
+			RejectionReason string `json:"rejection_reason"`
+		} `json:"order_result"`
+	} `json:"result,omitempty"`
+}
+
+// kiteGTT is one entry of the Kite /gtt/triggers response
+type kiteGTT struct {
+	ID        int              `json:"id"`
+	Type      string           `json:"type"`
+	Status    string           `json:"status"`
+	Condition kiteGTTCondition `json:"condition"`
+	Orders    []kiteGTTOrder   `json:"orders"`
+	UpdatedAt string           `json:"updated_at"`
+	ExpiresAt string           `json:"expires_at"`
+}
+
+// PlaceGTT creates a Kite GTT that places order when its trigger price is reached
+func (k *KiteBroker) PlaceGTT(ctx context.Context, order models.Order) (int, error) {
+	form, err := k.gttForm(ctx, order)
+	if err != nil {
+		return 0, err
+	}
+	var response struct {
+		TriggerID int `json:"trigger_id"`
+	}
+	if err := k.call(ctx, "POST", "/gtt/triggers", form, &response); err != nil {
+		return 0, fmt.Errorf("failed to place GTT: %w", err)
+	}
+	return response.TriggerID, nil
+}
+
+// ModifyGTT replaces the trigger prices and order of an active Kite GTT
+func (k *KiteBroker) ModifyGTT(ctx context.Context, triggerID int, order models.Order) error {
+	form, err := k.gttForm(ctx, order)
+	if err != nil {
+		return err
+	}
+	var response struct {
+		TriggerID int `json:"trigger_id"`
+	}
+	if err := k.call(ctx, "PUT", fmt.Sprintf("/gtt/triggers/%d", triggerID), form, &response); err != nil {
+		return fmt.Errorf("failed to modify GTT %d: %w", triggerID, err)
+	}
+	return nil
+}
+
+// DeleteGTT deletes a Kite GTT
+func (k *KiteBroker) DeleteGTT(ctx context.Context, triggerID int) error {
+	var response struct {
+		TriggerID int `json:"trigger_id"`
+	}
+	if err := k.call(ctx, "DELETE", fmt.Sprintf("/gtt/triggers/%d", triggerID), nil, &response); err != nil {
+		return fmt.Errorf("failed to delete GTT %d: %w", triggerID, err)
+	}
+	return nil
+}
+
+// GTTs lists the account's Kite GTTs, including ones that triggered or were deleted recently
+func (k *KiteBroker) GTTs(ctx context.Context) ([]models.GTTTrigger, error) {
+	var response []kiteGTT
+	if err := k.getData(ctx, "/gtt/triggers", &response); err != nil {
+		return nil, fmt.Errorf("failed to list GTTs: %w", err)
+	}
+
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		ist = time.UTC
+	}
+	triggers := make([]models.GTTTrigger, 0, len(response))
+	for _, gtt := range response {
+		trigger := models.GTTTrigger{
+			ID:            gtt.ID,
+			Type:          gtt.Type,
+			Status:        gtt.Status,
+			Exchange:      gtt.Condition.Exchange,
+			Symbol:        gtt.Condition.Tradingsymbol,
+			TriggerValues: gtt.Condition.TriggerValues,
+		}
+		// Timestamps are IST without a zone
+		trigger.UpdatedAt, _ = time.ParseInLocation("2006-01-02 15:04:05", gtt.UpdatedAt, ist)
+		trigger.ExpiresAt, _ = time.ParseInLocation("2006-01-02 15:04:05", gtt.ExpiresAt, ist)
+		// Of a two-leg GTT only the leg that fired has a result
+		for _, leg := range gtt.Orders {
+			if leg.Result != nil {
+				trigger.OrderID = leg.Result.OrderResult.OrderID
+				trigger.RejectionReason = leg.Result.OrderResult.RejectionReason
+			}
+		}
+		triggers = append(triggers, trigger)
+	}
+	return triggers, nil
+}
+
+// gttForm builds the form Kite takes to place or modify a GTT for order: a limit order per
+// trigger price, and the instrument's last price, which Kite needs to tell which way each
+// trigger is crossed
+func (k *KiteBroker) gttForm(ctx context.Context, order models.Order) (url.Values, error) {
+	if order.GTT == nil {
+		return nil, fmt.Errorf("order %s is not a GTT", order.ID)
+	}
+	kiteOrder := k.orderRequest(order)
+	instrument := kiteOrder.Exchange + ":" + kiteOrder.Tradingsymbol
+	quotes, err := k.Quotes(ctx, []string{instrument})
+	if err != nil {
+		return nil, err
+	}
+	quote, ok := quotes[instrument]
+	if !ok {
+		return nil, fmt.Errorf("no quote for %s", instrument)
+	}
+
+	triggerValues := order.GTT.TriggerValues(order.Price)
+	legs := make([]kiteGTTOrder, 0, len(triggerValues))
+	for _, price := range triggerValues {
+		legs = append(legs, kiteGTTOrder{
+			Exchange:        kiteOrder.Exchange,
+			Tradingsymbol:   kiteOrder.Tradingsymbol,
+			TransactionType: kiteOrder.TransactionType,
+			Quantity:        kiteOrder.Quantity,
+			OrderType:       "LIMIT",
+			Product:         kiteOrder.Product,
+			Price:           price,
+		})
+	}
+	condition, err := json.Marshal(kiteGTTCondition{
+		Exchange:      kiteOrder.Exchange,
+		Tradingsymbol: kiteOrder.Tradingsymbol,
+		TriggerValues: triggerValues,
+		LastPrice:     quote.LastPrice,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode GTT condition: %w", err)
+	}
+	orders, err := json.Marshal(legs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode GTT orders: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("type", order.GTT.Type)
+	form.Set("condition", string(condition))
+	form.Set("orders", string(orders))
+	return form, nil
+}
+
+// gttProvider returns the broker's GTT support, or ErrGTTUnsupported
+func (bm *BrokerManager) gttProvider() (GTTProvider, error) {
+	provider, ok := bm.broker.(GTTProvider)
+	if !ok {
+		return nil, ErrGTTUnsupported
+	}
+	return provider, nil
+}
+
+// PlaceGTT leaves a GTT for order at the broker and returns its trigger ID. Futures and
+// options orders the exchange would refuse are held back as they are for regular orders.
+func (bm *BrokerManager) PlaceGTT(ctx context.Context, order models.Order) (int, error) {
+	provider, err := bm.gttProvider()
+	if err != nil {
+		return 0, err
+	}
+	if err := bm.checkContract(order); err != nil {
+		return 0, err
+	}
+	if err := bm.rateLimit.Wait(ctx); err != nil {
+		return 0, fmt.Errorf("rate limit wait failed: %w", err)
+	}
+	return provider.PlaceGTT(ctx, order)
+}
+
+// ModifyGTT changes the trigger prices and order of an active GTT
+func (bm *BrokerManager) ModifyGTT(ctx context.Context, triggerID int, order models.Order) error {
+	provider, err := bm.gttProvider()
+	if err != nil {
+		return err
+	}
+	if err := bm.checkContract(order); err != nil {
+		return err
+	}
+	if err := bm.rateLimit.Wait(ctx); err != nil {
+		return fmt.Errorf("rate limit wait failed: %w", err)
+	}
+	return provider.ModifyGTT(ctx, triggerID, order)
+}
+
+// DeleteGTT deletes a GTT at the broker
+func (bm *BrokerManager) DeleteGTT(ctx context.Context, triggerID int) error {
+	provider, err := bm.gttProvider()
+	if err != nil {
+		return err
+	}
+	if err := bm.rateLimit.Wait(ctx); err != nil {
+		return fmt.Errorf("rate limit wait failed: %w", err)
+	}
+	return provider.DeleteGTT(ctx, triggerID)
+}
+
+// GTTs lists the GTTs held at the broker
+func (bm *BrokerManager) GTTs(ctx context.Context) ([]models.GTTTrigger, error) {
+	provider, err := bm.gttProvider()
+	if err != nil {
+		return nil, err
+	}
+	return provider.GTTs(ctx)
+}
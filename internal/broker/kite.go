@@ -8,15 +8,24 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/mach_five/trading-system/internal/clock"
 	"github.com/mach_five/trading-system/internal/config"
 	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/metrics"
 	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// DefaultKiteAPIURL is the Kite Connect API host used when no api_url is configured
+const DefaultKiteAPIURL = "https://api.kite.trade"
+
 // KiteBroker implements broker interface for Zerodha Kite Connect API
 type KiteBroker struct {
 	config        *config.Config
@@ -24,9 +33,11 @@ type KiteBroker struct {
 	apiKey        string
 	accessToken   string
 	refreshToken  string
-	baseURL       string
+	baseURL       string       // Login/session host (token refresh)
+	apiURL        string       // Kite Connect API host (orders, profile, quotes)
 	httpClient    *http.Client
 	marketHours   *MarketHours
+	clock         clock.Clock  // Timestamps execution results
 	tokenMutex    sync.RWMutex // Protects accessToken and refreshToken
 	tokenExpiry   time.Time    // When the current access token expires
 }
@@ -76,6 +87,10 @@ func NewKiteBroker(cfg *config.Config, log *logger.Logger) (*KiteBroker, error)
 	if baseURL == "" {
 		baseURL = "https://kite.zerodha.com" // Default to production
 	}
+	apiURL := strings.TrimRight(cfg.Broker.APIURL, "/")
+	if apiURL == "" {
+		apiURL = DefaultKiteAPIURL
+	}
 
 	// Configure HTTP client with connection pooling for high-frequency requests (1ms polling)
 	transport := &http.Transport{
@@ -92,11 +107,13 @@ func NewKiteBroker(cfg *config.Config, log *logger.Logger) (*KiteBroker, error)
 		accessToken:  cfg.Broker.APISecret, // Access token stored in APISecret field
 		refreshToken: cfg.Broker.RefreshToken,
 		baseURL:      baseURL,
+		apiURL:       apiURL,
 		httpClient: &http.Client{
 			Transport: transport,
 			Timeout:   30 * time.Second,
 		},
 		marketHours: NewMarketHours(),
+		clock:       clock.Real{},
 		tokenExpiry:  time.Now().Add(24 * time.Hour), // Default expiry (not used for auto-refresh)
 	}
 
@@ -112,16 +129,9 @@ func (k *KiteBroker) ExecuteOrder(ctx context.Context, order models.Order) (mode
 	// Use the AMO decision made at read time
 	useAMO := order.IsAMO
 
-	// Use exchange from order, or parse from symbol if not set
-	var exchange, tradingsymbol string
-	if order.Exchange != "" {
-		// Use exchange from order
-		exchange = order.Exchange
-		tradingsymbol = strings.ToUpper(order.Symbol)
-	} else {
-		// Fallback to parsing from symbol format (for backward compatibility)
-		exchange, tradingsymbol = k.parseSymbol(order.Symbol)
-	}
+	// Build the order request (exchange, side, type, product and variety)
+	kiteOrder := k.orderRequest(order)
+	exchange := kiteOrder.Exchange
 
 	// Optimized logging - only log essential info, detailed logs only on DEBUG
 	if useAMO {
@@ -141,41 +151,6 @@ func (k *KiteBroker) ExecuteOrder(ctx context.Context, order models.Order) (mode
 		}
 	}
 	
-	// Map order side
-	transactionType := "BUY"
-	if strings.ToUpper(order.Side) == "SELL" {
-		transactionType = "SELL"
-	}
-
-	// Map order type
-	orderType := "MARKET"
-	if strings.ToUpper(order.OrderType) == "LIMIT" {
-		orderType = "LIMIT"
-	}
-
-	// Build order request
-	kiteOrder := KiteOrderRequest{
-		Exchange:        exchange,
-		Tradingsymbol:   tradingsymbol,
-		TransactionType: transactionType,
-		OrderType:       orderType,
-		Quantity:        order.Quantity,
-		Product:         "CNC", // CNC (Cash and Carry) for delivery-based trades
-		Validity:        "DAY", // Default to DAY, can be configured
-	}
-
-	// Set variety to "amo" for After Market Orders
-	if useAMO {
-		kiteOrder.Variety = "amo"
-	} else {
-		kiteOrder.Variety = "regular"
-	}
-
-	// Add price for LIMIT orders
-	if orderType == "LIMIT" {
-		kiteOrder.Price = order.Price
-	}
-
 	// Make API request (use AMO endpoint if market is closed)
 	var result *KiteOrderResponse
 	var err error
@@ -190,7 +165,7 @@ func (k *KiteBroker) ExecuteOrder(ctx context.Context, order models.Order) (mode
 		return models.ExecutionResult{
 			OrderID:      order.ID,
 			Success:      false,
-			ExecutedAt:   time.Now(),
+			ExecutedAt:   k.clock.Now(),
 			ErrorMessage: err.Error(),
 		}, err
 	}
@@ -204,7 +179,7 @@ func (k *KiteBroker) ExecuteOrder(ctx context.Context, order models.Order) (mode
 		return models.ExecutionResult{
 			OrderID:      order.ID,
 			Success:      false,
-			ExecutedAt:   time.Now(),
+			ExecutedAt:   k.clock.Now(),
 			ErrorMessage: errorMsg,
 		}, fmt.Errorf("kite order failed: %s", errorMsg)
 	}
@@ -223,12 +198,64 @@ func (k *KiteBroker) ExecuteOrder(ctx context.Context, order models.Order) (mode
 		OrderID:        order.ID,
 		Success:        true,
 		ExecutionID:    result.Data.OrderID,
-		ExecutedAt:     time.Now(),
+		ExecutedAt:     k.clock.Now(),
 		ExecutedPrice:  order.Price, // For MARKET orders, this will be filled by Kite
 		ExecutedQuantity: order.Quantity,
 	}, nil
 }
 
+// orderRequest maps an order to the Kite order request used for placement and margin calculation
+func (k *KiteBroker) orderRequest(order models.Order) KiteOrderRequest {
+	// Use exchange from order, or parse from symbol if not set
+	var exchange, tradingsymbol string
+	if order.Exchange != "" {
+		exchange = order.Exchange
+		tradingsymbol = strings.ToUpper(order.Symbol)
+	} else {
+		// Fallback to parsing from symbol format (for backward compatibility)
+		exchange, tradingsymbol = k.parseSymbol(order.Symbol)
+	}
+
+	// Map order side
+	transactionType := "BUY"
+	if strings.ToUpper(order.Side) == "SELL" {
+		transactionType = "SELL"
+	}
+
+	// Map order type
+	orderType := "MARKET"
+	if strings.ToUpper(order.OrderType) == "LIMIT" {
+		orderType = "LIMIT"
+	}
+
+	kiteOrder := KiteOrderRequest{
+		Exchange:        exchange,
+		Tradingsymbol:   tradingsymbol,
+		TransactionType: transactionType,
+		OrderType:       orderType,
+		Variety:         "regular",
+		Quantity:        order.Quantity,
+		Product:         "CNC", // CNC (Cash and Carry) for delivery-based trades unless the order says otherwise
+		Validity:        "DAY", // Default to DAY, can be configured
+	}
+
+	// Intraday and futures and options orders carry their own product (MIS or NRML)
+	if order.Product != "" {
+		kiteOrder.Product = strings.ToUpper(order.Product)
+	}
+
+	// Set variety to "amo" for After Market Orders
+	if order.IsAMO {
+		kiteOrder.Variety = "amo"
+	}
+
+	// Add price for LIMIT orders
+	if orderType == "LIMIT" {
+		kiteOrder.Price = order.Price
+	}
+	return kiteOrder
+}
+
 // placeOrder places an order via Kite Connect API
 func (k *KiteBroker) placeOrder(ctx context.Context, orderReq KiteOrderRequest) (*KiteOrderResponse, error) {
 	// Safety check: AMO orders should use placeAMOOrder, not placeOrder
@@ -237,8 +264,11 @@ func (k *KiteBroker) placeOrder(ctx context.Context, orderReq KiteOrderRequest)
 		return k.placeAMOOrder(ctx, orderReq)
 	}
 
-	// Use api.kite.trade for regular orders (works with form-urlencoded)
-	apiURL := "https://api.kite.trade/orders/regular"
+	// Regular orders are posted form-urlencoded to the API host
+	apiURL := k.apiURL + "/orders/regular"
+
+	ctx, span := k.startHTTPSpan(ctx, "POST", apiURL, "regular")
+	defer span.End()
 
 	// Build form-urlencoded request body
 	formData := url.Values{}
@@ -258,7 +288,7 @@ func (k *KiteBroker) placeOrder(ctx context.Context, orderReq KiteOrderRequest)
 	
 	// Add price only for LIMIT orders
 	if orderReq.OrderType == "LIMIT" && orderReq.Price > 0 {
-		formData.Set("price", fmt.Sprintf("%.2f", orderReq.Price))
+		formData.Set("price", formatPrice(orderReq.Price))
 	}
 
 	body := []byte(formData.Encode())
@@ -290,12 +320,15 @@ func (k *KiteBroker) placeOrder(ctx context.Context, orderReq KiteOrderRequest)
 	// Make request (optimized - no logging in hot path)
 	resp, err := k.httpClient.Do(req)
 	if err != nil {
+		metrics.ObserveBrokerError("kite", 0)
+		tracing.RecordError(span, err)
 		k.logger.Error("❌ Network error during order placement: %v", err)
 		k.logger.Error("   URL: %s", apiURL)
 		k.logger.Error("   Request Body: %s", formData.Encode())
 		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
 
 	// Read response
 	respBody, err := io.ReadAll(resp.Body)
@@ -306,6 +339,7 @@ func (k *KiteBroker) placeOrder(ctx context.Context, orderReq KiteOrderRequest)
 	// Check HTTP status
 	if resp.StatusCode != http.StatusOK {
 		errorMsg := string(respBody)
+		metrics.ObserveBrokerError("kite", resp.StatusCode)
 		
 		// Log auth errors - token refresh is disabled, must be updated manually
 		if resp.StatusCode == 401 || resp.StatusCode == 403 {
@@ -318,7 +352,12 @@ func (k *KiteBroker) placeOrder(ctx context.Context, orderReq KiteOrderRequest)
 		k.logger.Error("   URL: %s", apiURL)
 		k.logger.Error("   Request Body: %s", formData.Encode())
 		k.logger.Error("   Response: %s", errorMsg)
-		return nil, fmt.Errorf("kite API returned status %d: %s", resp.StatusCode, errorMsg)
+		err := fmt.Errorf("kite API returned status %d: %s", resp.StatusCode, errorMsg)
+		if resp.StatusCode == 401 || resp.StatusCode == 403 {
+			err = fmt.Errorf("%w: %v", ErrTokenRejected, err)
+		}
+		tracing.RecordError(span, err)
+		return nil, err
 	}
 
 	// Parse response
@@ -332,9 +371,11 @@ func (k *KiteBroker) placeOrder(ctx context.Context, orderReq KiteOrderRequest)
 
 // placeAMOOrder places an After Market Order via Kite Connect API
 func (k *KiteBroker) placeAMOOrder(ctx context.Context, orderReq KiteOrderRequest) (*KiteOrderResponse, error) {
-	// Kite AMO orders use the dedicated AMO endpoint at api.kite.trade
-	// Use api.kite.trade for AMO orders (works with form-urlencoded)
-	amoURL := "https://api.kite.trade/orders/amo"
+	// Kite AMO orders use the dedicated AMO endpoint (form-urlencoded)
+	amoURL := k.apiURL + "/orders/amo"
+
+	ctx, span := k.startHTTPSpan(ctx, "POST", amoURL, "amo")
+	defer span.End()
 
 	// Ensure validity is DAY for AMO orders
 	orderReq.Validity = "DAY"
@@ -352,7 +393,7 @@ func (k *KiteBroker) placeAMOOrder(ctx context.Context, orderReq KiteOrderReques
 	
 	// Add price only for LIMIT orders
 	if orderReq.OrderType == "LIMIT" && orderReq.Price > 0 {
-		formData.Set("price", fmt.Sprintf("%.2f", orderReq.Price))
+		formData.Set("price", formatPrice(orderReq.Price))
 	}
 
 	body := []byte(formData.Encode())
@@ -386,12 +427,15 @@ func (k *KiteBroker) placeAMOOrder(ctx context.Context, orderReq KiteOrderReques
 	// Make request (optimized - no logging in hot path)
 	resp, err := k.httpClient.Do(req)
 	if err != nil {
+		metrics.ObserveBrokerError("kite", 0)
+		tracing.RecordError(span, err)
 		k.logger.Error("❌ Network error during AMO order placement: %v", err)
 		k.logger.Error("   URL: %s", amoURL)
 		k.logger.Error("   Request Body: %s", formData.Encode())
 		return nil, fmt.Errorf("failed to execute AMO order request: %w", err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
 
 	// Read response
 	respBody, err := io.ReadAll(resp.Body)
@@ -402,6 +446,7 @@ func (k *KiteBroker) placeAMOOrder(ctx context.Context, orderReq KiteOrderReques
 	// Check HTTP status
 	if resp.StatusCode != http.StatusOK {
 		errorMsg := string(respBody)
+		metrics.ObserveBrokerError("kite", resp.StatusCode)
 		
 		// Log auth errors - token refresh is disabled, must be updated manually
 		if resp.StatusCode == 401 || resp.StatusCode == 403 {
@@ -414,7 +459,12 @@ func (k *KiteBroker) placeAMOOrder(ctx context.Context, orderReq KiteOrderReques
 		k.logger.Error("   URL: %s", amoURL)
 		k.logger.Error("   Request Body: %s", formData.Encode())
 		k.logger.Error("   Response: %s", errorMsg)
-		return nil, fmt.Errorf("kite AMO API returned status %d: %s", resp.StatusCode, errorMsg)
+		err := fmt.Errorf("kite AMO API returned status %d: %s", resp.StatusCode, errorMsg)
+		if resp.StatusCode == 401 || resp.StatusCode == 403 {
+			err = fmt.Errorf("%w: %v", ErrTokenRejected, err)
+		}
+		tracing.RecordError(span, err)
+		return nil, err
 	}
 
 	// Parse response
@@ -426,6 +476,17 @@ func (k *KiteBroker) placeAMOOrder(ctx context.Context, orderReq KiteOrderReques
 	return &kiteResp, nil
 }
 
+// startHTTPSpan starts a client span around a Kite HTTP call
+func (k *KiteBroker) startHTTPSpan(ctx context.Context, method, apiURL, variety string) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(ctx, "kite.place_order",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", method),
+			attribute.String("http.url", apiURL),
+			attribute.String("kite.variety", variety),
+		))
+}
+
 // parseSymbol parses symbol to extract exchange and trading symbol
 // Supports formats: "NSE:RELIANCE", "BSE:RELIANCE", "RELIANCE" (defaults to NSE)
 func (k *KiteBroker) parseSymbol(symbol string) (string, string) {
@@ -438,11 +499,16 @@ func (k *KiteBroker) parseSymbol(symbol string) (string, string) {
 	return "NSE", strings.ToUpper(symbol)
 }
 
+// SetClock replaces the clock used to timestamp execution results
+func (k *KiteBroker) SetClock(c clock.Clock) {
+	k.clock = c
+}
+
 // HealthCheck checks Kite API health
 func (k *KiteBroker) HealthCheck(ctx context.Context) error {
 	// Check user profile as health check
-	// Use api.kite.trade without /oms prefix (tested and working)
-	url := "https://api.kite.trade/user/profile"
+	// Use the API host without /oms prefix (tested and working)
+	url := k.apiURL + "/user/profile"
 	
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -491,88 +557,17 @@ func (k *KiteBroker) HealthCheck(ctx context.Context) error {
 		k.tokenMutex.RUnlock()
 		k.logger.Error("   Access Token: %s (first 10 chars)", tokenPreview)
 		
-		return fmt.Errorf("health check returned status %d: %s", resp.StatusCode, errorDetails)
+		err := fmt.Errorf("health check returned status %d: %s", resp.StatusCode, errorDetails)
+		if resp.StatusCode == 401 || resp.StatusCode == 403 {
+			err = fmt.Errorf("%w: %v", ErrTokenRejected, err)
+		}
+		return err
 	}
 
 	// Health check passed - silently return (only log errors)
 	return nil
 }
 
-// ValidateSymbol validates if a symbol exists in Zerodha
-func (k *KiteBroker) ValidateSymbol(ctx context.Context, exchange, symbol string) (bool, error) {
-	// Use Kite API quote endpoint to validate symbol
-	// Format: https://api.kite.trade/quote/ltp?i=EXCHANGE:SYMBOL
-	// Example: https://api.kite.trade/quote/ltp?i=NSE:RELIANCE
-	
-	// Normalize exchange and symbol
-	exchange = strings.ToUpper(exchange)
-	symbol = strings.ToUpper(symbol)
-	
-	// Build URL with instrument identifier
-	instrumentID := fmt.Sprintf("%s:%s", exchange, symbol)
-	quoteURL := fmt.Sprintf("https://api.kite.trade/quote/ltp?i=%s", url.QueryEscape(instrumentID))
-	
-	req, err := http.NewRequestWithContext(ctx, "GET", quoteURL, nil)
-	if err != nil {
-		return false, fmt.Errorf("failed to create validation request: %w", err)
-	}
-	
-	// Get current access token
-	accessToken, err := k.getAccessToken(ctx)
-	if err != nil {
-		return false, fmt.Errorf("failed to get access token: %w", err)
-	}
-	
-	req.Header.Set("Authorization", fmt.Sprintf("token %s:%s", k.apiKey, accessToken))
-	req.Header.Set("X-Kite-Version", "3")
-	
-	// Make request
-	resp, err := k.httpClient.Do(req)
-	if err != nil {
-		return false, fmt.Errorf("failed to validate symbol: %w", err)
-	}
-	defer resp.Body.Close()
-	
-	// Read response
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return false, fmt.Errorf("failed to read validation response: %w", err)
-	}
-	
-	// Check if symbol is valid (200 OK means symbol exists)
-	if resp.StatusCode == http.StatusOK {
-		// Parse response to ensure it's valid JSON with data
-		var quoteResp map[string]interface{}
-		if err := json.Unmarshal(respBody, &quoteResp); err != nil {
-			return false, fmt.Errorf("invalid response format: %w", err)
-		}
-		
-		// Check if status is success and data exists
-		if status, ok := quoteResp["status"].(string); ok && status == "success" {
-			if data, ok := quoteResp["data"].(map[string]interface{}); ok && len(data) > 0 {
-				return true, nil
-			}
-		}
-		return false, fmt.Errorf("symbol not found in response")
-	}
-	
-	// 404 means symbol doesn't exist
-	if resp.StatusCode == http.StatusNotFound {
-		return false, nil
-	}
-	
-	// 403 means insufficient permissions - treat as validation unavailable (not invalid)
-	// This allows orders through if we can't validate due to permissions
-	if resp.StatusCode == http.StatusForbidden {
-		errorMsg := string(respBody)
-		return true, fmt.Errorf("validation unavailable (insufficient permissions): %s", errorMsg)
-	}
-	
-	// Other errors - treat as validation error but don't fail the order
-	errorMsg := string(respBody)
-	return true, fmt.Errorf("validation failed with status %d: %s", resp.StatusCode, errorMsg)
-}
-
 // refreshAccessToken refreshes the access token using the refresh token
 func (k *KiteBroker) refreshAccessToken(ctx context.Context) error {
 	k.tokenMutex.Lock()
@@ -745,6 +740,16 @@ func (k *KiteBroker) logRequestDetails(req *http.Request, url string, body []byt
 	}
 }
 
+// formatPrice formats a limit price with two decimals, or more when its tick grid needs them
+// (currency derivatives trade in 0.0025 ticks)
+func formatPrice(price float64) string {
+	formatted := strconv.FormatFloat(price, 'f', -1, 64)
+	if _, decimals, ok := strings.Cut(formatted, "."); ok && len(decimals) > 2 {
+		return formatted
+	}
+	return fmt.Sprintf("%.2f", price)
+}
+
 // maskString masks a string showing only first and last few characters
 func maskString(s string, visibleChars int) string {
 	if len(s) <= visibleChars*2 {
@@ -0,0 +1,80 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// kiteSegmentMargins is one segment of the Kite /user/margins response
+type kiteSegmentMargins struct {
+	Enabled  bool    `json:"enabled"`
+	Net      float64 `json:"net"`
+	Utilised struct {
+		Debits float64 `json:"debits"`
+	} `json:"utilised"`
+}
+
+// kiteMarginOrder is one order in a Kite /margins/orders request
+type kiteMarginOrder struct {
+	Exchange        string  `json:"exchange"`
+	Tradingsymbol   string  `json:"tradingsymbol"`
+	TransactionType string  `json:"transaction_type"`
+	Variety         string  `json:"variety"`
+	Product         string  `json:"product"`
+	OrderType       string  `json:"order_type"`
+	Quantity        int     `json:"quantity"`
+	Price           float64 `json:"price"`
+	TriggerPrice    float64 `json:"trigger_price"`
+}
+
+// Funds fetches the equity segment's net margin from Kite
+func (k *KiteBroker) Funds(ctx context.Context) (models.Funds, error) {
+	var margins struct {
+		Equity kiteSegmentMargins `json:"equity"`
+	}
+	if err := k.getData(ctx, "/user/margins", &margins); err != nil {
+		return models.Funds{}, fmt.Errorf("failed to fetch margins: %w", err)
+	}
+	return models.Funds{
+		Available: margins.Equity.Net,
+		Utilised:  margins.Equity.Utilised.Debits,
+		FetchedAt: k.clock.Now(),
+	}, nil
+}
+
+// OrderMargins asks Kite for the margin each order would block, in one request
+func (k *KiteBroker) OrderMargins(ctx context.Context, orders []models.Order) ([]float64, error) {
+	request := make([]kiteMarginOrder, 0, len(orders))
+	for _, order := range orders {
+		kiteOrder := k.orderRequest(order)
+		request = append(request, kiteMarginOrder{
+			Exchange:        kiteOrder.Exchange,
+			Tradingsymbol:   kiteOrder.Tradingsymbol,
+			TransactionType: kiteOrder.TransactionType,
+			Variety:         kiteOrder.Variety,
+			Product:         kiteOrder.Product,
+			OrderType:       kiteOrder.OrderType,
+			Quantity:        kiteOrder.Quantity,
+			Price:           kiteOrder.Price,
+		})
+	}
+
+	var response []struct {
+		Tradingsymbol string  `json:"tradingsymbol"`
+		Total         float64 `json:"total"`
+	}
+	if err := k.call(ctx, "POST", "/margins/orders", request, &response); err != nil {
+		return nil, fmt.Errorf("failed to calculate order margins: %w", err)
+	}
+	if len(response) != len(orders) {
+		return nil, fmt.Errorf("kite returned margins for %d of %d orders", len(response), len(orders))
+	}
+
+	margins := make([]float64, len(response))
+	for i, margin := range response {
+		margins[i] = margin.Total
+	}
+	return margins, nil
+}
@@ -0,0 +1,159 @@
+package broker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// kiteHolding is one entry of the Kite /portfolio/holdings response
+type kiteHolding struct {
+	Tradingsymbol string  `json:"tradingsymbol"`
+	Exchange      string  `json:"exchange"`
+	Quantity      int     `json:"quantity"`
+	T1Quantity    int     `json:"t1_quantity"`
+	UsedQuantity  int     `json:"used_quantity"`
+	AveragePrice  float64 `json:"average_price"`
+	LastPrice     float64 `json:"last_price"`
+}
+
+// kitePosition is one entry of the Kite /portfolio/positions response
+type kitePosition struct {
+	Tradingsymbol string  `json:"tradingsymbol"`
+	Exchange      string  `json:"exchange"`
+	Product       string  `json:"product"`
+	Quantity      int     `json:"quantity"`
+	AveragePrice  float64 `json:"average_price"`
+	LastPrice     float64 `json:"last_price"`
+}
+
+// Portfolio fetches holdings and net positions from Kite
+func (k *KiteBroker) Portfolio(ctx context.Context) (models.Portfolio, error) {
+	var holdings []kiteHolding
+	if err := k.getData(ctx, "/portfolio/holdings", &holdings); err != nil {
+		return models.Portfolio{}, fmt.Errorf("failed to fetch holdings: %w", err)
+	}
+	var positions struct {
+		Net []kitePosition `json:"net"`
+	}
+	if err := k.getData(ctx, "/portfolio/positions", &positions); err != nil {
+		return models.Portfolio{}, fmt.Errorf("failed to fetch positions: %w", err)
+	}
+
+	portfolio := models.Portfolio{FetchedAt: k.clock.Now()}
+	for _, h := range holdings {
+		portfolio.Holdings = append(portfolio.Holdings, models.Holding{
+			Exchange:     h.Exchange,
+			Symbol:       h.Tradingsymbol,
+			Quantity:     h.Quantity,
+			T1Quantity:   h.T1Quantity,
+			UsedQuantity: h.UsedQuantity,
+			AveragePrice: h.AveragePrice,
+			LastPrice:    h.LastPrice,
+		})
+	}
+	for _, p := range positions.Net {
+		portfolio.Positions = append(portfolio.Positions, models.Position{
+			Exchange:     p.Exchange,
+			Symbol:       p.Tradingsymbol,
+			Product:      p.Product,
+			Quantity:     p.Quantity,
+			AveragePrice: p.AveragePrice,
+			LastPrice:    p.LastPrice,
+		})
+	}
+	return portfolio, nil
+}
+
+// getData performs an authenticated GET against the Kite API and decodes the data
+// field of the response envelope into out
+func (k *KiteBroker) getData(ctx context.Context, path string, out interface{}) error {
+	return k.call(ctx, "GET", path, nil, out)
+}
+
+// call performs an authenticated request against the Kite API, sending body form-encoded when
+// it is url.Values and as JSON when it is anything else but nil, and decodes the data field of
+// the response envelope into out
+func (k *KiteBroker) call(ctx context.Context, method, path string, body, out interface{}) error {
+	apiURL := k.apiURL + path
+	ctx, span := tracing.Tracer().Start(ctx, "kite."+strings.ToLower(method),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", method),
+			attribute.String("http.url", apiURL),
+		))
+	defer span.End()
+
+	var reqBody io.Reader
+	contentType := "application/json"
+	if form, ok := body.(url.Values); ok {
+		reqBody = strings.NewReader(form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	} else if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s request: %w", path, err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	accessToken, err := k.getAccessToken(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get access token: %w", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("token %s:%s", k.apiKey, accessToken))
+	req.Header.Set("X-Kite-Version", "3")
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("request to %s failed: %w", path, err)
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", path, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("kite API returned status %d for %s: %s", resp.StatusCode, path, string(respBody))
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			err = fmt.Errorf("%w: %v", ErrTokenRejected, err)
+		}
+		tracing.RecordError(span, err)
+		return err
+	}
+
+	var envelope struct {
+		Status  string          `json:"status"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &envelope); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", path, err)
+	}
+	if envelope.Status != "success" {
+		return fmt.Errorf("kite API returned %q for %s: %s", envelope.Status, path, envelope.Message)
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("failed to parse %s data: %w", path, err)
+	}
+	return nil
+}
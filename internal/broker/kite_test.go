@@ -0,0 +1,233 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/broker/kitetest"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+func newTestLogger(t *testing.T) *logger.Logger {
+	t.Helper()
+	log, err := logger.NewLogger("error", filepath.Join(t.TempDir(), "broker.log"))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+	return log
+}
+
+// newTestKite returns a Kite broker wired to a fresh stand-in server
+func newTestKite(t *testing.T) (*KiteBroker, *kitetest.Server) {
+	t.Helper()
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{}
+	server.Configure(cfg)
+	kite, err := NewKiteBroker(cfg, newTestLogger(t))
+	if err != nil {
+		t.Fatalf("NewKiteBroker: %v", err)
+	}
+	return kite, server
+}
+
+func kiteOrder(isAMO bool) models.Order {
+	return models.Order{
+		ID:            "INFY-1",
+		Symbol:        "infy",
+		Exchange:      "NSE",
+		Price:         1500.5,
+		Quantity:      10,
+		OrderType:     "LIMIT",
+		Side:          "Buy",
+		ScheduledTime: time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC),
+		IsAMO:         isAMO,
+	}
+}
+
+func TestKiteExecuteOrderPlacesRegularOrder(t *testing.T) {
+	kite, server := newTestKite(t)
+
+	result, err := kite.ExecuteOrder(context.Background(), kiteOrder(false))
+	if err != nil {
+		t.Fatalf("ExecuteOrder: %v", err)
+	}
+
+	orders := server.Orders()
+	if len(orders) != 1 {
+		t.Fatalf("stand-in received %d orders, want 1", len(orders))
+	}
+	placed := orders[0]
+	if !result.Success || result.ExecutionID != placed.OrderID || result.OrderID != "INFY-1" {
+		t.Errorf("result = %+v, want success with execution ID %s", result, placed.OrderID)
+	}
+	if placed.Variety != "regular" || server.Requests(kitetest.PathAMOOrder) != 0 {
+		t.Errorf("order placed as %q, want regular endpoint only", placed.Variety)
+	}
+
+	want := map[string]string{
+		"exchange":         "NSE",
+		"tradingsymbol":    "INFY",
+		"transaction_type": "BUY",
+		"order_type":       "LIMIT",
+		"variety":          "regular",
+		"quantity":         "10",
+		"price":            "1500.50",
+		"product":          "CNC",
+		"validity":         "DAY",
+	}
+	for field, value := range want {
+		if got := placed.Form.Get(field); got != value {
+			t.Errorf("form %s = %q, want %q", field, got, value)
+		}
+	}
+}
+
+func TestKiteExecuteOrderPlacesAMO(t *testing.T) {
+	kite, server := newTestKite(t)
+
+	order := kiteOrder(true)
+	order.Side = "Sell"
+	if _, err := kite.ExecuteOrder(context.Background(), order); err != nil {
+		t.Fatalf("ExecuteOrder: %v", err)
+	}
+
+	orders := server.Orders()
+	if len(orders) != 1 || orders[0].Variety != "amo" {
+		t.Fatalf("stand-in orders = %+v, want one AMO", orders)
+	}
+	if server.Requests(kitetest.PathRegularOrder) != 0 {
+		t.Error("AMO order also hit the regular endpoint")
+	}
+	if got := orders[0].Form.Get("variety"); got != "amo" {
+		t.Errorf("variety = %q, want amo", got)
+	}
+	if got := orders[0].Form.Get("transaction_type"); got != "SELL" {
+		t.Errorf("transaction_type = %q, want SELL", got)
+	}
+}
+
+func TestKiteExecuteOrderMarketOrderOmitsPrice(t *testing.T) {
+	kite, server := newTestKite(t)
+
+	order := kiteOrder(false)
+	order.OrderType = "Market"
+	order.Exchange = ""
+	order.Symbol = "BSE:reliance"
+	if _, err := kite.ExecuteOrder(context.Background(), order); err != nil {
+		t.Fatalf("ExecuteOrder: %v", err)
+	}
+
+	form := server.Orders()[0].Form
+	if form.Get("order_type") != "MARKET" || form.Has("price") {
+		t.Errorf("form = %v, want MARKET without price", form)
+	}
+	if form.Get("exchange") != "BSE" || form.Get("tradingsymbol") != "RELIANCE" {
+		t.Errorf("instrument = %s:%s, want BSE:RELIANCE parsed from the symbol", form.Get("exchange"), form.Get("tradingsymbol"))
+	}
+}
+
+func TestKiteExecuteOrderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		failure kitetest.Failure
+		wantErr string
+	}{
+		{"input exception", kitetest.Failure{Status: http.StatusBadRequest, ErrorType: "InputException", Message: "Invalid `quantity`"}, "status 400"},
+		{"token exception", kitetest.Failure{Status: http.StatusForbidden, ErrorType: "TokenException", Message: "Token expired"}, "status 403"},
+		{"rate limited", kitetest.Failure{Status: http.StatusTooManyRequests, ErrorType: "NetworkException", Message: "Too many requests"}, "status 429"},
+		{"server error", kitetest.Failure{Status: http.StatusInternalServerError, ErrorType: "GeneralException", Message: "Internal error"}, "status 500"},
+		{"error envelope with 200", kitetest.Failure{Status: http.StatusOK, ErrorType: "OrderException", Message: "Insufficient funds"}, "Insufficient funds"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kite, server := newTestKite(t)
+			server.FailNext(kitetest.PathRegularOrder, tt.failure)
+
+			result, err := kite.ExecuteOrder(context.Background(), kiteOrder(false))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+			}
+			if result.Success || result.OrderID != "INFY-1" || result.ErrorMessage == "" {
+				t.Errorf("result = %+v, want a failed result for INFY-1 with an error message", result)
+			}
+			if len(server.Orders()) != 0 {
+				t.Error("failed order was recorded as placed")
+			}
+		})
+	}
+}
+
+func TestKiteExecuteOrderRejectsWrongToken(t *testing.T) {
+	kite, server := newTestKite(t)
+	server.SetAccessToken("rotated-token")
+
+	_, err := kite.ExecuteOrder(context.Background(), kiteOrder(true))
+	if !errors.Is(err, ErrTokenRejected) || !strings.Contains(err.Error(), "TokenException") {
+		t.Fatalf("err = %v, want ErrTokenRejected with a TokenException", err)
+	}
+}
+
+func TestKiteExecuteOrderNetworkError(t *testing.T) {
+	kite, server := newTestKite(t)
+	server.Close()
+
+	result, err := kite.ExecuteOrder(context.Background(), kiteOrder(false))
+	if err == nil || !strings.Contains(err.Error(), "failed to execute request") {
+		t.Fatalf("err = %v, want a network error", err)
+	}
+	if result.Success {
+		t.Error("result reported success after a network error")
+	}
+}
+
+func TestKiteHealthCheck(t *testing.T) {
+	kite, server := newTestKite(t)
+
+	if err := kite.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+	if server.Requests(kitetest.PathProfile) != 1 {
+		t.Errorf("profile requests = %d, want 1", server.Requests(kitetest.PathProfile))
+	}
+
+	server.SetAccessToken("rotated-token")
+	if err := kite.HealthCheck(context.Background()); !errors.Is(err, ErrTokenRejected) || !strings.Contains(err.Error(), "status 403") {
+		t.Errorf("HealthCheck with a stale token: err = %v, want ErrTokenRejected with status 403", err)
+	}
+}
+
+func TestBrokerManagerExecutesThroughKiteStandIn(t *testing.T) {
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{}
+	server.Configure(cfg)
+	cfg.Broker.RateLimit = config.RateLimitConfig{RequestsPerSecond: 10, BurstSize: 10}
+
+	manager, err := NewBrokerManager(cfg, newTestLogger(t))
+	if err != nil {
+		t.Fatalf("NewBrokerManager: %v", err)
+	}
+	if err := manager.HealthCheck(context.Background()); err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+
+	result, err := manager.ExecuteOrder(context.Background(), kiteOrder(false))
+	if err != nil || !result.Success {
+		t.Fatalf("ExecuteOrder = %+v, %v; want success", result, err)
+	}
+	if len(server.Orders()) != 1 {
+		t.Errorf("stand-in received %d orders, want 1", len(server.Orders()))
+	}
+}
@@ -0,0 +1,747 @@
+// Package kitetest provides an httptest stand-in for the Kite Connect API. It implements
+// the endpoints KiteBroker calls (regular and AMO orders, GTT triggers, user profile, LTP and
+// full quotes, holdings, positions, margins, the instruments dump and token refresh), records what it receives and can be told to fail the next request.
+package kitetest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/config"
+)
+
+// Default credentials accepted by a new server
+const (
+	DefaultAPIKey      = "test-api-key"
+	DefaultAccessToken = "test-access-token"
+)
+
+// Endpoint paths served by the stand-in
+const (
+	PathRegularOrder = "/orders/regular"
+	PathAMOOrder     = "/orders/amo"
+	PathProfile      = "/user/profile"
+	PathQuoteLTP     = "/quote/ltp"
+	PathQuote        = "/quote"
+	PathRefreshToken = "/session/refresh_token"
+	PathHoldings     = "/portfolio/holdings"
+	PathPositions    = "/portfolio/positions"
+	PathMargins      = "/user/margins"
+	PathOrderMargins = "/margins/orders"
+	PathInstruments  = "/instruments"
+	PathGTT          = "/gtt/triggers" // Followed by /<id> to get, modify or delete one trigger
+)
+
+// Instrument is a row of the instruments dump
+type Instrument struct {
+	Token          int64
+	ExchangeToken  string // Scrip code for BSE instruments
+	Tradingsymbol  string
+	Name           string
+	Expiry         string // YYYY-MM-DD; empty for cash instruments
+	Strike         float64
+	TickSize       float64
+	LotSize        int
+	InstrumentType string // EQ, FUT, CE or PE
+	Segment        string
+	Exchange       string
+}
+
+// PlacedOrder is an order received by the stand-in
+type PlacedOrder struct {
+	OrderID string
+	Variety string     // "regular" or "amo", from the endpoint used
+	Form    url.Values // Form fields exactly as posted
+}
+
+// GTTOrder is one leg of a GTT, the limit order placed when the trigger fires
+type GTTOrder struct {
+	Exchange        string  `json:"exchange"`
+	Tradingsymbol   string  `json:"tradingsymbol"`
+	TransactionType string  `json:"transaction_type"`
+	Quantity        int     `json:"quantity"`
+	OrderType       string  `json:"order_type"`
+	Product         string  `json:"product"`
+	Price           float64 `json:"price"`
+}
+
+// GTTCondition is the condition a GTT waits for
+type GTTCondition struct {
+	Exchange      string    `json:"exchange"`
+	Tradingsymbol string    `json:"tradingsymbol"`
+	TriggerValues []float64 `json:"trigger_values"`
+	LastPrice     float64   `json:"last_price"`
+}
+
+// GTT is a trigger held by the stand-in
+type GTT struct {
+	ID              int
+	Type            string // single or two-leg
+	Status          string // active, triggered, disabled, expired, cancelled, rejected or deleted
+	Condition       GTTCondition
+	Orders          []GTTOrder
+	TriggeredLeg    int    // Index of the leg placed when the GTT triggered
+	OrderID         string // Order placed when the GTT triggered
+	RejectionReason string
+	UpdatedAt       time.Time
+}
+
+// Failure is a canned error response
+type Failure struct {
+	Status    int    // HTTP status code; 200 returns a Kite error envelope with a success code
+	ErrorType string // Kite error_type, e.g. InputException, TokenException, OrderException
+	Message   string
+}
+
+// Server is a Kite Connect stand-in backed by httptest.Server
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	apiKey      string
+	accessToken string
+	quotes      map[string]float64    // Last price per EXCHANGE:SYMBOL
+	circuits    map[string][2]float64 // Lower and upper circuit limits per EXCHANGE:SYMBOL
+	sessions    map[string]session    // Day's open and volume per EXCHANGE:SYMBOL
+	holdings    []map[string]interface{}
+	positions   []map[string]interface{}
+	margin      float64 // Net equity margin reported by /user/margins
+	instruments []Instrument
+	failures    map[string][]Failure // Queued one-shot failures per path
+	orders      []PlacedOrder
+	requests    map[string]int
+	nextOrderID int
+	gtts        map[int]*GTT
+	nextGTTID   int
+}
+
+// NewServer starts a stand-in that accepts DefaultAPIKey and DefaultAccessToken.
+// Callers must Close it, typically with t.Cleanup(server.Close).
+func NewServer() *Server {
+	s := &Server{
+		apiKey:      DefaultAPIKey,
+		accessToken: DefaultAccessToken,
+		quotes:      make(map[string]float64),
+		circuits:    make(map[string][2]float64),
+		sessions:    make(map[string]session),
+		failures:    make(map[string][]Failure),
+		requests:    make(map[string]int),
+		nextOrderID: 240115000000001,
+		gtts:        make(map[int]*GTT),
+		nextGTTID:   1001,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathRegularOrder, s.handleOrder("regular"))
+	mux.HandleFunc(PathAMOOrder, s.handleOrder("amo"))
+	mux.HandleFunc(PathProfile, s.handleProfile)
+	mux.HandleFunc(PathQuoteLTP, s.handleQuote)
+	mux.HandleFunc(PathQuote, s.handleFullQuote)
+	mux.HandleFunc(PathRefreshToken, s.handleRefresh)
+	mux.HandleFunc(PathHoldings, s.handleHoldings)
+	mux.HandleFunc(PathPositions, s.handlePositions)
+	mux.HandleFunc(PathMargins, s.handleMargins)
+	mux.HandleFunc(PathOrderMargins, s.handleOrderMargins)
+	mux.HandleFunc(PathInstruments, s.handleInstruments)
+	mux.HandleFunc(PathGTT, s.handleGTTs)
+	mux.HandleFunc(PathGTT+"/", s.handleGTT)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Configure points cfg's Kite broker at the stand-in with its accepted credentials
+func (s *Server) Configure(cfg *config.Config) {
+	cfg.Broker.Type = "kite"
+	cfg.Broker.APIKey = s.apiKey
+	cfg.Broker.APISecret = s.accessToken
+	cfg.Broker.APIURL = s.URL
+	cfg.Broker.BaseURL = s.URL
+}
+
+// SetAccessToken changes the access token the stand-in accepts, e.g. to simulate expiry
+func (s *Server) SetAccessToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessToken = token
+}
+
+// SetQuote sets the last traded price returned for an instrument
+func (s *Server) SetQuote(exchange, symbol string, price float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quotes[instrument(exchange, symbol)] = price
+}
+
+// SetCircuitLimits sets the day's circuit band returned in an instrument's full quote
+func (s *Server) SetCircuitLimits(exchange, symbol string, lower, upper float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.circuits[instrument(exchange, symbol)] = [2]float64{lower, upper}
+}
+
+// session is an instrument's day so far, reported in its full quote
+type session struct {
+	open   float64
+	volume int64
+}
+
+// SetSession sets the day's open and the volume traded so far returned in an instrument's
+// full quote
+func (s *Server) SetSession(exchange, symbol string, open float64, volume int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[instrument(exchange, symbol)] = session{open: open, volume: volume}
+}
+
+// AddHolding adds a demat holding: settled quantity, unsettled T1 quantity and quantity
+// already used by sell orders today
+func (s *Server) AddHolding(exchange, symbol string, quantity, t1Quantity, usedQuantity int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.holdings = append(s.holdings, map[string]interface{}{
+		"tradingsymbol": strings.ToUpper(symbol),
+		"exchange":      strings.ToUpper(exchange),
+		"isin":          "INE000000000",
+		"product":       "CNC",
+		"quantity":      quantity,
+		"t1_quantity":   t1Quantity,
+		"used_quantity": usedQuantity,
+		"average_price": 100.0,
+		"last_price":    s.quotes[instrument(exchange, symbol)],
+	})
+}
+
+// AddPosition adds a net position for the day; negative quantities are short
+func (s *Server) AddPosition(exchange, symbol, product string, quantity int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.positions = append(s.positions, map[string]interface{}{
+		"tradingsymbol": strings.ToUpper(symbol),
+		"exchange":      strings.ToUpper(exchange),
+		"product":       product,
+		"quantity":      quantity,
+		"average_price": 100.0,
+		"last_price":    s.quotes[instrument(exchange, symbol)],
+	})
+}
+
+// SetMargin sets the net equity margin available for new orders
+func (s *Server) SetMargin(net float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.margin = net
+}
+
+// AddInstrument adds an instrument to the instruments dump
+func (s *Server) AddInstrument(instrument Instrument) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.instruments = append(s.instruments, instrument)
+}
+
+// FailNext makes the next request to path return failure instead of its normal response.
+// Repeated calls queue failures in order.
+func (s *Server) FailNext(path string, failure Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = append(s.failures[path], failure)
+}
+
+// Orders returns the orders placed so far
+func (s *Server) Orders() []PlacedOrder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]PlacedOrder(nil), s.orders...)
+}
+
+// GTTs returns the GTTs held so far, including deleted ones, in the order they were placed
+func (s *Server) GTTs() []GTT {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	gtts := make([]GTT, 0, len(s.gtts))
+	for _, gtt := range s.gtts {
+		gtts = append(gtts, *gtt)
+	}
+	sort.Slice(gtts, func(i, j int) bool { return gtts[i].ID < gtts[j].ID })
+	return gtts
+}
+
+// TriggerGTT fires an active GTT: the leg's order is placed as a regular order and the GTT
+// is marked triggered. A non-empty rejection leaves the order rejected with that reason.
+func (s *Server) TriggerGTT(id, leg int, rejection string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	gtt, ok := s.gtts[id]
+	if !ok || leg < 0 || leg >= len(gtt.Orders) {
+		return
+	}
+	order := gtt.Orders[leg]
+	orderID := fmt.Sprintf("%d", s.nextOrderID)
+	s.nextOrderID++
+	form := url.Values{}
+	form.Set("exchange", order.Exchange)
+	form.Set("tradingsymbol", order.Tradingsymbol)
+	form.Set("transaction_type", order.TransactionType)
+	form.Set("order_type", order.OrderType)
+	form.Set("quantity", strconv.Itoa(order.Quantity))
+	form.Set("product", order.Product)
+	form.Set("price", strconv.FormatFloat(order.Price, 'f', -1, 64))
+	form.Set("validity", "DAY")
+	s.orders = append(s.orders, PlacedOrder{OrderID: orderID, Variety: "regular", Form: form})
+
+	gtt.Status, gtt.TriggeredLeg, gtt.OrderID, gtt.RejectionReason = "triggered", leg, orderID, rejection
+	gtt.UpdatedAt = time.Now()
+}
+
+// SetGTTStatus changes a GTT's status, e.g. to simulate Kite disabling or expiring it
+func (s *Server) SetGTTStatus(id int, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if gtt, ok := s.gtts[id]; ok {
+		gtt.Status = status
+		gtt.UpdatedAt = time.Now()
+	}
+}
+
+// Requests returns how many requests path has received, including failed ones
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// begin counts the request and returns a queued failure or an authentication error, if any
+func (s *Server) begin(r *http.Request, authenticated bool) (Failure, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[r.URL.Path]++
+	if queued := s.failures[r.URL.Path]; len(queued) > 0 {
+		s.failures[r.URL.Path] = queued[1:]
+		return queued[0], true
+	}
+
+	if r.Header.Get("X-Kite-Version") != "3" {
+		return Failure{Status: http.StatusBadRequest, ErrorType: "InputException", Message: "Missing or invalid X-Kite-Version header"}, true
+	}
+	if authenticated && r.Header.Get("Authorization") != fmt.Sprintf("token %s:%s", s.apiKey, s.accessToken) {
+		return Failure{Status: http.StatusForbidden, ErrorType: "TokenException", Message: "Incorrect `api_key` or `access_token`."}, true
+	}
+	return Failure{}, false
+}
+
+// handleOrder accepts a regular or AMO order
+func (s *Server) handleOrder(variety string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError(w, Failure{Status: http.StatusMethodNotAllowed, ErrorType: "InputException", Message: "Method not allowed"})
+			return
+		}
+		if failure, failed := s.begin(r, true); failed {
+			writeError(w, failure)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			writeError(w, Failure{Status: http.StatusBadRequest, ErrorType: "InputException", Message: err.Error()})
+			return
+		}
+
+		for _, field := range []string{"exchange", "tradingsymbol", "transaction_type", "order_type", "quantity", "product", "validity"} {
+			if r.PostForm.Get(field) == "" {
+				writeError(w, Failure{Status: http.StatusBadRequest, ErrorType: "InputException", Message: fmt.Sprintf("Missing `%s`", field)})
+				return
+			}
+		}
+		if r.PostForm.Get("order_type") == "LIMIT" && r.PostForm.Get("price") == "" {
+			writeError(w, Failure{Status: http.StatusBadRequest, ErrorType: "InputException", Message: "Missing `price` for LIMIT order"})
+			return
+		}
+
+		s.mu.Lock()
+		orderID := fmt.Sprintf("%d", s.nextOrderID)
+		s.nextOrderID++
+		s.orders = append(s.orders, PlacedOrder{OrderID: orderID, Variety: variety, Form: r.PostForm})
+		s.mu.Unlock()
+
+		writeSuccess(w, map[string]string{"order_id": orderID})
+	}
+}
+
+// handleProfile returns a fixed user profile
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	if failure, failed := s.begin(r, true); failed {
+		writeError(w, failure)
+		return
+	}
+	writeSuccess(w, map[string]interface{}{
+		"user_id":   "AB1234",
+		"user_name": "Test User",
+		"broker":    "ZERODHA",
+		"exchanges": []string{"NSE", "BSE"},
+		"products":  []string{"CNC", "MIS", "NRML"},
+	})
+}
+
+// handleQuote returns last prices for known instruments; unknown ones are omitted, as Kite does
+func (s *Server) handleQuote(w http.ResponseWriter, r *http.Request) {
+	if failure, failed := s.begin(r, true); failed {
+		writeError(w, failure)
+		return
+	}
+
+	s.mu.Lock()
+	data := make(map[string]interface{})
+	for i, id := range r.URL.Query()["i"] {
+		if price, ok := s.quotes[strings.ToUpper(id)]; ok {
+			data[id] = map[string]interface{}{"instrument_token": i + 1, "last_price": price}
+		}
+	}
+	s.mu.Unlock()
+
+	writeSuccess(w, data)
+}
+
+// handleFullQuote returns the last price, open, volume and circuit limits of instruments with
+// a quote, session or circuit limits set; others are omitted, as Kite does
+func (s *Server) handleFullQuote(w http.ResponseWriter, r *http.Request) {
+	if failure, failed := s.begin(r, true); failed {
+		writeError(w, failure)
+		return
+	}
+
+	s.mu.Lock()
+	data := make(map[string]interface{})
+	for i, id := range r.URL.Query()["i"] {
+		price, quoted := s.quotes[strings.ToUpper(id)]
+		circuit, limited := s.circuits[strings.ToUpper(id)]
+		day, traded := s.sessions[strings.ToUpper(id)]
+		if quoted || limited || traded {
+			data[id] = map[string]interface{}{
+				"instrument_token":    i + 1,
+				"last_price":          price,
+				"volume":              day.volume,
+				"ohlc":                map[string]float64{"open": day.open},
+				"lower_circuit_limit": circuit[0],
+				"upper_circuit_limit": circuit[1],
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	writeSuccess(w, data)
+}
+
+// handleHoldings returns the holdings added with AddHolding
+func (s *Server) handleHoldings(w http.ResponseWriter, r *http.Request) {
+	if failure, failed := s.begin(r, true); failed {
+		writeError(w, failure)
+		return
+	}
+	s.mu.Lock()
+	holdings := append([]map[string]interface{}{}, s.holdings...)
+	s.mu.Unlock()
+	writeSuccess(w, holdings)
+}
+
+// handlePositions returns the positions added with AddPosition as both net and day positions
+func (s *Server) handlePositions(w http.ResponseWriter, r *http.Request) {
+	if failure, failed := s.begin(r, true); failed {
+		writeError(w, failure)
+		return
+	}
+	s.mu.Lock()
+	positions := append([]map[string]interface{}{}, s.positions...)
+	s.mu.Unlock()
+	writeSuccess(w, map[string]interface{}{"net": positions, "day": positions})
+}
+
+// handleMargins returns the margin set with SetMargin for the equity segment
+func (s *Server) handleMargins(w http.ResponseWriter, r *http.Request) {
+	if failure, failed := s.begin(r, true); failed {
+		writeError(w, failure)
+		return
+	}
+	s.mu.Lock()
+	net := s.margin
+	s.mu.Unlock()
+	writeSuccess(w, map[string]interface{}{
+		"equity": map[string]interface{}{
+			"enabled":   true,
+			"net":       net,
+			"available": map[string]float64{"cash": net, "live_balance": net},
+			"utilised":  map[string]float64{"debits": 0},
+		},
+		"commodity": map[string]interface{}{"enabled": false, "net": 0},
+	})
+}
+
+// handleOrderMargins returns each order's margin as its price (or last price for market
+// orders) times its quantity, which is what Kite blocks for a CNC buy
+func (s *Server) handleOrderMargins(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, Failure{Status: http.StatusMethodNotAllowed, ErrorType: "InputException", Message: "Method not allowed"})
+		return
+	}
+	if failure, failed := s.begin(r, true); failed {
+		writeError(w, failure)
+		return
+	}
+	var orders []struct {
+		Exchange        string  `json:"exchange"`
+		Tradingsymbol   string  `json:"tradingsymbol"`
+		TransactionType string  `json:"transaction_type"`
+		OrderType       string  `json:"order_type"`
+		Quantity        int     `json:"quantity"`
+		Price           float64 `json:"price"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&orders); err != nil {
+		writeError(w, Failure{Status: http.StatusBadRequest, ErrorType: "InputException", Message: err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	margins := make([]map[string]interface{}, 0, len(orders))
+	for _, order := range orders {
+		price := order.Price
+		if order.OrderType != "LIMIT" {
+			price = s.quotes[instrument(order.Exchange, order.Tradingsymbol)]
+		}
+		margins = append(margins, map[string]interface{}{
+			"type":          "equity",
+			"tradingsymbol": order.Tradingsymbol,
+			"exchange":      order.Exchange,
+			"total":         price * float64(order.Quantity),
+		})
+	}
+	s.mu.Unlock()
+	writeSuccess(w, margins)
+}
+
+// handleInstruments returns the instruments added with AddInstrument as a CSV dump
+func (s *Server) handleInstruments(w http.ResponseWriter, r *http.Request) {
+	if failure, failed := s.begin(r, true); failed {
+		writeError(w, failure)
+		return
+	}
+	s.mu.Lock()
+	instruments := append([]Instrument(nil), s.instruments...)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/csv")
+	out := csv.NewWriter(w)
+	out.Write([]string{"instrument_token", "exchange_token", "tradingsymbol", "name", "last_price", "expiry",
+		"strike", "tick_size", "lot_size", "instrument_type", "segment", "exchange"})
+	for _, i := range instruments {
+		out.Write([]string{
+			fmt.Sprint(i.Token), i.ExchangeToken, i.Tradingsymbol, i.Name, "0", i.Expiry,
+			fmt.Sprint(i.Strike), fmt.Sprint(i.TickSize), fmt.Sprint(i.LotSize), i.InstrumentType, i.Segment, i.Exchange,
+		})
+	}
+	out.Flush()
+}
+
+// handleRefresh issues a new access token and accepts it from then on
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if failure, failed := s.begin(r, false); failed {
+		writeError(w, failure)
+		return
+	}
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("refresh_token") == "" {
+		writeError(w, Failure{Status: http.StatusBadRequest, ErrorType: "InputException", Message: "Missing `refresh_token`"})
+		return
+	}
+
+	s.mu.Lock()
+	s.accessToken = fmt.Sprintf("refreshed-token-%d", s.requests[PathRefreshToken])
+	token := s.accessToken
+	s.mu.Unlock()
+
+	writeSuccess(w, map[string]string{"access_token": token, "refresh_token": r.PostForm.Get("refresh_token")})
+}
+
+// handleGTTs lists the GTTs or places a new one
+func (s *Server) handleGTTs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		writeError(w, Failure{Status: http.StatusMethodNotAllowed, ErrorType: "InputException", Message: "Method not allowed"})
+		return
+	}
+	if failure, failed := s.begin(r, true); failed {
+		writeError(w, failure)
+		return
+	}
+	if r.Method == http.MethodGet {
+		gtts := s.GTTs()
+		data := make([]map[string]interface{}, 0, len(gtts))
+		for _, gtt := range gtts {
+			data = append(data, gttData(gtt))
+		}
+		writeSuccess(w, data)
+		return
+	}
+
+	gtt, failure, ok := parseGTT(r)
+	if !ok {
+		writeError(w, failure)
+		return
+	}
+	s.mu.Lock()
+	gtt.ID = s.nextGTTID
+	s.nextGTTID++
+	s.gtts[gtt.ID] = &gtt
+	s.mu.Unlock()
+	writeSuccess(w, map[string]int{"trigger_id": gtt.ID})
+}
+
+// handleGTT returns, modifies or deletes one GTT
+func (s *Server) handleGTT(w http.ResponseWriter, r *http.Request) {
+	if failure, failed := s.begin(r, true); failed {
+		writeError(w, failure)
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, PathGTT+"/"))
+	s.mu.Lock()
+	current, found := s.gtts[id]
+	s.mu.Unlock()
+	if err != nil || !found {
+		writeError(w, Failure{Status: http.StatusNotFound, ErrorType: "GeneralException", Message: "Invalid trigger ID"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.mu.Lock()
+		data := gttData(*current)
+		s.mu.Unlock()
+		writeSuccess(w, data)
+	case http.MethodPut:
+		gtt, failure, ok := parseGTT(r)
+		if !ok {
+			writeError(w, failure)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if current.Status != "active" {
+			writeError(w, Failure{Status: http.StatusBadRequest, ErrorType: "InputException", Message: "Trigger is not active"})
+			return
+		}
+		gtt.ID = id
+		s.gtts[id] = &gtt
+		writeSuccess(w, map[string]int{"trigger_id": id})
+	case http.MethodDelete:
+		s.mu.Lock()
+		current.Status = "deleted"
+		current.UpdatedAt = time.Now()
+		s.mu.Unlock()
+		writeSuccess(w, map[string]int{"trigger_id": id})
+	default:
+		writeError(w, Failure{Status: http.StatusMethodNotAllowed, ErrorType: "InputException", Message: "Method not allowed"})
+	}
+}
+
+// parseGTT reads and checks the form of a GTT being placed or modified
+func parseGTT(r *http.Request) (GTT, Failure, bool) {
+	invalid := func(message string) (GTT, Failure, bool) {
+		return GTT{}, Failure{Status: http.StatusBadRequest, ErrorType: "InputException", Message: message}, false
+	}
+	if err := r.ParseForm(); err != nil {
+		return invalid(err.Error())
+	}
+	gtt := GTT{Type: r.PostForm.Get("type"), Status: "active", UpdatedAt: time.Now()}
+	legs := map[string]int{"single": 1, "two-leg": 2}[gtt.Type]
+	if legs == 0 {
+		return invalid("Invalid `type`")
+	}
+	if err := json.Unmarshal([]byte(r.PostForm.Get("condition")), &gtt.Condition); err != nil {
+		return invalid("Invalid `condition`")
+	}
+	if err := json.Unmarshal([]byte(r.PostForm.Get("orders")), &gtt.Orders); err != nil {
+		return invalid("Invalid `orders`")
+	}
+	if len(gtt.Condition.TriggerValues) != legs || len(gtt.Orders) != legs {
+		return invalid(fmt.Sprintf("A %s trigger needs %d trigger values and orders", gtt.Type, legs))
+	}
+	if legs == 2 && gtt.Condition.TriggerValues[0] >= gtt.Condition.TriggerValues[1] {
+		return invalid("Trigger values of a two-leg trigger must be stop-loss then target")
+	}
+	if gtt.Condition.LastPrice <= 0 {
+		return invalid("Missing `last_price`")
+	}
+	return gtt, Failure{}, true
+}
+
+// gttData formats a GTT as Kite lists it
+func gttData(gtt GTT) map[string]interface{} {
+	ist := time.FixedZone("IST", 5*3600+1800)
+	orders := make([]map[string]interface{}, 0, len(gtt.Orders))
+	for i, order := range gtt.Orders {
+		leg := map[string]interface{}{
+			"exchange":         order.Exchange,
+			"tradingsymbol":    order.Tradingsymbol,
+			"transaction_type": order.TransactionType,
+			"quantity":         order.Quantity,
+			"order_type":       order.OrderType,
+			"product":          order.Product,
+			"price":            order.Price,
+			"result":           nil,
+		}
+		if gtt.OrderID != "" && i == gtt.TriggeredLeg {
+			status := "success"
+			if gtt.RejectionReason != "" {
+				status = "failed"
+			}
+			leg["result"] = map[string]interface{}{
+				"order_result": map[string]string{
+					"order_id":         gtt.OrderID,
+					"status":           status,
+					"rejection_reason": gtt.RejectionReason,
+				},
+			}
+		}
+		orders = append(orders, leg)
+	}
+	return map[string]interface{}{
+		"id":         gtt.ID,
+		"type":       gtt.Type,
+		"status":     gtt.Status,
+		"condition":  gtt.Condition,
+		"orders":     orders,
+		"updated_at": gtt.UpdatedAt.In(ist).Format("2006-01-02 15:04:05"),
+		"expires_at": gtt.UpdatedAt.In(ist).AddDate(1, 0, 0).Format("2006-01-02 15:04:05"),
+	}
+}
+
+// writeSuccess writes Kite's success envelope
+func writeSuccess(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": "success", "data": data})
+}
+
+// writeError writes Kite's error envelope
+func writeError(w http.ResponseWriter, failure Failure) {
+	status := failure.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":     "error",
+		"message":    failure.Message,
+		"error_type": failure.ErrorType,
+	})
+}
+
+// instrument formats an EXCHANGE:SYMBOL instrument identifier
+func instrument(exchange, symbol string) string {
+	return strings.ToUpper(exchange) + ":" + strings.ToUpper(symbol)
+}
@@ -0,0 +1,89 @@
+package broker
+
+import (
+	"testing"
+	"time"
+)
+
+func istTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatalf("failed to load IST: %v", err)
+	}
+	at, err := time.ParseInLocation("2006-01-02 15:04:05", value, ist)
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", value, err)
+	}
+	return at
+}
+
+func TestIsMarketOpenBoundaries(t *testing.T) {
+	m := NewMarketHours()
+
+	tests := []struct {
+		name string
+		at   string
+		open bool
+	}{
+		{"just before open", "2024-01-15 08:59:59", false},
+		{"at open", "2024-01-15 09:00:00", true},
+		{"mid session", "2024-01-15 12:00:00", true},
+		{"last minute", "2024-01-15 15:29:59", true},
+		{"at close", "2024-01-15 15:30:00", false},
+		{"evening", "2024-01-15 20:00:00", false},
+		{"friday session", "2024-01-12 10:00:00", true},
+		{"saturday session hours", "2024-01-13 10:00:00", false},
+		{"sunday session hours", "2024-01-14 10:00:00", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := istTime(t, tt.at)
+			if got := m.IsMarketOpen(at); got != tt.open {
+				t.Errorf("IsMarketOpen(%s) = %v, want %v", tt.at, got, tt.open)
+			}
+			if got := m.ShouldUseAMO(at); got == tt.open {
+				t.Errorf("ShouldUseAMO(%s) = %v, want %v", tt.at, got, !tt.open)
+			}
+		})
+	}
+}
+
+func TestIsMarketOpenConvertsToIST(t *testing.T) {
+	m := NewMarketHours()
+
+	// 03:30 UTC is 09:00 IST
+	if !m.IsMarketOpen(time.Date(2024, 1, 15, 3, 30, 0, 0, time.UTC)) {
+		t.Error("03:30 UTC on a Monday should be inside IST market hours")
+	}
+	// 10:00 UTC is 15:30 IST
+	if m.IsMarketOpen(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)) {
+		t.Error("10:00 UTC on a Monday should be after the IST close")
+	}
+}
+
+func TestGetNextMarketOpenTime(t *testing.T) {
+	m := NewMarketHours()
+
+	tests := []struct {
+		name string
+		at   string
+		want string
+	}{
+		{"early morning opens same day", "2024-01-15 07:00:00", "2024-01-15 09:00:00"},
+		{"after close opens next day", "2024-01-15 16:00:00", "2024-01-16 09:00:00"},
+		{"friday after close skips weekend", "2024-01-12 16:00:00", "2024-01-15 09:00:00"},
+		{"saturday skips to monday", "2024-01-13 11:00:00", "2024-01-15 09:00:00"},
+		{"sunday morning skips to monday", "2024-01-14 07:00:00", "2024-01-15 09:00:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.GetNextMarketOpenTime(istTime(t, tt.at))
+			if want := istTime(t, tt.want); !got.Equal(want) {
+				t.Errorf("GetNextMarketOpenTime(%s) = %s, want %s", tt.at, got.Format(time.RFC3339), want.Format(time.RFC3339))
+			}
+		})
+	}
+}
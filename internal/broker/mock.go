@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"time"
 
+	"github.com/mach_five/trading-system/internal/clock"
 	"github.com/mach_five/trading-system/internal/config"
 	"github.com/mach_five/trading-system/internal/logger"
 	"github.com/mach_five/trading-system/internal/models"
@@ -15,6 +16,7 @@ import (
 type MockBroker struct {
 	config *config.Config
 	logger *logger.Logger
+	clock  clock.Clock
 }
 
 // NewMockBroker creates a new mock broker
@@ -22,9 +24,15 @@ func NewMockBroker(cfg *config.Config, log *logger.Logger) *MockBroker {
 	return &MockBroker{
 		config: cfg,
 		logger: log,
+		clock:  clock.Real{},
 	}
 }
 
+// SetClock replaces the clock used to timestamp execution results
+func (m *MockBroker) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
 // ExecuteOrder simulates order execution
 func (m *MockBroker) ExecuteOrder(ctx context.Context, order models.Order) (models.ExecutionResult, error) {
 	m.logger.Info("Mock broker executing order: %s", order.ID)
@@ -37,7 +45,7 @@ func (m *MockBroker) ExecuteOrder(ctx context.Context, order models.Order) (mode
 		return models.ExecutionResult{
 			OrderID:     order.ID,
 			Success:     false,
-			ExecutedAt:  time.Now(),
+			ExecutedAt:  m.clock.Now(),
 			ErrorMessage: "mock broker simulated failure",
 		}, fmt.Errorf("mock broker simulated failure")
 	}
@@ -49,7 +57,7 @@ func (m *MockBroker) ExecuteOrder(ctx context.Context, order models.Order) (mode
 		OrderID:        order.ID,
 		Success:        true,
 		ExecutionID:    fmt.Sprintf("MOCK-%d", time.Now().UnixNano()),
-		ExecutedAt:     time.Now(),
+		ExecutedAt:     m.clock.Now(),
 		ExecutedPrice:  executedPrice,
 		ExecutedQuantity: order.Quantity,
 	}
@@ -63,9 +71,4 @@ func (m *MockBroker) HealthCheck(ctx context.Context) error {
 	return nil
 }
 
-// ValidateSymbol always returns true for mock broker (no validation)
-func (m *MockBroker) ValidateSymbol(ctx context.Context, exchange, symbol string) (bool, error) {
-	return true, nil
-}
-
 
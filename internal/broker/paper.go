@@ -0,0 +1,590 @@
+package broker
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/journal"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/quotes"
+)
+
+// PaperBroker is a deterministic simulated exchange for rehearsing real order books.
+// It keeps cash, positions and an order book; LIMIT orders rest until the price feed
+// crosses them. All randomness comes from a seeded source so runs are reproducible.
+type PaperBroker struct {
+	config    *config.Config
+	logger    *logger.Logger
+	journal   *journal.Journal
+	clock     clock.Clock
+	mu        sync.Mutex
+	rng       *rand.Rand
+	cash      float64
+	positions map[string]*PaperPosition // Keyed by EXCHANGE:SYMBOL
+	prices    map[string]float64        // Last traded price per EXCHANGE:SYMBOL
+	opens     map[string]float64        // First price seen per EXCHANGE:SYMBOL, quoted as the day's open
+	book      []PaperOrder              // Resting (unfilled) orders in placement order
+	fills     []models.Fill
+	nextID    int
+	onFill    func(models.Fill) // Called for every fill with p.mu held
+	source    quotes.Source     // Live quotes polled by PollQuotes; nil when prices only come from UpdatePrice
+	sourceErr error             // Last PollQuotes failure, so an outage is logged once
+}
+
+// PaperPosition is the simulated holding in one instrument
+type PaperPosition struct {
+	Exchange     string  `json:"exchange"`
+	Symbol       string  `json:"symbol"`
+	Quantity     int     `json:"quantity"` // Negative for short positions
+	AveragePrice float64 `json:"average_price"`
+	RealisedPnL  float64 `json:"realised_pnl"`
+}
+
+// PaperOrder is an order resting in the simulated order book
+type PaperOrder struct {
+	ExecutionID string       `json:"execution_id"`
+	Order       models.Order `json:"order"`
+	PlacedAt    time.Time    `json:"placed_at"`
+}
+
+// NewPaperBroker creates a new paper trading broker
+func NewPaperBroker(cfg *config.Config, log *logger.Logger) (*PaperBroker, error) {
+	paperCfg := cfg.Broker.Paper
+
+	j, err := journal.Open(cfg.Journal.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+
+	p := &PaperBroker{
+		config:    cfg,
+		logger:    log,
+		journal:   j,
+		clock:     clock.Real{},
+		rng:       rand.New(rand.NewSource(paperCfg.Seed)),
+		cash:      paperCfg.InitialCash,
+		positions: make(map[string]*PaperPosition),
+		prices:    make(map[string]float64),
+		opens:     make(map[string]float64),
+	}
+
+	if paperCfg.PricesPath != "" {
+		if err := p.loadPrices(paperCfg.PricesPath); err != nil {
+			return nil, fmt.Errorf("failed to load paper prices: %w", err)
+		}
+	}
+
+	switch paperCfg.QuoteSource {
+	case "", config.PaperQuoteSourceOff:
+	case config.PaperQuoteSourceKite:
+		kite, err := NewKiteBroker(cfg, log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Kite quote source: %w", err)
+		}
+		p.source = kite
+		log.Info("📡 Paper prices follow Kite quotes (every %dms)", paperCfg.QuoteIntervalMs)
+	default:
+		return nil, fmt.Errorf("unknown paper quote source %q (supported: off, kite)", paperCfg.QuoteSource)
+	}
+
+	log.Info("📝 Paper broker ready (seed: %d, cash: %.2f, latency: %dms+%dms jitter, reject rate: %.2f%%)",
+		paperCfg.Seed, paperCfg.InitialCash, paperCfg.LatencyMs, paperCfg.LatencyJitterMs, paperCfg.RejectRate*100)
+	return p, nil
+}
+
+// ExecuteOrder places an order on the simulated exchange. Orders that cross the last
+// price fill immediately; the rest stay in the book until UpdatePrice crosses them.
+func (p *PaperBroker) ExecuteOrder(ctx context.Context, order models.Order) (models.ExecutionResult, error) {
+	if err := p.simulateLatency(ctx); err != nil {
+		return models.ExecutionResult{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.clock.Now()
+	if reason := p.rejectReason(order); reason != "" {
+		p.logger.Warn("📝 Paper broker rejected order %s: %s", order.ID, reason)
+		return models.ExecutionResult{
+			OrderID:      order.ID,
+			Success:      false,
+			ExecutedAt:   now,
+			ErrorMessage: reason,
+		}, fmt.Errorf("paper broker rejected order: %s", reason)
+	}
+
+	p.nextID++
+	resting := PaperOrder{
+		ExecutionID: fmt.Sprintf("PAPER-%06d", p.nextID),
+		Order:       order,
+		PlacedAt:    now,
+	}
+
+	result := models.ExecutionResult{
+		OrderID:     order.ID,
+		Success:     true,
+		ExecutionID: resting.ExecutionID,
+		ExecutedAt:  now,
+	}
+
+	// AMO orders wait for the next price update, as they would wait for the market to open
+	if ltp, ok := p.prices[instrumentKey(order.Exchange, order.Symbol)]; ok && !order.IsAMO {
+		if fillPrice, crosses := matchPrice(order, ltp, true); crosses {
+			fill := p.applyFill(resting, fillPrice, now)
+			result.ExecutedPrice = fill.Price
+			result.ExecutedQuantity = fill.Quantity
+			return result, nil
+		}
+	}
+
+	p.book = append(p.book, resting)
+	p.logger.Info("📝 Paper order %s resting in book (%s %d %s @ %.2f)",
+		resting.ExecutionID, order.Side, order.Quantity, order.Symbol, order.Price)
+	return result, nil
+}
+
+// SetClock replaces the clock used to timestamp acknowledgements and fills
+func (p *PaperBroker) SetClock(c clock.Clock) {
+	p.clock = c
+}
+
+// OnFill registers handler to be called for every fill. It runs while the broker is locked,
+// so it must not call back into the broker.
+func (p *PaperBroker) OnFill(handler func(models.Fill)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onFill = handler
+}
+
+// UpdatePrice feeds a new last traded price and fills any resting orders it crosses
+func (p *PaperBroker) UpdatePrice(exchange, symbol string, price float64, at time.Time) []models.Fill {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := instrumentKey(exchange, symbol)
+	p.prices[key] = price
+	if _, ok := p.opens[key]; !ok {
+		p.opens[key] = price
+	}
+
+	var fills []models.Fill
+	remaining := p.book[:0]
+	for _, resting := range p.book {
+		if instrumentKey(resting.Order.Exchange, resting.Order.Symbol) == key {
+			if fillPrice, crosses := matchPrice(resting.Order, price, false); crosses {
+				fills = append(fills, p.applyFill(resting, fillPrice, at))
+				continue
+			}
+		}
+		remaining = append(remaining, resting)
+	}
+	p.book = remaining
+	return fills
+}
+
+// HasQuoteSource reports whether PollQuotes follows a live quote source
+func (p *PaperBroker) HasQuoteSource() bool {
+	return p.source != nil
+}
+
+// PollQuotes fetches the last price of instruments, keyed EXCHANGE:SYMBOL, and of every
+// instrument with a resting order from the quote source and feeds them to UpdatePrice
+func (p *PaperBroker) PollQuotes(ctx context.Context, instruments []string) ([]models.Fill, error) {
+	if p.source == nil {
+		return nil, nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, instrument := range instruments {
+		exchange, symbol, _ := strings.Cut(instrument, ":")
+		wanted[instrumentKey(exchange, symbol)] = true
+	}
+	for _, resting := range p.OpenOrders() {
+		wanted[instrumentKey(resting.Order.Exchange, resting.Order.Symbol)] = true
+	}
+	if len(wanted) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(wanted))
+	for key := range wanted {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	latest, err := p.source.Quotes(ctx, keys)
+	if err != nil {
+		if p.sourceErr == nil {
+			p.logger.Warn("⚠️  Paper quote poll for %d instruments failed, prices are stale: %v", len(keys), err)
+		}
+		p.sourceErr = err
+		return nil, err
+	}
+	if p.sourceErr != nil {
+		p.logger.Info("📡 Paper quote polling recovered")
+		p.sourceErr = nil
+	}
+
+	var fills []models.Fill
+	for _, key := range keys {
+		quote, ok := latest[key]
+		if !ok || quote.LastPrice <= 0 {
+			continue
+		}
+		at := quote.At
+		if at.IsZero() {
+			at = p.clock.Now()
+		}
+		if quote.Open > 0 {
+			p.mu.Lock()
+			p.opens[key] = quote.Open
+			p.mu.Unlock()
+		}
+		exchange, symbol, _ := strings.Cut(key, ":")
+		fills = append(fills, p.UpdatePrice(exchange, symbol, quote.LastPrice, at)...)
+	}
+	return fills, nil
+}
+
+// HealthCheck always succeeds for the simulated exchange
+func (p *PaperBroker) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+// Cash returns the simulated cash balance
+func (p *PaperBroker) Cash() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cash
+}
+
+// Positions returns the simulated positions sorted by instrument
+func (p *PaperBroker) Positions() []PaperPosition {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	positions := make([]PaperPosition, 0, len(p.positions))
+	for _, position := range p.positions {
+		positions = append(positions, *position)
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return instrumentKey(positions[i].Exchange, positions[i].Symbol) < instrumentKey(positions[j].Exchange, positions[j].Symbol)
+	})
+	return positions
+}
+
+// Portfolio reports long simulated positions as holdings, with resting sells as their
+// used quantity, and short positions as positions
+func (p *PaperBroker) Portfolio(ctx context.Context) (models.Portfolio, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	portfolio := models.Portfolio{FetchedAt: p.clock.Now()}
+	for key, position := range p.positions {
+		if position.Quantity > 0 {
+			portfolio.Holdings = append(portfolio.Holdings, models.Holding{
+				Exchange:     position.Exchange,
+				Symbol:       position.Symbol,
+				Quantity:     position.Quantity,
+				UsedQuantity: p.restingQuantity(key, "Sell"),
+				AveragePrice: position.AveragePrice,
+				LastPrice:    p.prices[key],
+			})
+		} else if position.Quantity < 0 {
+			portfolio.Positions = append(portfolio.Positions, models.Position{
+				Exchange:     position.Exchange,
+				Symbol:       position.Symbol,
+				Quantity:     position.Quantity,
+				AveragePrice: position.AveragePrice,
+				LastPrice:    p.prices[key],
+			})
+		}
+	}
+	sort.Slice(portfolio.Holdings, func(i, j int) bool { return portfolio.Holdings[i].Symbol < portfolio.Holdings[j].Symbol })
+	sort.Slice(portfolio.Positions, func(i, j int) bool { return portfolio.Positions[i].Symbol < portfolio.Positions[j].Symbol })
+	return portfolio, nil
+}
+
+// Funds reports the simulated cash not blocked by resting buy orders
+func (p *PaperBroker) Funds(ctx context.Context) (models.Funds, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	reserved := p.reservedCash()
+	return models.Funds{Available: p.cash - reserved, Utilised: reserved, FetchedAt: p.clock.Now()}, nil
+}
+
+// OrderMargins returns the cash each order would block: its limit price, or the last
+// price for market orders, times its quantity
+func (p *PaperBroker) OrderMargins(ctx context.Context, orders []models.Order) ([]float64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	margins := make([]float64, len(orders))
+	for i, order := range orders {
+		price := order.Price
+		if strings.ToUpper(order.OrderType) != "LIMIT" {
+			price = p.prices[instrumentKey(order.Exchange, order.Symbol)]
+		}
+		margins[i] = price * float64(order.Quantity)
+	}
+	return margins, nil
+}
+
+// OpenOrders returns the orders resting in the simulated book
+func (p *PaperBroker) OpenOrders() []PaperOrder {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]PaperOrder(nil), p.book...)
+}
+
+// Fills returns every simulated fill in execution order
+func (p *PaperBroker) Fills() []models.Fill {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]models.Fill(nil), p.fills...)
+}
+
+// simulateLatency waits for the configured acknowledgement latency plus seeded jitter
+func (p *PaperBroker) simulateLatency(ctx context.Context) error {
+	paperCfg := p.config.Broker.Paper
+
+	p.mu.Lock()
+	latency := time.Duration(paperCfg.LatencyMs) * time.Millisecond
+	if paperCfg.LatencyJitterMs > 0 {
+		latency += time.Duration(p.rng.Intn(paperCfg.LatencyJitterMs+1)) * time.Millisecond
+	}
+	p.mu.Unlock()
+
+	if latency <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rejectReason applies the configured rejection rules; callers must hold p.mu
+func (p *PaperBroker) rejectReason(order models.Order) string {
+	paperCfg := p.config.Broker.Paper
+
+	// Always draw first so the random sequence does not depend on which rules below match
+	randomReject := p.rng.Float64() < paperCfg.RejectRate
+
+	if order.Quantity <= 0 {
+		return "quantity must be positive"
+	}
+	for _, symbol := range paperCfg.RejectSymbols {
+		if strings.EqualFold(strings.TrimSpace(symbol), order.Symbol) {
+			return fmt.Sprintf("symbol %s is configured to be rejected", order.Symbol)
+		}
+	}
+	if randomReject {
+		return "simulated random rejection"
+	}
+
+	key := instrumentKey(order.Exchange, order.Symbol)
+	if strings.EqualFold(order.Side, "Sell") {
+		if paperCfg.AllowShort {
+			return ""
+		}
+		held := 0
+		if position, ok := p.positions[key]; ok {
+			held = position.Quantity
+		}
+		if available := held - p.restingQuantity(key, "Sell"); order.Quantity > available {
+			return fmt.Sprintf("insufficient position: have %d available, need %d", available, order.Quantity)
+		}
+		return ""
+	}
+
+	price := order.Price
+	if strings.ToUpper(order.OrderType) != "LIMIT" {
+		price = p.prices[key]
+	}
+	if required := price * float64(order.Quantity); required > p.cash-p.reservedCash() {
+		return fmt.Sprintf("insufficient funds: need %.2f, available %.2f", required, p.cash-p.reservedCash())
+	}
+	return ""
+}
+
+// restingQuantity sums the quantity of resting orders on one side of an instrument
+func (p *PaperBroker) restingQuantity(key, side string) int {
+	total := 0
+	for _, resting := range p.book {
+		if instrumentKey(resting.Order.Exchange, resting.Order.Symbol) == key && strings.EqualFold(resting.Order.Side, side) {
+			total += resting.Order.Quantity
+		}
+	}
+	return total
+}
+
+// reservedCash is the cash blocked by resting buy orders
+func (p *PaperBroker) reservedCash() float64 {
+	reserved := 0.0
+	for _, resting := range p.book {
+		if strings.EqualFold(resting.Order.Side, "Buy") {
+			reserved += resting.Order.Price * float64(resting.Order.Quantity)
+		}
+	}
+	return reserved
+}
+
+// applyFill updates cash and positions for a fill and journals it; callers must hold p.mu
+func (p *PaperBroker) applyFill(resting PaperOrder, price float64, at time.Time) models.Fill {
+	order := resting.Order
+	key := instrumentKey(order.Exchange, order.Symbol)
+
+	position, ok := p.positions[key]
+	if !ok {
+		position = &PaperPosition{Exchange: strings.ToUpper(order.Exchange), Symbol: strings.ToUpper(order.Symbol)}
+		p.positions[key] = position
+	}
+
+	signed := order.Quantity
+	if strings.EqualFold(order.Side, "Sell") {
+		signed = -order.Quantity
+	}
+	realised := position.RealisedPnL
+	position.apply(signed, price)
+	p.cash -= float64(signed) * price
+
+	fill := models.Fill{
+		OrderID:     order.ID,
+		ExecutionID: resting.ExecutionID,
+		Symbol:      order.Symbol,
+		Exchange:    order.Exchange,
+		Side:        order.Side,
+		Quantity:    order.Quantity,
+		Price:       price,
+		FilledAt:    at,
+		RealisedPnL: position.RealisedPnL - realised,
+	}
+	p.fills = append(p.fills, fill)
+
+	if err := p.journal.Record(models.JournalEntry{
+		Timestamp: at,
+		Event:     models.JournalEventFill,
+		Broker:    "paper",
+		Fill:      &fill,
+	}); err != nil {
+		p.logger.Warn("⚠️  Failed to journal paper fill for %s: %v", order.ID, err)
+	}
+
+	p.logger.Info("📝 Paper fill %s: %s %d %s @ %.2f (cash: %.2f)",
+		resting.ExecutionID, order.Side, order.Quantity, order.Symbol, price, p.cash)
+	if p.onFill != nil {
+		p.onFill(fill)
+	}
+	return fill
+}
+
+// apply adds a signed quantity at price, realising P&L on any part that closes the position
+func (pos *PaperPosition) apply(signed int, price float64) {
+	if pos.Quantity == 0 || (pos.Quantity > 0) == (signed > 0) {
+		total := abs(pos.Quantity) + abs(signed)
+		pos.AveragePrice = (pos.AveragePrice*float64(abs(pos.Quantity)) + price*float64(abs(signed))) / float64(total)
+		pos.Quantity += signed
+		return
+	}
+
+	closing := abs(signed)
+	if closing > abs(pos.Quantity) {
+		closing = abs(pos.Quantity)
+	}
+	if pos.Quantity > 0 {
+		pos.RealisedPnL += (price - pos.AveragePrice) * float64(closing)
+	} else {
+		pos.RealisedPnL += (pos.AveragePrice - price) * float64(closing)
+	}
+
+	wasLong := pos.Quantity > 0
+	pos.Quantity += signed
+	switch {
+	case pos.Quantity == 0:
+		pos.AveragePrice = 0
+	case (pos.Quantity > 0) != wasLong:
+		// Flipped through zero: the remainder opens a new position at this price
+		pos.AveragePrice = price
+	}
+}
+
+// matchPrice decides whether an order trades at ltp and at what price. Orders that cross on
+// arrival trade at the market price; resting orders that are crossed later trade at their limit.
+func matchPrice(order models.Order, ltp float64, onArrival bool) (float64, bool) {
+	if strings.ToUpper(order.OrderType) != "LIMIT" {
+		return ltp, ltp > 0
+	}
+
+	isBuy := !strings.EqualFold(order.Side, "Sell")
+	crosses := (isBuy && ltp <= order.Price) || (!isBuy && ltp >= order.Price)
+	if !crosses {
+		return 0, false
+	}
+	if onArrival {
+		return ltp, true
+	}
+	return order.Price, true
+}
+
+// loadPrices reads initial last traded prices from a CSV of exchange,symbol,price
+func (p *PaperBroker) loadPrices(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 3
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+		if err != nil {
+			// Header or malformed row
+			continue
+		}
+		p.prices[instrumentKey(record[0], record[1])] = price
+		p.opens[instrumentKey(record[0], record[1])] = price
+	}
+
+	p.logger.Info("📝 Loaded %d paper prices from %s", len(p.prices), path)
+	return nil
+}
+
+// instrumentKey identifies an instrument as EXCHANGE:SYMBOL
+func instrumentKey(exchange, symbol string) string {
+	exchange = strings.ToUpper(strings.TrimSpace(exchange))
+	if exchange == "" {
+		exchange = "NSE"
+	}
+	return exchange + ":" + strings.ToUpper(strings.TrimSpace(symbol))
+}
+
+// abs returns the absolute value of an integer
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,363 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/broker/kitetest"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/journal"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// newTestPaper returns a paper broker with 100000 cash and no latency; configure adjusts
+// the paper settings before it is created
+func newTestPaper(t *testing.T, configure func(cfg *config.Config)) *PaperBroker {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Broker.Paper.Seed = 1
+	cfg.Broker.Paper.InitialCash = 100000
+	cfg.Journal.Path = filepath.Join(t.TempDir(), "journal.jsonl")
+	if configure != nil {
+		configure(cfg)
+	}
+	paper, err := NewPaperBroker(cfg, newTestLogger(t))
+	if err != nil {
+		t.Fatalf("NewPaperBroker: %v", err)
+	}
+	return paper
+}
+
+func paperOrder(id, side, orderType string, price float64, quantity int) models.Order {
+	return models.Order{ID: id, Symbol: "INFY", Exchange: "NSE", Side: side, OrderType: orderType,
+		Price: price, Quantity: quantity}
+}
+
+func TestPaperPollQuotesFillsRestingOrders(t *testing.T) {
+	ctx := context.Background()
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+	paper := newTestPaper(t, func(cfg *config.Config) {
+		server.Configure(cfg)
+		cfg.Broker.Paper.QuoteSource = config.PaperQuoteSourceKite
+	})
+	if !paper.HasQuoteSource() {
+		t.Fatal("HasQuoteSource = false with a Kite quote source")
+	}
+
+	// With no price yet the buy rests in the book
+	if _, err := paper.ExecuteOrder(ctx, paperOrder("BUY", "Buy", "LIMIT", 100, 10)); err != nil {
+		t.Fatalf("ExecuteOrder: %v", err)
+	}
+
+	server.SetQuote("NSE", "INFY", 105)
+	server.SetQuote("NSE", "TCS", 3000)
+	fills, err := paper.PollQuotes(ctx, []string{"nse:tcs"})
+	if err != nil {
+		t.Fatalf("PollQuotes: %v", err)
+	}
+	if len(fills) != 0 || len(paper.OpenOrders()) != 1 {
+		t.Fatalf("fills = %+v at 105, want the buy at 100 still resting", fills)
+	}
+	latest, _ := paper.Quotes(ctx, []string{"NSE:TCS"})
+	if latest["NSE:TCS"].LastPrice != 3000 {
+		t.Errorf("TCS last price = %.2f, want the polled 3000", latest["NSE:TCS"].LastPrice)
+	}
+
+	server.SetQuote("NSE", "INFY", 99)
+	fills, err = paper.PollQuotes(ctx, nil)
+	if err != nil {
+		t.Fatalf("PollQuotes: %v", err)
+	}
+	if len(fills) != 1 || fills[0].Price != 100 || len(paper.OpenOrders()) != 0 {
+		t.Fatalf("fills = %+v at 99, want the resting buy filled at its limit", fills)
+	}
+
+	// A failed poll leaves prices as they were
+	server.FailNext(kitetest.PathQuote, kitetest.Failure{Status: 500})
+	if _, err := paper.PollQuotes(ctx, []string{"NSE:INFY"}); err == nil {
+		t.Error("PollQuotes succeeded with the quote source failing")
+	}
+}
+
+func TestPaperWithoutQuoteSource(t *testing.T) {
+	paper := newTestPaper(t, nil)
+	if paper.HasQuoteSource() {
+		t.Fatal("HasQuoteSource = true with no quote source configured")
+	}
+	if fills, err := paper.PollQuotes(context.Background(), []string{"NSE:INFY"}); fills != nil || err != nil {
+		t.Errorf("PollQuotes = %v, %v; want nothing to do", fills, err)
+	}
+
+	cfg := &config.Config{}
+	cfg.Journal.Path = filepath.Join(t.TempDir(), "journal.jsonl")
+	cfg.Broker.Paper.QuoteSource = "yahoo"
+	if _, err := NewPaperBroker(cfg, newTestLogger(t)); err == nil {
+		t.Error("NewPaperBroker accepted an unknown quote source")
+	}
+}
+
+// runPaperDay places the same mix of orders and price moves on a fresh broker and returns
+// its fills and the orders it rejected
+func runPaperDay(t *testing.T, seed int64) ([]models.Fill, []string) {
+	t.Helper()
+	ctx := context.Background()
+	paper := newTestPaper(t, func(cfg *config.Config) {
+		cfg.Broker.Paper.Seed = seed
+		cfg.Broker.Paper.RejectRate = 0.3
+		cfg.Broker.Paper.LatencyJitterMs = 2
+	})
+	paper.SetClock(clock.NewSimulated(time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC)))
+	paper.UpdatePrice("NSE", "INFY", 100, time.Time{})
+
+	var rejected []string
+	for i := 0; i < 20; i++ {
+		side := "Buy"
+		if i%3 == 2 {
+			side = "Sell"
+		}
+		order := paperOrder(fmt.Sprintf("ORDER-%d", i), side, "LIMIT", float64(95+i%10), 5)
+		if _, err := paper.ExecuteOrder(ctx, order); err != nil {
+			rejected = append(rejected, order.ID)
+		}
+		paper.UpdatePrice("NSE", "INFY", float64(94+(i*7)%12), time.Time{})
+	}
+	return paper.Fills(), rejected
+}
+
+func TestPaperSameSeedSameResults(t *testing.T) {
+	fills, rejected := runPaperDay(t, 42)
+	if len(fills) == 0 || len(rejected) == 0 {
+		t.Fatalf("%d fills and %d rejections; want some of each to compare", len(fills), len(rejected))
+	}
+
+	againFills, againRejected := runPaperDay(t, 42)
+	if !reflect.DeepEqual(fills, againFills) || !reflect.DeepEqual(rejected, againRejected) {
+		t.Errorf("seed 42 gave different results on a second run:\n%v %v\n%v %v", fills, rejected, againFills, againRejected)
+	}
+
+	if _, otherRejected := runPaperDay(t, 7); reflect.DeepEqual(rejected, otherRejected) {
+		t.Errorf("seeds 42 and 7 rejected the same orders %v; want the seed to drive rejections", rejected)
+	}
+}
+
+func TestPaperLimitCrossing(t *testing.T) {
+	tests := []struct {
+		name      string
+		side      string
+		orderType string
+		limit     float64
+		arrival   float64 // Last price when the order arrives
+		update    float64 // Price fed afterwards; 0 for none
+		filled    bool
+		fillPrice float64
+	}{
+		{"buy at or above the price fills at the price", "Buy", "LIMIT", 101, 100, 0, true, 100},
+		{"buy at the price fills", "Buy", "LIMIT", 100, 100, 0, true, 100},
+		{"buy below the price rests", "Buy", "LIMIT", 99, 100, 0, false, 0},
+		{"resting buy fills at its limit when the price falls through it", "Buy", "LIMIT", 99, 100, 98, true, 99},
+		{"resting buy stays above its limit", "Buy", "LIMIT", 99, 100, 99.5, false, 0},
+		{"sell at or below the price fills at the price", "Sell", "LIMIT", 99, 100, 0, true, 100},
+		{"sell above the price rests", "Sell", "LIMIT", 101, 100, 0, false, 0},
+		{"resting sell fills at its limit when the price rises through it", "Sell", "LIMIT", 101, 100, 102, true, 101},
+		{"resting sell stays below its limit", "Sell", "LIMIT", 101, 100, 100.5, false, 0},
+		{"market buy fills at the price", "Buy", "MARKET", 0, 100, 0, true, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			paper := newTestPaper(t, func(cfg *config.Config) { cfg.Broker.Paper.AllowShort = true })
+			paper.UpdatePrice("NSE", "INFY", tt.arrival, time.Time{})
+
+			result, err := paper.ExecuteOrder(ctx, paperOrder("ORDER", tt.side, tt.orderType, tt.limit, 10))
+			if err != nil {
+				t.Fatalf("ExecuteOrder: %v", err)
+			}
+			if tt.update > 0 {
+				paper.UpdatePrice("NSE", "INFY", tt.update, time.Time{})
+			} else if result.ExecutedPrice != tt.fillPrice {
+				t.Errorf("executed at %.2f on arrival, want %.2f", result.ExecutedPrice, tt.fillPrice)
+			}
+
+			fills := paper.Fills()
+			if filled := len(fills) == 1; filled != tt.filled {
+				t.Fatalf("fills = %+v, want filled %v", fills, tt.filled)
+			}
+			if tt.filled && fills[0].Price != tt.fillPrice {
+				t.Errorf("filled at %.2f, want %.2f", fills[0].Price, tt.fillPrice)
+			}
+			if resting := len(paper.OpenOrders()) == 1; resting == tt.filled {
+				t.Errorf("%d orders resting, want the order resting only while unfilled", len(paper.OpenOrders()))
+			}
+		})
+	}
+}
+
+func TestPaperRejectReasons(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Config)
+		order     models.Order
+		reason    string // Empty when the order is accepted
+	}{
+		{"buy within cash", nil, paperOrder("ORDER", "Buy", "LIMIT", 100, 991), ""},
+		{"buy beyond cash", nil, paperOrder("ORDER", "Buy", "LIMIT", 100, 992), "insufficient funds"},
+		{"market buy beyond cash at the last price", nil, paperOrder("ORDER", "Buy", "MARKET", 0, 992), "insufficient funds"},
+		{"sell within the position", nil, paperOrder("ORDER", "Sell", "LIMIT", 120, 10), ""},
+		{"short sell", nil, paperOrder("ORDER", "Sell", "LIMIT", 120, 11), "insufficient position"},
+		{"short sell allowed", func(cfg *config.Config) { cfg.Broker.Paper.AllowShort = true },
+			paperOrder("ORDER", "Sell", "LIMIT", 120, 11), ""},
+		{"zero quantity", nil, paperOrder("ORDER", "Buy", "LIMIT", 100, 0), "quantity must be positive"},
+		{"rejected symbol", func(cfg *config.Config) { cfg.Broker.Paper.RejectSymbols = []string{" infy "} },
+			paperOrder("ORDER", "Buy", "LIMIT", 100, 1), "configured to be rejected"},
+		{"random rejection", func(cfg *config.Config) { cfg.Broker.Paper.RejectRate = 1 },
+			paperOrder("ORDER", "Buy", "LIMIT", 100, 1), "simulated random rejection"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			paper := newTestPaper(t, tt.configure)
+			holdPaper(t, paper)
+
+			result, err := paper.ExecuteOrder(ctx, tt.order)
+			if tt.reason == "" {
+				if err != nil {
+					t.Fatalf("ExecuteOrder: %v, want it accepted", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(result.ErrorMessage, tt.reason) || result.Success {
+				t.Errorf("ExecuteOrder = %+v, %v; want rejected with %q", result, err, tt.reason)
+			}
+		})
+	}
+}
+
+// holdPaper buys 10 INFY at 90, leaving 99100 cash, and moves the price to 100. Under
+// rejection rules that refuse every order the broker is left holding nothing.
+func holdPaper(t *testing.T, paper *PaperBroker) {
+	t.Helper()
+	paper.UpdatePrice("NSE", "INFY", 90, time.Time{})
+	paper.ExecuteOrder(context.Background(), paperOrder("HOLD", "Buy", "LIMIT", 90, 10))
+	paper.UpdatePrice("NSE", "INFY", 100, time.Time{})
+}
+
+func TestPaperRestingBuysReserveCash(t *testing.T) {
+	ctx := context.Background()
+	paper := newTestPaper(t, nil)
+	holdPaper(t, paper)
+
+	// A buy resting at 50 blocks 50000 of the 99100 cash
+	if _, err := paper.ExecuteOrder(ctx, paperOrder("REST", "Buy", "LIMIT", 50, 1000)); err != nil {
+		t.Fatalf("ExecuteOrder(REST): %v", err)
+	}
+	if _, err := paper.ExecuteOrder(ctx, paperOrder("ORDER", "Buy", "LIMIT", 100, 492)); err == nil {
+		t.Error("a buy for 49200 was accepted with 49100 left unreserved")
+	}
+	if _, err := paper.ExecuteOrder(ctx, paperOrder("ORDER", "Buy", "LIMIT", 100, 491)); err != nil {
+		t.Errorf("a buy for 49100 was rejected with 49100 left unreserved: %v", err)
+	}
+}
+
+func TestPaperLatencyHonoursCancellation(t *testing.T) {
+	paper := newTestPaper(t, func(cfg *config.Config) { cfg.Broker.Paper.LatencyMs = 10000 })
+	paper.UpdatePrice("NSE", "INFY", 100, time.Time{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := paper.ExecuteOrder(ctx, paperOrder("ORDER", "Buy", "MARKET", 0, 1))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ExecuteOrder = %v, want the context's deadline error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ExecuteOrder returned after %v, want it to stop waiting when the context ends", elapsed)
+	}
+	if len(paper.Fills()) != 0 || len(paper.OpenOrders()) != 0 {
+		t.Error("a cancelled order reached the book")
+	}
+}
+
+func TestPaperFillBookkeeping(t *testing.T) {
+	ctx := context.Background()
+	paper := newTestPaper(t, func(cfg *config.Config) { cfg.Broker.Paper.AllowShort = true })
+
+	var notified []models.Fill
+	paper.OnFill(func(fill models.Fill) { notified = append(notified, fill) })
+
+	steps := []struct {
+		side     string
+		quantity int
+		price    float64
+		cash     float64
+		position int
+		average  float64
+		realised float64 // Realised P&L of this fill
+		total    float64 // Position's realised P&L so far
+	}{
+		{"Buy", 10, 100, 99000, 10, 100, 0, 0},
+		{"Buy", 10, 110, 97900, 20, 105, 0, 0},
+		{"Sell", 5, 120, 98500, 15, 105, 75, 75},
+		{"Sell", 25, 90, 100750, -10, 90, -225, -150}, // Closes 15 at a loss and opens a 10 short
+		{"Buy", 4, 80, 100430, -6, 90, 40, -110},
+		{"Buy", 6, 95, 99860, 0, 0, -30, -140},
+	}
+	for i, step := range steps {
+		paper.UpdatePrice("NSE", "INFY", step.price, time.Time{})
+		if _, err := paper.ExecuteOrder(ctx, paperOrder(fmt.Sprintf("STEP-%d", i), step.side, "MARKET", 0, step.quantity)); err != nil {
+			t.Fatalf("step %d: ExecuteOrder: %v", i, err)
+		}
+
+		positions := paper.Positions()
+		if len(positions) != 1 {
+			t.Fatalf("step %d: positions = %+v, want INFY", i, positions)
+		}
+		got := positions[0]
+		fill := paper.Fills()[i]
+		if paper.Cash() != step.cash || got.Quantity != step.position || math.Abs(got.AveragePrice-step.average) > 1e-9 ||
+			fill.RealisedPnL != step.realised || got.RealisedPnL != step.total {
+			t.Errorf("step %d: cash %.2f, position %d @ %.2f, fill P&L %.2f, total P&L %.2f; want %.2f, %d @ %.2f, %.2f, %.2f",
+				i, paper.Cash(), got.Quantity, got.AveragePrice, fill.RealisedPnL, got.RealisedPnL,
+				step.cash, step.position, step.average, step.realised, step.total)
+		}
+	}
+	if !reflect.DeepEqual(notified, paper.Fills()) {
+		t.Errorf("OnFill saw %d fills, want every fill", len(notified))
+	}
+}
+
+func TestPaperFillsAreJournaled(t *testing.T) {
+	ctx := context.Background()
+	var journalPath string
+	paper := newTestPaper(t, func(cfg *config.Config) { journalPath = cfg.Journal.Path })
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	paper.UpdatePrice("NSE", "INFY", 100, day.Add(9*time.Hour))
+	if _, err := paper.ExecuteOrder(ctx, paperOrder("BUY", "Buy", "LIMIT", 95, 10)); err != nil {
+		t.Fatalf("ExecuteOrder: %v", err)
+	}
+	paper.UpdatePrice("NSE", "INFY", 94, day.Add(10*time.Hour))
+
+	entries, err := journal.ReadEntries(journalPath, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("ReadEntries: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("journal has %d entries, want the one fill", len(entries))
+	}
+	entry := entries[0]
+	if entry.Event != models.JournalEventFill || entry.Broker != "paper" || entry.Fill == nil ||
+		!reflect.DeepEqual(*entry.Fill, paper.Fills()[0]) || !entry.Timestamp.Equal(day.Add(10*time.Hour)) {
+		t.Errorf("journal entry = %+v, want the fill at 10:00", entry)
+	}
+}
@@ -0,0 +1,165 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/metrics"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// ErrInsufficientHoldings is wrapped by errors for sell orders larger than the quantity held
+var ErrInsufficientHoldings = errors.New("insufficient holdings")
+
+// ErrPortfolioUnsupported is returned when the broker cannot report holdings and positions
+var ErrPortfolioUnsupported = errors.New("broker does not report holdings and positions")
+
+// PortfolioProvider is implemented by brokers that can report holdings and positions
+type PortfolioProvider interface {
+	Portfolio(ctx context.Context) (models.Portfolio, error)
+}
+
+// PortfolioView caches a broker's portfolio and tracks the sell quantity reserved
+// against it since it was fetched, so concurrent sells cannot oversell a holding
+type PortfolioView struct {
+	provider        PortfolioProvider
+	refreshInterval time.Duration
+	clock           clock.Clock
+	mu              sync.Mutex
+	portfolio       models.Portfolio
+	fetchedAt       time.Time
+	reserved        map[string]int // Sell quantity reserved per symbol since the last fetch
+}
+
+// NewPortfolioView creates a view that fetches from provider at most once per refreshInterval
+func NewPortfolioView(provider PortfolioProvider, refreshInterval time.Duration) *PortfolioView {
+	return &PortfolioView{
+		provider:        provider,
+		refreshInterval: refreshInterval,
+		clock:           clock.Real{},
+		reserved:        make(map[string]int),
+	}
+}
+
+// SetClock replaces the clock used to age the cached portfolio
+func (v *PortfolioView) SetClock(c clock.Clock) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.clock = c
+}
+
+// Get returns the cached portfolio, fetching it first if it is stale or refresh is set
+func (v *PortfolioView) Get(ctx context.Context, refresh bool) (models.Portfolio, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.ensureFresh(ctx, refresh); err != nil {
+		return models.Portfolio{}, err
+	}
+	return v.portfolio, nil
+}
+
+// Reserve takes up to quantity of symbol from what is still available and returns the
+// quantity reserved along with what was available before the reservation
+func (v *PortfolioView) Reserve(ctx context.Context, symbol string, quantity int) (reserved, available int, err error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.ensureFresh(ctx, false); err != nil {
+		return 0, 0, err
+	}
+
+	symbol = models.PortfolioSymbol(symbol)
+	available = v.portfolio.Available(symbol) - v.reserved[symbol]
+	if available < 0 {
+		available = 0
+	}
+	reserved = quantity
+	if reserved > available {
+		reserved = available
+	}
+	v.reserved[symbol] += reserved
+	return reserved, available, nil
+}
+
+// Release returns quantity reserved for a sell that was not placed
+func (v *PortfolioView) Release(symbol string, quantity int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	symbol = models.PortfolioSymbol(symbol)
+	if v.reserved[symbol] -= quantity; v.reserved[symbol] <= 0 {
+		delete(v.reserved, symbol)
+	}
+}
+
+// ensureFresh fetches the portfolio if needed; callers must hold v.mu. A fetch replaces
+// the reservations because the broker's used quantities now include the placed sells.
+func (v *PortfolioView) ensureFresh(ctx context.Context, force bool) error {
+	now := v.clock.Now()
+	if !force && !v.fetchedAt.IsZero() && now.Sub(v.fetchedAt) < v.refreshInterval {
+		return nil
+	}
+	portfolio, err := v.provider.Portfolio(ctx)
+	if err != nil {
+		return err
+	}
+	v.portfolio = portfolio
+	v.fetchedAt = now
+	v.reserved = make(map[string]int)
+	return nil
+}
+
+// Portfolio returns the broker's cached portfolio, fetching it if stale or refresh is set
+func (bm *BrokerManager) Portfolio(ctx context.Context, refresh bool) (models.Portfolio, error) {
+	if bm.portfolio == nil {
+		return models.Portfolio{}, ErrPortfolioUnsupported
+	}
+	return bm.portfolio.Get(ctx, refresh)
+}
+
+// checkSell applies the sell policy to order and returns the order to place along with
+// the quantity reserved for it. Sells go out unchecked when no policy is configured, the
+// broker has no portfolio or it cannot be fetched, as they did before the check existed.
+// Only delivery (CNC) sells are checked.
+func (bm *BrokerManager) checkSell(ctx context.Context, order models.Order) (models.Order, int, error) {
+	policy := bm.config.Broker.Portfolio.SellPolicy
+	if !strings.EqualFold(order.Side, "SELL") || policy == "" || policy == config.SellPolicyOff {
+		return order, 0, nil
+	}
+	// Intraday and futures and options sells open or close positions rather than draw on holdings
+	if order.Product != "" && !strings.EqualFold(order.Product, "CNC") {
+		return order, 0, nil
+	}
+	if bm.portfolio == nil {
+		metrics.SellOrdersChecked.WithLabelValues("unchecked").Inc()
+		return order, 0, nil
+	}
+
+	reserved, available, err := bm.portfolio.Reserve(ctx, order.Symbol, order.Quantity)
+	if err != nil {
+		metrics.SellOrdersChecked.WithLabelValues("unchecked").Inc()
+		bm.logger.Warn("⚠️  Could not fetch portfolio, sending sell order %s unchecked: %v", order.ID, err)
+		return order, 0, nil
+	}
+	if reserved == order.Quantity {
+		metrics.SellOrdersChecked.WithLabelValues("allowed").Inc()
+		return order, reserved, nil
+	}
+
+	if reserved == 0 || policy == config.SellPolicyReject {
+		bm.portfolio.Release(order.Symbol, reserved)
+		metrics.SellOrdersChecked.WithLabelValues("rejected").Inc()
+		return order, 0, rejected(fmt.Errorf("%w: sell of %d %s exceeds available quantity %d",
+			ErrInsufficientHoldings, order.Quantity, order.Symbol, available))
+	}
+
+	bm.logger.Warn("✂️  Capping sell order %s from %d to %d %s (available quantity)",
+		order.ID, order.Quantity, reserved, order.Symbol)
+	metrics.SellOrdersChecked.WithLabelValues("capped").Inc()
+	order.Quantity = reserved
+	return order, reserved, nil
+}
@@ -0,0 +1,159 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/mach_five/trading-system/internal/broker/kitetest"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// newTestManager returns a Kite broker manager with the given sell policy; the stand-in
+// holds 30 settled + 10 T1 INFY shares with 5 already used, a 5-share CNC position
+// bought today and a 100-share intraday position that does not count
+func newTestManager(t *testing.T, policy string) (*BrokerManager, *kitetest.Server) {
+	t.Helper()
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+	server.AddHolding("NSE", "INFY", 30, 10, 5)
+	server.AddPosition("NSE", "INFY", "CNC", 5)
+	server.AddPosition("NSE", "INFY", "MIS", 100)
+
+	cfg := &config.Config{}
+	server.Configure(cfg)
+	cfg.Broker.RateLimit = config.RateLimitConfig{RequestsPerSecond: 100, BurstSize: 100}
+	cfg.Broker.Portfolio = config.PortfolioConfig{SellPolicy: policy, RefreshInterval: time.Minute}
+
+	manager, err := NewBrokerManager(cfg, newTestLogger(t))
+	if err != nil {
+		t.Fatalf("NewBrokerManager: %v", err)
+	}
+	return manager, server
+}
+
+func sellOrder(id string, quantity int) models.Order {
+	order := kiteOrder(false)
+	order.ID = id
+	order.Side = "Sell"
+	order.Quantity = quantity
+	return order
+}
+
+func TestKitePortfolio(t *testing.T) {
+	manager, _ := newTestManager(t, config.SellPolicyCap)
+
+	portfolio, err := manager.Portfolio(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Portfolio: %v", err)
+	}
+	if len(portfolio.Holdings) != 1 || len(portfolio.Positions) != 2 {
+		t.Fatalf("portfolio = %+v, want 1 holding and 2 positions", portfolio)
+	}
+	if h := portfolio.Holdings[0]; h.Symbol != "INFY" || h.Quantity != 30 || h.T1Quantity != 10 || h.UsedQuantity != 5 {
+		t.Errorf("holding = %+v", h)
+	}
+	if got := portfolio.Available("NSE:infy"); got != 40 {
+		t.Errorf("Available = %d, want 35 sellable + 5 CNC bought today", got)
+	}
+}
+
+func TestBrokerManagerCapsSells(t *testing.T) {
+	manager, server := newTestManager(t, config.SellPolicyCap)
+	ctx := context.Background()
+
+	result, err := manager.ExecuteOrder(ctx, sellOrder("S-1", 50))
+	if err != nil || !result.Success || result.CappedQuantity != 40 {
+		t.Fatalf("ExecuteOrder = %+v, %v; want success capped to 40", result, err)
+	}
+	if orders := server.Orders(); len(orders) != 1 || orders[0].Form.Get("quantity") != "40" {
+		t.Fatalf("stand-in received %+v, want one sell of 40", orders)
+	}
+
+	// The first sell reserved everything until the portfolio is fetched again
+	_, err = manager.ExecuteOrder(ctx, sellOrder("S-2", 10))
+	if !errors.Is(err, ErrInsufficientHoldings) {
+		t.Fatalf("second sell error = %v, want ErrInsufficientHoldings", err)
+	}
+	if len(server.Orders()) != 1 {
+		t.Errorf("rejected sell reached the broker")
+	}
+
+	// Buys are never checked
+	if result, err := manager.ExecuteOrder(ctx, kiteOrder(false)); err != nil || result.CappedQuantity != 0 {
+		t.Errorf("buy = %+v, %v; want unchecked success", result, err)
+	}
+	if got := server.Requests(kitetest.PathHoldings); got != 1 {
+		t.Errorf("holdings fetched %d times, want 1 within the refresh interval", got)
+	}
+}
+
+func TestBrokerManagerRejectsSells(t *testing.T) {
+	manager, server := newTestManager(t, config.SellPolicyReject)
+	ctx := context.Background()
+
+	result, err := manager.ExecuteOrder(ctx, sellOrder("S-1", 41))
+	if !errors.Is(err, ErrInsufficientHoldings) || !errors.Is(err, ErrOrderRejected) || result.Success || result.ErrorMessage == "" {
+		t.Fatalf("ExecuteOrder = %+v, %v; want ErrInsufficientHoldings as a local rejection", result, err)
+	}
+
+	// A failed placement releases its reservation
+	server.FailNext(kitetest.PathRegularOrder, kitetest.Failure{Status: http.StatusBadRequest, ErrorType: "InputException", Message: "Invalid price"})
+	if _, err := manager.ExecuteOrder(ctx, sellOrder("S-2", 40)); err == nil || errors.Is(err, ErrOrderRejected) {
+		t.Fatalf("ExecuteOrder error = %v, want the queued broker failure", err)
+	}
+	if result, err := manager.ExecuteOrder(ctx, sellOrder("S-3", 40)); err != nil || !result.Success {
+		t.Fatalf("retry = %+v, %v; want success after the reservation was released", result, err)
+	}
+	if orders := server.Orders(); len(orders) != 1 || orders[0].Form.Get("quantity") != "40" {
+		t.Errorf("stand-in received %+v, want one sell of 40", orders)
+	}
+}
+
+func TestLocalRejectionsDoNotUseRateLimit(t *testing.T) {
+	manager, server := newTestManager(t, config.SellPolicyReject)
+	manager.rateLimit = rate.NewLimiter(rate.Every(time.Hour), 1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for _, id := range []string{"S-1", "S-2", "S-3"} {
+		if _, err := manager.ExecuteOrder(ctx, sellOrder(id, 41)); !errors.Is(err, ErrOrderRejected) {
+			t.Fatalf("ExecuteOrder(%s) error = %v, want a local rejection", id, err)
+		}
+	}
+
+	// The only token is still there for the first order that goes to the broker
+	if result, err := manager.ExecuteOrder(ctx, kiteOrder(false)); err != nil || !result.Success {
+		t.Fatalf("buy = %+v, %v; want it sent with the rate limit token the rejections left", result, err)
+	}
+	if _, err := manager.ExecuteOrder(ctx, kiteOrder(false)); err == nil || !strings.Contains(err.Error(), "rate limit") {
+		t.Errorf("second buy error = %v, want it held by the rate limit", err)
+	}
+	if len(server.Orders()) != 1 {
+		t.Errorf("stand-in received %d orders, want 1", len(server.Orders()))
+	}
+}
+
+func TestBrokerManagerSendsSellsUncheckedWhenPortfolioFails(t *testing.T) {
+	manager, server := newTestManager(t, config.SellPolicyReject)
+	server.FailNext(kitetest.PathHoldings, kitetest.Failure{Status: http.StatusServiceUnavailable, ErrorType: "NetworkException", Message: "down"})
+
+	result, err := manager.ExecuteOrder(context.Background(), sellOrder("S-1", 500))
+	if err != nil || !result.Success || result.CappedQuantity != 0 {
+		t.Fatalf("ExecuteOrder = %+v, %v; want the sell sent unchecked", result, err)
+	}
+
+	mock, err := NewBrokerManager(&config.Config{Broker: config.BrokerConfig{Type: "mock"}}, newTestLogger(t))
+	if err != nil {
+		t.Fatalf("NewBrokerManager(mock): %v", err)
+	}
+	if _, err := mock.Portfolio(context.Background(), false); !errors.Is(err, ErrPortfolioUnsupported) {
+		t.Errorf("mock Portfolio error = %v, want ErrPortfolioUnsupported", err)
+	}
+}
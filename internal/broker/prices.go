@@ -0,0 +1,157 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/instruments"
+	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/pricing"
+)
+
+// CircuitLimitProvider is implemented by brokers that can report an instrument's circuit
+// band for the day
+type CircuitLimitProvider interface {
+	CircuitLimits(ctx context.Context, exchange, symbol string) (pricing.Band, error)
+}
+
+// cachedBand is a circuit band fetched for one instruments day
+type cachedBand struct {
+	day  string
+	band pricing.Band
+}
+
+// CircuitLimits fetches an instrument's daily circuit limits from the Kite full quote
+func (k *KiteBroker) CircuitLimits(ctx context.Context, exchange, symbol string) (pricing.Band, error) {
+	instrument := strings.ToUpper(exchange) + ":" + strings.ToUpper(symbol)
+	var quotes map[string]struct {
+		LowerCircuitLimit float64 `json:"lower_circuit_limit"`
+		UpperCircuitLimit float64 `json:"upper_circuit_limit"`
+	}
+	if err := k.getData(ctx, "/quote?i="+url.QueryEscape(instrument), &quotes); err != nil {
+		return pricing.Band{}, fmt.Errorf("failed to fetch circuit limits: %w", err)
+	}
+	quote, ok := quotes[instrument]
+	if !ok {
+		return pricing.Band{}, fmt.Errorf("no quote for %s", instrument)
+	}
+	return pricing.Band{Lower: quote.LowerCircuitLimit, Upper: quote.UpperCircuitLimit}, nil
+}
+
+// normalizePrice puts a limit order's price on its instrument's tick grid and applies the
+// price band policy, appending any change to order.PriceAdjustments. The tick size comes
+// from the instrument master and the band from those prefetched; either is skipped when unknown.
+func (bm *BrokerManager) normalizePrice(ctx context.Context, order models.Order) (models.Order, error) {
+	if !strings.EqualFold(order.OrderType, "LIMIT") {
+		return order, nil
+	}
+	exchange, symbol := orderInstrument(order)
+
+	var tickSize float64
+	if bm.instruments != nil {
+		master, err := bm.instruments.Master(ctx)
+		if err != nil {
+			bm.logger.Warn("⚠️  Instrument master unavailable, %s price not rounded to tick size: %v", order.ID, err)
+		} else if instrument, ok := master.Lookup(exchange, symbol); ok {
+			tickSize = instrument.TickSize
+		}
+	}
+
+	policy := bm.config.Broker.Prices.BandPolicy
+	var band pricing.Band
+	if policy != "" && policy != config.PriceBandPolicyOff {
+		band = bm.circuitBand(exchange, symbol)
+	}
+
+	adjusted := len(order.PriceAdjustments)
+	order.PriceAdjustments = append([]models.PriceAdjustment(nil), order.PriceAdjustments...)
+	if err := pricing.Normalize(&order, tickSize, band, policy, bm.clock.Now()); err != nil {
+		return order, rejected(err)
+	}
+	for _, adjustment := range order.PriceAdjustments[adjusted:] {
+		bm.logger.Warn("🔧 Order %s %s price moved from %.2f to %.2f (%s)", order.ID, strings.ToLower(order.Side),
+			adjustment.From, adjustment.To, adjustment.Reason)
+	}
+	return order, nil
+}
+
+// PrefetchBands fetches the circuit bands of the instruments of limit orders that are not yet
+// cached for the instruments day, so that ExecuteOrder never waits on a quote. Instruments
+// whose limits cannot be fetched are retried on the next call.
+func (bm *BrokerManager) PrefetchBands(ctx context.Context, orders []models.Order) {
+	policy := bm.config.Broker.Prices.BandPolicy
+	if policy == "" || policy == config.PriceBandPolicyOff {
+		return
+	}
+	provider, ok := bm.broker.(CircuitLimitProvider)
+	if !ok {
+		return
+	}
+	day := instruments.Day(bm.clock.Now())
+
+	seen := make(map[string]bool)
+	for _, order := range orders {
+		if !strings.EqualFold(order.OrderType, "LIMIT") {
+			continue
+		}
+		exchange, symbol := orderInstrument(order)
+		key := exchange + ":" + symbol
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		bm.mu.RLock()
+		cached, ok := bm.bands[key]
+		bm.mu.RUnlock()
+		if ok && cached.day == day {
+			continue
+		}
+
+		band, err := provider.CircuitLimits(ctx, exchange, symbol)
+		if err != nil {
+			bm.logger.Warn("⚠️  Could not fetch circuit limits for %s: %v", key, err)
+			continue
+		}
+		bm.mu.Lock()
+		bm.bands[key] = cachedBand{day: day, band: band}
+		bm.mu.Unlock()
+	}
+}
+
+// circuitBand returns the instrument's circuit band cached by PrefetchBands for the
+// instruments day, or a zero band when the broker cannot report it or it was not fetched
+func (bm *BrokerManager) circuitBand(exchange, symbol string) pricing.Band {
+	if _, ok := bm.broker.(CircuitLimitProvider); !ok {
+		return pricing.Band{}
+	}
+	key := exchange + ":" + symbol
+
+	bm.mu.RLock()
+	cached, ok := bm.bands[key]
+	bm.mu.RUnlock()
+	if !ok || cached.day != instruments.Day(bm.clock.Now()) {
+		bm.logger.Warn("⚠️  No circuit limits fetched for %s today, price band not checked", key)
+		return pricing.Band{}
+	}
+	return cached.band
+}
+
+// orderInstrument returns the upper-cased exchange and tradingsymbol an order trades,
+// taking the exchange from an EXCHANGE:SYMBOL symbol when the order does not set one
+func orderInstrument(order models.Order) (string, string) {
+	exchange, symbol := strings.ToUpper(order.Exchange), strings.ToUpper(order.Symbol)
+	if prefix, rest, ok := strings.Cut(symbol, ":"); ok {
+		if exchange == "" {
+			exchange = prefix
+		}
+		symbol = rest
+	}
+	if exchange == "" {
+		exchange = "NSE"
+	}
+	return exchange, symbol
+}
@@ -0,0 +1,91 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/broker/kitetest"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/pricing"
+)
+
+func TestBrokerManagerNormalizesLimitPrices(t *testing.T) {
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+	server.AddInstrument(kitetest.Instrument{Token: 408065, ExchangeToken: "1594", Tradingsymbol: "INFY", Name: "INFOSYS",
+		TickSize: 0.05, LotSize: 1, InstrumentType: "EQ", Segment: "NSE", Exchange: "NSE"})
+	server.SetCircuitLimits("NSE", "INFY", 1400, 1600)
+
+	cfg := &config.Config{}
+	server.Configure(cfg)
+	cfg.Broker.RateLimit = config.RateLimitConfig{RequestsPerSecond: 100, BurstSize: 100}
+	cfg.Broker.Prices.BandPolicy = config.PriceBandPolicyAdjust
+	cfg.Instruments = config.InstrumentsConfig{Enabled: true, CacheDir: t.TempDir()}
+	manager, err := NewBrokerManager(cfg, newTestLogger(t))
+	if err != nil {
+		t.Fatalf("NewBrokerManager: %v", err)
+	}
+	manager.SetClock(clock.NewSimulated(time.Date(2024, 1, 15, 4, 0, 0, 0, time.UTC)))
+	ctx := context.Background()
+
+	// Bands are fetched ahead of execution, once a day per instrument
+	manager.PrefetchBands(ctx, []models.Order{kiteOrder(false), sellOrder("S-0", 5)})
+	if got := server.Requests(kitetest.PathQuote); got != 1 {
+		t.Fatalf("circuit limits fetched %d times, want once for INFY", got)
+	}
+
+	// A buy above the band is rounded down to the tick grid, then lowered to the upper limit
+	buy := kiteOrder(false)
+	buy.Price = 1650.123
+	result, err := manager.ExecuteOrder(ctx, buy)
+	if err != nil {
+		t.Fatalf("ExecuteOrder(buy): %v", err)
+	}
+	if len(result.PriceAdjustments) != 2 || result.PriceAdjustments[0].To != 1650.1 ||
+		result.PriceAdjustments[1].Reason != models.PriceAdjustmentCircuit || result.PriceAdjustments[1].To != 1600 {
+		t.Errorf("buy adjustments = %+v, want tick to 1650.1 then circuit to 1600", result.PriceAdjustments)
+	}
+
+	// A sell is rounded up
+	sell := sellOrder("S-1", 5)
+	sell.Price = 1500.01
+	if _, err := manager.ExecuteOrder(ctx, sell); err != nil {
+		t.Fatalf("ExecuteOrder(sell): %v", err)
+	}
+
+	// A buy below the band can only be fixed by paying more, so it is not sent
+	low := kiteOrder(false)
+	low.ID, low.Price = "LOW", 1300
+	if _, err := manager.ExecuteOrder(ctx, low); !errors.Is(err, pricing.ErrOutsideBand) || !errors.Is(err, ErrOrderRejected) {
+		t.Errorf("ExecuteOrder(below band) error = %v, want ErrOutsideBand as a local rejection", err)
+	}
+
+	orders := server.Orders()
+	if len(orders) != 2 {
+		t.Fatalf("placed %d orders, want 2", len(orders))
+	}
+	if got := orders[0].Form.Get("price"); got != "1600.00" {
+		t.Errorf("buy sent at %s, want 1600.00", got)
+	}
+	if got := orders[1].Form.Get("price"); got != "1500.05" {
+		t.Errorf("sell sent at %s, want 1500.05", got)
+	}
+	if got := server.Requests(kitetest.PathQuote); got != 1 {
+		t.Errorf("circuit limits fetched %d times, want none during execution", got)
+	}
+
+	// Without a prefetched band the price is only rounded
+	unbanded := kiteOrder(false)
+	unbanded.ID, unbanded.Symbol, unbanded.Price = "TCS-1", "TCS", 3300
+	server.SetCircuitLimits("NSE", "TCS", 3400, 3600)
+	if result, err := manager.ExecuteOrder(ctx, unbanded); err != nil || len(result.PriceAdjustments) != 0 {
+		t.Errorf("ExecuteOrder(no band) = %+v, %v; want it sent unadjusted", result, err)
+	}
+	if got := server.Requests(kitetest.PathQuote); got != 1 {
+		t.Errorf("circuit limits fetched %d times, want none during execution", got)
+	}
+}
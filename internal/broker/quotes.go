@@ -0,0 +1,82 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/quotes"
+)
+
+// ErrQuotesUnsupported is returned when the broker cannot quote instruments
+var ErrQuotesUnsupported = errors.New("broker does not provide quotes")
+
+// QuoteProvider is implemented by brokers that can report instruments' last price, open and
+// volume, keyed EXCHANGE:SYMBOL
+type QuoteProvider interface {
+	Quotes(ctx context.Context, instruments []string) (map[string]quotes.Quote, error)
+}
+
+// Quotes fetches full quotes from Kite for instruments keyed EXCHANGE:SYMBOL in one request
+func (k *KiteBroker) Quotes(ctx context.Context, instruments []string) (map[string]quotes.Quote, error) {
+	query := url.Values{}
+	for _, instrument := range instruments {
+		query.Add("i", strings.ToUpper(instrument))
+	}
+	var response map[string]struct {
+		LastPrice float64 `json:"last_price"`
+		Volume    int64   `json:"volume"`
+		OHLC      struct {
+			Open float64 `json:"open"`
+		} `json:"ohlc"`
+	}
+	if err := k.getData(ctx, "/quote?"+query.Encode(), &response); err != nil {
+		return nil, fmt.Errorf("failed to fetch quotes: %w", err)
+	}
+
+	now := k.clock.Now()
+	result := make(map[string]quotes.Quote, len(response))
+	for instrument, quote := range response {
+		result[strings.ToUpper(instrument)] = quotes.Quote{
+			LastPrice: quote.LastPrice,
+			Open:      quote.OHLC.Open,
+			Volume:    quote.Volume,
+			At:        now,
+		}
+	}
+	return result, nil
+}
+
+// Quotes reports the simulated last price and open of instruments with a price; the
+// simulated exchange does not track volume
+func (p *PaperBroker) Quotes(ctx context.Context, instruments []string) (map[string]quotes.Quote, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result := make(map[string]quotes.Quote, len(instruments))
+	for _, instrument := range instruments {
+		exchange, symbol, _ := strings.Cut(instrument, ":")
+		key := instrumentKey(exchange, symbol)
+		if price, ok := p.prices[key]; ok {
+			result[key] = quotes.Quote{LastPrice: price, Open: p.opens[key], At: p.clock.Now()}
+		}
+	}
+	return result, nil
+}
+
+// Quotes fetches quotes from the broker, for the quote feed conditional orders are checked against
+func (bm *BrokerManager) Quotes(ctx context.Context, instruments []string) (map[string]quotes.Quote, error) {
+	provider, ok := bm.broker.(QuoteProvider)
+	if !ok {
+		return nil, ErrQuotesUnsupported
+	}
+	return provider.Quotes(ctx, instruments)
+}
+
+// QuoteKey returns the EXCHANGE:SYMBOL key an order's instrument is quoted under
+func QuoteKey(order models.Order) string {
+	exchange, symbol := orderInstrument(order)
+	return exchange + ":" + symbol
+}
@@ -0,0 +1,64 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/sizing"
+)
+
+// SizeOrders works out the quantity of orders sized from a percentage of available funds,
+// which the order source cannot do since it does not see the account. Other orders pass
+// through unchanged. Orders that cannot be sized, because funds are unknown or the share
+// buys less than one lot, are held back.
+func (bm *BrokerManager) SizeOrders(ctx context.Context, orders []models.Order) ([]models.Order, []FundsRejection) {
+	pending := false
+	for _, order := range orders {
+		if order.FundsPercent > 0 {
+			pending = true
+			break
+		}
+	}
+	if !pending {
+		return orders, nil
+	}
+
+	funds, err := bm.Funds(ctx, false)
+	sized := make([]models.Order, 0, len(orders))
+	var rejections []FundsRejection
+	for _, order := range orders {
+		if order.FundsPercent <= 0 {
+			sized = append(sized, order)
+			continue
+		}
+		if err != nil {
+			rejections = append(rejections, FundsRejection{Order: order,
+				Reason: fmt.Sprintf("cannot size %.2f%% of available funds: %v", order.FundsPercent, err)})
+			continue
+		}
+
+		request := sizing.Request{
+			Strategy: sizing.StrategyFundsPercent,
+			Side:     order.Side,
+			Price:    order.Price,
+			Percent:  order.FundsPercent,
+			Funds:    funds.Available,
+			LotSize:  order.LotSize,
+		}
+		if bm.config.Sizing.EstimateCharges {
+			charges := sizing.ChargesFor(order.Product, order.InstrumentType)
+			request.Charges = &charges
+		}
+		decision, sizeErr := sizing.Size(request)
+		if sizeErr != nil {
+			rejections = append(rejections, FundsRejection{Order: order, Reason: sizeErr.Error()})
+			continue
+		}
+		bm.logger.Info("📐 Order %s sized to %d %s from available funds %.2f: %s", order.ID, decision.Quantity,
+			order.Symbol, funds.Available, decision)
+		order.Quantity = decision.Quantity
+		sized = append(sized, order)
+	}
+	return sized, rejections
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// ErrOrderCancelled is returned by StoreOrder for an order cancelled through the admin API
+// whose source row has not changed since
+var ErrOrderCancelled = errors.New("order cancelled")
+
+// cancelledKey holds the source version of a cancelled order until its data would have expired
+func (r *RedisCache) cancelledKey(orderID string) string {
+	return r.key("cancelled:%s", orderID)
+}
+
+// CancelOrder removes an order like RemoveOrder and leaves a tombstone, so a reader refresh
+// does not queue the same order again. An edited source row has a new version and is queued.
+func (r *RedisCache) CancelOrder(ctx context.Context, entry models.OrderCacheEntry) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	orderID := entry.Order.ID
+	ttl := entry.ExpiryTime.Sub(r.clock.Now())
+	if ttl < 0 {
+		ttl = 0
+	}
+	ttl += LateOrderRetention
+
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, r.cancelledKey(orderID), sourceVersion(entry.Order), ttl)
+	pipe.Del(ctx, r.key("order:%s", orderID))
+	pipe.ZRem(ctx, r.key(pendingOrdersKey), orderID)
+	pipe.ZRem(ctx, r.key(inFlightKey), orderID)
+	pipe.HDel(ctx, r.key(claimsKey), orderID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to cancel order: %w", err)
+	}
+	return nil
+}
+
+// sourceVersion fingerprints the fields of an order that come from its source row, leaving
+// out those set afresh on every read such as CreatedAt and the trace context
+func sourceVersion(order models.Order) string {
+	condition, gtt := "", ""
+	if order.Condition != nil {
+		condition = order.Condition.String()
+	}
+	if order.GTT != nil {
+		gtt = fmt.Sprintf("%s %v", order.GTT.Type, order.GTT.StopLoss)
+	}
+	source := fmt.Sprintf("%s|%s|%s|%s|%v|%d|%d|%v|%v|%d|%s|%s|%s|%s|%s",
+		order.Symbol, order.Exchange, order.Side, order.OrderType, order.Price, order.Quantity,
+		order.ScheduledTime.Unix(), order.ExpiryWindow, order.FundsPercent, order.LotSize, order.Product,
+		order.InstrumentType, order.Expiry.Format(time.DateOnly), condition, gtt)
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:8])
+}
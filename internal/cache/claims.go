@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// Redis keys for claimed orders. in_flight is scored by lease deadline (unix ms); in_flight:claims
+// holds each claim's owner, original pending_orders score and whether it reached the broker.
+const (
+	pendingOrdersKey = "pending_orders"
+	inFlightKey      = "in_flight"
+	claimsKey        = "in_flight:claims"
+)
+
+// claimScript atomically moves every order scheduled at or before ARGV[1] from pending_orders
+// to in_flight with a lease ending at ARGV[2], owned by ARGV[3]. With a fourth key, the claim
+// is refused unless it holds fencing token ARGV[4].
+var claimScript = redis.NewScript(`
+if #KEYS == 4 and redis.call('GET', KEYS[4]) ~= ARGV[4] then return redis.error_reply('FENCED') end
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'WITHSCORES')
+local claimed = {}
+for i = 1, #due, 2 do
+	local id = due[i]
+	redis.call('ZREM', KEYS[1], id)
+	redis.call('ZADD', KEYS[2], ARGV[2], id)
+	redis.call('HSET', KEYS[3], id, cjson.encode({owner = ARGV[3], score = tonumber(due[i + 1]), submitted = false}))
+	claimed[#claimed + 1] = id
+end
+return claimed
+`)
+
+// submitScript marks a claim as submitted to the broker and renews its lease to ARGV[4], but
+// only while ARGV[2] still owns an unexpired lease at ARGV[3] (and, with a third key, holds
+// fencing token ARGV[5])
+var submitScript = redis.NewScript(`
+if #KEYS == 3 and redis.call('GET', KEYS[3]) ~= ARGV[5] then return redis.error_reply('FENCED') end
+local raw = redis.call('HGET', KEYS[2], ARGV[1])
+if not raw then return 0 end
+local claim = cjson.decode(raw)
+local lease = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if claim.owner ~= ARGV[2] or not lease or tonumber(lease) < tonumber(ARGV[3]) then return 0 end
+claim.submitted = true
+redis.call('HSET', KEYS[2], ARGV[1], cjson.encode(claim))
+redis.call('ZADD', KEYS[1], ARGV[4], ARGV[1])
+return 1
+`)
+
+// releaseScript returns an unsubmitted claim owned by ARGV[2] to pending_orders
+var releaseScript = redis.NewScript(`
+local raw = redis.call('HGET', KEYS[3], ARGV[1])
+if not raw then return 0 end
+local claim = cjson.decode(raw)
+if claim.owner ~= ARGV[2] or claim.submitted then return 0 end
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+redis.call('ZADD', KEYS[1], claim.score, ARGV[1])
+return 1
+`)
+
+// sweepScript clears leases that ended at or before ARGV[1]. Claims that never reached the
+// broker go back to pending_orders; the rest are returned as abandoned.
+var sweepScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+local requeued, abandoned = {}, {}
+for _, id in ipairs(expired) do
+	local raw = redis.call('HGET', KEYS[3], id)
+	redis.call('ZREM', KEYS[2], id)
+	redis.call('HDEL', KEYS[3], id)
+	local claim = raw and cjson.decode(raw)
+	if claim and not claim.submitted then
+		redis.call('ZADD', KEYS[1], claim.score, id)
+		requeued[#requeued + 1] = id
+	else
+		abandoned[#abandoned + 1] = id
+	end
+end
+return {requeued, abandoned}
+`)
+
+// storeScript saves order data and queues the order unless it is currently claimed, so a reader
+// refresh cannot put an in-flight order back in the queue. A fresh copy supersedes any
+// quarantined one. An order cancelled at the same source version is left alone (-1).
+var storeScript = redis.NewScript(`
+if redis.call('GET', KEYS[5]) == ARGV[5] then return -1 end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+redis.call('HDEL', KEYS[4], ARGV[4])
+if redis.call('ZSCORE', KEYS[3], ARGV[4]) then return 0 end
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[4])
+return 1
+`)
+
+// ClaimDueOrders atomically claims every order scheduled at or before now for owner, holding it
+// in in_flight until lease ends. Claimed orders past their expiry time are returned separately
+// as late. A late entry with only an order ID means the order's data outlived LateOrderRetention.
+// ErrFenced is returned if fence is set and no longer current.
+func (r *RedisCache) ClaimDueOrders(ctx context.Context, now time.Time, owner string, lease time.Duration, fence Fence) ([]models.Order, []models.OrderCacheEntry, error) {
+	claimCtx, cancel := r.withTimeout(ctx)
+	orderIDs, err := claimScript.Run(claimCtx, r.client, r.fenceKeys(fence, r.key(pendingOrdersKey), r.key(inFlightKey), r.key(claimsKey)),
+		now.Unix(), r.clock.Now().Add(lease).UnixMilli(), owner, fence.Token).StringSlice()
+	cancel()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to claim due orders: %w", fenceError(err))
+	}
+
+	var orders []models.Order
+	var late []models.OrderCacheEntry
+	for _, orderID := range orderIDs {
+		entry, err := r.GetOrder(ctx, orderID)
+		if err == ErrOrderNotFound {
+			// Claimed but the order data is gone: report it rather than dropping it silently
+			late = append(late, models.OrderCacheEntry{Order: models.Order{ID: orderID}})
+			continue
+		} else if errors.Is(err, ErrOrderQuarantined) {
+			// Already moved out of in_flight; it shows up in the quarantine instead
+			continue
+		} else if err != nil {
+			// Left in flight; the sweeper re-queues it once the lease ends
+			continue
+		}
+
+		// Orders past their expiry window are left to the late-order policy
+		if now.After(entry.ExpiryTime) {
+			late = append(late, *entry)
+			continue
+		}
+
+		orders = append(orders, entry.Order)
+	}
+
+	return orders, late, nil
+}
+
+// MarkSubmitted records that owner is about to send a claimed order to the broker and renews its
+// lease. It returns false if the lease has ended or been taken over, and ErrFenced if fence is
+// set and no longer current; in either case the order must not be submitted.
+func (r *RedisCache) MarkSubmitted(ctx context.Context, orderID, owner string, lease time.Duration, fence Fence) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	now := r.clock.Now()
+	marked, err := submitScript.Run(ctx, r.client, r.fenceKeys(fence, r.key(inFlightKey), r.key(claimsKey)),
+		orderID, owner, now.UnixMilli(), now.Add(lease).UnixMilli(), fence.Token).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark order %s submitted: %w", orderID, fenceError(err))
+	}
+	return marked == 1, nil
+}
+
+// ReleaseClaim returns an order claimed by owner to pending_orders, e.g. when a halt blocks it.
+// Orders already submitted to the broker are never released.
+func (r *RedisCache) ReleaseClaim(ctx context.Context, orderID, owner string) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	released, err := releaseScript.Run(ctx, r.client, []string{r.key(pendingOrdersKey), r.key(inFlightKey), r.key(claimsKey)},
+		orderID, owner).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to release order %s: %w", orderID, err)
+	}
+	return released == 1, nil
+}
+
+// SweepExpiredLeases clears claims whose lease ended before now. Orders that never reached the
+// broker are re-queued; the IDs of orders submitted by an instance that then went away are
+// returned as abandoned, and their data is left for the caller to record and remove.
+func (r *RedisCache) SweepExpiredLeases(ctx context.Context, now time.Time) (requeued, abandoned []string, err error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := sweepScript.Run(ctx, r.client, []string{r.key(pendingOrdersKey), r.key(inFlightKey), r.key(claimsKey)},
+		now.UnixMilli()).Slice()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sweep expired leases: %w", err)
+	}
+	if len(result) != 2 {
+		return nil, nil, fmt.Errorf("unexpected sweep result: %v", result)
+	}
+	return toStrings(result[0]), toStrings(result[1]), nil
+}
+
+// InFlightCount returns the number of claimed orders that have not been resolved
+func (r *RedisCache) InFlightCount(ctx context.Context) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	count, err := r.client.ZCard(ctx, r.key(inFlightKey)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count in-flight orders: %w", err)
+	}
+	return count, nil
+}
+
+// toStrings converts a nested Lua array reply to a string slice
+func toStrings(value interface{}) []string {
+	items, _ := value.([]interface{})
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
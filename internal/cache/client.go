@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mach_five/trading-system/internal/config"
+)
+
+// clusterKeyPrefix is the hash tag prepended to every key in cluster mode
+const clusterKeyPrefix = "{trading}:"
+
+// newUniversalClient builds the Redis client for cfg: a cluster client in cluster mode, a
+// Sentinel failover client when a master name is set, otherwise a single-node client
+func newUniversalClient(cfg config.RedisConfig) (redis.UniversalClient, error) {
+	opts, err := universalOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ClusterMode {
+		// NewUniversalClient only picks a cluster client for multiple addresses; managed
+		// clusters are usually reached through a single discovery endpoint
+		return redis.NewClusterClient(opts.Cluster()), nil
+	}
+	return redis.NewUniversalClient(opts), nil
+}
+
+// universalOptions maps the Redis configuration onto go-redis options
+func universalOptions(cfg config.RedisConfig) (*redis.UniversalOptions, error) {
+	addrs := cfg.Addrs
+	if len(addrs) == 0 {
+		addrs = []string{cfg.Addr}
+	}
+	if len(addrs) > 1 && !cfg.ClusterMode && cfg.MasterName == "" {
+		return nil, fmt.Errorf("multiple Redis addresses need REDIS_CLUSTER or REDIS_SENTINEL_MASTER")
+	}
+
+	opts := &redis.UniversalOptions{
+		Addrs:            addrs,
+		DB:               cfg.DB,
+		Username:         cfg.Username,
+		Password:         cfg.Password,
+		SentinelPassword: cfg.SentinelPassword,
+		MasterName:       cfg.MasterName,
+		PoolSize:         cfg.PoolSize,
+		MinIdleConns:     cfg.MinIdleConns,
+		DialTimeout:      cfg.DialTimeout,
+		ReadTimeout:      cfg.ReadTimeout,
+		WriteTimeout:     cfg.WriteTimeout,
+	}
+
+	if cfg.TLS.Enabled {
+		tlsConfig, err := loadTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLSConfig = tlsConfig
+	}
+	return opts, nil
+}
+
+// loadTLSConfig builds the client TLS configuration from PEM files
+func loadTLSConfig(cfg config.RedisTLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+
+	if cfg.CAFile != "" {
+		caData, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Redis CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificates found in Redis CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Redis client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,228 @@
+package cache
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+)
+
+func TestUniversalOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       config.RedisConfig
+		wantAddrs int
+		wantErr   string
+	}{
+		{name: "single node", cfg: config.RedisConfig{Addr: "localhost:6379"}, wantAddrs: 1},
+		{name: "sentinel", cfg: config.RedisConfig{Addrs: []string{"s1:26379", "s2:26379", "s3:26379"}, MasterName: "mymaster"}, wantAddrs: 3},
+		{name: "cluster seeds", cfg: config.RedisConfig{Addrs: []string{"n1:6379", "n2:6379"}, ClusterMode: true}, wantAddrs: 2},
+		{name: "several addresses without a mode", cfg: config.RedisConfig{Addrs: []string{"a:6379", "b:6379"}}, wantErr: "REDIS_CLUSTER"},
+		{name: "missing CA file", cfg: config.RedisConfig{Addr: "localhost:6379", TLS: config.RedisTLSConfig{Enabled: true, CAFile: "/nonexistent/ca.pem"}}, wantErr: "CA file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := universalOptions(tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("universalOptions: %v", err)
+			}
+			if len(opts.Addrs) != tt.wantAddrs {
+				t.Errorf("Addrs = %v, want %d addresses", opts.Addrs, tt.wantAddrs)
+			}
+		})
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate for 127.0.0.1 and returns the server
+// TLS config and the path of the PEM file to trust
+func writeTestCertificate(t *testing.T) (*tls.Config, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "redis-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate: %v", err)
+	}
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}, caPath
+}
+
+func TestNewRedisCacheWithConfigTLS(t *testing.T) {
+	ctx := context.Background()
+	serverTLS, caPath := writeTestCertificate(t)
+	server, err := miniredis.RunTLS(serverTLS)
+	if err != nil {
+		t.Fatalf("RunTLS: %v", err)
+	}
+	t.Cleanup(server.Close)
+
+	// Without TLS the handshake never completes
+	if _, err := NewRedisCacheWithConfig(config.RedisConfig{Addr: server.Addr(), DialTimeout: time.Second, ReadTimeout: 200 * time.Millisecond}); err == nil {
+		t.Fatal("plain-text client connected to a TLS-only server")
+	}
+
+	cache, err := NewRedisCacheWithConfig(config.RedisConfig{
+		Addr: server.Addr(),
+		TLS:  config.RedisTLSConfig{Enabled: true, CAFile: caPath},
+	})
+	if err != nil {
+		t.Fatalf("NewRedisCacheWithConfig: %v", err)
+	}
+	t.Cleanup(func() { cache.Close() })
+
+	if err := cache.HealthCheck(ctx); err != nil {
+		t.Errorf("HealthCheck over TLS: %v", err)
+	}
+}
+
+func TestClusterModeKeepsKeysInOneSlot(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	cache, err := NewRedisCacheWithConfig(config.RedisConfig{Addr: server.Addr(), ClusterMode: true})
+	if err != nil {
+		t.Fatalf("NewRedisCacheWithConfig: %v", err)
+	}
+	t.Cleanup(func() { cache.Close() })
+
+	now := time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC)
+	cache.SetClock(clock.NewSimulated(now))
+	if err := cache.StoreOrder(ctx, testOrder("A", now), now.Add(DefaultExpiryWindow)); err != nil {
+		t.Fatalf("StoreOrder: %v", err)
+	}
+	if !server.Exists("{trading}:order:A") || !server.Exists("{trading}:pending_orders") {
+		t.Fatalf("keys = %v, want every key under the {trading} hash tag", server.Keys())
+	}
+
+	due, _, err := cache.ClaimDueOrders(ctx, now, "test", time.Minute, Fence{})
+	if err != nil || len(due) != 1 {
+		t.Fatalf("ClaimDueOrders = %v, %v; want order A", due, err)
+	}
+}
+
+// stallingServer answers the connection check and then stops replying, like a Redis node
+// that hangs mid-session
+func stallingServer(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 4096)
+				if _, err := conn.Read(buf); err != nil {
+					return
+				}
+				conn.Write([]byte("+PONG\r\n"))
+				for {
+					if _, err := conn.Read(buf); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestCommandTimeoutBoundsStalledCalls(t *testing.T) {
+	cache, err := NewRedisCacheWithConfig(config.RedisConfig{
+		Addr:           stallingServer(t),
+		PoolSize:       1,
+		ReadTimeout:    time.Minute,
+		CommandTimeout: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewRedisCacheWithConfig: %v", err)
+	}
+	t.Cleanup(func() { cache.Close() })
+
+	started := time.Now()
+	_, err = cache.PendingCount(context.Background())
+	if err == nil {
+		t.Fatal("PendingCount succeeded against a stalled server")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("PendingCount took %v, want it cut off by the 100ms command timeout", elapsed)
+	}
+}
+
+func TestCancelledContextAbortsCall(t *testing.T) {
+	cache, err := NewRedisCacheWithConfig(config.RedisConfig{
+		Addr:           stallingServer(t),
+		PoolSize:       1,
+		ReadTimeout:    time.Minute,
+		CommandTimeout: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewRedisCacheWithConfig: %v", err)
+	}
+	t.Cleanup(func() { cache.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := cache.ClaimDueOrders(ctx, time.Now(), "test", time.Minute, Fence{})
+		done <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("ClaimDueOrders succeeded after its context was cancelled")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ClaimDueOrders kept waiting on a stalled server after shutdown began")
+	}
+}
@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// deadLettersKey holds orders that did not execute, keyed by order ID
+const deadLettersKey = "dead_letters"
+
+// ErrDeadLetterNotFound is returned when no dead letter exists for an order ID
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetterOrder moves an order out of the queues and into the dead-letter store in one
+// transaction, replacing any earlier dead letter for the same order
+func (r *RedisCache) DeadLetterOrder(ctx context.Context, letter models.DeadLetter) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	orderID := letter.Order.ID
+	if letter.DeadLetteredAt.IsZero() {
+		letter.DeadLetteredAt = r.clock.Now()
+	}
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter: %w", err)
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, r.key(deadLettersKey), orderID, data)
+	pipe.Del(ctx, r.key("order:%s", orderID))
+	pipe.ZRem(ctx, r.key(pendingOrdersKey), orderID)
+	pipe.ZRem(ctx, r.key(inFlightKey), orderID)
+	pipe.HDel(ctx, r.key(claimsKey), orderID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to dead-letter order %s: %w", orderID, err)
+	}
+	return nil
+}
+
+// GetDeadLetter returns the dead letter for an order
+func (r *RedisCache) GetDeadLetter(ctx context.Context, orderID string) (*models.DeadLetter, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	data, err := r.client.HGet(ctx, r.key(deadLettersKey), orderID).Result()
+	if err == redis.Nil {
+		return nil, ErrDeadLetterNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get dead letter: %w", err)
+	}
+
+	var letter models.DeadLetter
+	if err := json.Unmarshal([]byte(data), &letter); err != nil {
+		return nil, fmt.Errorf("failed to decode dead letter %s: %w", orderID, err)
+	}
+	return &letter, nil
+}
+
+// ListDeadLetters returns dead letters, oldest first. Unreadable records are returned with
+// only the order ID and the decode error so they can still be discarded.
+func (r *RedisCache) ListDeadLetters(ctx context.Context) ([]models.DeadLetter, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	values, err := r.client.HGetAll(ctx, r.key(deadLettersKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters: %w", err)
+	}
+
+	letters := make([]models.DeadLetter, 0, len(values))
+	for orderID, value := range values {
+		var letter models.DeadLetter
+		if err := json.Unmarshal([]byte(value), &letter); err != nil {
+			letter = models.DeadLetter{Order: models.Order{ID: orderID}, Error: fmt.Sprintf("unreadable dead letter: %v", err)}
+		}
+		letters = append(letters, letter)
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].DeadLetteredAt.Before(letters[j].DeadLetteredAt)
+	})
+	return letters, nil
+}
+
+// DeadLetterCount returns the number of dead letters
+func (r *RedisCache) DeadLetterCount(ctx context.Context) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	count, err := r.client.HLen(ctx, r.key(deadLettersKey)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count dead letters: %w", err)
+	}
+	return count, nil
+}
+
+// RequeueDeadLetter queues order, a dead letter updated with its new schedule, and removes the
+// dead letter
+func (r *RedisCache) RequeueDeadLetter(ctx context.Context, order models.Order, expiryTime time.Time) error {
+	if err := r.StoreOrder(ctx, order, expiryTime); err != nil {
+		return err
+	}
+	// If this fails the dead letter stays; requeueing it again only rewrites the same order
+	if _, err := r.DiscardDeadLetter(ctx, order.ID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DiscardDeadLetter deletes a dead letter, reporting whether it existed
+func (r *RedisCache) DiscardDeadLetter(ctx context.Context, orderID string) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	removed, err := r.client.HDel(ctx, r.key(deadLettersKey), orderID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to discard dead letter: %w", err)
+	}
+	return removed > 0, nil
+}
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// Keys holding GTT state: the GTT orders the order source currently lists, and the GTTs
+// placed for them, both keyed by order ID
+const (
+	gttDesiredKey = "gtt:desired"
+	gttRecordsKey = "gtt:records"
+)
+
+// SetDesiredGTTs replaces the GTT orders the order source lists. Orders missing from a later
+// call are no longer wanted, so their GTTs are deleted.
+func (r *RedisCache) SetDesiredGTTs(ctx context.Context, orders []models.Order) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	values := make(map[string]interface{}, len(orders))
+	for _, order := range orders {
+		data, err := json.Marshal(order)
+		if err != nil {
+			return fmt.Errorf("failed to marshal GTT order %s: %w", order.ID, err)
+		}
+		values[order.ID] = data
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, r.key(gttDesiredKey))
+	if len(values) > 0 {
+		pipe.HSet(ctx, r.key(gttDesiredKey), values)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to store GTT orders: %w", err)
+	}
+	return nil
+}
+
+// DesiredGTTs returns the GTT orders the order source lists, keyed by order ID
+func (r *RedisCache) DesiredGTTs(ctx context.Context) (map[string]models.Order, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	values, err := r.client.HGetAll(ctx, r.key(gttDesiredKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load GTT orders: %w", err)
+	}
+	orders := make(map[string]models.Order, len(values))
+	for orderID, value := range values {
+		var order models.Order
+		if err := json.Unmarshal([]byte(value), &order); err != nil {
+			return nil, fmt.Errorf("failed to decode GTT order %s: %w", orderID, err)
+		}
+		orders[orderID] = order
+	}
+	return orders, nil
+}
+
+// SaveGTTRecord stores the GTT placed for an order
+func (r *RedisCache) SaveGTTRecord(ctx context.Context, record models.GTTRecord) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal GTT record: %w", err)
+	}
+	if err := r.client.HSet(ctx, r.key(gttRecordsKey), record.Order.ID, data).Err(); err != nil {
+		return fmt.Errorf("failed to store GTT record: %w", err)
+	}
+	return nil
+}
+
+// DeleteGTTRecord forgets the GTT placed for an order
+func (r *RedisCache) DeleteGTTRecord(ctx context.Context, orderID string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if err := r.client.HDel(ctx, r.key(gttRecordsKey), orderID).Err(); err != nil {
+		return fmt.Errorf("failed to delete GTT record: %w", err)
+	}
+	return nil
+}
+
+// GTTRecords returns the GTTs placed for orders, keyed by order ID
+func (r *RedisCache) GTTRecords(ctx context.Context) (map[string]models.GTTRecord, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	values, err := r.client.HGetAll(ctx, r.key(gttRecordsKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load GTT records: %w", err)
+	}
+	records := make(map[string]models.GTTRecord, len(values))
+	for orderID, value := range values {
+		var record models.GTTRecord
+		if err := json.Unmarshal([]byte(value), &record); err != nil {
+			return nil, fmt.Errorf("failed to decode GTT record %s: %w", orderID, err)
+		}
+		records[orderID] = record
+	}
+	return records, nil
+}
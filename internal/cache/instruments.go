@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// instrumentsKey holds the latest instrument master dump and the day it was published for
+const instrumentsKey = "instruments"
+
+// ErrInstrumentsNotFound is returned when no instrument master dump is cached
+var ErrInstrumentsNotFound = errors.New("instruments not cached")
+
+// StoreInstruments replaces the cached instrument master dump with the one published for day
+func (r *RedisCache) StoreInstruments(ctx context.Context, day string, data []byte) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if err := r.client.HSet(ctx, r.key(instrumentsKey), "day", day, "data", data).Err(); err != nil {
+		return fmt.Errorf("failed to cache instruments: %w", err)
+	}
+	return nil
+}
+
+// LoadInstruments returns the cached instrument master dump and the day it was published for
+func (r *RedisCache) LoadInstruments(ctx context.Context) (string, []byte, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	fields, err := r.client.HGetAll(ctx, r.key(instrumentsKey)).Result()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load cached instruments: %w", err)
+	}
+	if fields["day"] == "" || fields["data"] == "" {
+		return "", nil, ErrInstrumentsNotFound
+	}
+	return fields["day"], []byte(fields["data"]), nil
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrFenced is returned when a write carries a fencing token older than the current leader's
+var ErrFenced = errors.New("stale fencing token")
+
+// Fence guards a write with a leader fencing token. The zero value disables the check.
+type Fence struct {
+	Role  string
+	Token int64
+}
+
+// leaderKey holds the current leader's instance ID with the lease as its TTL
+func (r *RedisCache) leaderKey(role string) string {
+	return r.key("leader:%s", role)
+}
+
+// leaderTokenKey is incremented on every acquisition; its value is the current fencing token
+func (r *RedisCache) leaderTokenKey(role string) string {
+	return r.key("leader:%s:token", role)
+}
+
+// acquireLeaderScript takes the lease for ARGV[1] if it is free (or already ours) and issues a
+// new fencing token. It returns {token, holder}, with token 0 when another instance holds it.
+var acquireLeaderScript = redis.NewScript(`
+local holder = redis.call('GET', KEYS[1])
+if holder and holder ~= ARGV[1] then return {0, holder} end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return {redis.call('INCR', KEYS[2]), ARGV[1]}
+`)
+
+// renewLeaderScript extends the lease if ARGV[1] still holds it
+var renewLeaderScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then return 0 end
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 1
+`)
+
+// resignLeaderScript releases the lease if ARGV[1] still holds it
+var resignLeaderScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) ~= ARGV[1] then return 0 end
+return redis.call('DEL', KEYS[1])
+`)
+
+// AcquireLeadership tries to become leader for role. It returns the new fencing token, or 0
+// and the current holder if another instance holds the lease.
+func (r *RedisCache) AcquireLeadership(ctx context.Context, role, instanceID string, lease time.Duration) (int64, string, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	result, err := acquireLeaderScript.Run(ctx, r.client, []string{r.leaderKey(role), r.leaderTokenKey(role)},
+		instanceID, lease.Milliseconds()).Slice()
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to acquire %s leadership: %w", role, err)
+	}
+	if len(result) != 2 {
+		return 0, "", fmt.Errorf("unexpected leadership result: %v", result)
+	}
+	token, _ := result[0].(int64)
+	holder, _ := result[1].(string)
+	return token, holder, nil
+}
+
+// RenewLeadership extends instanceID's lease on role, reporting false if it no longer holds it
+func (r *RedisCache) RenewLeadership(ctx context.Context, role, instanceID string, lease time.Duration) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	renewed, err := renewLeaderScript.Run(ctx, r.client, []string{r.leaderKey(role)},
+		instanceID, lease.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to renew %s leadership: %w", role, err)
+	}
+	return renewed == 1, nil
+}
+
+// ResignLeadership releases instanceID's lease on role so a standby can take over immediately
+func (r *RedisCache) ResignLeadership(ctx context.Context, role, instanceID string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	if err := resignLeaderScript.Run(ctx, r.client, []string{r.leaderKey(role)}, instanceID).Err(); err != nil {
+		return fmt.Errorf("failed to resign %s leadership: %w", role, err)
+	}
+	return nil
+}
+
+// fenceKeys returns keys with the fencing token key appended when fence is set
+func (r *RedisCache) fenceKeys(fence Fence, keys ...string) []string {
+	if fence.Role != "" {
+		keys = append(keys, r.leaderTokenKey(fence.Role))
+	}
+	return keys
+}
+
+// fenceError maps a script's fencing rejection to ErrFenced
+func fenceError(err error) error {
+	if err != nil && strings.Contains(err.Error(), "FENCED") {
+		return ErrFenced
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// quarantineKey holds undecodable cache entries keyed by order ID
+const quarantineKey = "quarantine"
+
+// ErrOrderQuarantined is returned when an order's cache entry could not be decoded and was
+// moved to the quarantine
+var ErrOrderQuarantined = errors.New("order quarantined")
+
+// quarantineOrder moves an undecodable entry out of the queues and into the quarantine, so it
+// is neither executed with garbled fields nor retried on every poll
+func (r *RedisCache) quarantineOrder(ctx context.Context, orderID, data string, cause error) error {
+	record, err := json.Marshal(models.QuarantinedOrder{
+		OrderID:       orderID,
+		Reason:        cause.Error(),
+		Data:          data,
+		QuarantinedAt: r.clock.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal quarantined order: %w", err)
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.HSet(ctx, r.key(quarantineKey), orderID, record)
+	pipe.Del(ctx, r.key("order:%s", orderID))
+	pipe.ZRem(ctx, r.key(pendingOrdersKey), orderID)
+	pipe.ZRem(ctx, r.key(inFlightKey), orderID)
+	pipe.HDel(ctx, r.key(claimsKey), orderID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to quarantine order: %w", err)
+	}
+	return nil
+}
+
+// ListQuarantined returns quarantined entries, oldest first
+func (r *RedisCache) ListQuarantined(ctx context.Context) ([]models.QuarantinedOrder, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	values, err := r.client.HGetAll(ctx, r.key(quarantineKey)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quarantined orders: %w", err)
+	}
+
+	entries := make([]models.QuarantinedOrder, 0, len(values))
+	for orderID, value := range values {
+		var entry models.QuarantinedOrder
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			entry = models.QuarantinedOrder{OrderID: orderID, Reason: "unreadable quarantine record", Data: value}
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].QuarantinedAt.Before(entries[j].QuarantinedAt)
+	})
+	return entries, nil
+}
+
+// QuarantineCount returns the number of quarantined entries
+func (r *RedisCache) QuarantineCount(ctx context.Context) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	count, err := r.client.HLen(ctx, r.key(quarantineKey)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count quarantined orders: %w", err)
+	}
+	return count, nil
+}
+
+// DiscardQuarantined deletes a quarantined entry, reporting whether it existed
+func (r *RedisCache) DiscardQuarantined(ctx context.Context, orderID string) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	removed, err := r.client.HDel(ctx, r.key(quarantineKey), orderID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to discard quarantined order: %w", err)
+	}
+	return removed > 0, nil
+}
+
+// MigrateOrders rewrites queued and in-flight entries from older schema versions at the current
+// version, keeping their TTLs. Entries that cannot be decoded are quarantined and returned.
+func (r *RedisCache) MigrateOrders(ctx context.Context) (migrated int, quarantined []string, err error) {
+	var orderIDs []string
+	for _, key := range []string{pendingOrdersKey, inFlightKey} {
+		rangeCtx, cancel := r.withTimeout(ctx)
+		ids, err := r.client.ZRange(rangeCtx, r.key(key), 0, -1).Result()
+		cancel()
+		if err != nil {
+			return migrated, quarantined, fmt.Errorf("failed to list orders to migrate: %w", err)
+		}
+		orderIDs = append(orderIDs, ids...)
+	}
+
+	for _, orderID := range orderIDs {
+		entry, err := r.GetOrder(ctx, orderID)
+		switch {
+		case errors.Is(err, ErrOrderQuarantined):
+			quarantined = append(quarantined, orderID)
+			continue
+		case errors.Is(err, ErrOrderNotFound):
+			continue
+		case err != nil:
+			return migrated, quarantined, err
+		case entry.Version == models.OrderCacheEntryVersion:
+			continue
+		}
+
+		if err := r.rewriteEntry(ctx, orderID, entry); err != nil {
+			return migrated, quarantined, err
+		}
+		migrated++
+	}
+	return migrated, quarantined, nil
+}
+
+// rewriteEntry stores entry at the current schema version without changing its TTL. The
+// write is skipped if the order was removed in the meantime.
+func (r *RedisCache) rewriteEntry(ctx context.Context, orderID string, entry *models.OrderCacheEntry) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	data, err := entry.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal order %s: %w", orderID, err)
+	}
+	err = r.client.SetArgs(ctx, r.key("order:%s", orderID), data, redis.SetArgs{Mode: "XX", KeepTTL: true}).Err()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to migrate order %s: %w", orderID, err)
+	}
+	return nil
+}
@@ -2,47 +2,123 @@ package cache
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
 	"github.com/mach_five/trading-system/internal/models"
 )
 
+// DefaultExpiryWindow is how long after its scheduled time an order stays executable
+// when neither the order nor its source sets a window
+const DefaultExpiryWindow = 10 * time.Second
+
+// LateOrderRetention is how long an unexecuted order is kept past its expiry time so the
+// trigger can apply the late-order policy and record the outcome
+const LateOrderRetention = 24 * time.Hour
+
+// maxAuditEntries bounds the audit_log list
+const maxAuditEntries = 1000
+
+// DefaultCommandTimeout bounds each cache call when RedisConfig.CommandTimeout is unset
+const DefaultCommandTimeout = 5 * time.Second
+
+// ErrOrderNotFound is returned when an order is not present in the cache
+var ErrOrderNotFound = errors.New("order not found")
+
 // RedisCache implements cache interface using Redis
 type RedisCache struct {
-	client *redis.Client
-	ctx    context.Context
+	client    redis.UniversalClient // Single node, Sentinel failover or cluster client
+	clock     clock.Clock
+	timeout   time.Duration // Per-call deadline applied on top of the caller's context
+	keyPrefix string        // Hash tag keeping every key in one cluster slot; empty outside cluster mode
 }
 
-// NewRedisCache creates a new Redis cache instance
+// NewRedisCache creates a new Redis cache instance for a single node
 func NewRedisCache(addr, password string, db int) (*RedisCache, error) {
-	rdb := redis.NewClient(&redis.Options{
+	return NewRedisCacheWithConfig(config.RedisConfig{
 		Addr:     addr,
 		Password: password,
 		DB:       db,
 	})
+}
+
+// NewRedisCacheWithConfig creates a Redis cache using a single node, Sentinel or cluster
+// client as configured, with optional TLS
+func NewRedisCacheWithConfig(cfg config.RedisConfig) (*RedisCache, error) {
+	rdb, err := newUniversalClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &RedisCache{
+		client:  rdb,
+		clock:   clock.Real{},
+		timeout: cfg.CommandTimeout,
+	}
+	if cache.timeout <= 0 {
+		cache.timeout = DefaultCommandTimeout
+	}
 
-	ctx := context.Background()
-	
 	// Test connection
-	if err := rdb.Ping(ctx).Err(); err != nil {
+	if err := cache.HealthCheck(context.Background()); err != nil {
+		rdb.Close()
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	return &RedisCache{
-		client: rdb,
-		ctx:    ctx,
-	}, nil
+	if cfg.ClusterMode {
+		// Scripts touch several keys at once, which a cluster only allows within one slot
+		cache.keyPrefix = clusterKeyPrefix
+	}
+	return cache, nil
+}
+
+// key builds a Redis key, adding the cluster hash tag when needed
+func (r *RedisCache) key(format string, args ...interface{}) string {
+	return r.keyPrefix + fmt.Sprintf(format, args...)
+}
+
+// withTimeout bounds a single cache call so a stalled Redis cannot hold up the caller, while
+// still aborting early if ctx is cancelled
+func (r *RedisCache) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, r.timeout)
+}
+
+// SetClock replaces the clock used for entry timestamps and TTLs
+func (r *RedisCache) SetClock(c clock.Clock) {
+	r.clock = c
+}
+
+// ExpiryFor returns when order stops being executable: its own window if set, otherwise
+// the window configured for conditional orders or for its source, otherwise DefaultExpiryWindow
+func ExpiryFor(order models.Order, expiry config.ExpiryConfig) time.Time {
+	window := order.ExpiryWindow
+	if window <= 0 && order.Condition != nil {
+		window = expiry.ConditionalWindow
+	}
+	if window <= 0 {
+		window = expiry.WindowFor(order.Side)
+	}
+	if window <= 0 {
+		window = DefaultExpiryWindow
+	}
+	return order.ScheduledTime.Add(window)
 }
 
 // StoreOrder stores an order in cache with expiry
-func (r *RedisCache) StoreOrder(order models.Order, expiryTime time.Time) error {
+func (r *RedisCache) StoreOrder(ctx context.Context, order models.Order, expiryTime time.Time) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
 	orderID := order.ID
 	entry := models.OrderCacheEntry{
 		Order:      order,
 		ExpiryTime: expiryTime,
-		CreatedAt:  time.Now(),
+		CreatedAt:  r.clock.Now(),
 	}
 
 	data, err := entry.ToJSON()
@@ -50,104 +126,225 @@ func (r *RedisCache) StoreOrder(order models.Order, expiryTime time.Time) error
 		return fmt.Errorf("failed to marshal order: %w", err)
 	}
 
-	key := fmt.Sprintf("order:%s", orderID)
-	ttl := time.Until(expiryTime)
+	key := r.key("order:%s", orderID)
+	ttl := expiryTime.Sub(r.clock.Now())
 	if ttl <= 0 {
 		return fmt.Errorf("expiry time is in the past")
 	}
+	ttl += LateOrderRetention
 
-	// Store order
-	if err := r.client.Set(r.ctx, key, data, ttl).Err(); err != nil {
+	// Store the order and queue it (score = scheduled time as unix timestamp) unless it is in flight
+	// or was cancelled
+	stored, err := storeScript.Run(ctx, r.client, []string{key, r.key(pendingOrdersKey), r.key(inFlightKey), r.key(quarantineKey), r.cancelledKey(orderID)},
+		data, ttl.Milliseconds(), order.ScheduledTime.Unix(), orderID, sourceVersion(order)).Int()
+	if err != nil {
 		return fmt.Errorf("failed to store order: %w", err)
 	}
-
-	// Add to pending orders sorted set (score = scheduled time as unix timestamp)
-	score := float64(order.ScheduledTime.Unix())
-	if err := r.client.ZAdd(r.ctx, "pending_orders", &redis.Z{
-		Score:  score,
-		Member: orderID,
-	}).Err(); err != nil {
-		return fmt.Errorf("failed to add to pending orders: %w", err)
+	if stored < 0 {
+		return fmt.Errorf("%w: %s", ErrOrderCancelled, orderID)
 	}
 
 	return nil
 }
 
-// GetOrdersDueForExecution returns orders that are due for execution
-func (r *RedisCache) GetOrdersDueForExecution(now time.Time) ([]models.Order, error) {
-	// Query pending_orders sorted set for orders where scheduled_time <= now
-	maxScore := float64(now.Unix())
-	
-	orderIDs, err := r.client.ZRangeByScore(r.ctx, "pending_orders", &redis.ZRangeBy{
-		Min: "0",
-		Max: fmt.Sprintf("%.0f", maxScore),
-	}).Result()
-	
+// GetOrder returns the cache entry for a single order
+func (r *RedisCache) GetOrder(ctx context.Context, orderID string) (*models.OrderCacheEntry, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	key := r.key("order:%s", orderID)
+	data, err := r.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, ErrOrderNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	var entry models.OrderCacheEntry
+	if err := entry.FromJSON([]byte(data)); err != nil {
+		if qErr := r.quarantineOrder(ctx, orderID, data, err); qErr != nil {
+			return nil, fmt.Errorf("failed to decode order %s: %v (quarantine failed: %v)", orderID, err, qErr)
+		}
+		return nil, fmt.Errorf("%w: order %s: %v", ErrOrderQuarantined, orderID, err)
+	}
+	return &entry, nil
+}
+
+// ListPendingOrders returns all pending orders ordered by scheduled time
+func (r *RedisCache) ListPendingOrders(ctx context.Context) ([]models.OrderCacheEntry, error) {
+	rangeCtx, cancel := r.withTimeout(ctx)
+	orderIDs, err := r.client.ZRange(rangeCtx, r.key(pendingOrdersKey), 0, -1).Result()
+	cancel()
 	if err != nil {
-		return nil, fmt.Errorf("failed to query pending orders: %w", err)
+		return nil, fmt.Errorf("failed to list pending orders: %w", err)
 	}
 
-	var orders []models.Order
+	entries := make([]models.OrderCacheEntry, 0, len(orderIDs))
 	for _, orderID := range orderIDs {
-		key := fmt.Sprintf("order:%s", orderID)
-		data, err := r.client.Get(r.ctx, key).Result()
-		if err == redis.Nil {
-			// Order expired or was removed, remove from sorted set
-			r.client.ZRem(r.ctx, "pending_orders", orderID)
-			continue
+		entry, err := r.GetOrder(ctx, orderID)
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
 		} else if err != nil {
+			// Entries whose data is gone are reported when they are claimed; undecodable
+			// entries have been moved to the quarantine
 			continue
 		}
+		entries = append(entries, *entry)
+	}
+	return entries, nil
+}
 
-		var entry models.OrderCacheEntry
-		if err := entry.FromJSON([]byte(data)); err != nil {
-			continue
-		}
+// RemoveOrder removes an order from cache, the pending queue and any claim on it
+func (r *RedisCache) RemoveOrder(ctx context.Context, orderID string) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-		// Check if order is still within expiry window
-		if now.After(entry.ExpiryTime) {
-			// Expired, remove it
-			r.RemoveOrder(entry.Order.ID)
-			continue
-		}
+	key := r.key("order:%s", orderID)
 
-		orders = append(orders, entry.Order)
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.ZRem(ctx, r.key(pendingOrdersKey), orderID)
+	pipe.ZRem(ctx, r.key(inFlightKey), orderID)
+	pipe.HDel(ctx, r.key(claimsKey), orderID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to remove order: %w", err)
 	}
 
-	return orders, nil
+	return nil
 }
 
-// RemoveOrder removes an order from cache
-func (r *RedisCache) RemoveOrder(orderID string) error {
-	key := fmt.Sprintf("order:%s", orderID)
-	
-	// Remove from hash
-	if err := r.client.Del(r.ctx, key).Err(); err != nil {
-		return fmt.Errorf("failed to remove order: %w", err)
+// PendingCount returns the number of orders in the pending_orders sorted set
+func (r *RedisCache) PendingCount(ctx context.Context) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	count, err := r.client.ZCard(ctx, r.key(pendingOrdersKey)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count pending orders: %w", err)
+	}
+	return count, nil
+}
+
+// SetHalt stores or replaces a trading halt
+func (r *RedisCache) SetHalt(ctx context.Context, halt models.Halt) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	data, err := json.Marshal(halt)
+	if err != nil {
+		return fmt.Errorf("failed to marshal halt: %w", err)
+	}
+	if err := r.client.HSet(ctx, r.key("trading_halts"), halt.Key(), data).Err(); err != nil {
+		return fmt.Errorf("failed to store halt: %w", err)
+	}
+	return nil
+}
+
+// ClearHalt removes a trading halt, reporting whether it existed
+func (r *RedisCache) ClearHalt(ctx context.Context, key string) (bool, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	removed, err := r.client.HDel(ctx, r.key("trading_halts"), key).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to clear halt: %w", err)
+	}
+	return removed > 0, nil
+}
+
+// GetHalts returns all active trading halts keyed by Halt.Key
+func (r *RedisCache) GetHalts(ctx context.Context) (map[string]models.Halt, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	values, err := r.client.HGetAll(ctx, r.key("trading_halts")).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get halts: %w", err)
+	}
+
+	halts := make(map[string]models.Halt, len(values))
+	for key, value := range values {
+		var halt models.Halt
+		if err := json.Unmarshal([]byte(value), &halt); err != nil {
+			continue
+		}
+		halts[key] = halt
 	}
+	return halts, nil
+}
+
+// AppendAudit adds an entry to the audit log, keeping the most recent maxAuditEntries
+func (r *RedisCache) AppendAudit(ctx context.Context, entry models.AuditEntry) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
 
-	// Remove from sorted set
-	if err := r.client.ZRem(r.ctx, "pending_orders", orderID).Err(); err != nil {
-		return fmt.Errorf("failed to remove from pending orders: %w", err)
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
 	}
 
+	pipe := r.client.TxPipeline()
+	pipe.LPush(ctx, r.key("audit_log"), data)
+	pipe.LTrim(ctx, r.key("audit_log"), 0, maxAuditEntries-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", err)
+	}
 	return nil
 }
 
-// TryLock attempts to acquire a lock for order execution (prevents duplicate execution)
-func (r *RedisCache) TryLock(orderID string, ttl time.Duration) (bool, error) {
-	lockKey := fmt.Sprintf("lock:order:%s", orderID)
-	result, err := r.client.SetNX(r.ctx, lockKey, "1", ttl).Result()
+// GetAuditLog returns up to limit audit entries, newest first
+func (r *RedisCache) GetAuditLog(ctx context.Context, limit int64) ([]models.AuditEntry, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	values, err := r.client.LRange(ctx, r.key("audit_log"), 0, limit-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	entries := make([]models.AuditEntry, 0, len(values))
+	for _, value := range values {
+		var entry models.AuditEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// IncrementConsecutiveFailures increments and returns the broker failure streak
+func (r *RedisCache) IncrementConsecutiveFailures(ctx context.Context) (int64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	count, err := r.client.Incr(ctx, r.key("kill_switch:consecutive_failures")).Result()
 	if err != nil {
-		return false, fmt.Errorf("failed to acquire lock: %w", err)
+		return 0, fmt.Errorf("failed to increment failure streak: %w", err)
 	}
-	return result, nil
+	return count, nil
 }
 
-// ReleaseLock releases the lock for an order
-func (r *RedisCache) ReleaseLock(orderID string) error {
-	lockKey := fmt.Sprintf("lock:order:%s", orderID)
-	return r.client.Del(r.ctx, lockKey).Err()
+// ResetConsecutiveFailures clears the broker failure streak
+func (r *RedisCache) ResetConsecutiveFailures(ctx context.Context) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.client.Del(ctx, r.key("kill_switch:consecutive_failures")).Err()
+}
+
+// AddDailyPnL adds delta to the realised P&L for day (YYYY-MM-DD) and returns the new total
+func (r *RedisCache) AddDailyPnL(ctx context.Context, day string, delta float64) (float64, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	key := r.key("kill_switch:pnl:%s", day)
+	total, err := r.client.IncrByFloat(ctx, key, delta).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to update daily P&L: %w", err)
+	}
+	r.client.Expire(ctx, key, 7*24*time.Hour)
+	return total, nil
 }
 
 // Close closes the Redis connection
@@ -156,7 +353,10 @@ func (r *RedisCache) Close() error {
 }
 
 // HealthCheck checks if Redis is accessible
-func (r *RedisCache) HealthCheck() error {
-	return r.client.Ping(r.ctx).Err()
+func (r *RedisCache) HealthCheck(ctx context.Context) error {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	return r.client.Ping(ctx).Err()
 }
 
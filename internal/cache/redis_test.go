@@ -0,0 +1,417 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+func newTestCache(t *testing.T, now time.Time) (*RedisCache, *clock.Simulated) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	cache, err := NewRedisCache(server.Addr(), "", 0)
+	if err != nil {
+		t.Fatalf("failed to connect to miniredis: %v", err)
+	}
+	t.Cleanup(func() { cache.Close() })
+
+	sim := clock.NewSimulated(now)
+	cache.SetClock(sim)
+	return cache, sim
+}
+
+func testOrder(id string, scheduled time.Time) models.Order {
+	return models.Order{
+		ID:            id,
+		Symbol:        "INFY",
+		Exchange:      "NSE",
+		Price:         100,
+		Quantity:      10,
+		OrderType:     "LIMIT",
+		Side:          "Buy",
+		ScheduledTime: scheduled,
+	}
+}
+
+func TestStoreOrderUsesClockForCreatedAtAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	cache, _ := newTestCache(t, now)
+
+	scheduled := now.Add(time.Minute)
+	if err := cache.StoreOrder(ctx, testOrder("A", scheduled), scheduled.Add(DefaultExpiryWindow)); err != nil {
+		t.Fatalf("StoreOrder: %v", err)
+	}
+	entry, err := cache.GetOrder(ctx, "A")
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if !entry.CreatedAt.Equal(now) {
+		t.Errorf("CreatedAt = %v, want simulated now %v", entry.CreatedAt, now)
+	}
+
+	// An expiry that is in the past for the simulated clock is rejected, even though
+	// it is long after the wall-clock epoch
+	if err := cache.StoreOrder(ctx, testOrder("B", now.Add(-time.Minute)), now.Add(-time.Second)); err == nil {
+		t.Error("StoreOrder accepted an expiry before the simulated now")
+	}
+}
+
+func TestClaimDueOrdersScheduleAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 15, 9, 14, 0, 0, time.UTC)
+	cache, sim := newTestCache(t, start)
+
+	scheduled := time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC)
+	for _, id := range []string{"A", "B"} {
+		if err := cache.StoreOrder(ctx, testOrder(id, scheduled), scheduled.Add(DefaultExpiryWindow)); err != nil {
+			t.Fatalf("StoreOrder: %v", err)
+		}
+	}
+
+	sim.Set(scheduled.Add(-time.Second))
+	due, late, err := cache.ClaimDueOrders(ctx, sim.Now(), "test", time.Minute, Fence{})
+	if err != nil {
+		t.Fatalf("ClaimDueOrders: %v", err)
+	}
+	if len(due) != 0 || len(late) != 0 {
+		t.Fatalf("one second early: claimed %d due and %d late orders, want none", len(due), len(late))
+	}
+
+	// Still executable on the last instant of the expiry window
+	sim.Set(scheduled.Add(DefaultExpiryWindow))
+	due, late, err = cache.ClaimDueOrders(ctx, sim.Now(), "test", time.Minute, Fence{})
+	if err != nil {
+		t.Fatalf("ClaimDueOrders: %v", err)
+	}
+	if len(due) != 2 || len(late) != 0 {
+		t.Fatalf("at end of expiry window: got %d due and %d late, want 2 due", len(due), len(late))
+	}
+	if pending, _ := cache.PendingCount(ctx); pending != 0 {
+		t.Errorf("PendingCount = %d after claim, want 0", pending)
+	}
+	if inFlight, _ := cache.InFlightCount(ctx); inFlight != 2 {
+		t.Errorf("InFlightCount = %d after claim, want 2", inFlight)
+	}
+
+	if released, err := cache.ReleaseClaim(ctx, "B", "test"); err != nil || !released {
+		t.Fatalf("ReleaseClaim = %v, %v; want released", released, err)
+	}
+	sim.Advance(time.Second)
+	due, late, err = cache.ClaimDueOrders(ctx, sim.Now(), "test", time.Minute, Fence{})
+	if err != nil {
+		t.Fatalf("ClaimDueOrders: %v", err)
+	}
+	if len(due) != 0 {
+		t.Fatalf("after expiry: got %d due orders, want 0", len(due))
+	}
+	if len(late) != 1 || late[0].Order.ID != "B" {
+		t.Fatalf("after expiry: late = %v, want order B", late)
+	}
+	// Late orders stay cached until the trigger resolves them
+	if _, err := cache.GetOrder(ctx, "B"); err != nil {
+		t.Errorf("late order no longer cached: %v", err)
+	}
+}
+
+func TestClaimDueOrdersReportsLostOrderData(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	cache, _ := newTestCache(t, now)
+
+	if err := cache.StoreOrder(ctx, testOrder("A", now), now.Add(DefaultExpiryWindow)); err != nil {
+		t.Fatalf("StoreOrder: %v", err)
+	}
+	// Simulate the order key outliving its TTL while the queue entry remains
+	if err := cache.client.Del(ctx, "order:A").Err(); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+
+	due, late, err := cache.ClaimDueOrders(ctx, now, "test", time.Minute, Fence{})
+	if err != nil {
+		t.Fatalf("ClaimDueOrders: %v", err)
+	}
+	if len(due) != 0 || len(late) != 1 || late[0].Order.ID != "A" {
+		t.Fatalf("due = %v, late = %v; want order A reported as late", due, late)
+	}
+}
+
+func TestClaimDueOrdersIsExclusive(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC)
+	cache, _ := newTestCache(t, now)
+	if err := cache.StoreOrder(ctx, testOrder("A", now), now.Add(DefaultExpiryWindow)); err != nil {
+		t.Fatalf("StoreOrder: %v", err)
+	}
+
+	first, _, err := cache.ClaimDueOrders(ctx, now, "replica-1", time.Minute, Fence{})
+	if err != nil {
+		t.Fatalf("ClaimDueOrders: %v", err)
+	}
+	second, _, err := cache.ClaimDueOrders(ctx, now, "replica-2", time.Minute, Fence{})
+	if err != nil {
+		t.Fatalf("ClaimDueOrders: %v", err)
+	}
+	if len(first) != 1 || len(second) != 0 {
+		t.Fatalf("replica-1 claimed %d, replica-2 claimed %d; want 1 and 0", len(first), len(second))
+	}
+
+	if ok, _ := cache.MarkSubmitted(ctx, "A", "replica-2", time.Minute, Fence{}); ok {
+		t.Error("MarkSubmitted succeeded for a replica that does not own the claim")
+	}
+	if ok, _ := cache.ReleaseClaim(ctx, "A", "replica-2"); ok {
+		t.Error("ReleaseClaim succeeded for a replica that does not own the claim")
+	}
+
+	// A reader refresh must not put the in-flight order back in the queue
+	if err := cache.StoreOrder(ctx, testOrder("A", now), now.Add(DefaultExpiryWindow)); err != nil {
+		t.Fatalf("StoreOrder: %v", err)
+	}
+	if pending, _ := cache.PendingCount(ctx); pending != 0 {
+		t.Errorf("PendingCount = %d after re-storing an in-flight order, want 0", pending)
+	}
+
+	if ok, err := cache.MarkSubmitted(ctx, "A", "replica-1", time.Minute, Fence{}); err != nil || !ok {
+		t.Fatalf("MarkSubmitted = %v, %v; want success for the owner", ok, err)
+	}
+	if ok, _ := cache.ReleaseClaim(ctx, "A", "replica-1"); ok {
+		t.Error("ReleaseClaim returned a submitted order to the queue")
+	}
+
+	if err := cache.RemoveOrder(ctx, "A"); err != nil {
+		t.Fatalf("RemoveOrder: %v", err)
+	}
+	if inFlight, _ := cache.InFlightCount(ctx); inFlight != 0 {
+		t.Errorf("InFlightCount = %d after RemoveOrder, want 0", inFlight)
+	}
+}
+
+func TestSweepExpiredLeases(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC)
+	cache, sim := newTestCache(t, now)
+	for _, id := range []string{"QUEUED", "SUBMITTED"} {
+		if err := cache.StoreOrder(ctx, testOrder(id, now), now.Add(time.Hour)); err != nil {
+			t.Fatalf("StoreOrder: %v", err)
+		}
+	}
+	if _, _, err := cache.ClaimDueOrders(ctx, now, "crashed", 30*time.Second, Fence{}); err != nil {
+		t.Fatalf("ClaimDueOrders: %v", err)
+	}
+	if ok, _ := cache.MarkSubmitted(ctx, "SUBMITTED", "crashed", 30*time.Second, Fence{}); !ok {
+		t.Fatal("MarkSubmitted failed for the owner")
+	}
+
+	requeued, abandoned, err := cache.SweepExpiredLeases(ctx, now.Add(29*time.Second))
+	if err != nil {
+		t.Fatalf("SweepExpiredLeases: %v", err)
+	}
+	if len(requeued) != 0 || len(abandoned) != 0 {
+		t.Fatalf("sweep before the lease ended: requeued %v, abandoned %v; want none", requeued, abandoned)
+	}
+
+	sim.Advance(31 * time.Second)
+	if ok, _ := cache.MarkSubmitted(ctx, "QUEUED", "crashed", 30*time.Second, Fence{}); ok {
+		t.Error("MarkSubmitted succeeded after the lease ended")
+	}
+
+	requeued, abandoned, err = cache.SweepExpiredLeases(ctx, sim.Now())
+	if err != nil {
+		t.Fatalf("SweepExpiredLeases: %v", err)
+	}
+	if len(requeued) != 1 || requeued[0] != "QUEUED" {
+		t.Errorf("requeued = %v, want the unsubmitted order", requeued)
+	}
+	if len(abandoned) != 1 || abandoned[0] != "SUBMITTED" {
+		t.Errorf("abandoned = %v, want the submitted order", abandoned)
+	}
+
+	due, _, err := cache.ClaimDueOrders(ctx, sim.Now(), "replacement", 30*time.Second, Fence{})
+	if err != nil {
+		t.Fatalf("ClaimDueOrders: %v", err)
+	}
+	if len(due) != 1 || due[0].ID != "QUEUED" {
+		t.Errorf("replacement claimed %v, want the re-queued order", due)
+	}
+}
+
+func TestExpiryForPrefersOrderThenSourceWindow(t *testing.T) {
+	scheduled := time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC)
+	expiry := config.ExpiryConfig{Window: 30 * time.Second, SellWindow: time.Minute}
+
+	buy := testOrder("B", scheduled)
+	if got := ExpiryFor(buy, expiry); !got.Equal(scheduled.Add(30 * time.Second)) {
+		t.Errorf("buy expiry = %v, want the default window", got)
+	}
+
+	sell := testOrder("S", scheduled)
+	sell.Side = "Sell"
+	if got := ExpiryFor(sell, expiry); !got.Equal(scheduled.Add(time.Minute)) {
+		t.Errorf("sell expiry = %v, want the sell window", got)
+	}
+
+	sell.ExpiryWindow = 5 * time.Second
+	if got := ExpiryFor(sell, expiry); !got.Equal(scheduled.Add(5 * time.Second)) {
+		t.Errorf("per-order expiry = %v, want the order's own window", got)
+	}
+
+	if got := ExpiryFor(buy, config.ExpiryConfig{}); !got.Equal(scheduled.Add(DefaultExpiryWindow)) {
+		t.Errorf("unconfigured expiry = %v, want DefaultExpiryWindow", got)
+	}
+}
+
+func TestUndecodableEntriesAreQuarantined(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC)
+	cache, _ := newTestCache(t, now)
+	for _, id := range []string{"GOOD", "BAD"} {
+		if err := cache.StoreOrder(ctx, testOrder(id, now), now.Add(time.Minute)); err != nil {
+			t.Fatalf("StoreOrder: %v", err)
+		}
+	}
+	// Written by a newer build with a schema this one cannot read
+	if err := cache.client.Set(ctx, "order:BAD", `{"version":99,"order":{"id":"BAD"}}`, time.Hour).Err(); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	due, _, err := cache.ClaimDueOrders(ctx, now, "test", time.Minute, Fence{})
+	if err != nil {
+		t.Fatalf("ClaimDueOrders: %v", err)
+	}
+	if len(due) != 1 || due[0].ID != "GOOD" {
+		t.Fatalf("claimed %v, want only the decodable order", due)
+	}
+	if inFlight, _ := cache.InFlightCount(ctx); inFlight != 1 {
+		t.Errorf("InFlightCount = %d, want the quarantined order out of in_flight", inFlight)
+	}
+	if _, err := cache.GetOrder(ctx, "BAD"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("GetOrder(BAD) = %v, want ErrOrderNotFound once quarantined", err)
+	}
+
+	quarantined, err := cache.ListQuarantined(ctx)
+	if err != nil {
+		t.Fatalf("ListQuarantined: %v", err)
+	}
+	if len(quarantined) != 1 || quarantined[0].OrderID != "BAD" || !strings.Contains(quarantined[0].Reason, "version 99") {
+		t.Fatalf("quarantined = %+v, want BAD with the decode error", quarantined)
+	}
+	if !strings.Contains(quarantined[0].Data, `"version":99`) {
+		t.Errorf("quarantined data = %q, want the raw entry", quarantined[0].Data)
+	}
+
+	// A fresh copy from the sheet supersedes the quarantined one
+	if err := cache.StoreOrder(ctx, testOrder("BAD", now), now.Add(time.Minute)); err != nil {
+		t.Fatalf("StoreOrder: %v", err)
+	}
+	if count, _ := cache.QuarantineCount(ctx); count != 0 {
+		t.Errorf("QuarantineCount = %d after re-storing the order, want 0", count)
+	}
+}
+
+func TestMigrateOrdersUpgradesLegacyEntries(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	cache, _ := newTestCache(t, now)
+	scheduled := now.Add(15 * time.Minute)
+	for _, id := range []string{"LEGACY", "CURRENT", "CORRUPT"} {
+		if err := cache.StoreOrder(ctx, testOrder(id, scheduled), scheduled.Add(time.Minute)); err != nil {
+			t.Fatalf("StoreOrder: %v", err)
+		}
+	}
+	ttl := cache.client.TTL(ctx, "order:LEGACY").Val()
+
+	legacy := `{"order":{"id":"LEGACY","symbol":"INFY","exchange":"NSE","price":100,"quantity":10,` +
+		`"order_type":"LIMIT","side":"Buy","scheduled_time":"2024-01-15T09:15:00Z","created_at":"0001-01-01T00:00:00Z",` +
+		`"is_amo":false},"expiry_time":"2024-01-15T09:16:00Z","created_at":"2024-01-15T09:00:00Z"}`
+	if err := cache.client.Set(ctx, "order:LEGACY", legacy, ttl).Err(); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := cache.client.Set(ctx, "order:CORRUPT", "{not json", ttl).Err(); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	migrated, quarantined, err := cache.MigrateOrders(ctx)
+	if err != nil {
+		t.Fatalf("MigrateOrders: %v", err)
+	}
+	if migrated != 1 {
+		t.Errorf("migrated = %d, want only the legacy entry", migrated)
+	}
+	if len(quarantined) != 1 || quarantined[0] != "CORRUPT" {
+		t.Errorf("quarantined = %v, want CORRUPT", quarantined)
+	}
+
+	entry, err := cache.GetOrder(ctx, "LEGACY")
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if entry.Version != models.OrderCacheEntryVersion {
+		t.Errorf("Version = %d after migration, want %d", entry.Version, models.OrderCacheEntryVersion)
+	}
+	if got := cache.client.TTL(ctx, "order:LEGACY").Val(); got <= 0 || got > ttl {
+		t.Errorf("TTL = %v after migration, want the original %v kept", got, ttl)
+	}
+	if pending, _ := cache.PendingCount(ctx); pending != 2 {
+		t.Errorf("PendingCount = %d, want the legacy and current orders still queued", pending)
+	}
+}
+
+func TestDeadLetterOrderRemovesItFromTheQueues(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC)
+	cache, sim := newTestCache(t, now)
+	order := testOrder("A", now)
+	if err := cache.StoreOrder(ctx, order, now.Add(time.Minute)); err != nil {
+		t.Fatalf("StoreOrder: %v", err)
+	}
+	if _, _, err := cache.ClaimDueOrders(ctx, now, "test", time.Minute, Fence{}); err != nil {
+		t.Fatalf("ClaimDueOrders: %v", err)
+	}
+
+	order.Attempts = 1
+	if err := cache.DeadLetterOrder(ctx, models.DeadLetter{Order: order, Reason: models.DeadLetterFailed, Error: "403", Attempts: 1}); err != nil {
+		t.Fatalf("DeadLetterOrder: %v", err)
+	}
+	if inFlight, _ := cache.InFlightCount(ctx); inFlight != 0 {
+		t.Errorf("InFlightCount = %d after dead-lettering, want 0", inFlight)
+	}
+	if _, err := cache.GetOrder(ctx, "A"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("GetOrder = %v, want ErrOrderNotFound", err)
+	}
+
+	letters, err := cache.ListDeadLetters(ctx)
+	if err != nil {
+		t.Fatalf("ListDeadLetters: %v", err)
+	}
+	if len(letters) != 1 || letters[0].Error != "403" || !letters[0].DeadLetteredAt.Equal(now) {
+		t.Fatalf("dead letters = %+v, want A stamped with the cache clock", letters)
+	}
+
+	sim.Advance(time.Hour)
+	order.ScheduledTime = sim.Now()
+	if err := cache.RequeueDeadLetter(ctx, order, sim.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("RequeueDeadLetter: %v", err)
+	}
+	if _, err := cache.GetDeadLetter(ctx, "A"); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("GetDeadLetter = %v after requeue, want ErrDeadLetterNotFound", err)
+	}
+	entry, err := cache.GetOrder(ctx, "A")
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if entry.Order.Attempts != 1 || !entry.Order.ScheduledTime.Equal(sim.Now()) {
+		t.Errorf("requeued order = %+v, want the new schedule and the attempt count kept", entry.Order)
+	}
+
+	if removed, err := cache.DiscardDeadLetter(ctx, "A"); err != nil || removed {
+		t.Errorf("DiscardDeadLetter = %v, %v; want nothing left to discard", removed, err)
+	}
+}
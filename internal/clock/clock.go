@@ -0,0 +1,52 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time. Scheduling code takes a Clock instead of calling time.Now
+// directly so it can run against simulated time (replays) and fixed times (tests).
+type Clock interface {
+	Now() time.Time
+}
+
+// Real is the wall clock
+type Real struct{}
+
+// Now returns the current wall-clock time
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// Simulated is a clock that only moves when told to
+type Simulated struct {
+	mu  sync.RWMutex
+	now time.Time
+}
+
+// NewSimulated creates a simulated clock starting at start
+func NewSimulated(start time.Time) *Simulated {
+	return &Simulated{now: start}
+}
+
+// Now returns the simulated time
+func (s *Simulated) Now() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.now
+}
+
+// Set moves the simulated time to t
+func (s *Simulated) Set(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = t
+}
+
+// Advance moves the simulated time forward by d
+func (s *Simulated) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = s.now.Add(d)
+}
@@ -0,0 +1,26 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulatedOnlyMovesWhenTold(t *testing.T) {
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	sim := NewSimulated(start)
+
+	if got := sim.Now(); !got.Equal(start) {
+		t.Fatalf("Now() = %v, want %v", got, start)
+	}
+
+	sim.Advance(90 * time.Second)
+	if want := start.Add(90 * time.Second); !sim.Now().Equal(want) {
+		t.Fatalf("after Advance, Now() = %v, want %v", sim.Now(), want)
+	}
+
+	later := time.Date(2024, 1, 16, 15, 30, 0, 0, time.UTC)
+	sim.Set(later)
+	if !sim.Now().Equal(later) {
+		t.Fatalf("after Set, Now() = %v, want %v", sim.Now(), later)
+	}
+}
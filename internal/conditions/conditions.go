@@ -0,0 +1,74 @@
+// Package conditions parses and evaluates the market conditions that hold a due order back
+// until they are met.
+package conditions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/quotes"
+)
+
+// metrics maps the names accepted in the order source to condition metrics
+var metrics = map[string]string{
+	"ltp":        models.ConditionLTP,
+	"price":      models.ConditionLTP,
+	"change":     models.ConditionChangePct,
+	"change%":    models.ConditionChangePct,
+	"change_pct": models.ConditionChangePct,
+	"volume":     models.ConditionVolume,
+}
+
+// Parse reads a condition written as "<metric> <operator> <value>", e.g. "ltp >= 1500",
+// "change% <= -2" or "volume >= 100000". Operators are >= and <=; > and < are read the same way.
+func Parse(expr string) (models.Condition, error) {
+	expr = strings.ToLower(strings.TrimSpace(expr))
+	index := strings.IndexAny(expr, "<>")
+	if index < 0 {
+		return models.Condition{}, fmt.Errorf("condition %q has no >= or <= operator", expr)
+	}
+	name := strings.TrimSpace(expr[:index])
+	operator, rest := expr[index:index+1]+"=", strings.TrimPrefix(expr[index+1:], "=")
+
+	metric, ok := metrics[name]
+	if !ok {
+		return models.Condition{}, fmt.Errorf("condition %q has unknown metric %q (expected ltp, change%% or volume)", expr, name)
+	}
+	value, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(rest), "%"), 64)
+	if err != nil {
+		return models.Condition{}, fmt.Errorf("condition %q has invalid value %q", expr, strings.TrimSpace(rest))
+	}
+	if metric != models.ConditionChangePct && value < 0 {
+		return models.Condition{}, fmt.Errorf("condition %q has a negative %s", expr, metric)
+	}
+	return models.Condition{Metric: metric, Operator: operator, Value: value}, nil
+}
+
+// Evaluate reports whether quote meets condition and the value it observed. A change
+// condition is never met before the day's open is known.
+func Evaluate(condition models.Condition, quote quotes.Quote) (bool, float64) {
+	var observed float64
+	switch condition.Metric {
+	case models.ConditionLTP:
+		observed = quote.LastPrice
+	case models.ConditionChangePct:
+		if quote.Open <= 0 {
+			return false, 0
+		}
+		observed = (quote.LastPrice - quote.Open) / quote.Open * 100
+	case models.ConditionVolume:
+		observed = float64(quote.Volume)
+	default:
+		return false, 0
+	}
+
+	switch condition.Operator {
+	case ">=":
+		return observed >= condition.Value, observed
+	case "<=":
+		return observed <= condition.Value, observed
+	}
+	return false, observed
+}
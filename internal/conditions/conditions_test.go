@@ -0,0 +1,60 @@
+package conditions
+
+import (
+	"testing"
+
+	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/quotes"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string // Condition.String(); empty for a parse error
+	}{
+		{"ltp >= 1500", "ltp >= 1500"},
+		{"LTP<=1499.5", "ltp <= 1499.5"},
+		{"price > 100", "ltp >= 100"},
+		{"change% <= -2%", "change_pct <= -2"},
+		{"volume >= 100000", "volume >= 100000"},
+		{"ltp = 1500", ""},
+		{"bid >= 10", ""},
+		{"ltp >= abc", ""},
+		{"volume >= -5", ""},
+	}
+	for _, tt := range tests {
+		condition, err := Parse(tt.expr)
+		got := ""
+		if err == nil {
+			got = condition.String()
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %q (err %v), want %q", tt.expr, got, err, tt.want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	quote := quotes.Quote{LastPrice: 98, Open: 100, Volume: 5000}
+	tests := []struct {
+		condition models.Condition
+		met       bool
+	}{
+		{models.Condition{Metric: models.ConditionLTP, Operator: "<=", Value: 98}, true},
+		{models.Condition{Metric: models.ConditionLTP, Operator: ">=", Value: 98.05}, false},
+		{models.Condition{Metric: models.ConditionChangePct, Operator: "<=", Value: -2}, true},
+		{models.Condition{Metric: models.ConditionChangePct, Operator: ">=", Value: 1}, false},
+		{models.Condition{Metric: models.ConditionVolume, Operator: ">=", Value: 5000}, true},
+	}
+	for _, tt := range tests {
+		if met, observed := Evaluate(tt.condition, quote); met != tt.met {
+			t.Errorf("Evaluate(%s) = %v (observed %v), want %v", tt.condition, met, observed, tt.met)
+		}
+	}
+
+	// Without the open a change cannot be worked out
+	change := models.Condition{Metric: models.ConditionChangePct, Operator: "<=", Value: 0}
+	if met, _ := Evaluate(change, quotes.Quote{LastPrice: 98}); met {
+		t.Error("Evaluate(change) without an open = true, want false")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,6 +16,16 @@ type Config struct {
 	Broker       BrokerConfig
 	Logging      LoggingConfig
 	Trigger      TriggerConfig
+	Metrics      MetricsConfig
+	Tracing      TracingConfig
+	Admin        AdminConfig
+	KillSwitch   KillSwitchConfig
+	Journal      JournalConfig
+	Leader       LeaderConfig
+	Notify       NotifyConfig
+	Instruments  InstrumentsConfig
+	Sizing       SizingConfig
+	GTT          GTTConfig
 }
 
 // GoogleSheetsConfig holds Google Sheets API configuration
@@ -28,9 +39,31 @@ type GoogleSheetsConfig struct {
 
 // RedisConfig holds Redis connection configuration
 type RedisConfig struct {
-	Addr     string
-	Password string
-	DB       int
+	Addr             string
+	Addrs            []string // Cluster seed nodes or Sentinel addresses; Addr is used when empty
+	Username         string   // ACL user (Redis 6+)
+	Password         string
+	DB               int      // Ignored in cluster mode
+	MasterName       string   // Sentinel master name; enables Sentinel failover
+	SentinelPassword string
+	ClusterMode      bool     // Use a cluster client even with a single seed address
+	TLS              RedisTLSConfig
+	PoolSize         int           // Connections per node (0 = go-redis default of 10 per CPU)
+	MinIdleConns     int
+	DialTimeout      time.Duration
+	ReadTimeout      time.Duration
+	WriteTimeout     time.Duration
+	CommandTimeout   time.Duration // Deadline for each cache call, including scripts and pipelines
+}
+
+// RedisTLSConfig holds TLS settings for the Redis connection
+type RedisTLSConfig struct {
+	Enabled            bool
+	CAFile             string // PEM bundle used to verify the server (system roots when empty)
+	CertFile           string // Client certificate for mutual TLS
+	KeyFile            string
+	ServerName         string // Overrides the name checked against the server certificate
+	InsecureSkipVerify bool   // Skip server verification; local testing only
 }
 
 // BrokerConfig holds broker configuration
@@ -41,9 +74,79 @@ type BrokerConfig struct {
 	APISecret    string
 	RefreshToken string // For Kite: refresh token to get new access tokens
 	BaseURL      string
+	APIURL       string // Kite Connect API host for orders, profile and quotes
 	RateLimit    RateLimitConfig
+	Paper        PaperConfig // Used when Type is "paper"
+	Portfolio    PortfolioConfig
+	Funds        FundsConfig
+	Prices       PriceConfig
+	Derivatives  DerivativesConfig
+}
+
+// PortfolioConfig controls how sell orders are checked against holdings and positions
+type PortfolioConfig struct {
+	SellPolicy      string        // What to do with a sell larger than the available quantity
+	RefreshInterval time.Duration // How long a fetched portfolio is reused before it is fetched again
+}
+
+// FundsConfig controls how each execution cycle's buy orders are checked against available margin
+type FundsConfig struct {
+	BuyPolicy       string        // What to do when the cycle's buys need more margin than is available
+	RefreshInterval time.Duration // How long fetched funds are reused before they are fetched again
+}
+
+// Buy funds policies: how the broker layer treats a batch of buys that needs more margin than is available
+const (
+	FundsPolicyOff      = "off"      // Send buys unchecked
+	FundsPolicyReject   = "reject"   // Fail every buy in the batch
+	FundsPolicyScale    = "scale"    // Scale every buy down by the same factor so the batch fits
+	FundsPolicyPriority = "priority" // Fund buys in schedule and sheet row order; fail those that no longer fit
+)
+
+// PriceConfig controls how limit prices are checked against the day's circuit band
+type PriceConfig struct {
+	BandPolicy string // What to do with a limit price outside the band
+}
+
+// DerivativesConfig holds the expiry-day rules for futures and options orders
+type DerivativesConfig struct {
+	ExpiryDayCutoff string // HH:MM (IST) after which no orders are placed in contracts expiring that day; empty for none
 }
 
+// Price band policies: how the broker layer treats a limit price outside the day's circuit band
+const (
+	PriceBandPolicyOff    = "off"    // Send the price as it is
+	PriceBandPolicyAdjust = "adjust" // Lower buys to the upper limit and raise sells to the lower limit; fail the rest
+	PriceBandPolicyReject = "reject" // Fail every order priced outside the band
+)
+
+// Sell policies: how the broker layer treats a sell order for more than the account holds
+const (
+	SellPolicyOff    = "off"    // Send sells unchecked
+	SellPolicyReject = "reject" // Fail the order without sending it
+	SellPolicyCap    = "cap"    // Reduce the quantity to what is available; fail it if nothing is
+)
+
+// PaperConfig holds the simulated exchange settings for the paper broker
+type PaperConfig struct {
+	Seed            int64    `json:"seed"`              // Seed for all simulated randomness
+	InitialCash     float64  `json:"initial_cash"`      // Starting cash balance
+	LatencyMs       int      `json:"latency_ms"`        // Fixed order acknowledgement latency
+	LatencyJitterMs int      `json:"latency_jitter_ms"` // Additional seeded random latency (0 - N ms)
+	RejectRate      float64  `json:"reject_rate"`       // Fraction of orders rejected at random (seeded)
+	RejectSymbols   []string `json:"reject_symbols"`    // Symbols that are always rejected
+	AllowShort      bool     `json:"allow_short"`       // Allow sells beyond the simulated position
+	PricesPath      string   `json:"prices_path"`       // CSV of exchange,symbol,price used as initial LTPs
+	QuoteSource     string   `json:"quote_source"`      // Live quotes that move the simulated prices: "off" or "kite"
+	QuoteIntervalMs int      `json:"quote_interval_ms"` // How often the quote source is polled
+}
+
+// Paper quote sources: where the paper broker's prices come from once the trigger is running
+const (
+	PaperQuoteSourceOff  = "off"  // Prices stay at prices_path
+	PaperQuoteSourceKite = "kite" // Poll Kite quotes with the broker API key and access token
+)
+
 // RateLimitConfig holds rate limiting configuration
 type RateLimitConfig struct {
 	RequestsPerSecond int
@@ -63,8 +166,133 @@ type TriggerConfig struct {
 	WorkerPoolSize    int
 	CheckInterval     time.Duration // How often to check for due orders
 	HealthCheckInterval time.Duration // How often to run health checks
+	Expiry              ExpiryConfig
+	InstanceID          string        // Identifies this trigger replica as the owner of claimed orders
+	ClaimLease          time.Duration // How long a claimed order is reserved for this replica
+	SweepInterval       time.Duration // How often to re-queue or fail claims whose lease ran out
+	Quotes              QuotesConfig
+}
+
+// QuotesConfig controls the quote polling conditional orders are checked against
+type QuotesConfig struct {
+	PollInterval time.Duration // How often the broker is asked for quotes of instruments with armed orders
+	MaxAge       time.Duration // Quotes older than this are not used to fire orders
+}
+
+// Late-order policies: what the trigger does with an order found past its expiry window
+const (
+	LatePolicySkip    = "skip"    // Record it as expired without executing
+	LatePolicyExecute = "execute" // Execute it anyway if it is no more than LateGrace past the window
+	LatePolicyAMO     = "amo"     // Place it as an AMO if the market is closed, otherwise record it as expired
+)
+
+// ExpiryConfig controls how long orders stay executable and what happens to late ones
+type ExpiryConfig struct {
+	Window            time.Duration // Default window after the scheduled time
+	BuyWindow         time.Duration // Overrides Window for to_buy orders (0 = use Window)
+	SellWindow        time.Duration // Overrides Window for to_sell orders (0 = use Window)
+	ConditionalWindow time.Duration // Overrides the above for orders waiting on a condition (0 = do not override)
+	LatePolicy        string        // skip, execute or amo
+	LateGrace         time.Duration // With the execute policy: how far past the window an order may still run
+}
+
+// WindowFor returns the expiry window configured for orders from side's sheet
+func (e ExpiryConfig) WindowFor(side string) time.Duration {
+	switch strings.ToUpper(side) {
+	case "BUY":
+		if e.BuyWindow > 0 {
+			return e.BuyWindow
+		}
+	case "SELL":
+		if e.SellWindow > 0 {
+			return e.SellWindow
+		}
+	}
+	return e.Window
 }
 
+// MetricsConfig holds Prometheus metrics endpoint configuration
+type MetricsConfig struct {
+	Enabled     bool
+	TriggerAddr string // Listen address for the trigger process /metrics endpoint
+	ReaderAddr  string // Listen address for the read process /metrics endpoint
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	Exporter     string  // none, otlp or file
+	OTLPEndpoint string  // host:port of the OTLP/HTTP collector
+	OTLPInsecure bool    // Use plain HTTP to reach the collector
+	FilePath     string  // Destination for the file exporter (one JSON span per line)
+	SampleRatio  float64 // Fraction of new traces to sample (0.0 - 1.0)
+}
+
+// AdminConfig holds configuration for the trigger's HTTP admin API
+type AdminConfig struct {
+	Enabled bool
+	Addr    string // Listen address; localhost only by default
+	Token   string // Bearer token required on every request
+}
+
+// KillSwitchConfig holds automatic kill switch thresholds (0 disables a rule)
+type KillSwitchConfig struct {
+	MaxConsecutiveFailures int     // Trip after this many consecutive broker failures
+	DailyLossLimit         float64 // Trip when realised daily loss reaches this amount
+}
+
+// JournalConfig holds execution journal configuration
+type JournalConfig struct {
+	Path string // JSON-lines file shared by live and paper executions
+}
+
+// LeaderConfig holds active/standby leader election configuration
+type LeaderConfig struct {
+	Enabled       bool
+	InstanceID    string        // Identifies this process in the leader lease
+	Lease         time.Duration // How long leadership lasts without renewal; bounds failover time
+	RenewInterval time.Duration // How often the leader renews and standbys campaign
+}
+
+// NotifyConfig holds alert delivery configuration; a sink is enabled by setting its address
+type NotifyConfig struct {
+	WebhookURL       string // Generic webhook receiving each event as JSON
+	SlackWebhookURL  string // Slack incoming webhook
+	TelegramBotToken string
+	TelegramChatID   string
+	TelegramAPIURL   string // Telegram Bot API host; overridden in tests
+	SMTPAddr         string // host:port of the mail server
+	SMTPUsername     string // PLAIN auth user; no auth when empty
+	SMTPPassword     string
+	SMTPFrom         string
+	SMTPTo           []string
+	Rules            map[string][]string // Event type -> sink names ("*" for every configured sink)
+	Throttle         time.Duration       // Minimum gap between alerts of one event type
+	DedupWindow      time.Duration       // Identical alerts within this window are dropped
+	DailySummaryAt   string              // HH:MM (IST) to send the daily summary; empty disables it
+	Timeout          time.Duration       // Deadline for each delivery attempt
+}
+
+// InstrumentsConfig holds instrument master settings
+type InstrumentsConfig struct {
+	Enabled  bool   // Validate order source symbols and round prices to tick size
+	URL      string // Instruments CSV dump; the broker API host's /instruments when empty
+	CacheDir string // Directory holding the day's dump; no local copy when empty
+}
+
+// SizingConfig holds order sizing settings
+type SizingConfig struct {
+	EstimateCharges bool // Leave room for brokerage and statutory charges when sizing from money
+}
+
+// GTTConfig holds settings for GTT rows left standing at the broker
+type GTTConfig struct {
+	SyncInterval time.Duration // How often the trigger reconciles GTTs with the order source and the broker
+	StatusColumn string        // Sheet column GTT statuses are written back to; not written when empty
+}
+
+// defaultNotifyRules sends everything except per-order successes to every configured sink
+const defaultNotifyRules = "order_failure=*;health_degraded=*;token_expired=*;daily_summary=*"
+
 // LoadConfig loads configuration from environment variables
 func LoadConfig() (*Config, error) {
 	cfg := &Config{}
@@ -72,8 +300,8 @@ func LoadConfig() (*Config, error) {
 	// Google Sheets config
 	cfg.GoogleSheets.CredentialsPath = getEnv("GOOGLE_SHEETS_CREDENTIALS_PATH", "./config/google-credentials.json")
 	cfg.GoogleSheets.SheetID = getEnv("GOOGLE_SHEET_ID", "")
-	cfg.GoogleSheets.BuyRange = getEnv("GOOGLE_SHEET_BUY_RANGE", "to_buy!B3:K")
-	cfg.GoogleSheets.SellRange = getEnv("GOOGLE_SHEET_SELL_RANGE", "to_sell!B3:K")
+	cfg.GoogleSheets.BuyRange = getEnv("GOOGLE_SHEET_BUY_RANGE", "to_buy!B3:T")
+	cfg.GoogleSheets.SellRange = getEnv("GOOGLE_SHEET_SELL_RANGE", "to_sell!B3:T")
 	refreshInterval := getEnv("GOOGLE_SHEETS_REFRESH_INTERVAL", "1m")
 	var err error
 	cfg.GoogleSheets.RefreshInterval, err = time.ParseDuration(refreshInterval)
@@ -85,6 +313,38 @@ func LoadConfig() (*Config, error) {
 	cfg.Redis.Addr = getEnv("REDIS_ADDR", "localhost:6379")
 	cfg.Redis.Password = getEnv("REDIS_PASSWORD", "")
 	cfg.Redis.DB, _ = strconv.Atoi(getEnv("REDIS_DB", "0"))
+	cfg.Redis.Addrs = splitList(getEnv("REDIS_ADDRS", ""))
+	cfg.Redis.Username = getEnv("REDIS_USERNAME", "")
+	cfg.Redis.MasterName = getEnv("REDIS_SENTINEL_MASTER", "")
+	cfg.Redis.SentinelPassword = getEnv("REDIS_SENTINEL_PASSWORD", "")
+	cfg.Redis.ClusterMode, _ = strconv.ParseBool(getEnv("REDIS_CLUSTER", "false"))
+	if cfg.Redis.MasterName != "" && cfg.Redis.ClusterMode {
+		return nil, fmt.Errorf("REDIS_SENTINEL_MASTER and REDIS_CLUSTER cannot both be set")
+	}
+	cfg.Redis.TLS.Enabled, _ = strconv.ParseBool(getEnv("REDIS_TLS_ENABLED", "false"))
+	cfg.Redis.TLS.CAFile = getEnv("REDIS_TLS_CA_FILE", "")
+	cfg.Redis.TLS.CertFile = getEnv("REDIS_TLS_CERT_FILE", "")
+	cfg.Redis.TLS.KeyFile = getEnv("REDIS_TLS_KEY_FILE", "")
+	cfg.Redis.TLS.ServerName = getEnv("REDIS_TLS_SERVER_NAME", "")
+	cfg.Redis.TLS.InsecureSkipVerify, _ = strconv.ParseBool(getEnv("REDIS_TLS_INSECURE_SKIP_VERIFY", "false"))
+	cfg.Redis.PoolSize, _ = strconv.Atoi(getEnv("REDIS_POOL_SIZE", "0"))
+	cfg.Redis.MinIdleConns, _ = strconv.Atoi(getEnv("REDIS_MIN_IDLE_CONNS", "0"))
+	cfg.Redis.DialTimeout, err = time.ParseDuration(getEnv("REDIS_DIAL_TIMEOUT", "5s"))
+	if err != nil {
+		cfg.Redis.DialTimeout = 5 * time.Second
+	}
+	cfg.Redis.ReadTimeout, err = time.ParseDuration(getEnv("REDIS_READ_TIMEOUT", "3s"))
+	if err != nil {
+		cfg.Redis.ReadTimeout = 3 * time.Second
+	}
+	cfg.Redis.WriteTimeout, err = time.ParseDuration(getEnv("REDIS_WRITE_TIMEOUT", "3s"))
+	if err != nil {
+		cfg.Redis.WriteTimeout = 3 * time.Second
+	}
+	cfg.Redis.CommandTimeout, err = time.ParseDuration(getEnv("REDIS_COMMAND_TIMEOUT", "5s"))
+	if err != nil {
+		cfg.Redis.CommandTimeout = 5 * time.Second
+	}
 
 	// Broker config
 	cfg.Broker.ConfigPath = getEnv("BROKER_CONFIG_PATH", "./config/broker-config.json")
@@ -93,6 +353,64 @@ func LoadConfig() (*Config, error) {
 	cfg.Broker.APISecret = getEnv("BROKER_API_SECRET", "")
 	cfg.Broker.RefreshToken = getEnv("BROKER_REFRESH_TOKEN", "")
 	cfg.Broker.BaseURL = getEnv("BROKER_BASE_URL", "")
+	cfg.Broker.APIURL = getEnv("BROKER_API_URL", "")
+
+	// Paper broker config
+	cfg.Broker.Paper.Seed, _ = strconv.ParseInt(getEnv("PAPER_SEED", "1"), 10, 64)
+	cfg.Broker.Paper.InitialCash, _ = strconv.ParseFloat(getEnv("PAPER_INITIAL_CASH", "1000000"), 64)
+	cfg.Broker.Paper.LatencyMs, _ = strconv.Atoi(getEnv("PAPER_LATENCY_MS", "50"))
+	cfg.Broker.Paper.LatencyJitterMs, _ = strconv.Atoi(getEnv("PAPER_LATENCY_JITTER_MS", "0"))
+	cfg.Broker.Paper.RejectRate, _ = strconv.ParseFloat(getEnv("PAPER_REJECT_RATE", "0"), 64)
+	if symbols := getEnv("PAPER_REJECT_SYMBOLS", ""); symbols != "" {
+		cfg.Broker.Paper.RejectSymbols = strings.Split(symbols, ",")
+	}
+	cfg.Broker.Paper.AllowShort, _ = strconv.ParseBool(getEnv("PAPER_ALLOW_SHORT", "false"))
+	cfg.Broker.Paper.PricesPath = getEnv("PAPER_PRICES_PATH", "")
+	cfg.Broker.Paper.QuoteSource = strings.ToLower(getEnv("PAPER_QUOTE_SOURCE", PaperQuoteSourceOff))
+	cfg.Broker.Paper.QuoteIntervalMs, _ = strconv.Atoi(getEnv("PAPER_QUOTE_INTERVAL_MS", "1000"))
+
+	// Portfolio config
+	cfg.Broker.Portfolio.SellPolicy = strings.ToLower(getEnv("SELL_QUANTITY_POLICY", SellPolicyCap))
+	switch cfg.Broker.Portfolio.SellPolicy {
+	case SellPolicyOff, SellPolicyReject, SellPolicyCap:
+	default:
+		return nil, fmt.Errorf("invalid SELL_QUANTITY_POLICY %q (supported: off, reject, cap)", cfg.Broker.Portfolio.SellPolicy)
+	}
+	cfg.Broker.Portfolio.RefreshInterval, err = time.ParseDuration(getEnv("PORTFOLIO_REFRESH_INTERVAL", "30s"))
+	if err != nil || cfg.Broker.Portfolio.RefreshInterval < 0 {
+		cfg.Broker.Portfolio.RefreshInterval = 30 * time.Second
+	}
+
+	// Funds config
+	cfg.Broker.Funds.BuyPolicy = strings.ToLower(getEnv("BUY_FUNDS_POLICY", FundsPolicyPriority))
+	switch cfg.Broker.Funds.BuyPolicy {
+	case FundsPolicyOff, FundsPolicyReject, FundsPolicyScale, FundsPolicyPriority:
+	default:
+		return nil, fmt.Errorf("invalid BUY_FUNDS_POLICY %q (supported: off, reject, scale, priority)", cfg.Broker.Funds.BuyPolicy)
+	}
+	cfg.Broker.Funds.RefreshInterval, err = time.ParseDuration(getEnv("FUNDS_REFRESH_INTERVAL", "30s"))
+	if err != nil || cfg.Broker.Funds.RefreshInterval < 0 {
+		cfg.Broker.Funds.RefreshInterval = 30 * time.Second
+	}
+
+	// Price band config
+	cfg.Broker.Prices.BandPolicy = strings.ToLower(getEnv("PRICE_BAND_POLICY", PriceBandPolicyAdjust))
+	switch cfg.Broker.Prices.BandPolicy {
+	case PriceBandPolicyOff, PriceBandPolicyAdjust, PriceBandPolicyReject:
+	default:
+		return nil, fmt.Errorf("invalid PRICE_BAND_POLICY %q (supported: off, adjust, reject)", cfg.Broker.Prices.BandPolicy)
+	}
+
+	// Futures and options expiry-day cutoff
+	cfg.Broker.Derivatives.ExpiryDayCutoff = getEnv("FNO_EXPIRY_DAY_CUTOFF", "15:00")
+	if strings.EqualFold(cfg.Broker.Derivatives.ExpiryDayCutoff, "off") {
+		cfg.Broker.Derivatives.ExpiryDayCutoff = ""
+	}
+	if cfg.Broker.Derivatives.ExpiryDayCutoff != "" {
+		if _, err := time.Parse("15:04", cfg.Broker.Derivatives.ExpiryDayCutoff); err != nil {
+			return nil, fmt.Errorf("invalid FNO_EXPIRY_DAY_CUTOFF %q (expected HH:MM)", cfg.Broker.Derivatives.ExpiryDayCutoff)
+		}
+	}
 
 	// Rate limit config
 	cfg.Broker.RateLimit.RequestsPerSecond, _ = strconv.Atoi(getEnv("BROKER_RATE_LIMIT_RPS", "10"))
@@ -124,6 +442,120 @@ func LoadConfig() (*Config, error) {
 		cfg.Trigger.HealthCheckInterval = 30 * time.Second
 	}
 
+	// Order claims: each replica claims due orders under a lease so several triggers can run at once
+	instanceID := defaultInstanceID()
+	cfg.Trigger.InstanceID = getEnv("TRIGGER_INSTANCE_ID", instanceID)
+	cfg.Trigger.ClaimLease, err = time.ParseDuration(getEnv("TRIGGER_CLAIM_LEASE", "30s"))
+	if err != nil || cfg.Trigger.ClaimLease <= 0 {
+		cfg.Trigger.ClaimLease = 30 * time.Second
+	}
+	cfg.Trigger.SweepInterval, err = time.ParseDuration(getEnv("TRIGGER_SWEEP_INTERVAL", "15s"))
+	if err != nil || cfg.Trigger.SweepInterval <= 0 {
+		cfg.Trigger.SweepInterval = 15 * time.Second
+	}
+
+	// Order expiry windows and late-order policy
+	cfg.Trigger.Expiry.Window, err = time.ParseDuration(getEnv("ORDER_EXPIRY_WINDOW", "10s"))
+	if err != nil || cfg.Trigger.Expiry.Window <= 0 {
+		cfg.Trigger.Expiry.Window = 10 * time.Second
+	}
+	cfg.Trigger.Expiry.BuyWindow, _ = time.ParseDuration(getEnv("ORDER_EXPIRY_WINDOW_BUY", "0s"))
+	cfg.Trigger.Expiry.SellWindow, _ = time.ParseDuration(getEnv("ORDER_EXPIRY_WINDOW_SELL", "0s"))
+	cfg.Trigger.Expiry.ConditionalWindow, _ = time.ParseDuration(getEnv("ORDER_EXPIRY_WINDOW_CONDITIONAL", "6h"))
+	cfg.Trigger.Expiry.LatePolicy = strings.ToLower(getEnv("LATE_ORDER_POLICY", LatePolicySkip))
+	switch cfg.Trigger.Expiry.LatePolicy {
+	case LatePolicySkip, LatePolicyExecute, LatePolicyAMO:
+	default:
+		return nil, fmt.Errorf("invalid LATE_ORDER_POLICY %q (supported: skip, execute, amo)", cfg.Trigger.Expiry.LatePolicy)
+	}
+	cfg.Trigger.Expiry.LateGrace, err = time.ParseDuration(getEnv("LATE_ORDER_GRACE", "5m"))
+	if err != nil {
+		cfg.Trigger.Expiry.LateGrace = 5 * time.Minute
+	}
+
+	// Quote polling for conditional orders
+	cfg.Trigger.Quotes.PollInterval, err = time.ParseDuration(getEnv("QUOTE_POLL_INTERVAL", "1s"))
+	if err != nil || cfg.Trigger.Quotes.PollInterval <= 0 {
+		cfg.Trigger.Quotes.PollInterval = time.Second
+	}
+	cfg.Trigger.Quotes.MaxAge, err = time.ParseDuration(getEnv("QUOTE_MAX_AGE", "10s"))
+	if err != nil || cfg.Trigger.Quotes.MaxAge <= 0 {
+		cfg.Trigger.Quotes.MaxAge = 10 * time.Second
+	}
+
+	// Metrics config (trigger and reader run as separate processes, so they need separate ports)
+	cfg.Metrics.Enabled, _ = strconv.ParseBool(getEnv("METRICS_ENABLED", "true"))
+	cfg.Metrics.TriggerAddr = getEnv("METRICS_TRIGGER_ADDR", "127.0.0.1:9101")
+	cfg.Metrics.ReaderAddr = getEnv("METRICS_READER_ADDR", "127.0.0.1:9102")
+
+	// Tracing config
+	cfg.Tracing.Exporter = getEnv("TRACING_EXPORTER", "none")
+	cfg.Tracing.OTLPEndpoint = getEnv("TRACING_OTLP_ENDPOINT", "localhost:4318")
+	cfg.Tracing.OTLPInsecure, _ = strconv.ParseBool(getEnv("TRACING_OTLP_INSECURE", "true"))
+	cfg.Tracing.FilePath = getEnv("TRACING_FILE_PATH", "./logs/traces.jsonl")
+	cfg.Tracing.SampleRatio, err = strconv.ParseFloat(getEnv("TRACING_SAMPLE_RATIO", "1.0"), 64)
+	if err != nil || cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		cfg.Tracing.SampleRatio = 1.0
+	}
+
+	// Admin API config
+	cfg.Admin.Enabled, _ = strconv.ParseBool(getEnv("ADMIN_ENABLED", "true"))
+	cfg.Admin.Addr = getEnv("ADMIN_ADDR", "127.0.0.1:8081")
+	cfg.Admin.Token = getEnv("ADMIN_TOKEN", "")
+
+	// Kill switch config
+	cfg.KillSwitch.MaxConsecutiveFailures, _ = strconv.Atoi(getEnv("KILL_SWITCH_MAX_CONSECUTIVE_FAILURES", "5"))
+	cfg.KillSwitch.DailyLossLimit, _ = strconv.ParseFloat(getEnv("KILL_SWITCH_DAILY_LOSS_LIMIT", "0"), 64)
+
+	// Journal config
+	cfg.Journal.Path = getEnv("JOURNAL_PATH", "./logs/journal.jsonl")
+
+	// Leader election config (only the leader reads sheets or dispatches orders)
+	cfg.Leader.Enabled, _ = strconv.ParseBool(getEnv("LEADER_ELECTION_ENABLED", "false"))
+	cfg.Leader.InstanceID = getEnv("LEADER_INSTANCE_ID", instanceID)
+	cfg.Leader.Lease, err = time.ParseDuration(getEnv("LEADER_LEASE", "10s"))
+	if err != nil || cfg.Leader.Lease <= 0 {
+		cfg.Leader.Lease = 10 * time.Second
+	}
+	cfg.Leader.RenewInterval, err = time.ParseDuration(getEnv("LEADER_RENEW_INTERVAL", "3s"))
+	if err != nil || cfg.Leader.RenewInterval <= 0 || cfg.Leader.RenewInterval >= cfg.Leader.Lease {
+		cfg.Leader.RenewInterval = cfg.Leader.Lease / 3
+	}
+
+	// Notification config
+	cfg.Notify.WebhookURL = getEnv("NOTIFY_WEBHOOK_URL", "")
+	cfg.Notify.SlackWebhookURL = getEnv("NOTIFY_SLACK_WEBHOOK_URL", "")
+	cfg.Notify.TelegramBotToken = getEnv("NOTIFY_TELEGRAM_BOT_TOKEN", "")
+	cfg.Notify.TelegramChatID = getEnv("NOTIFY_TELEGRAM_CHAT_ID", "")
+	cfg.Notify.TelegramAPIURL = getEnv("NOTIFY_TELEGRAM_API_URL", "https://api.telegram.org")
+	cfg.Notify.SMTPAddr = getEnv("NOTIFY_SMTP_ADDR", "")
+	cfg.Notify.SMTPUsername = getEnv("NOTIFY_SMTP_USERNAME", "")
+	cfg.Notify.SMTPPassword = getEnv("NOTIFY_SMTP_PASSWORD", "")
+	cfg.Notify.SMTPFrom = getEnv("NOTIFY_SMTP_FROM", "")
+	cfg.Notify.SMTPTo = splitList(getEnv("NOTIFY_SMTP_TO", ""))
+	cfg.Notify.Rules, err = parseNotifyRules(getEnv("NOTIFY_RULES", defaultNotifyRules))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Notify.Throttle, err = time.ParseDuration(getEnv("NOTIFY_THROTTLE", "1m"))
+	if err != nil || cfg.Notify.Throttle < 0 {
+		cfg.Notify.Throttle = 1 * time.Minute
+	}
+	cfg.Notify.DedupWindow, err = time.ParseDuration(getEnv("NOTIFY_DEDUP_WINDOW", "15m"))
+	if err != nil || cfg.Notify.DedupWindow < 0 {
+		cfg.Notify.DedupWindow = 15 * time.Minute
+	}
+	cfg.Notify.DailySummaryAt = getEnv("NOTIFY_DAILY_SUMMARY_AT", "15:45")
+	if cfg.Notify.DailySummaryAt != "" {
+		if _, err := time.Parse("15:04", cfg.Notify.DailySummaryAt); err != nil {
+			return nil, fmt.Errorf("invalid NOTIFY_DAILY_SUMMARY_AT %q (expected HH:MM)", cfg.Notify.DailySummaryAt)
+		}
+	}
+	cfg.Notify.Timeout, err = time.ParseDuration(getEnv("NOTIFY_TIMEOUT", "10s"))
+	if err != nil || cfg.Notify.Timeout <= 0 {
+		cfg.Notify.Timeout = 10 * time.Second
+	}
+
 	// Load broker config from file if path is provided
 	if cfg.Broker.ConfigPath != "" {
 		if err := cfg.loadBrokerConfigFromFile(); err != nil {
@@ -131,6 +563,27 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 	
+	// Instrument master config (defaults on for Kite, whose dump it downloads)
+	cfg.Instruments.Enabled, _ = strconv.ParseBool(getEnv("INSTRUMENTS_ENABLED", strconv.FormatBool(cfg.Broker.Type == "kite")))
+	cfg.Instruments.URL = getEnv("INSTRUMENTS_URL", "")
+	cfg.Instruments.CacheDir = getEnv("INSTRUMENTS_CACHE_DIR", "./data/instruments")
+
+	// Order sizing config
+	cfg.Sizing.EstimateCharges, _ = strconv.ParseBool(getEnv("SIZING_ESTIMATE_CHARGES", "true"))
+
+	// GTT config
+	cfg.GTT.SyncInterval, err = time.ParseDuration(getEnv("GTT_SYNC_INTERVAL", "1m"))
+	if err != nil || cfg.GTT.SyncInterval <= 0 {
+		cfg.GTT.SyncInterval = time.Minute
+	}
+	cfg.GTT.StatusColumn = strings.ToUpper(getEnv("GTT_STATUS_COLUMN", "T"))
+	if cfg.GTT.StatusColumn == "OFF" {
+		cfg.GTT.StatusColumn = ""
+	}
+	if cfg.GTT.StatusColumn != "" && strings.Trim(cfg.GTT.StatusColumn, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return nil, fmt.Errorf("invalid GTT_STATUS_COLUMN %q (expected a column letter such as T, or off)", cfg.GTT.StatusColumn)
+	}
+	
 	// Debug: Log broker type after loading
 	// Note: We can't use logger here as it's not created yet, but config is loaded correctly
 
@@ -150,7 +603,9 @@ func (c *Config) loadBrokerConfigFromFile() error {
 		APISecret    string          `json:"api_secret"`
 		RefreshToken string          `json:"refresh_token"`
 		BaseURL      string          `json:"base_url"`
+		APIURL       string          `json:"api_url"`
 		RateLimit    RateLimitConfig `json:"rate_limit"`
+		Paper        json.RawMessage `json:"paper"`
 	}
 
 	if err := json.Unmarshal(data, &fileConfig); err != nil {
@@ -173,13 +628,59 @@ func (c *Config) loadBrokerConfigFromFile() error {
 	if fileConfig.BaseURL != "" {
 		c.Broker.BaseURL = fileConfig.BaseURL
 	}
+	if fileConfig.APIURL != "" {
+		c.Broker.APIURL = fileConfig.APIURL
+	}
 	if fileConfig.RateLimit.RequestsPerSecond > 0 {
 		c.Broker.RateLimit = fileConfig.RateLimit
 	}
+	if len(fileConfig.Paper) > 0 {
+		// Overlay onto the env/default values so the file only needs the fields it changes
+		if err := json.Unmarshal(fileConfig.Paper, &c.Broker.Paper); err != nil {
+			return fmt.Errorf("invalid paper broker config: %w", err)
+		}
+	}
 
 	return nil
 }
 
+// splitList splits a comma-separated list, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseNotifyRules parses "event=sink,sink;event=*" into a map of event type to sink names
+func parseNotifyRules(value string) (map[string][]string, error) {
+	rules := make(map[string][]string)
+	for _, rule := range strings.Split(value, ";") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+		event, sinks, ok := strings.Cut(rule, "=")
+		event = strings.TrimSpace(event)
+		if !ok || event == "" {
+			return nil, fmt.Errorf("invalid NOTIFY_RULES entry %q (expected event=sink,sink)", rule)
+		}
+		rules[event] = splitList(sinks)
+	}
+	return rules, nil
+}
+
+// defaultInstanceID identifies a trigger replica by host name and process ID
+func defaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "trigger"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -0,0 +1,115 @@
+// Package derivatives holds the rules for futures and options orders: which products they
+// may use, which exchange they trade on and when an expiring contract may still be traded.
+package derivatives
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Order products
+const (
+	ProductCNC  = "CNC"  // Delivery; cash equities only
+	ProductMIS  = "MIS"  // Intraday, squared off by the broker before the close
+	ProductNRML = "NRML" // Overnight futures and options positions
+)
+
+// Errors returned by the checks below
+var (
+	ErrInvalidProduct  = errors.New("product not allowed for instrument")
+	ErrContractExpired = errors.New("contract has expired")
+	ErrExpiryDayCutoff = errors.New("contract expires today and the expiry-day cutoff has passed")
+)
+
+// expiryCloseHour and expiryCloseMinute are when trading in an expiring contract ends, IST
+const (
+	expiryCloseHour   = 15
+	expiryCloseMinute = 30
+)
+
+// IsDerivative reports whether an instrument type is a future or an option
+func IsDerivative(instrumentType string) bool {
+	switch strings.ToUpper(instrumentType) {
+	case "FUT", "CE", "PE":
+		return true
+	}
+	return false
+}
+
+// DefaultProduct returns the product used when the order source gives none: CNC for
+// cash equities and NRML for futures and options
+func DefaultProduct(instrumentType string) string {
+	if IsDerivative(instrumentType) {
+		return ProductNRML
+	}
+	return ProductCNC
+}
+
+// CheckProduct checks a product is allowed for an instrument type: CNC for cash equities,
+// NRML for futures and options and MIS for both
+func CheckProduct(product, instrumentType string) error {
+	product = strings.ToUpper(product)
+	switch {
+	case product == ProductMIS:
+		return nil
+	case product == ProductCNC && !IsDerivative(instrumentType):
+		return nil
+	case product == ProductNRML && IsDerivative(instrumentType):
+		return nil
+	}
+	kind := "cash equities"
+	if IsDerivative(instrumentType) {
+		kind = "futures and options"
+	}
+	return fmt.Errorf("%w: %s cannot be used for %s", ErrInvalidProduct, product, kind)
+}
+
+// Segment returns the exchange an underlying's derivatives trade on: NFO for NSE and BFO
+// for BSE. Derivatives exchanges are returned unchanged.
+func Segment(exchange string) string {
+	switch exchange = strings.ToUpper(exchange); exchange {
+	case "NSE":
+		return "NFO"
+	case "BSE":
+		return "BFO"
+	}
+	return exchange
+}
+
+// ExpiryClose returns when trading in a contract expiring on expiry ends: the close of its
+// expiry date, IST
+func ExpiryClose(expiry time.Time) time.Time {
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		ist = time.UTC
+	}
+	return time.Date(expiry.Year(), expiry.Month(), expiry.Day(), expiryCloseHour, expiryCloseMinute, 0, 0, ist)
+}
+
+// CheckExpiry applies the expiry-day rules to an order placed at at in a contract expiring
+// on expiry: nothing is placed once the contract has expired, and nothing is placed on its
+// expiry day after cutoff (HH:MM IST; empty for no cutoff). A zero expiry is not checked.
+func CheckExpiry(expiry, at time.Time, cutoff string) error {
+	if expiry.IsZero() {
+		return nil
+	}
+	closeAt := ExpiryClose(expiry)
+	at = at.In(closeAt.Location())
+	if !at.Before(closeAt) {
+		return fmt.Errorf("%w: expired %s", ErrContractExpired, closeAt.Format("2006-01-02 15:04 IST"))
+	}
+	if cutoff == "" || at.Year() != closeAt.Year() || at.YearDay() != closeAt.YearDay() {
+		return nil
+	}
+	cutoffTime, err := time.Parse("15:04", cutoff)
+	if err != nil {
+		return fmt.Errorf("invalid expiry-day cutoff %q: %w", cutoff, err)
+	}
+	cutoffAt := time.Date(at.Year(), at.Month(), at.Day(), cutoffTime.Hour(), cutoffTime.Minute(), 0, 0, closeAt.Location())
+	if !at.Before(cutoffAt) {
+		return fmt.Errorf("%w (%s IST)", ErrExpiryDayCutoff, cutoff)
+	}
+	return nil
+}
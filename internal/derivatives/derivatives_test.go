@@ -0,0 +1,57 @@
+package derivatives
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckProduct(t *testing.T) {
+	tests := []struct {
+		product, instrumentType string
+		ok                      bool
+	}{
+		{"CNC", "EQ", true},
+		{"MIS", "EQ", true},
+		{"NRML", "EQ", false},
+		{"CNC", "FUT", false},
+		{"nrml", "CE", true},
+		{"MIS", "PE", true},
+	}
+	for _, tt := range tests {
+		err := CheckProduct(tt.product, tt.instrumentType)
+		if (err == nil) != tt.ok {
+			t.Errorf("CheckProduct(%s, %s) = %v, want ok=%v", tt.product, tt.instrumentType, err, tt.ok)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidProduct) {
+			t.Errorf("CheckProduct(%s, %s) error = %v, want ErrInvalidProduct", tt.product, tt.instrumentType, err)
+		}
+	}
+}
+
+func TestCheckExpiry(t *testing.T) {
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatalf("failed to load IST: %v", err)
+	}
+	expiry := time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		at   time.Time
+		want error
+	}{
+		{"day before", time.Date(2024, 1, 24, 15, 10, 0, 0, ist), nil},
+		{"expiry morning", time.Date(2024, 1, 25, 9, 15, 0, 0, ist), nil},
+		{"after cutoff", time.Date(2024, 1, 25, 15, 0, 0, 0, ist), ErrExpiryDayCutoff},
+		{"at close", time.Date(2024, 1, 25, 15, 30, 0, 0, ist), ErrContractExpired},
+		{"day after", time.Date(2024, 1, 26, 9, 15, 0, 0, ist), ErrContractExpired},
+	}
+	for _, tt := range tests {
+		if err := CheckExpiry(expiry, tt.at, "15:00"); !errors.Is(err, tt.want) {
+			t.Errorf("%s: CheckExpiry() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	if err := CheckExpiry(time.Time{}, time.Date(2030, 1, 1, 0, 0, 0, 0, ist), "15:00"); err != nil {
+		t.Errorf("CheckExpiry(no expiry) = %v, want nil", err)
+	}
+}
@@ -0,0 +1,238 @@
+// Package instruments maintains the Kite instrument master: the daily dump of every
+// tradable instrument with its tick size and lot size, cached on disk and in Redis.
+package instruments
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Instrument is one row of the Kite instruments dump
+type Instrument struct {
+	Token          int64     `json:"instrument_token"`
+	ExchangeToken  string    `json:"exchange_token"` // Scrip code for BSE instruments
+	Tradingsymbol  string    `json:"tradingsymbol"`
+	Name           string    `json:"name"`
+	LastPrice      float64   `json:"last_price"` // Previous close when the dump was generated
+	Expiry         time.Time `json:"expiry"`     // Zero for cash instruments
+	Strike         float64   `json:"strike"`
+	TickSize       float64   `json:"tick_size"`
+	LotSize        int       `json:"lot_size"`
+	InstrumentType string    `json:"instrument_type"` // EQ, FUT, CE or PE
+	Segment        string    `json:"segment"`
+	Exchange       string    `json:"exchange"`
+}
+
+// columns lists the dump's columns; Parse locates them by header so their order may change
+var columns = []string{
+	"instrument_token", "exchange_token", "tradingsymbol", "name", "last_price", "expiry",
+	"strike", "tick_size", "lot_size", "instrument_type", "segment", "exchange",
+}
+
+// Parse reads an instruments CSV dump
+func Parse(r io.Reader) ([]Instrument, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("instruments dump is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read instruments header: %w", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.TrimSpace(name)] = i
+	}
+	for _, name := range columns {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("instruments dump has no %s column", name)
+		}
+	}
+
+	var instruments []Instrument
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read instruments line %d: %w", line, err)
+		}
+		field := func(name string) string {
+			if i := index[name]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		instrument := Instrument{
+			ExchangeToken:  field("exchange_token"),
+			Tradingsymbol:  strings.ToUpper(field("tradingsymbol")),
+			Name:           field("name"),
+			InstrumentType: strings.ToUpper(field("instrument_type")),
+			Segment:        strings.ToUpper(field("segment")),
+			Exchange:       strings.ToUpper(field("exchange")),
+		}
+		if instrument.Tradingsymbol == "" || instrument.Exchange == "" {
+			continue
+		}
+		instrument.Token, _ = strconv.ParseInt(field("instrument_token"), 10, 64)
+		instrument.LastPrice, _ = strconv.ParseFloat(field("last_price"), 64)
+		instrument.Strike, _ = strconv.ParseFloat(field("strike"), 64)
+		instrument.TickSize, _ = strconv.ParseFloat(field("tick_size"), 64)
+		instrument.LotSize, _ = strconv.Atoi(field("lot_size"))
+		if expiry := field("expiry"); expiry != "" {
+			if instrument.Expiry, err = time.Parse("2006-01-02", expiry); err != nil {
+				return nil, fmt.Errorf("invalid expiry %q on instruments line %d", expiry, line)
+			}
+		}
+		instruments = append(instruments, instrument)
+	}
+	return instruments, nil
+}
+
+// Master indexes one day's instruments by tradingsymbol, BSE scrip code and name
+type Master struct {
+	day         string
+	instruments []Instrument
+	bySymbol    map[string]int   // EXCHANGE:TRADINGSYMBOL
+	byBSECode   map[string]int   // BSE exchange token
+	byName      map[string]int   // EXCHANGE:NAME of cash equities; -1 when several share a name
+	contracts   map[string][]int // EXCHANGE:UNDERLYING of futures and options
+}
+
+// NewMaster indexes instruments published for day (YYYY-MM-DD)
+func NewMaster(day string, instruments []Instrument) *Master {
+	m := &Master{
+		day:         day,
+		instruments: instruments,
+		bySymbol:    make(map[string]int, len(instruments)),
+		byBSECode:   make(map[string]int),
+		byName:      make(map[string]int),
+		contracts:   make(map[string][]int),
+	}
+	for i, instrument := range instruments {
+		m.bySymbol[key(instrument.Exchange, instrument.Tradingsymbol)] = i
+		if instrument.Exchange == "BSE" && instrument.ExchangeToken != "" {
+			m.byBSECode[instrument.ExchangeToken] = i
+		}
+		if instrument.InstrumentType == "EQ" && instrument.Name != "" {
+			nameKey := key(instrument.Exchange, instrument.Name)
+			if _, seen := m.byName[nameKey]; seen {
+				m.byName[nameKey] = -1
+			} else {
+				m.byName[nameKey] = i
+			}
+		}
+		switch instrument.InstrumentType {
+		case "FUT", "CE", "PE":
+			contractKey := key(instrument.Exchange, instrument.Name)
+			m.contracts[contractKey] = append(m.contracts[contractKey], i)
+		}
+	}
+	return m
+}
+
+// Day returns the date the instruments were published for
+func (m *Master) Day() string {
+	return m.day
+}
+
+// Len returns the number of instruments
+func (m *Master) Len() int {
+	return len(m.instruments)
+}
+
+// Lookup finds an instrument by exchange and tradingsymbol
+func (m *Master) Lookup(exchange, symbol string) (Instrument, bool) {
+	return m.get(m.bySymbol, key(exchange, symbol))
+}
+
+// LookupBSECode finds a BSE instrument by its scrip code
+func (m *Master) LookupBSECode(code string) (Instrument, bool) {
+	return m.get(m.byBSECode, strings.TrimSpace(code))
+}
+
+// LookupName finds a cash equity by exchange and name; names shared by several
+// instruments are not matched
+func (m *Master) LookupName(exchange, name string) (Instrument, bool) {
+	return m.get(m.byName, key(exchange, name))
+}
+
+// Resolve finds the instrument an order source row refers to: by tradingsymbol, then by BSE
+// scrip code, then by name. A scrip code match on a row for another exchange is mapped to
+// that exchange by tradingsymbol.
+func (m *Master) Resolve(exchange, symbol, bseCode, name string) (Instrument, bool) {
+	if instrument, ok := m.Lookup(exchange, symbol); ok {
+		return instrument, true
+	}
+	if bseCode != "" {
+		if instrument, ok := m.LookupBSECode(bseCode); ok {
+			if strings.EqualFold(exchange, "BSE") {
+				return instrument, true
+			}
+			if listed, ok := m.Lookup(exchange, instrument.Tradingsymbol); ok {
+				return listed, true
+			}
+		}
+	}
+	if name != "" {
+		return m.LookupName(exchange, name)
+	}
+	return Instrument{}, false
+}
+
+// Contract finds a future or option on an underlying by type (FUT, CE or PE), strike
+// (options only) and expiry. A zero expiry picks the nearest contract that has not expired
+// by from's date.
+func (m *Master) Contract(exchange, underlying, instrumentType string, strike float64, expiry, from time.Time) (Instrument, bool) {
+	instrumentType = strings.ToUpper(strings.TrimSpace(instrumentType))
+	fromDate := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	best := -1
+	for _, i := range m.contracts[key(exchange, underlying)] {
+		instrument := m.instruments[i]
+		if instrument.InstrumentType != instrumentType {
+			continue
+		}
+		if instrumentType != "FUT" && math.Abs(instrument.Strike-strike) > 1e-6 {
+			continue
+		}
+		if !expiry.IsZero() {
+			if instrument.Expiry.Equal(time.Date(expiry.Year(), expiry.Month(), expiry.Day(), 0, 0, 0, 0, time.UTC)) {
+				return instrument, true
+			}
+			continue
+		}
+		if instrument.Expiry.Before(fromDate) {
+			continue
+		}
+		if best < 0 || instrument.Expiry.Before(m.instruments[best].Expiry) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return Instrument{}, false
+	}
+	return m.instruments[best], true
+}
+
+// get returns the instrument at index[k]
+func (m *Master) get(index map[string]int, k string) (Instrument, bool) {
+	i, ok := index[k]
+	if !ok || i < 0 {
+		return Instrument{}, false
+	}
+	return m.instruments[i], true
+}
+
+// key builds an EXCHANGE:VALUE index key
+func key(exchange, value string) string {
+	return strings.ToUpper(strings.TrimSpace(exchange)) + ":" + strings.ToUpper(strings.TrimSpace(value))
+}
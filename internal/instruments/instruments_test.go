@@ -0,0 +1,181 @@
+package instruments
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/mach_five/trading-system/internal/broker/kitetest"
+	"github.com/mach_five/trading-system/internal/cache"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+)
+
+const dump = `instrument_token,exchange_token,tradingsymbol,name,last_price,expiry,strike,tick_size,lot_size,instrument_type,segment,exchange
+408065,1594,INFY,INFOSYS,0,,0,0.05,1,EQ,NSE,NSE
+128053508,500209,INFY,INFOSYS,0,,0,0.05,1,EQ,BSE,BSE
+128083204,500325,RELIANCE,RELIANCE INDUSTRIES,0,,0,0.05,1,EQ,BSE,BSE
+738561,2885,RELIANCE,RELIANCE INDUSTRIES,0,,0,0.05,1,EQ,NSE,NSE
+12345,500,SAMENAME,TWIN,0,,0,0.01,1,EQ,NSE,NSE
+12346,501,SAMENAME2,TWIN,0,,0,0.01,1,EQ,NSE,NSE
+13238786,51714,NIFTY24JANFUT,NIFTY,0,2024-01-25,0,0.05,50,FUT,NFO-FUT,NFO
+13317890,52024,NIFTY24FEBFUT,NIFTY,0,2024-02-29,0,0.05,50,FUT,NFO-FUT,NFO
+10560258,41251,NIFTY24JAN21500CE,NIFTY,0,2024-01-25,21500,0.05,50,CE,NFO-OPT,NFO
+10560514,41252,NIFTY24JAN21500PE,NIFTY,0,2024-01-25,21500,0.05,50,PE,NFO-OPT,NFO
+`
+
+func TestParseAndResolve(t *testing.T) {
+	parsed, err := Parse(strings.NewReader(dump))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	master := NewMaster("2024-01-15", parsed)
+	if master.Len() != 10 {
+		t.Fatalf("Len = %d, want 10", master.Len())
+	}
+
+	future, ok := master.Lookup("nfo", "nifty24janfut")
+	if !ok || future.LotSize != 50 || future.Expiry.Format("2006-01-02") != "2024-01-25" {
+		t.Errorf("Lookup(NFO, NIFTY24JANFUT) = %+v, %v", future, ok)
+	}
+
+	tests := []struct {
+		exchange, symbol, bseCode, name string
+		want                            string // EXCHANGE:SYMBOL, empty if unresolved
+	}{
+		{"NSE", "INFY", "", "", "NSE:INFY"},
+		{"NSE", "RELIANCE-EQ", "500325", "", "NSE:RELIANCE"},      // BSE code mapped to the NSE listing
+		{"BSE", "RIL", "500325", "", "BSE:RELIANCE"},              // BSE code
+		{"NSE", "RIL", "", "reliance industries", "NSE:RELIANCE"}, // Name
+		{"NSE", "TWIN", "", "TWIN", ""},                           // Ambiguous name
+		{"NSE", "UNKNOWN", "999999", "NOBODY", ""},
+	}
+	for _, tt := range tests {
+		instrument, ok := master.Resolve(tt.exchange, tt.symbol, tt.bseCode, tt.name)
+		got := ""
+		if ok {
+			got = instrument.Exchange + ":" + instrument.Tradingsymbol
+		}
+		if got != tt.want {
+			t.Errorf("Resolve(%s, %s, %s, %s) = %q, want %q", tt.exchange, tt.symbol, tt.bseCode, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestContract(t *testing.T) {
+	parsed, err := Parse(strings.NewReader(dump))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	master := NewMaster("2024-01-15", parsed)
+	date := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+
+	tests := []struct {
+		name           string
+		instrumentType string
+		strike         float64
+		expiry, from   time.Time
+		want           string
+	}{
+		{"nearest future", "FUT", 0, time.Time{}, date("2024-01-15"), "NIFTY24JANFUT"},
+		{"nearest future on expiry day", "FUT", 0, time.Time{}, date("2024-01-25"), "NIFTY24JANFUT"},
+		{"rolls to next month", "FUT", 0, time.Time{}, date("2024-01-26"), "NIFTY24FEBFUT"},
+		{"future by expiry", "fut", 0, date("2024-02-29"), date("2024-01-15"), "NIFTY24FEBFUT"},
+		{"call by strike", "CE", 21500, time.Time{}, date("2024-01-15"), "NIFTY24JAN21500CE"},
+		{"unlisted strike", "PE", 21550, time.Time{}, date("2024-01-15"), ""},
+		{"unlisted expiry", "FUT", 0, date("2024-03-28"), date("2024-01-15"), ""},
+	}
+	for _, tt := range tests {
+		instrument, ok := master.Contract("NFO", "nifty", tt.instrumentType, tt.strike, tt.expiry, tt.from)
+		if got := instrument.Tradingsymbol; ok != (tt.want != "") || got != tt.want {
+			t.Errorf("%s: Contract() = %q, %v; want %q", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+func TestDayRollsOverAtPublishTime(t *testing.T) {
+	ist, _ := time.LoadLocation("Asia/Kolkata")
+	if got := Day(time.Date(2024, 1, 15, 8, 29, 0, 0, ist)); got != "2024-01-14" {
+		t.Errorf("Day before publish = %s, want 2024-01-14", got)
+	}
+	if got := Day(time.Date(2024, 1, 15, 8, 30, 0, 0, ist)); got != "2024-01-15" {
+		t.Errorf("Day at publish = %s, want 2024-01-15", got)
+	}
+}
+
+// newTestStore returns a store downloading from a Kite stand-in that lists INFY, with its
+// own cache directory and a Redis cache
+func newTestStore(t *testing.T, server *kitetest.Server, redisCache *cache.RedisCache, now time.Time) (*Store, string) {
+	t.Helper()
+	log, err := logger.NewLogger("error", filepath.Join(t.TempDir(), "instruments.log"))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	cfg := &config.Config{}
+	server.Configure(cfg)
+	cfg.Instruments = config.InstrumentsConfig{Enabled: true, CacheDir: t.TempDir()}
+	store := NewStore(cfg, redisCache, log)
+	store.SetClock(clock.NewSimulated(now))
+	return store, cfg.Instruments.CacheDir
+}
+
+func TestStoreDownloadsOncePerDay(t *testing.T) {
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+	server.AddInstrument(kitetest.Instrument{Token: 408065, ExchangeToken: "1594", Tradingsymbol: "INFY", Name: "INFOSYS",
+		TickSize: 0.05, LotSize: 1, InstrumentType: "EQ", Segment: "NSE", Exchange: "NSE"})
+
+	redisCache, err := cache.NewRedisCache(miniredis.RunT(t).Addr(), "", 0)
+	if err != nil {
+		t.Fatalf("failed to connect to miniredis: %v", err)
+	}
+	t.Cleanup(func() { redisCache.Close() })
+
+	ist, _ := time.LoadLocation("Asia/Kolkata")
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, ist)
+	ctx := context.Background()
+
+	store, dir := newTestStore(t, server, redisCache, now)
+	master, err := store.Master(ctx)
+	if err != nil {
+		t.Fatalf("Master: %v", err)
+	}
+	if _, ok := master.Lookup("NSE", "INFY"); !ok || master.Day() != "2024-01-15" {
+		t.Fatalf("master for %s does not list NSE:INFY", master.Day())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "instruments-2024-01-15.csv.gz")); err != nil {
+		t.Errorf("dump not written to the cache directory: %v", err)
+	}
+	if _, err := store.Master(ctx); err != nil || server.Requests(kitetest.PathInstruments) != 1 {
+		t.Errorf("second Master call: %v, %d downloads; want 1", err, server.Requests(kitetest.PathInstruments))
+	}
+
+	// Another process the same day loads the dump from Redis instead of downloading it
+	other, _ := newTestStore(t, server, redisCache, now)
+	if _, err := other.Master(ctx); err != nil || server.Requests(kitetest.PathInstruments) != 1 {
+		t.Errorf("Master from Redis: %v, %d downloads; want 1", err, server.Requests(kitetest.PathInstruments))
+	}
+
+	// The next day's download fails, so yesterday's dump stays in use
+	store.SetClock(clock.NewSimulated(now.Add(24 * time.Hour)))
+	server.FailNext(kitetest.PathInstruments, kitetest.Failure{Status: 503, ErrorType: "NetworkException", Message: "down"})
+	master, err = store.Master(ctx)
+	if err != nil || master.Day() != "2024-01-15" {
+		t.Errorf("Master after failed download = %v, %v; want the 2024-01-15 dump", master, err)
+	}
+	master, err = store.Master(ctx)
+	if err != nil || master.Day() != "2024-01-16" {
+		t.Errorf("Master after retry = %v, %v; want the 2024-01-16 dump", master, err)
+	}
+}
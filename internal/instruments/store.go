@@ -0,0 +1,261 @@
+package instruments
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/cache"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/metrics"
+)
+
+// DefaultURL is Kite's instruments dump
+const DefaultURL = "https://api.kite.trade/instruments"
+
+// PublishTime is when, after midnight IST, Kite publishes the day's instruments dump.
+// Before it the previous day's dump is still current.
+const PublishTime = 8*time.Hour + 30*time.Minute
+
+// filePrefix and fileSuffix name the gzipped dumps kept in the cache directory
+const (
+	filePrefix = "instruments-"
+	fileSuffix = ".csv.gz"
+)
+
+// Day returns the date (YYYY-MM-DD) of the instruments dump current at now
+func Day(now time.Time) string {
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		ist = time.UTC
+	}
+	return now.In(ist).Add(-PublishTime).Format("2006-01-02")
+}
+
+// Store loads the instrument master once per day. It reads the day's dump from the cache
+// directory, then Redis, and downloads it from Kite only when neither has it; a downloaded
+// dump is written to both. When the download fails the newest dump available is used.
+type Store struct {
+	cache      *cache.RedisCache // Nil: no Redis copy
+	logger     *logger.Logger
+	clock      clock.Clock
+	httpClient *http.Client
+	url        string
+	dir        string
+	apiKey     string
+	token      string
+
+	mu     sync.Mutex
+	master *Master
+}
+
+// NewStore creates an instrument store; cache may be nil
+func NewStore(cfg *config.Config, cache *cache.RedisCache, log *logger.Logger) *Store {
+	url := cfg.Instruments.URL
+	if url == "" {
+		url = DefaultURL
+		if apiURL := strings.TrimRight(cfg.Broker.APIURL, "/"); apiURL != "" {
+			url = apiURL + "/instruments"
+		}
+	}
+	return &Store{
+		cache:      cache,
+		logger:     log,
+		clock:      clock.Real{},
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+		url:        url,
+		dir:        cfg.Instruments.CacheDir,
+		apiKey:     cfg.Broker.APIKey,
+		token:      cfg.Broker.APISecret, // Kite access token
+	}
+}
+
+// SetClock replaces the clock used to decide which day's dump is current
+func (s *Store) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// Master returns the current day's instrument master, loading it if the day has changed
+func (s *Store) Master(ctx context.Context) (*Master, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := Day(s.clock.Now())
+	if s.master != nil && s.master.Day() == day {
+		return s.master, nil
+	}
+
+	master, err := s.load(ctx, day)
+	if err != nil {
+		if s.master == nil {
+			if s.master = s.loadNewest(ctx); s.master == nil {
+				return nil, err
+			}
+			metrics.InstrumentsLoaded.Set(float64(s.master.Len()))
+		}
+		s.logger.Warn("⚠️  Could not load instruments for %s, using those from %s: %v", day, s.master.Day(), err)
+		return s.master, nil
+	}
+	s.master = master
+	metrics.InstrumentsLoaded.Set(float64(master.Len()))
+	return master, nil
+}
+
+// load reads day's dump from the cache directory or Redis, downloading it if neither has it
+func (s *Store) load(ctx context.Context, day string) (*Master, error) {
+	if s.dir != "" {
+		if data, err := os.ReadFile(s.path(day)); err == nil {
+			master, err := decode(day, data)
+			if err == nil {
+				s.logger.Debug("📂 Loaded %d instruments for %s from %s", master.Len(), day, s.path(day))
+				return master, nil
+			}
+			s.logger.Warn("⚠️  Ignoring unreadable instruments file %s: %v", s.path(day), err)
+		}
+	}
+
+	if s.cache != nil {
+		if cachedDay, data, err := s.cache.LoadInstruments(ctx); err == nil && cachedDay == day {
+			if master, err := decode(day, data); err == nil {
+				s.logger.Debug("📦 Loaded %d instruments for %s from Redis", master.Len(), day)
+				s.writeFile(day, data)
+				return master, nil
+			}
+		}
+	}
+
+	data, err := s.download(ctx)
+	if err != nil {
+		return nil, err
+	}
+	master, err := decode(day, data)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("📥 Downloaded %d instruments for %s", master.Len(), day)
+	s.writeFile(day, data)
+	if s.cache != nil {
+		if err := s.cache.StoreInstruments(ctx, day, data); err != nil {
+			s.logger.Warn("⚠️  Failed to cache instruments in Redis: %v", err)
+		}
+	}
+	return master, nil
+}
+
+// loadNewest returns the newest dump in the cache directory or Redis, or nil if there is none
+func (s *Store) loadNewest(ctx context.Context) *Master {
+	if s.dir != "" {
+		files, _ := filepath.Glob(filepath.Join(s.dir, filePrefix+"*"+fileSuffix))
+		sort.Sort(sort.Reverse(sort.StringSlice(files)))
+		for _, file := range files {
+			day := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), filePrefix), fileSuffix)
+			if data, err := os.ReadFile(file); err == nil {
+				if master, err := decode(day, data); err == nil {
+					return master
+				}
+			}
+		}
+	}
+	if s.cache != nil {
+		if day, data, err := s.cache.LoadInstruments(ctx); err == nil {
+			if master, err := decode(day, data); err == nil {
+				return master
+			}
+		}
+	}
+	return nil
+}
+
+// download fetches the instruments dump and returns it gzipped
+func (s *Store) download(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create instruments request: %w", err)
+	}
+	req.Header.Set("X-Kite-Version", "3")
+	if s.apiKey != "" && s.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("token %s:%s", s.apiKey, s.token))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download instruments: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("instruments download returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var data bytes.Buffer
+	zw := gzip.NewWriter(&data)
+	if _, err := io.Copy(zw, resp.Body); err != nil {
+		return nil, fmt.Errorf("failed to download instruments: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress instruments: %w", err)
+	}
+	return data.Bytes(), nil
+}
+
+// writeFile saves day's gzipped dump to the cache directory and removes older dumps
+func (s *Store) writeFile(day string, data []byte) {
+	if s.dir == "" {
+		return
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		s.logger.Warn("⚠️  Failed to create instruments directory %s: %v", s.dir, err)
+		return
+	}
+	tmp := s.path(day) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		s.logger.Warn("⚠️  Failed to write instruments file: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, s.path(day)); err != nil {
+		s.logger.Warn("⚠️  Failed to write instruments file: %v", err)
+		return
+	}
+
+	files, _ := filepath.Glob(filepath.Join(s.dir, filePrefix+"*"+fileSuffix))
+	for _, file := range files {
+		if file != s.path(day) {
+			os.Remove(file)
+		}
+	}
+}
+
+// path returns the cache file for day's dump
+func (s *Store) path(day string) string {
+	return filepath.Join(s.dir, filePrefix+day+fileSuffix)
+}
+
+// decode parses a gzipped dump into day's master
+func decode(day string, data []byte) (*Master, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress instruments: %w", err)
+	}
+	defer zr.Close()
+	instruments, err := Parse(zr)
+	if err != nil {
+		return nil, err
+	}
+	if len(instruments) == 0 {
+		return nil, fmt.Errorf("instruments dump for %s lists no instruments", day)
+	}
+	return NewMaster(day, instruments), nil
+}
@@ -0,0 +1,105 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// Journal appends execution events to a JSON-lines file. Live and paper brokers
+// write to the same journal so their results can be compared and reported alike.
+type Journal struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+}
+
+var (
+	openMu   sync.Mutex
+	journals = map[string]*Journal{}
+)
+
+// Open returns the journal for path, creating the file if needed. Journals are shared
+// per path within a process so concurrent writers never interleave partial lines.
+func Open(path string) (*Journal, error) {
+	openMu.Lock()
+	defer openMu.Unlock()
+
+	if j, ok := journals[path]; ok {
+		return j, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+
+	j := &Journal{path: path, file: file}
+	journals[path] = j
+	return j, nil
+}
+
+// Record appends an entry to the journal. A nil journal silently discards entries.
+func (j *Journal) Record(entry models.JournalEntry) error {
+	if j == nil {
+		return nil
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal journal entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write journal entry: %w", err)
+	}
+	return nil
+}
+
+// Path returns the journal file path
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// ReadEntries returns the journal entries in path with from <= timestamp < to
+func ReadEntries(path string, from, to time.Time) ([]models.JournalEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	defer file.Close()
+
+	var entries []models.JournalEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry models.JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Skip partially written lines rather than failing the whole read
+			continue
+		}
+		if entry.Timestamp.Before(from) || !entry.Timestamp.Before(to) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	return entries, nil
+}
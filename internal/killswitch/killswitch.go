@@ -0,0 +1,218 @@
+package killswitch
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/cache"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// autoTripActor identifies halts set by the kill switch itself
+const autoTripActor = "auto-trip"
+
+// KillSwitch manages trading halts stored in Redis so every process sees the same state
+type KillSwitch struct {
+	config      *config.Config
+	cache       *cache.RedisCache
+	logger      *logger.Logger
+	clock       clock.Clock
+	istLocation *time.Location
+}
+
+// Halts is a snapshot of the active halts used to check a batch of orders
+type Halts map[string]models.Halt
+
+// NewKillSwitch creates a new kill switch backed by the Redis cache
+func NewKillSwitch(cfg *config.Config, cache *cache.RedisCache, log *logger.Logger) *KillSwitch {
+	istLocation, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		istLocation = time.UTC
+	}
+
+	return &KillSwitch{
+		config:      cfg,
+		cache:       cache,
+		logger:      log,
+		clock:       clock.Real{},
+		istLocation: istLocation,
+	}
+}
+
+// SetClock replaces the clock used for halt and audit timestamps and the P&L trading day
+func (k *KillSwitch) SetClock(c clock.Clock) {
+	k.clock = c
+}
+
+// Load returns the currently active halts
+func (k *KillSwitch) Load(ctx context.Context) (Halts, error) {
+	halts, err := k.cache.GetHalts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Halts(halts), nil
+}
+
+// Tripped reports whether the global kill switch is active
+func (h Halts) Tripped() bool {
+	_, ok := h[models.HaltScopeGlobal]
+	return ok
+}
+
+// Blocks returns the halt preventing order from executing, if any
+func (h Halts) Blocks(order models.Order) (models.Halt, bool) {
+	if halt, ok := h[models.HaltScopeGlobal]; ok {
+		return halt, true
+	}
+	symbolHalt := models.Halt{Scope: models.HaltScopeSymbol, Target: normaliseTarget(order.Symbol)}
+	if halt, ok := h[symbolHalt.Key()]; ok {
+		return halt, true
+	}
+	sideHalt := models.Halt{Scope: models.HaltScopeSide, Target: normaliseTarget(order.Side)}
+	if halt, ok := h[sideHalt.Key()]; ok {
+		return halt, true
+	}
+	return models.Halt{}, false
+}
+
+// Trip activates the global kill switch
+func (k *KillSwitch) Trip(ctx context.Context, reason, actor string) error {
+	return k.Halt(ctx, models.HaltScopeGlobal, "", reason, actor)
+}
+
+// Reset deactivates the global kill switch
+func (k *KillSwitch) Reset(ctx context.Context, actor string) error {
+	if err := k.cache.ResetConsecutiveFailures(ctx); err != nil {
+		k.logger.Warn("⚠️  Failed to reset broker failure streak: %v", err)
+	}
+	return k.Resume(ctx, models.HaltScopeGlobal, "", actor)
+}
+
+// Halt stops trading for the given scope and target (symbol or side)
+func (k *KillSwitch) Halt(ctx context.Context, scope, target, reason, actor string) error {
+	halt, err := newHalt(scope, target, reason, actor)
+	if err != nil {
+		return err
+	}
+	halt.Since = k.clock.Now()
+
+	if err := k.cache.SetHalt(ctx, halt); err != nil {
+		return err
+	}
+
+	k.logger.Warn("🛑 Trading halt set: %s (reason: %s, by: %s)", halt.Key(), reason, actor)
+	k.audit(ctx, "halt", actor, fmt.Sprintf("%s: %s", halt.Key(), reason))
+	return nil
+}
+
+// Resume lifts the halt for the given scope and target
+func (k *KillSwitch) Resume(ctx context.Context, scope, target, actor string) error {
+	halt, err := newHalt(scope, target, "", actor)
+	if err != nil {
+		return err
+	}
+
+	removed, err := k.cache.ClearHalt(ctx, halt.Key())
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("no active halt for %s", halt.Key())
+	}
+
+	k.logger.Info("▶️  Trading halt lifted: %s (by: %s)", halt.Key(), actor)
+	k.audit(ctx, "resume", actor, halt.Key())
+	return nil
+}
+
+// RecordExecution updates the broker failure streak and trips the kill switch when it is exceeded
+func (k *KillSwitch) RecordExecution(ctx context.Context, success bool) {
+	if success {
+		if err := k.cache.ResetConsecutiveFailures(ctx); err != nil {
+			k.logger.Warn("⚠️  Failed to reset broker failure streak: %v", err)
+		}
+		return
+	}
+
+	failures, err := k.cache.IncrementConsecutiveFailures(ctx)
+	if err != nil {
+		k.logger.Warn("⚠️  Failed to record broker failure: %v", err)
+		return
+	}
+
+	limit := k.config.KillSwitch.MaxConsecutiveFailures
+	if limit > 0 && failures == int64(limit) {
+		reason := fmt.Sprintf("%d consecutive broker failures", failures)
+		if err := k.Trip(ctx, reason, autoTripActor); err != nil {
+			k.logger.Error("❌ Failed to trip kill switch after %s: %v", reason, err)
+		}
+	}
+}
+
+// RecordPnL adds realised P&L for today and trips the kill switch once the daily loss limit is breached
+func (k *KillSwitch) RecordPnL(ctx context.Context, delta float64) {
+	day := k.clock.Now().In(k.istLocation).Format("2006-01-02")
+	total, err := k.cache.AddDailyPnL(ctx, day, delta)
+	if err != nil {
+		k.logger.Warn("⚠️  Failed to record P&L: %v", err)
+		return
+	}
+
+	limit := k.config.KillSwitch.DailyLossLimit
+	if limit > 0 && total <= -limit && total-delta > -limit {
+		reason := fmt.Sprintf("daily loss %.2f breached limit %.2f", -total, limit)
+		if err := k.Trip(ctx, reason, autoTripActor); err != nil {
+			k.logger.Error("❌ Failed to trip kill switch after %s: %v", reason, err)
+		}
+	}
+}
+
+// AuditLog returns the most recent audit entries, newest first
+func (k *KillSwitch) AuditLog(ctx context.Context, limit int64) ([]models.AuditEntry, error) {
+	return k.cache.GetAuditLog(ctx, limit)
+}
+
+// audit records a state change in the shared audit log
+func (k *KillSwitch) audit(ctx context.Context, action, actor, details string) {
+	entry := models.AuditEntry{
+		Timestamp: k.clock.Now(),
+		Action:    action,
+		Actor:     actor,
+		Details:   details,
+	}
+	k.logger.Info("📝 AUDIT %s | %s | %s", action, actor, details)
+	if err := k.cache.AppendAudit(ctx, entry); err != nil {
+		k.logger.Error("❌ Failed to write audit entry (%s %s): %v", action, details, err)
+	}
+}
+
+// newHalt validates scope/target and builds a halt
+func newHalt(scope, target, reason, actor string) (models.Halt, error) {
+	scope = strings.ToLower(strings.TrimSpace(scope))
+	switch scope {
+	case models.HaltScopeGlobal:
+		target = ""
+	case models.HaltScopeSymbol, models.HaltScopeSide:
+		target = normaliseTarget(target)
+		if target == "" {
+			return models.Halt{}, fmt.Errorf("%s halt requires a target", scope)
+		}
+		if scope == models.HaltScopeSide && target != "BUY" && target != "SELL" {
+			return models.Halt{}, fmt.Errorf("side halt target must be Buy or Sell")
+		}
+	default:
+		return models.Halt{}, fmt.Errorf("unknown halt scope: %s (supported: global, symbol, side)", scope)
+	}
+
+	return models.Halt{Scope: scope, Target: target, Reason: reason, Actor: actor}, nil
+}
+
+// normaliseTarget upper-cases targets so "infy" and "INFY" refer to the same halt
+func normaliseTarget(target string) string {
+	return strings.ToUpper(strings.TrimSpace(target))
+}
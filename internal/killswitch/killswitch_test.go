@@ -0,0 +1,104 @@
+package killswitch
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/mach_five/trading-system/internal/cache"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+)
+
+// newTestKillSwitch returns a kill switch on miniredis with the given limits
+func newTestKillSwitch(t *testing.T, maxFailures int, dailyLoss float64) *KillSwitch {
+	t.Helper()
+	server := miniredis.RunT(t)
+	redisCache, err := cache.NewRedisCache(server.Addr(), "", 0)
+	if err != nil {
+		t.Fatalf("failed to connect to miniredis: %v", err)
+	}
+	t.Cleanup(func() { redisCache.Close() })
+
+	log, err := logger.NewLogger("error", filepath.Join(t.TempDir(), "killswitch.log"))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	cfg := &config.Config{}
+	cfg.KillSwitch.MaxConsecutiveFailures = maxFailures
+	cfg.KillSwitch.DailyLossLimit = dailyLoss
+	k := NewKillSwitch(cfg, redisCache, log)
+	k.SetClock(clock.NewSimulated(time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)))
+	return k
+}
+
+func tripped(t *testing.T, k *KillSwitch) bool {
+	t.Helper()
+	halts, err := k.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return halts.Tripped()
+}
+
+func TestFailureStreakTripsAtLimit(t *testing.T) {
+	ctx := context.Background()
+	k := newTestKillSwitch(t, 3, 0)
+
+	k.RecordExecution(ctx, false)
+	k.RecordExecution(ctx, false)
+	if tripped(t, k) {
+		t.Fatal("tripped after 2 failures, want the limit of 3")
+	}
+	k.RecordExecution(ctx, false)
+	if !tripped(t, k) {
+		t.Fatal("not tripped after 3 consecutive failures")
+	}
+}
+
+func TestSuccessResetsFailureStreak(t *testing.T) {
+	ctx := context.Background()
+	k := newTestKillSwitch(t, 3, 0)
+
+	k.RecordExecution(ctx, false)
+	k.RecordExecution(ctx, false)
+	k.RecordExecution(ctx, true)
+	k.RecordExecution(ctx, false)
+	k.RecordExecution(ctx, false)
+	if tripped(t, k) {
+		t.Fatal("tripped after a success broke the streak")
+	}
+	k.RecordExecution(ctx, false)
+	if !tripped(t, k) {
+		t.Fatal("not tripped after 3 failures following the success")
+	}
+}
+
+func TestDailyLossLimitTrips(t *testing.T) {
+	ctx := context.Background()
+	k := newTestKillSwitch(t, 0, 1000)
+
+	k.RecordPnL(ctx, -600)
+	k.RecordPnL(ctx, 300)
+	k.RecordPnL(ctx, -600)
+	if tripped(t, k) {
+		t.Fatal("tripped at a daily loss of 900, want the limit of 1000")
+	}
+	k.RecordPnL(ctx, -100)
+	if !tripped(t, k) {
+		t.Fatal("not tripped at a daily loss of 1000")
+	}
+
+	// The loss limit is not checked when it is unset
+	off := newTestKillSwitch(t, 0, 0)
+	off.RecordPnL(ctx, -1000000)
+	if tripped(t, off) {
+		t.Error("tripped with no daily loss limit")
+	}
+}
@@ -0,0 +1,201 @@
+package leader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/cache"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/metrics"
+)
+
+// Roles that elect a leader independently
+const (
+	RoleTrigger = "trigger"
+	RoleReader  = "reader"
+)
+
+// Status describes this process's view of leadership for a role
+type Status struct {
+	Enabled    bool      `json:"enabled"`
+	Role       string    `json:"role"`
+	InstanceID string    `json:"instance_id"`
+	Leader     bool      `json:"leader"`
+	Holder     string    `json:"holder,omitempty"`        // Current leader as last seen by this process
+	Token      int64     `json:"fencing_token,omitempty"` // Set while this process is leader
+	Since      time.Time `json:"since,omitempty"`         // When this process became leader
+	ValidUntil time.Time `json:"valid_until,omitempty"`   // Leadership lapses at this time unless renewed
+}
+
+// Elector runs active/standby leader election for one role on a Redis lease. Leadership is
+// only trusted until the local lease deadline, which is measured from before the last
+// successful acquire or renew, so a leader cut off from Redis steps down before a standby
+// can take over.
+type Elector struct {
+	cache         *cache.RedisCache
+	logger        *logger.Logger
+	clock         clock.Clock
+	role          string
+	instanceID    string
+	lease         time.Duration
+	renewInterval time.Duration
+
+	mu         sync.RWMutex
+	leader     bool
+	holder     string
+	token      int64
+	since      time.Time
+	validUntil time.Time
+}
+
+// NewElector creates an elector for role using the leader election configuration
+func NewElector(cfg *config.Config, cache *cache.RedisCache, role string, log *logger.Logger) *Elector {
+	return &Elector{
+		cache:         cache,
+		logger:        log,
+		clock:         clock.Real{},
+		role:          role,
+		instanceID:    cfg.Leader.InstanceID,
+		lease:         cfg.Leader.Lease,
+		renewInterval: cfg.Leader.RenewInterval,
+	}
+}
+
+// SetClock replaces the clock used for the local lease deadline
+func (e *Elector) SetClock(c clock.Clock) {
+	e.clock = c
+}
+
+// Run renews leadership, or campaigns for it, every renew interval until ctx is cancelled,
+// then resigns so a standby can take over without waiting for the lease to run out
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Resign even though ctx is done; the cache call still has its own timeout
+			e.Resign(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+			e.Campaign(ctx)
+		}
+	}
+}
+
+// Campaign renews the lease if this process is leader, otherwise tries to acquire it
+func (e *Elector) Campaign(ctx context.Context) {
+	started := e.clock.Now()
+
+	if e.IsLeader() {
+		renewed, err := e.cache.RenewLeadership(ctx, e.role, e.instanceID, e.lease)
+		switch {
+		case err != nil:
+			// Keep leading until the local deadline; IsLeader lapses on its own after that
+			e.logger.Warn("⚠️  Failed to renew %s leadership, valid until %s: %v",
+				e.role, e.Status().ValidUntil.Format(time.RFC3339), err)
+		case !renewed:
+			e.stepDown("lease was taken over")
+		default:
+			e.mu.Lock()
+			e.validUntil = started.Add(e.lease)
+			e.mu.Unlock()
+		}
+		return
+	}
+
+	e.mu.RLock()
+	wasLeader := e.leader
+	e.mu.RUnlock()
+	if wasLeader {
+		e.stepDown("lease expired without renewal")
+	}
+
+	token, holder, err := e.cache.AcquireLeadership(ctx, e.role, e.instanceID, e.lease)
+	if err != nil {
+		e.logger.Warn("⚠️  Failed to campaign for %s leadership: %v", e.role, err)
+		return
+	}
+
+	e.mu.Lock()
+	e.holder = holder
+	if token > 0 {
+		e.leader = true
+		e.token = token
+		e.since = started
+		e.validUntil = started.Add(e.lease)
+	}
+	e.mu.Unlock()
+
+	if token > 0 {
+		metrics.Leader.WithLabelValues(e.role).Set(1)
+		e.logger.Success("👑 %s became %s leader (fencing token %d)", e.instanceID, e.role, token)
+	} else {
+		metrics.Leader.WithLabelValues(e.role).Set(0)
+		e.logger.Debug("Standing by as %s; %s leader is %s", e.instanceID, e.role, holder)
+	}
+}
+
+// Resign gives up leadership if this process holds it
+func (e *Elector) Resign(ctx context.Context) {
+	e.mu.RLock()
+	wasLeader := e.leader
+	e.mu.RUnlock()
+	if !wasLeader {
+		return
+	}
+
+	if err := e.cache.ResignLeadership(ctx, e.role, e.instanceID); err != nil {
+		e.logger.Warn("Failed to resign %s leadership: %v", e.role, err)
+	}
+	e.stepDown("resigned")
+}
+
+// stepDown clears local leadership
+func (e *Elector) stepDown(reason string) {
+	e.mu.Lock()
+	e.leader = false
+	e.token = 0
+	e.since = time.Time{}
+	e.validUntil = time.Time{}
+	e.mu.Unlock()
+
+	metrics.Leader.WithLabelValues(e.role).Set(0)
+	e.logger.Warn("⬇️  %s is no longer %s leader: %s", e.instanceID, e.role, reason)
+}
+
+// IsLeader reports whether this process holds an unexpired lease
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader && e.clock.Now().Before(e.validUntil)
+}
+
+// Fence returns the fencing token to attach to writes made as leader
+func (e *Elector) Fence() cache.Fence {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return cache.Fence{Role: e.role, Token: e.token}
+}
+
+// Status returns this process's view of leadership for the health output
+func (e *Elector) Status() Status {
+	leader := e.IsLeader()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return Status{
+		Enabled:    true,
+		Role:       e.role,
+		InstanceID: e.instanceID,
+		Leader:     leader,
+		Holder:     e.holder,
+		Token:      e.token,
+		Since:      e.since,
+		ValidUntil: e.validUntil,
+	}
+}
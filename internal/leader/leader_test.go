@@ -0,0 +1,124 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/mach_five/trading-system/internal/cache"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+)
+
+// newTestElectors returns two electors for the same role sharing one Redis and clock
+func newTestElectors(t *testing.T) (*Elector, *Elector, *cache.RedisCache, *miniredis.Miniredis, *clock.Simulated) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	redisCache, err := cache.NewRedisCache(server.Addr(), "", 0)
+	if err != nil {
+		t.Fatalf("failed to connect to miniredis: %v", err)
+	}
+	t.Cleanup(func() { redisCache.Close() })
+
+	log, err := logger.NewLogger("error", filepath.Join(t.TempDir(), "leader.log"))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	sim := clock.NewSimulated(time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC))
+	electors := make([]*Elector, 2)
+	for i, id := range []string{"vm-a", "vm-b"} {
+		cfg := &config.Config{}
+		cfg.Leader = config.LeaderConfig{Enabled: true, InstanceID: id, Lease: 10 * time.Second, RenewInterval: 3 * time.Second}
+		electors[i] = NewElector(cfg, redisCache, RoleTrigger, log)
+		electors[i].SetClock(sim)
+	}
+	return electors[0], electors[1], redisCache, server, sim
+}
+
+func TestElectorSingleLeader(t *testing.T) {
+	ctx := context.Background()
+	a, b, _, _, _ := newTestElectors(t)
+
+	a.Campaign(ctx)
+	b.Campaign(ctx)
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("leader a = %v, b = %v; want only a", a.IsLeader(), b.IsLeader())
+	}
+	if status := b.Status(); status.Holder != "vm-a" || status.Token != 0 {
+		t.Errorf("standby status = %+v, want holder vm-a and no token", status)
+	}
+	if a.Fence().Token == 0 {
+		t.Error("leader has no fencing token")
+	}
+}
+
+func TestElectorFailover(t *testing.T) {
+	ctx := context.Background()
+	a, b, _, server, sim := newTestElectors(t)
+	a.Campaign(ctx)
+	b.Campaign(ctx)
+	firstToken := a.Fence().Token
+
+	// a stops renewing; its lease runs out in Redis and locally
+	server.FastForward(11 * time.Second)
+	sim.Advance(11 * time.Second)
+	if a.IsLeader() {
+		t.Error("a still leader after its lease ran out")
+	}
+
+	b.Campaign(ctx)
+	if !b.IsLeader() {
+		t.Fatal("standby did not take over after the lease ran out")
+	}
+	if b.Fence().Token <= firstToken {
+		t.Errorf("new fencing token %d, want greater than %d", b.Fence().Token, firstToken)
+	}
+
+	// a comes back and finds b in charge
+	a.Campaign(ctx)
+	if a.IsLeader() {
+		t.Error("old leader regained leadership while the new leader holds the lease")
+	}
+}
+
+func TestElectorResignHandsOverImmediately(t *testing.T) {
+	ctx := context.Background()
+	a, b, _, _, _ := newTestElectors(t)
+	a.Campaign(ctx)
+	a.Resign(ctx)
+	if a.IsLeader() {
+		t.Fatal("a still leader after resigning")
+	}
+
+	b.Campaign(ctx)
+	if !b.IsLeader() {
+		t.Fatal("standby did not take over after the leader resigned")
+	}
+}
+
+func TestStaleFenceRejectsClaims(t *testing.T) {
+	ctx := context.Background()
+	a, b, redisCache, server, sim := newTestElectors(t)
+	a.Campaign(ctx)
+	stale := a.Fence()
+
+	server.FastForward(11 * time.Second)
+	sim.Advance(11 * time.Second)
+	b.Campaign(ctx)
+
+	// A paused old leader resumes and tries to dispatch with its old token
+	_, _, err := redisCache.ClaimDueOrders(ctx, sim.Now(), "vm-a", time.Minute, stale)
+	if !errors.Is(err, cache.ErrFenced) {
+		t.Errorf("claim with a stale token: err = %v, want ErrFenced", err)
+	}
+	if _, _, err := redisCache.ClaimDueOrders(ctx, sim.Now(), "vm-b", time.Minute, b.Fence()); err != nil {
+		t.Errorf("claim with the current token: %v", err)
+	}
+}
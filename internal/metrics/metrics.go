@@ -0,0 +1,289 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "trading"
+
+// Registry holds every trading system metric. A dedicated registry (rather than
+// the global default) keeps the exposed series limited to what we register here.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// SchedulerDelay is the time between an order's scheduled time and the start of its execution
+	SchedulerDelay = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "scheduler_delay_seconds",
+		Help:      "Delay between an order's scheduled time and the start of its execution.",
+		Buckets:   []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	})
+
+	// BrokerLatency is the time spent inside the broker for a single order
+	BrokerLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "broker_latency_seconds",
+		Help:      "Time spent placing a single order with the broker.",
+		Buckets:   []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"broker"})
+
+	// OrderTotalTime is the end-to-end time the trigger spends on a single order
+	OrderTotalTime = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "order_total_seconds",
+		Help:      "End-to-end time the trigger spends executing a single order.",
+		Buckets:   []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	})
+
+	// OrdersRead counts orders parsed from the order source
+	OrdersRead = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_read_total",
+		Help:      "Orders parsed from the order source.",
+	}, []string{"side"})
+
+	// OrdersCached counts orders stored in the cache by the reader
+	OrdersCached = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_cached_total",
+		Help:      "Orders stored in the cache by the reader.",
+	})
+
+	// OrdersExecuted counts orders accepted by the broker
+	OrdersExecuted = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_executed_total",
+		Help:      "Orders accepted by the broker.",
+	})
+
+	// OrdersFailed counts orders that failed during execution
+	OrdersFailed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_failed_total",
+		Help:      "Orders that failed during execution.",
+	})
+
+	// OrdersExpired counts orders dropped because their expiry window passed
+	OrdersExpired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_expired_total",
+		Help:      "Orders dropped because their expiry window passed before execution.",
+	})
+
+	// OrdersConditionMet counts armed orders released for execution because their condition held
+	OrdersConditionMet = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_condition_met_total",
+		Help:      "Conditional orders whose market condition held, releasing them for execution.",
+	})
+
+	// GTTEvents counts GTTs placed, modified and deleted, and status changes reported by the broker
+	GTTEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "gtt_events_total",
+		Help:      "GTT changes by event (placed, modified, deleted, or the new status reported by the broker).",
+	}, []string{"event"})
+
+	// OrdersAbandoned counts claimed orders whose lease ran out after they were submitted to the broker
+	OrdersAbandoned = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_abandoned_total",
+		Help:      "Claimed orders whose lease expired after submission to the broker, with an unknown outcome.",
+	})
+
+	// BrokerErrors counts broker errors by HTTP status code ("network" when no response was received)
+	BrokerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "broker_errors_total",
+		Help:      "Broker errors by broker and HTTP status code.",
+	}, []string{"broker", "status_code"})
+
+	// PendingOrders is the current depth of the pending_orders sorted set
+	PendingOrders = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_orders",
+		Help:      "Number of orders waiting in the pending_orders sorted set.",
+	})
+
+	// InFlightOrders is the number of orders claimed by a trigger and not yet resolved
+	InFlightOrders = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "in_flight_orders",
+		Help:      "Number of orders claimed by a trigger instance and not yet resolved.",
+	})
+
+	// OrdersDeadLettered counts orders moved to the dead-letter queue by reason
+	OrdersDeadLettered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_dead_lettered_total",
+		Help:      "Orders moved to the dead-letter queue, by reason (failed, rejected, expired, abandoned).",
+	}, []string{"reason"})
+
+	// DeadLetterOrders is the number of orders waiting in the dead-letter queue
+	DeadLetterOrders = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "dead_letter_orders",
+		Help:      "Number of orders in the dead-letter queue awaiting requeue or discard.",
+	})
+
+	// QuarantinedOrders is the number of undecodable cache entries awaiting inspection
+	QuarantinedOrders = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "quarantined_orders",
+		Help:      "Number of order cache entries that could not be decoded and were quarantined.",
+	})
+
+	// Leader is 1 while this process holds leadership for a role, 0 while it is on standby
+	Leader = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "leader",
+		Help:      "Whether this process is the elected leader for a role (1 = leader, 0 = standby).",
+	}, []string{"role"})
+
+	// Notifications counts alert deliveries per sink and result (sent or failed)
+	Notifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Alert deliveries per sink and result (sent, failed).",
+	}, []string{"sink", "result"})
+
+	// NotificationsSuppressed counts alerts dropped before delivery, by event type and reason
+	NotificationsSuppressed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_suppressed_total",
+		Help:      "Alerts not delivered individually, by event type and reason (duplicate, throttled, dropped).",
+	}, []string{"event", "reason"})
+
+	// SellOrdersChecked counts sell orders checked against the portfolio, by outcome
+	SellOrdersChecked = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sell_orders_checked_total",
+		Help:      "Sell orders checked against holdings and positions, by outcome (allowed, capped, rejected, unchecked).",
+	}, []string{"outcome"})
+
+	// BuyOrdersChecked counts buy orders checked against available funds, by outcome
+	BuyOrdersChecked = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "buy_orders_checked_total",
+		Help:      "Buy orders checked against available funds, by outcome (allowed, scaled, rejected, unchecked).",
+	}, []string{"outcome"})
+
+	// AvailableFunds is the broker margin available for new orders at the last fetch
+	AvailableFunds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "available_funds",
+		Help:      "Broker margin available for new orders when funds were last fetched.",
+	})
+
+	// SheetRowsRejected counts order source rows the reader refused to cache, by reason
+	SheetRowsRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sheet_rows_rejected_total",
+		Help:      "Order source rows not cached, by reason (unknown_instrument, invalid_product, contract_expired, unsized, invalid_condition, invalid_gtt).",
+	}, []string{"reason"})
+
+	// InstrumentsLoaded is the size of the instrument master in use
+	InstrumentsLoaded = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "instruments_loaded",
+		Help:      "Instruments in the instrument master currently in use.",
+	})
+
+	// HealthCheckUp is 1 when the last health check of a component passed, 0 otherwise
+	HealthCheckUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "health_check_up",
+		Help:      "Result of the last health check per component (1 = healthy, 0 = unhealthy).",
+	}, []string{"component"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		SchedulerDelay,
+		BrokerLatency,
+		OrderTotalTime,
+		OrdersRead,
+		OrdersCached,
+		OrdersExecuted,
+		OrdersFailed,
+		OrdersExpired,
+		OrdersConditionMet,
+		GTTEvents,
+		OrdersAbandoned,
+		BrokerErrors,
+		PendingOrders,
+		InFlightOrders,
+		OrdersDeadLettered,
+		DeadLetterOrders,
+		QuarantinedOrders,
+		Leader,
+		Notifications,
+		NotificationsSuppressed,
+		SellOrdersChecked,
+		BuyOrdersChecked,
+		AvailableFunds,
+		SheetRowsRejected,
+		InstrumentsLoaded,
+		HealthCheckUp,
+	)
+}
+
+// ObserveBrokerError records a broker error; statusCode 0 means no HTTP response was received
+func ObserveBrokerError(broker string, statusCode int) {
+	code := "network"
+	if statusCode > 0 {
+		code = strconv.Itoa(statusCode)
+	}
+	BrokerErrors.WithLabelValues(broker, code).Inc()
+}
+
+// SetHealth records the result of a component health check
+func SetHealth(component string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	HealthCheckUp.WithLabelValues(component).Set(value)
+}
+
+// Handler returns the HTTP handler serving the metrics registry
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Serve exposes /metrics on addr until ctx is cancelled
+func Serve(ctx context.Context, addr string, log *logger.Logger) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler())
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Info("📈 Metrics endpoint listening on http://%s/metrics", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error("❌ Metrics endpoint stopped: %v", err)
+		return err
+	}
+	return nil
+}
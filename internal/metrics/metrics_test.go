@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// family returns the metric family the registry exposes under name
+func family(t *testing.T, name string) *dto.MetricFamily {
+	t.Helper()
+	families, err := Registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	for _, f := range families {
+		if f.GetName() == name {
+			return f
+		}
+	}
+	t.Fatalf("metric %s is not exposed", name)
+	return nil
+}
+
+// labelNames returns the label names of a family's first series
+func labelNames(f *dto.MetricFamily) []string {
+	names := []string{}
+	for _, label := range f.GetMetric()[0].GetLabel() {
+		names = append(names, label.GetName())
+	}
+	return names
+}
+
+func TestCounters(t *testing.T) {
+	tests := []struct {
+		name    string
+		labels  []string
+		counter prometheus.Counter
+		record  func()
+		want    float64 // Increase after record
+	}{
+		{"trading_sell_orders_checked_total", []string{"outcome"}, SellOrdersChecked.WithLabelValues("capped"),
+			func() { SellOrdersChecked.WithLabelValues("capped").Inc() }, 1},
+		{"trading_buy_orders_checked_total", []string{"outcome"}, BuyOrdersChecked.WithLabelValues("unchecked"),
+			func() { BuyOrdersChecked.WithLabelValues("unchecked").Add(3) }, 3},
+		{"trading_broker_errors_total", []string{"broker", "status_code"}, BrokerErrors.WithLabelValues("kite", "network"),
+			func() { ObserveBrokerError("kite", 0) }, 1},
+		{"trading_orders_dead_lettered_total", []string{"reason"}, OrdersDeadLettered.WithLabelValues("rejected"),
+			func() { OrdersDeadLettered.WithLabelValues("rejected").Inc() }, 1},
+		{"trading_sheet_rows_rejected_total", []string{"reason"}, SheetRowsRejected.WithLabelValues("unsized"),
+			func() { SheetRowsRejected.WithLabelValues("unsized").Inc() }, 1},
+		{"trading_orders_executed_total", []string{}, OrdersExecuted,
+			func() { OrdersExecuted.Inc() }, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(tt.counter)
+			tt.record()
+			if got := testutil.ToFloat64(tt.counter) - before; got != tt.want {
+				t.Errorf("increased by %v, want %v", got, tt.want)
+			}
+			if got := labelNames(family(t, tt.name)); !reflect.DeepEqual(got, tt.labels) {
+				t.Errorf("labels = %v, want %v", got, tt.labels)
+			}
+		})
+	}
+
+	// Broker errors with a response are labelled by status code
+	before := testutil.ToFloat64(BrokerErrors.WithLabelValues("kite", "429"))
+	ObserveBrokerError("kite", 429)
+	if got := testutil.ToFloat64(BrokerErrors.WithLabelValues("kite", "429")) - before; got != 1 {
+		t.Errorf("status 429 counted %v times, want 1", got)
+	}
+}
+
+func TestGauges(t *testing.T) {
+	AvailableFunds.Set(125000.5)
+	if got := testutil.ToFloat64(AvailableFunds); got != 125000.5 {
+		t.Errorf("AvailableFunds = %v, want 125000.5", got)
+	}
+	if got := labelNames(family(t, "trading_available_funds")); len(got) != 0 {
+		t.Errorf("available funds labels = %v, want none", got)
+	}
+
+	SetHealth("broker", false)
+	SetHealth("cache", true)
+	if broker, cache := testutil.ToFloat64(HealthCheckUp.WithLabelValues("broker")), testutil.ToFloat64(HealthCheckUp.WithLabelValues("cache")); broker != 0 || cache != 1 {
+		t.Errorf("health = broker %v, cache %v; want 0 and 1", broker, cache)
+	}
+	if got := labelNames(family(t, "trading_health_check_up")); !reflect.DeepEqual(got, []string{"component"}) {
+		t.Errorf("health labels = %v, want [component]", got)
+	}
+}
+
+func TestBrokerLatency(t *testing.T) {
+	count := func() uint64 {
+		for _, metric := range family(t, "trading_broker_latency_seconds").GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "broker" && label.GetValue() == "paper" {
+					return metric.GetHistogram().GetSampleCount()
+				}
+			}
+		}
+		return 0
+	}
+
+	BrokerLatency.WithLabelValues("paper").Observe(0.02)
+	before := count()
+	BrokerLatency.WithLabelValues("paper").Observe(0.3)
+	BrokerLatency.WithLabelValues("paper").Observe(1.2)
+	if got := count() - before; got != 2 {
+		t.Errorf("observed %d latencies, want 2", got)
+	}
+	if got := labelNames(family(t, "trading_broker_latency_seconds")); !reflect.DeepEqual(got, []string{"broker"}) {
+		t.Errorf("labels = %v, want [broker]", got)
+	}
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OrderCacheEntryVersion is the cache entry schema written by this build. Entries without a
+// version field predate versioning and are treated as version 1.
+const OrderCacheEntryVersion = 3
+
+// ErrInvalidCacheEntry is returned for cache entries that cannot be decoded into an executable order
+var ErrInvalidCacheEntry = errors.New("invalid order cache entry")
+
+// cacheEntryUpgrades rewrites an entry's raw fields from the keyed version to the next one.
+// Add a step here whenever OrderCacheEntryVersion is bumped.
+var cacheEntryUpgrades = map[int]func(fields map[string]json.RawMessage) error{
+	1: upgradeCacheEntryV1,
+	2: upgradeCacheEntryV2,
+}
+
+// upgradeCacheEntryV1 upgrades unversioned entries. Version 2 only introduced the version
+// field; fields added to Order since are optional and decode to their zero values.
+func upgradeCacheEntryV1(fields map[string]json.RawMessage) error {
+	return nil
+}
+
+// upgradeCacheEntryV2 upgrades entries from before Order.Attempts. Older entries were never
+// requeued after a failure, so the missing count decodes to zero.
+func upgradeCacheEntryV2(fields map[string]json.RawMessage) error {
+	return nil
+}
+
+// DecodeOrderCacheEntry decodes a cache entry written by this or an earlier build. Older
+// layouts are upgraded in memory, leaving Version as stored so callers can rewrite them.
+// Entries from a newer build, with unknown fields or missing required fields are rejected
+// with ErrInvalidCacheEntry rather than being partially decoded.
+func DecodeOrderCacheEntry(data []byte) (*OrderCacheEntry, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("%w: malformed JSON: %v", ErrInvalidCacheEntry, err)
+	}
+
+	version := 1
+	if raw, ok := fields["version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, fmt.Errorf("%w: malformed version: %v", ErrInvalidCacheEntry, err)
+		}
+	}
+	if version < 1 || version > OrderCacheEntryVersion {
+		return nil, fmt.Errorf("%w: schema version %d is not supported (this build reads up to %d)",
+			ErrInvalidCacheEntry, version, OrderCacheEntryVersion)
+	}
+
+	for v := version; v < OrderCacheEntryVersion; v++ {
+		if err := cacheEntryUpgrades[v](fields); err != nil {
+			return nil, fmt.Errorf("%w: upgrade from version %d failed: %v", ErrInvalidCacheEntry, v, err)
+		}
+	}
+	fields["version"] = json.RawMessage(fmt.Sprint(version))
+
+	upgraded, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCacheEntry, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(upgraded))
+	decoder.DisallowUnknownFields()
+	var entry OrderCacheEntry
+	if err := decoder.Decode(&entry); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCacheEntry, err)
+	}
+
+	if err := entry.Validate(); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Validate checks that the entry holds everything needed to execute the order
+func (e *OrderCacheEntry) Validate() error {
+	var missing []string
+	if e.Order.ID == "" {
+		missing = append(missing, "order.id")
+	}
+	if e.Order.Symbol == "" {
+		missing = append(missing, "order.symbol")
+	}
+	if e.Order.Quantity <= 0 && e.Order.FundsPercent <= 0 {
+		missing = append(missing, "order.quantity")
+	}
+	if e.Order.ScheduledTime.IsZero() {
+		missing = append(missing, "order.scheduled_time")
+	}
+	if e.ExpiryTime.IsZero() {
+		missing = append(missing, "expiry_time")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing %s", ErrInvalidCacheEntry, strings.Join(missing, ", "))
+	}
+	if !strings.EqualFold(e.Order.Side, "Buy") && !strings.EqualFold(e.Order.Side, "Sell") {
+		return fmt.Errorf("%w: unknown side %q", ErrInvalidCacheEntry, e.Order.Side)
+	}
+	return nil
+}
+
+// QuarantinedOrder is a cache entry that could not be decoded, kept for inspection instead of
+// being dropped
+type QuarantinedOrder struct {
+	OrderID       string    `json:"order_id"`
+	Reason        string    `json:"reason"`
+	Data          string    `json:"data"` // Raw entry as found in the cache
+	QuarantinedAt time.Time `json:"quarantined_at"`
+}
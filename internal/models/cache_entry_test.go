@@ -0,0 +1,82 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeOrderCacheEntryUpgradesUnversionedEntries(t *testing.T) {
+	// Written by a reader from before cache entries were versioned
+	legacy := `{"order":{"id":"INFY:1","symbol":"INFY","exchange":"NSE","price":1500,"quantity":1,
+		"order_type":"LIMIT","side":"Buy","scheduled_time":"2024-01-15T09:15:00+05:30",
+		"created_at":"2024-01-15T09:00:00+05:30","is_amo":false},
+		"expiry_time":"2024-01-15T09:15:10+05:30","created_at":"2024-01-15T09:00:00+05:30"}`
+
+	entry, err := DecodeOrderCacheEntry([]byte(legacy))
+	if err != nil {
+		t.Fatalf("DecodeOrderCacheEntry: %v", err)
+	}
+	if entry.Version != 1 {
+		t.Errorf("Version = %d, want 1 as stored", entry.Version)
+	}
+	if entry.Order.Symbol != "INFY" || entry.Order.Quantity != 1 {
+		t.Errorf("decoded order = %+v", entry.Order)
+	}
+
+	data, err := entry.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON: %v", err)
+	}
+	rewritten, err := DecodeOrderCacheEntry(data)
+	if err != nil {
+		t.Fatalf("DecodeOrderCacheEntry after rewrite: %v", err)
+	}
+	if rewritten.Version != OrderCacheEntryVersion {
+		t.Errorf("rewritten Version = %d, want %d", rewritten.Version, OrderCacheEntryVersion)
+	}
+}
+
+func TestDecodeOrderCacheEntryRejectsInvalidEntries(t *testing.T) {
+	valid := OrderCacheEntry{
+		Order: Order{ID: "A", Symbol: "INFY", Quantity: 1, Side: "Sell",
+			ScheduledTime: time.Date(2024, 1, 15, 9, 15, 0, 0, time.UTC)},
+		ExpiryTime: time.Date(2024, 1, 15, 9, 15, 10, 0, time.UTC),
+	}
+	data, err := valid.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON: %v", err)
+	}
+	if _, err := DecodeOrderCacheEntry(data); err != nil {
+		t.Fatalf("valid entry rejected: %v", err)
+	}
+	current := fmt.Sprintf(`"version":%d`, OrderCacheEntryVersion)
+	next := fmt.Sprintf(`"version":%d`, OrderCacheEntryVersion+1)
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "malformed", data: `{"order":`, want: "malformed JSON"},
+		{name: "newer version", data: strings.Replace(string(data), current, next, 1), want: fmt.Sprintf("version %d", OrderCacheEntryVersion+1)},
+		{name: "unknown field", data: strings.Replace(string(data), current, current+`,"legs":[]`, 1), want: "unknown field"},
+		{name: "missing fields", data: `{"version":1,"order":{"id":"A","side":"Buy"}}`, want: "order.symbol, order.quantity, order.scheduled_time, expiry_time"},
+		{name: "wrong type", data: strings.Replace(string(data), `"quantity":1`, `"quantity":"1"`, 1), want: "quantity"},
+		{name: "unknown side", data: strings.Replace(string(data), `"side":"Sell"`, `"side":"Short"`, 1), want: "unknown side"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeOrderCacheEntry([]byte(tt.data))
+			if !errors.Is(err, ErrInvalidCacheEntry) {
+				t.Fatalf("err = %v, want ErrInvalidCacheEntry", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// GTT types
+const (
+	GTTSingle = "single"  // One trigger price; the order is placed when the price reaches it
+	GTTOCO    = "two-leg" // One-cancels-other: a stop-loss and a target, whichever is reached first
+)
+
+// GTT statuses, as Kite reports them
+const (
+	GTTStatusActive    = "active"
+	GTTStatusTriggered = "triggered"
+	GTTStatusDisabled  = "disabled"
+	GTTStatusExpired   = "expired"
+	GTTStatusCancelled = "cancelled"
+	GTTStatusRejected  = "rejected"
+	GTTStatusDeleted   = "deleted"
+)
+
+// GTT marks an order as a Good Till Triggered instruction left standing at the broker
+// instead of an order placed at its scheduled time
+type GTT struct {
+	Type     string  `json:"type"`                // single or two-leg
+	StopLoss float64 `json:"stop_loss,omitempty"` // Lower trigger and limit price of a two-leg GTT; the order's Price is the target
+}
+
+// TriggerValues returns the GTT's trigger prices for an order at price, lowest first
+func (g GTT) TriggerValues(price float64) []float64 {
+	if g.Type == GTTOCO {
+		return []float64{g.StopLoss, price}
+	}
+	return []float64{price}
+}
+
+// GTTTrigger is a GTT as the broker reports it
+type GTTTrigger struct {
+	ID              int       `json:"id"`
+	Type            string    `json:"type"`
+	Status          string    `json:"status"`
+	Exchange        string    `json:"exchange"`
+	Symbol          string    `json:"symbol"`
+	TriggerValues   []float64 `json:"trigger_values"`
+	OrderID         string    `json:"order_id,omitempty"`         // Order placed when the GTT triggered
+	RejectionReason string    `json:"rejection_reason,omitempty"` // Why that order was rejected, if it was
+	UpdatedAt       time.Time `json:"updated_at"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+// GTTRecord tracks the GTT placed for an order-source row
+type GTTRecord struct {
+	Order     Order     `json:"order"`
+	TriggerID int       `json:"trigger_id"`
+	Status    string    `json:"status"`             // One of the GTTStatus* values
+	OrderID   string    `json:"order_id,omitempty"` // Order placed when the GTT triggered
+	Error     string    `json:"error,omitempty"`    // Rejection reason, or the last failed modify or delete
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Terminal reports whether the GTT can no longer trigger
+func (r GTTRecord) Terminal() bool {
+	return r.Status != GTTStatusActive
+}
+
+// String summarises the record as it is written back to the order source
+func (r GTTRecord) String() string {
+	summary := fmt.Sprintf("%s #%d", r.Status, r.TriggerID)
+	if r.OrderID != "" {
+		summary += " order " + r.OrderID
+	}
+	if r.Error != "" {
+		summary += ": " + r.Error
+	}
+	return summary
+}
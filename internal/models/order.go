@@ -2,6 +2,9 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,10 +20,57 @@ type Order struct {
 	ScheduledTime time.Time `json:"scheduled_time"`
 	CreatedAt     time.Time `json:"created_at"`
 	IsAMO         bool      `json:"is_amo"`     // Whether this order should be placed as After Market Order
+	ExpiryWindow  time.Duration `json:"expiry_window,omitempty"` // Per-order override of the source's expiry window
+	TraceContext  map[string]string `json:"trace_context,omitempty"` // W3C trace context captured when the order was parsed
+	Attempts      int       `json:"attempts,omitempty"`   // Times the order has been sent to the broker, across requeues
+	SourceRow     int       `json:"source_row,omitempty"` // Row in the order source; orders due together are funded in row order
+	PriceAdjustments []PriceAdjustment `json:"price_adjustments,omitempty"` // Changes made to Price so the exchange accepts it, oldest first
+	FundsPercent  float64   `json:"funds_percent,omitempty"` // Percentage of available funds to buy with; Quantity is sized when the order is due
+	LotSize       int       `json:"lot_size,omitempty"`      // Quantity is a whole multiple of this; 0 or 1 for cash equities
+	Product       string    `json:"product,omitempty"`       // CNC, MIS or NRML; CNC when empty
+	InstrumentType string   `json:"instrument_type,omitempty"` // FUT, CE or PE for futures and options; EQ when empty
+	Expiry        time.Time `json:"expiry,omitempty"`        // Contract expiry date of futures and options; zero for cash equities
+	Condition     *Condition `json:"condition,omitempty"`    // Market condition that must hold before the order fires; nil for time-only orders
+	GTT           *GTT      `json:"gtt,omitempty"`           // Set when the order is left at the broker as a GTT until ScheduledTime
+}
+
+// Condition metrics
+const (
+	ConditionLTP       = "ltp"        // Last traded price
+	ConditionChangePct = "change_pct" // Percent move of the last price from the day's open
+	ConditionVolume    = "volume"     // Volume traded so far today
+)
+
+// Condition is a market condition that must hold once an order is due before it is sent,
+// such as "ltp >= 1500"
+type Condition struct {
+	Metric   string  `json:"metric"`   // ltp, change_pct or volume
+	Operator string  `json:"operator"` // >= or <=
+	Value    float64 `json:"value"`
+}
+
+// String formats the condition as it is written in the order source
+func (c Condition) String() string {
+	return fmt.Sprintf("%s %s %s", c.Metric, c.Operator, strconv.FormatFloat(c.Value, 'f', -1, 64))
+}
+
+// Price adjustment reasons
+const (
+	PriceAdjustmentTick    = "tick"    // Rounded onto the instrument's tick grid
+	PriceAdjustmentCircuit = "circuit" // Moved inside the day's circuit band
+)
+
+// PriceAdjustment records a change made to an order's limit price before placement
+type PriceAdjustment struct {
+	Reason string    `json:"reason"`
+	From   float64   `json:"from"`
+	To     float64   `json:"to"`
+	At     time.Time `json:"at"`
 }
 
 // OrderCacheEntry represents an order stored in cache
 type OrderCacheEntry struct {
+	Version       int       `json:"version"` // Schema version the entry was written with
 	Order         Order     `json:"order"`
 	ExpiryTime    time.Time `json:"expiry_time"`
 	CreatedAt     time.Time `json:"created_at"`
@@ -35,6 +85,8 @@ type ExecutionResult struct {
 	ErrorMessage string    `json:"error_message,omitempty"`
 	ExecutedPrice float64  `json:"executed_price,omitempty"`
 	ExecutedQuantity int   `json:"executed_quantity,omitempty"`
+	CappedQuantity int     `json:"capped_quantity,omitempty"` // Quantity sent when a sell was capped to the available quantity
+	PriceAdjustments []PriceAdjustment `json:"price_adjustments,omitempty"` // Price changes made by the broker layer before sending
 }
 
 // ProfilingMetrics tracks timing information for order execution
@@ -51,14 +103,109 @@ type ProfilingMetrics struct {
 	CompletedAt       time.Time     `json:"completed_at"`
 }
 
-// ToJSON converts OrderCacheEntry to JSON
+// Fill represents a (possibly partial) execution of a placed order
+type Fill struct {
+	OrderID     string    `json:"order_id"`
+	ExecutionID string    `json:"execution_id"`
+	Symbol      string    `json:"symbol"`
+	Exchange    string    `json:"exchange"`
+	Side        string    `json:"side"`
+	Quantity    int       `json:"quantity"`
+	Price       float64   `json:"price"`
+	FilledAt    time.Time `json:"filled_at"`
+	RealisedPnL float64   `json:"realised_pnl,omitempty"` // P&L realised by the part of the fill that closed a position
+}
+
+// Journal event types
+const (
+	JournalEventRead      = "read"      // Order parsed from the sheet and cached by the reader
+	JournalEventExecution = "execution" // Order handed to the broker (success or failure)
+	JournalEventFill      = "fill"      // Order filled by the broker
+	JournalEventExpired   = "expired"   // Order passed its expiry window without being executed
+	JournalEventAbandoned = "abandoned" // Claim lease ran out after submission; the broker outcome is unknown
+	JournalEventRejected  = "rejected"  // Order held back before reaching the broker, e.g. for insufficient funds
+	JournalEventGTTPlaced   = "gtt_placed"   // GTT left at the broker for an order-source row
+	JournalEventGTTModified = "gtt_modified" // GTT changed to follow its row's price, quantity or stop-loss
+	JournalEventGTTDeleted  = "gtt_deleted"  // GTT deleted because its row is gone or past its valid-until time
+	JournalEventGTTStatus   = "gtt_status"   // Broker reported a new GTT status, e.g. triggered or expired
+)
+
+// JournalEntry is one line of the execution journal shared by live and paper trading
+type JournalEntry struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Event     string            `json:"event"`
+	Broker    string            `json:"broker"`
+	Order     *Order            `json:"order,omitempty"`
+	Result    *ExecutionResult  `json:"result,omitempty"`
+	Fill      *Fill             `json:"fill,omitempty"`
+	Metrics   *ProfilingMetrics `json:"metrics,omitempty"`
+	GTT       *GTTRecord        `json:"gtt,omitempty"`
+}
+
+// Dead-letter reasons
+const (
+	DeadLetterFailed    = "failed"    // Broker call returned an error
+	DeadLetterRejected  = "rejected"  // Broker answered but did not accept the order
+	DeadLetterExpired   = "expired"   // Order passed its expiry window without being executed
+	DeadLetterAbandoned = "abandoned" // Claim lease ran out after submission; check the broker before requeueing
+)
+
+// DeadLetter is an order that did not execute, kept so it can be inspected and requeued
+type DeadLetter struct {
+	Order          Order     `json:"order"`
+	Reason         string    `json:"reason"` // One of the DeadLetter* reasons
+	Error          string    `json:"error"`
+	Attempts       int       `json:"attempts"`         // Broker submissions so far, including earlier requeues
+	ExpiryTime     time.Time `json:"expiry_time"`      // Expiry window of the attempt that failed
+	DeadLetteredAt time.Time `json:"dead_lettered_at"`
+}
+
+// Halt scopes
+const (
+	HaltScopeGlobal = "global" // Kill switch: stops all trading
+	HaltScopeSymbol = "symbol" // Stops trading in one symbol
+	HaltScopeSide   = "side"   // Stops all Buy or all Sell orders
+)
+
+// Halt represents an active trading halt
+type Halt struct {
+	Scope  string    `json:"scope"`            // global, symbol or side
+	Target string    `json:"target,omitempty"` // Symbol or side being halted (empty for global)
+	Reason string    `json:"reason"`
+	Actor  string    `json:"actor"` // Who or what set the halt (admin API, auto-trip, ...)
+	Since  time.Time `json:"since"`
+}
+
+// Key returns the identifier of the halt within the halts hash
+func (h Halt) Key() string {
+	if h.Scope == HaltScopeGlobal {
+		return HaltScopeGlobal
+	}
+	return h.Scope + ":" + h.Target
+}
+
+// AuditEntry records a change to trading state
+type AuditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	Details   string    `json:"details"`
+}
+
+// ToJSON converts OrderCacheEntry to JSON at the current schema version
 func (e *OrderCacheEntry) ToJSON() ([]byte, error) {
+	e.Version = OrderCacheEntryVersion
 	return json.Marshal(e)
 }
 
-// FromJSON creates OrderCacheEntry from JSON
+// FromJSON creates OrderCacheEntry from JSON, upgrading older versions and checking the schema
 func (e *OrderCacheEntry) FromJSON(data []byte) error {
-	return json.Unmarshal(data, e)
+	entry, err := DecodeOrderCacheEntry(data)
+	if err != nil {
+		return err
+	}
+	*e = *entry
+	return nil
 }
 
 // GenerateOrderID generates a unique order ID
@@ -67,3 +214,16 @@ func GenerateOrderID(symbol string, scheduledTime time.Time) string {
 }
 
 
+
+// SlippageBps measures a fill against the planned price in basis points, signed so that
+// paying more on a buy or receiving less on a sell is positive
+func (o Order) SlippageBps(fillPrice float64) float64 {
+	if o.Price <= 0 {
+		return 0
+	}
+	slippage := (fillPrice - o.Price) / o.Price * 10000
+	if strings.EqualFold(o.Side, "Sell") {
+		slippage = -slippage
+	}
+	return slippage
+}
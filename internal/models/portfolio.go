@@ -0,0 +1,80 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Holding is a delivery holding in the demat account
+type Holding struct {
+	Exchange     string  `json:"exchange"`
+	Symbol       string  `json:"symbol"`
+	Quantity     int     `json:"quantity"`      // Settled quantity
+	T1Quantity   int     `json:"t1_quantity"`   // Bought on the previous day, not yet settled
+	UsedQuantity int     `json:"used_quantity"` // Already committed to sell orders today
+	AveragePrice float64 `json:"average_price"`
+	LastPrice    float64 `json:"last_price"`
+}
+
+// Sellable returns the quantity of the holding that can still be sold
+func (h Holding) Sellable() int {
+	if n := h.Quantity + h.T1Quantity - h.UsedQuantity; n > 0 {
+		return n
+	}
+	return 0
+}
+
+// Position is an open net position for the day
+type Position struct {
+	Exchange     string  `json:"exchange"`
+	Symbol       string  `json:"symbol"`
+	Product      string  `json:"product,omitempty"` // CNC, MIS or NRML; empty when the broker has no products
+	Quantity     int     `json:"quantity"`          // Net quantity; negative for short positions
+	AveragePrice float64 `json:"average_price"`
+	LastPrice    float64 `json:"last_price"`
+}
+
+// Portfolio is a broker's holdings and positions at a point in time
+type Portfolio struct {
+	Holdings  []Holding  `json:"holdings"`
+	Positions []Position `json:"positions"`
+	FetchedAt time.Time  `json:"fetched_at"`
+}
+
+// Available returns the quantity of symbol that a delivery sell can draw on: sellable
+// holdings plus today's long delivery positions. Short positions are not subtracted
+// because sells against holdings are already counted in the holdings' used quantity.
+func (p Portfolio) Available(symbol string) int {
+	symbol = PortfolioSymbol(symbol)
+	available := 0
+	for _, holding := range p.Holdings {
+		if PortfolioSymbol(holding.Symbol) == symbol {
+			available += holding.Sellable()
+		}
+	}
+	for _, position := range p.Positions {
+		if PortfolioSymbol(position.Symbol) != symbol || position.Quantity <= 0 {
+			continue
+		}
+		if position.Product == "" || strings.EqualFold(position.Product, "CNC") {
+			available += position.Quantity
+		}
+	}
+	return available
+}
+
+// PortfolioSymbol normalises a symbol for portfolio lookups. Holdings belong to the
+// instrument rather than the exchange, so any "EXCHANGE:" prefix is dropped.
+func PortfolioSymbol(symbol string) string {
+	if _, after, found := strings.Cut(symbol, ":"); found {
+		symbol = after
+	}
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
+
+// Funds is the margin an account has for new orders
+type Funds struct {
+	Available float64   `json:"available"` // Net margin available for new orders
+	Utilised  float64   `json:"utilised"`  // Margin blocked by open orders and positions
+	FetchedAt time.Time `json:"fetched_at"`
+}
@@ -0,0 +1,376 @@
+// Package notify delivers operational alerts (order outcomes, degraded health, rejected
+// broker tokens and the daily summary) to webhook, Slack, Telegram and email sinks.
+// Each event type is routed to sinks by rule, identical alerts are de-duplicated and
+// bursts of one event type are throttled into a single follow-up digest.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/metrics"
+)
+
+// Event types
+const (
+	EventOrderSuccess   = "order_success"   // An order was accepted by the broker
+	EventOrderFailure   = "order_failure"   // An order failed or was rejected and moved to the dead-letter queue
+	EventHealthDegraded = "health_degraded" // The cache or broker health check failed
+	EventTokenExpired   = "token_expired"   // The broker rejected the access token
+	EventDailySummary   = "daily_summary"   // End-of-day execution summary
+)
+
+// EventTypes lists every event type a rule can route
+var EventTypes = []string{EventOrderSuccess, EventOrderFailure, EventHealthDegraded, EventTokenExpired, EventDailySummary}
+
+// Sink names used in NOTIFY_RULES
+const (
+	SinkWebhook  = "webhook"
+	SinkSlack    = "slack"
+	SinkTelegram = "telegram"
+	SinkEmail    = "email"
+)
+
+// AllSinks in a rule routes an event type to every configured sink
+const AllSinks = "*"
+
+// queueSize bounds the number of alerts waiting for delivery
+const queueSize = 256
+
+// Event is a single alert
+type Event struct {
+	Type    string            `json:"type"`
+	Title   string            `json:"title"`
+	Message string            `json:"message,omitempty"`
+	Fields  map[string]string `json:"fields,omitempty"`
+	Key     string            `json:"key,omitempty"` // Identifies duplicates; defaults to the title and message
+	Time    time.Time         `json:"time"`
+}
+
+// icons prefix the text rendering of each event type
+var icons = map[string]string{
+	EventOrderSuccess:   "✅",
+	EventOrderFailure:   "❌",
+	EventHealthDegraded: "⚠️",
+	EventTokenExpired:   "🔑",
+	EventDailySummary:   "📊",
+}
+
+// Text renders the event as plain text for chat and email sinks
+func (e Event) Text() string {
+	var b strings.Builder
+	if icon, ok := icons[e.Type]; ok {
+		b.WriteString(icon + " ")
+	}
+	b.WriteString(e.Title)
+	if e.Message != "" {
+		b.WriteString("\n" + e.Message)
+	}
+
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\n%s: %s", k, e.Fields[k])
+	}
+	return b.String()
+}
+
+// dedupKey identifies identical alerts
+func (e Event) dedupKey() string {
+	if e.Key != "" {
+		return e.Type + "|" + e.Key
+	}
+	return e.Type + "|" + e.Title + "|" + e.Message
+}
+
+// Sink delivers alerts to one destination
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, event Event) error
+}
+
+// throttleState tracks alerts of one event type held back by the throttle
+type throttleState struct {
+	lastSent   time.Time
+	suppressed int
+	latest     Event
+}
+
+// Notifier routes events to sinks with de-duplication and throttling
+type Notifier struct {
+	sinks       map[string]Sink
+	rules       map[string][]string
+	throttle    time.Duration
+	dedupWindow time.Duration
+	timeout     time.Duration
+	logger      *logger.Logger
+	clock       clock.Clock
+	queue       chan Event
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // Last delivery per dedup key
+	throttled map[string]*throttleState
+}
+
+// NewNotifier creates a notifier with a sink for every destination set in the configuration.
+// Without any configured sink the notifier discards every event.
+func NewNotifier(cfg *config.Config, log *logger.Logger) *Notifier {
+	n := &Notifier{
+		sinks:       make(map[string]Sink),
+		rules:       cfg.Notify.Rules,
+		throttle:    cfg.Notify.Throttle,
+		dedupWindow: cfg.Notify.DedupWindow,
+		timeout:     cfg.Notify.Timeout,
+		logger:      log,
+		clock:       clock.Real{},
+		queue:       make(chan Event, queueSize),
+		seen:        make(map[string]time.Time),
+		throttled:   make(map[string]*throttleState),
+	}
+	if n.timeout <= 0 {
+		n.timeout = 10 * time.Second
+	}
+
+	if cfg.Notify.WebhookURL != "" {
+		n.AddSink(NewWebhookSink(cfg.Notify.WebhookURL))
+	}
+	if cfg.Notify.SlackWebhookURL != "" {
+		n.AddSink(NewSlackSink(cfg.Notify.SlackWebhookURL))
+	}
+	if cfg.Notify.TelegramBotToken != "" && cfg.Notify.TelegramChatID != "" {
+		n.AddSink(NewTelegramSink(cfg.Notify.TelegramAPIURL, cfg.Notify.TelegramBotToken, cfg.Notify.TelegramChatID))
+	}
+	if cfg.Notify.SMTPAddr != "" && len(cfg.Notify.SMTPTo) > 0 {
+		n.AddSink(NewSMTPSink(cfg.Notify.SMTPAddr, cfg.Notify.SMTPUsername, cfg.Notify.SMTPPassword, cfg.Notify.SMTPFrom, cfg.Notify.SMTPTo))
+	}
+
+	for event, sinks := range n.rules {
+		if !knownEventType(event) {
+			log.Warn("Notification rule for unknown event type %q is ignored", event)
+		}
+		for _, name := range sinks {
+			if name != AllSinks && n.sinks[name] == nil {
+				log.Warn("Notification rule for %s names sink %q, which is not configured", event, name)
+			}
+		}
+	}
+	return n
+}
+
+// knownEventType reports whether eventType is one of EventTypes
+func knownEventType(eventType string) bool {
+	for _, known := range EventTypes {
+		if known == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// AddSink registers a sink, replacing any sink with the same name
+func (n *Notifier) AddSink(sink Sink) {
+	n.sinks[sink.Name()] = sink
+}
+
+// SetClock replaces the clock used to timestamp events and apply throttling
+func (n *Notifier) SetClock(c clock.Clock) {
+	n.clock = c
+}
+
+// Enabled reports whether any sink is configured
+func (n *Notifier) Enabled() bool {
+	return len(n.sinks) > 0
+}
+
+// Notify queues an event for delivery by Run without blocking. Events are dropped when
+// no sink is configured or the queue is full.
+func (n *Notifier) Notify(event Event) {
+	if !n.Enabled() {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = n.clock.Now()
+	}
+	select {
+	case n.queue <- event:
+	default:
+		metrics.NotificationsSuppressed.WithLabelValues(event.Type, "dropped").Inc()
+		n.logger.Warn("Notification queue full, dropping %s alert: %s", event.Type, event.Title)
+	}
+}
+
+// Run delivers queued events until ctx is cancelled, then flushes what is left in the
+// queue. It also sends a digest of throttled alerts once their throttle window ends.
+func (n *Notifier) Run(ctx context.Context) {
+	if !n.Enabled() {
+		return
+	}
+
+	flushInterval := n.throttle
+	if flushInterval <= 0 || flushInterval > time.Minute {
+		flushInterval = time.Minute
+	}
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	// Alerts raised just before shutdown still go out; each send is bounded by the timeout
+	sendCtx := context.WithoutCancel(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case event := <-n.queue:
+					n.Deliver(sendCtx, event)
+				default:
+					return
+				}
+			}
+		case event := <-n.queue:
+			n.Deliver(sendCtx, event)
+		case <-ticker.C:
+			n.FlushThrottled(sendCtx)
+		}
+	}
+}
+
+// Deliver applies de-duplication and throttling and sends the event to the sinks its
+// rule names. It returns the delivery errors, if any.
+func (n *Notifier) Deliver(ctx context.Context, event Event) error {
+	if event.Time.IsZero() {
+		event.Time = n.clock.Now()
+	}
+	sinks := n.route(event.Type)
+	if len(sinks) == 0 {
+		return nil
+	}
+	if !n.admit(event) {
+		return nil
+	}
+	return n.send(ctx, event, sinks)
+}
+
+// FlushThrottled sends one digest per event type whose alerts were held back by the
+// throttle and whose throttle window has ended
+func (n *Notifier) FlushThrottled(ctx context.Context) error {
+	now := n.clock.Now()
+	var digests []Event
+
+	n.mu.Lock()
+	for eventType, state := range n.throttled {
+		if state.suppressed == 0 || now.Sub(state.lastSent) < n.throttle {
+			continue
+		}
+		digests = append(digests, Event{
+			Type:    eventType,
+			Title:   fmt.Sprintf("%d more %s alerts since %s", state.suppressed, eventType, state.lastSent.Format("15:04:05")),
+			Message: "Latest: " + state.latest.Title,
+			Fields:  state.latest.Fields,
+			Time:    now,
+		})
+		state.lastSent = now
+		state.suppressed = 0
+	}
+	n.mu.Unlock()
+
+	var errs []error
+	for _, digest := range digests {
+		if err := n.send(ctx, digest, n.route(digest.Type)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// route returns the sinks an event type is sent to
+func (n *Notifier) route(eventType string) []Sink {
+	var sinks []Sink
+	for _, name := range n.rules[eventType] {
+		if name == AllSinks {
+			sinks = sinks[:0]
+			for _, sinkName := range n.sinkNames() {
+				sinks = append(sinks, n.sinks[sinkName])
+			}
+			return sinks
+		}
+		if sink, ok := n.sinks[name]; ok {
+			sinks = append(sinks, sink)
+		}
+	}
+	return sinks
+}
+
+// sinkNames returns the configured sink names in a stable order
+func (n *Notifier) sinkNames() []string {
+	names := make([]string, 0, len(n.sinks))
+	for name := range n.sinks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// admit decides whether an event is delivered now. Duplicates within the dedup window are
+// dropped; further alerts of a type within its throttle window are counted for the digest.
+func (n *Notifier) admit(event Event) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := event.Time
+	key := event.dedupKey()
+	if last, ok := n.seen[key]; ok && n.dedupWindow > 0 && now.Sub(last) < n.dedupWindow {
+		metrics.NotificationsSuppressed.WithLabelValues(event.Type, "duplicate").Inc()
+		return false
+	}
+
+	state := n.throttled[event.Type]
+	if state == nil {
+		state = &throttleState{}
+		n.throttled[event.Type] = state
+	}
+	if n.throttle > 0 && !state.lastSent.IsZero() && now.Sub(state.lastSent) < n.throttle {
+		state.suppressed++
+		state.latest = event
+		metrics.NotificationsSuppressed.WithLabelValues(event.Type, "throttled").Inc()
+		return false
+	}
+
+	state.lastSent = now
+	n.seen[key] = now
+	for k, seenAt := range n.seen {
+		if now.Sub(seenAt) >= n.dedupWindow {
+			delete(n.seen, k)
+		}
+	}
+	return true
+}
+
+// send delivers an event to each sink, bounding every attempt by the notifier timeout
+func (n *Notifier) send(ctx context.Context, event Event, sinks []Sink) error {
+	var errs []error
+	for _, sink := range sinks {
+		sendCtx, cancel := context.WithTimeout(ctx, n.timeout)
+		err := sink.Send(sendCtx, event)
+		cancel()
+		if err != nil {
+			metrics.Notifications.WithLabelValues(sink.Name(), "failed").Inc()
+			n.logger.Warn("📣 Failed to send %s alert to %s: %v", event.Type, sink.Name(), err)
+			errs = append(errs, fmt.Errorf("%s: %w", sink.Name(), err))
+			continue
+		}
+		metrics.Notifications.WithLabelValues(sink.Name(), "sent").Inc()
+		n.logger.Debug("📣 Sent %s alert to %s: %s", event.Type, sink.Name(), event.Title)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,237 @@
+package notify
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/notify/notifytest"
+)
+
+const testBotToken = "123456:test-bot-token"
+
+var testStart = time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)
+
+// newTestNotifier returns a notifier wired to fresh HTTP and SMTP stand-ins with every sink enabled
+func newTestNotifier(t *testing.T, rules map[string][]string) (*Notifier, *notifytest.Server, *notifytest.SMTPServer, *clock.Simulated) {
+	t.Helper()
+	log, err := logger.NewLogger("error", filepath.Join(t.TempDir(), "notify.log"))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	server := notifytest.NewServer()
+	t.Cleanup(server.Close)
+	smtpServer, err := notifytest.NewSMTPServer()
+	if err != nil {
+		t.Fatalf("failed to start SMTP stand-in: %v", err)
+	}
+	t.Cleanup(smtpServer.Close)
+
+	cfg := &config.Config{}
+	cfg.Notify.WebhookURL = server.WebhookURL()
+	cfg.Notify.SlackWebhookURL = server.SlackURL()
+	cfg.Notify.TelegramAPIURL = server.URL
+	cfg.Notify.TelegramBotToken = testBotToken
+	cfg.Notify.TelegramChatID = "-1001"
+	cfg.Notify.SMTPAddr = smtpServer.Addr()
+	cfg.Notify.SMTPUsername = "alerts"
+	cfg.Notify.SMTPPassword = "secret"
+	cfg.Notify.SMTPFrom = "alerts@example.com"
+	cfg.Notify.SMTPTo = []string{"ops@example.com", "desk@example.com"}
+	cfg.Notify.Rules = rules
+	cfg.Notify.Throttle = time.Minute
+	cfg.Notify.DedupWindow = 10 * time.Minute
+	cfg.Notify.Timeout = 5 * time.Second
+
+	sim := clock.NewSimulated(testStart)
+	n := NewNotifier(cfg, log)
+	n.SetClock(sim)
+	return n, server, smtpServer, sim
+}
+
+func orderFailure(orderID string) Event {
+	return Event{
+		Type:    EventOrderFailure,
+		Title:   "Order " + orderID + " failed",
+		Message: "broker returned HTTP 500",
+		Fields:  map[string]string{"Symbol": "RELIANCE", "Side": "BUY"},
+		Key:     orderID,
+	}
+}
+
+func TestEventIsDeliveredToEverySink(t *testing.T) {
+	n, server, smtpServer, _ := newTestNotifier(t, map[string][]string{EventOrderFailure: {AllSinks}})
+
+	if err := n.Deliver(context.Background(), orderFailure("ORD-1")); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	webhooks := server.Requests(notifytest.PathWebhook)
+	if len(webhooks) != 1 {
+		t.Fatalf("webhook received %d requests, want 1", len(webhooks))
+	}
+	var event Event
+	if err := webhooks[0].JSON(&event); err != nil {
+		t.Fatalf("webhook body is not an event: %v", err)
+	}
+	if event.Type != EventOrderFailure || event.Key != "ORD-1" || event.Fields["Symbol"] != "RELIANCE" || !event.Time.Equal(testStart) {
+		t.Errorf("webhook event = %+v", event)
+	}
+
+	slack := server.Requests(notifytest.PathSlack)
+	if len(slack) != 1 {
+		t.Fatalf("Slack received %d requests, want 1", len(slack))
+	}
+	var slackBody map[string]string
+	slack[0].JSON(&slackBody)
+	if !strings.Contains(slackBody["text"], "Order ORD-1 failed") || !strings.Contains(slackBody["text"], "Symbol: RELIANCE") {
+		t.Errorf("Slack text = %q", slackBody["text"])
+	}
+
+	telegram := server.Requests(notifytest.TelegramPath(testBotToken))
+	if len(telegram) != 1 {
+		t.Fatalf("Telegram received %d requests, want 1", len(telegram))
+	}
+	var telegramBody map[string]string
+	telegram[0].JSON(&telegramBody)
+	if telegramBody["chat_id"] != "-1001" || !strings.Contains(telegramBody["text"], "broker returned HTTP 500") {
+		t.Errorf("Telegram body = %v", telegramBody)
+	}
+
+	mails := smtpServer.Mails()
+	if len(mails) != 1 {
+		t.Fatalf("SMTP received %d mails, want 1", len(mails))
+	}
+	mail := mails[0]
+	if mail.From != "alerts@example.com" || len(mail.To) != 2 || mail.Auth != "\x00alerts\x00secret" {
+		t.Errorf("mail envelope = from %q to %v auth %q", mail.From, mail.To, mail.Auth)
+	}
+	if !strings.Contains(mail.Data, "Subject: [trading-system] Order ORD-1 failed\r\n") {
+		t.Errorf("mail is missing the subject:\n%s", mail.Data)
+	}
+}
+
+func TestRulesRouteEventsToNamedSinks(t *testing.T) {
+	n, server, smtpServer, _ := newTestNotifier(t, map[string][]string{
+		EventTokenExpired: {SinkTelegram, SinkEmail},
+	})
+	ctx := context.Background()
+
+	n.Deliver(ctx, Event{Type: EventTokenExpired, Title: "Kite access token rejected"})
+	n.Deliver(ctx, Event{Type: EventOrderSuccess, Title: "Order ORD-1 executed"}) // No rule: not sent
+
+	if got := len(server.Requests(notifytest.TelegramPath(testBotToken))); got != 1 {
+		t.Errorf("Telegram received %d requests, want 1", got)
+	}
+	if got := len(smtpServer.Mails()); got != 1 {
+		t.Errorf("SMTP received %d mails, want 1", got)
+	}
+	if got := len(server.Requests(notifytest.PathWebhook)) + len(server.Requests(notifytest.PathSlack)); got != 0 {
+		t.Errorf("webhook and Slack received %d requests, want 0", got)
+	}
+}
+
+func TestDuplicateAndThrottledAlertsAreSuppressed(t *testing.T) {
+	n, server, _, sim := newTestNotifier(t, map[string][]string{
+		EventOrderFailure:   {SinkWebhook},
+		EventHealthDegraded: {SinkWebhook},
+	})
+	ctx := context.Background()
+	webhookCount := func() int { return len(server.Requests(notifytest.PathWebhook)) }
+
+	n.Deliver(ctx, orderFailure("ORD-1"))
+	n.Deliver(ctx, orderFailure("ORD-1")) // Duplicate
+	if got := webhookCount(); got != 1 {
+		t.Fatalf("webhook received %d requests after a duplicate, want 1", got)
+	}
+
+	// Other failures within the throttle window are held back for the digest
+	sim.Advance(10 * time.Second)
+	n.Deliver(ctx, orderFailure("ORD-2"))
+	n.Deliver(ctx, orderFailure("ORD-3"))
+	// The throttle is per event type
+	n.Deliver(ctx, Event{Type: EventHealthDegraded, Title: "Cache health check failed", Key: "cache"})
+	if got := webhookCount(); got != 2 {
+		t.Fatalf("webhook received %d requests within the throttle window, want 2", got)
+	}
+
+	// Nothing is flushed until the window ends
+	n.FlushThrottled(ctx)
+	if got := webhookCount(); got != 2 {
+		t.Fatalf("digest sent before the throttle window ended")
+	}
+
+	sim.Advance(time.Minute)
+	if err := n.FlushThrottled(ctx); err != nil {
+		t.Fatalf("FlushThrottled: %v", err)
+	}
+	requests := server.Requests(notifytest.PathWebhook)
+	if len(requests) != 3 {
+		t.Fatalf("webhook received %d requests after the flush, want 3", len(requests))
+	}
+	var digest Event
+	requests[2].JSON(&digest)
+	if digest.Type != EventOrderFailure || !strings.HasPrefix(digest.Title, "2 more order_failure alerts") || !strings.Contains(digest.Message, "ORD-3") {
+		t.Errorf("digest = %+v", digest)
+	}
+
+	// The duplicate window outlasts the throttle
+	sim.Advance(2 * time.Minute)
+	n.Deliver(ctx, orderFailure("ORD-1"))
+	if got := webhookCount(); got != 3 {
+		t.Errorf("duplicate within the dedup window was delivered")
+	}
+	sim.Advance(10 * time.Minute)
+	n.Deliver(ctx, orderFailure("ORD-1"))
+	if got := webhookCount(); got != 4 {
+		t.Errorf("alert after the dedup window was not delivered")
+	}
+}
+
+func TestFailedDeliveryIsReported(t *testing.T) {
+	n, server, _, _ := newTestNotifier(t, map[string][]string{EventHealthDegraded: {SinkSlack, SinkTelegram}})
+	server.FailNext(2)
+
+	err := n.Deliver(context.Background(), Event{Type: EventHealthDegraded, Title: "Broker health check failed"})
+	if err == nil {
+		t.Fatal("Deliver succeeded although both sinks failed")
+	}
+	if !strings.Contains(err.Error(), "slack") || !strings.Contains(err.Error(), "telegram") {
+		t.Errorf("error %q does not name both sinks", err)
+	}
+	if strings.Contains(err.Error(), testBotToken) {
+		t.Errorf("error %q leaks the bot token", err)
+	}
+}
+
+func TestRunDeliversQueuedEventsBeforeStopping(t *testing.T) {
+	n, server, _, _ := newTestNotifier(t, map[string][]string{EventOrderFailure: {SinkWebhook}})
+	n.throttle = 0
+
+	n.Notify(orderFailure("ORD-1"))
+	n.Notify(orderFailure("ORD-2"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		n.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+	if got := len(server.Requests(notifytest.PathWebhook)); got != 2 {
+		t.Errorf("webhook received %d requests, want 2", got)
+	}
+}
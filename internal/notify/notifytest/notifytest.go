@@ -0,0 +1,245 @@
+// Package notifytest provides local stand-ins for the notification sinks: an HTTP server
+// that accepts generic webhook, Slack and Telegram Bot API requests, and a minimal SMTP
+// server. Both record what they receive and the HTTP server can be told to fail.
+package notifytest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// Paths served by the HTTP stand-in
+const (
+	PathWebhook = "/webhook"
+	PathSlack   = "/slack"
+)
+
+// Request is a request received by the HTTP stand-in
+type Request struct {
+	Path string
+	Body []byte
+}
+
+// JSON decodes the request body into v
+func (r Request) JSON(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Server is an HTTP stand-in for the webhook, Slack and Telegram sinks
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []Request
+	failures int // Remaining requests to answer with HTTP 500
+}
+
+// NewServer starts an HTTP stand-in. Telegram requests are accepted on /bot<token>/sendMessage.
+// Callers must Close it, typically with t.Cleanup(server.Close).
+func NewServer() *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// WebhookURL returns the generic webhook endpoint
+func (s *Server) WebhookURL() string { return s.URL + PathWebhook }
+
+// SlackURL returns the Slack incoming webhook endpoint
+func (s *Server) SlackURL() string { return s.URL + PathSlack }
+
+// TelegramPath returns the sendMessage path for a bot token
+func TelegramPath(token string) string { return "/bot" + token + "/sendMessage" }
+
+// FailNext answers the next n requests with HTTP 500
+func (s *Server) FailNext(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = n
+}
+
+// Requests returns the requests received on path
+func (s *Server) Requests(path string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var requests []Request
+	for _, r := range s.requests {
+		if r.Path == path {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	fail := s.failures > 0
+	if fail {
+		s.failures--
+	}
+	s.requests = append(s.requests, Request{Path: r.URL.Path, Body: body})
+	s.mu.Unlock()
+
+	isTelegram := strings.HasPrefix(r.URL.Path, "/bot") && strings.HasSuffix(r.URL.Path, "/sendMessage")
+	if fail {
+		if isTelegram {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"ok":false,"error_code":500,"description":"Internal Server Error"}`)
+			return
+		}
+		http.Error(w, "simulated failure", http.StatusInternalServerError)
+		return
+	}
+
+	if isTelegram {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ok":true,"result":{"message_id":1}}`)
+		return
+	}
+	fmt.Fprint(w, "ok")
+}
+
+// Mail is a message received by the SMTP stand-in
+type Mail struct {
+	From string
+	To   []string
+	Data string // Headers and body as sent, with CRLF line endings
+	Auth string // Decoded AUTH PLAIN credentials ("\x00user\x00password"), empty without auth
+}
+
+// SMTPServer is a minimal SMTP stand-in. It does not offer STARTTLS and accepts any
+// AUTH PLAIN credentials.
+type SMTPServer struct {
+	listener net.Listener
+
+	mu    sync.Mutex
+	mails []Mail
+	wg    sync.WaitGroup
+}
+
+// NewSMTPServer starts an SMTP stand-in on a local port. Callers must Close it.
+func NewSMTPServer() (*SMTPServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &SMTPServer{listener: listener}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the host:port the server listens on
+func (s *SMTPServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Mails returns the messages received so far
+func (s *SMTPServer) Mails() []Mail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Mail(nil), s.mails...)
+}
+
+// Close stops the server and waits for open sessions to end
+func (s *SMTPServer) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *SMTPServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.session(conn)
+		}()
+	}
+}
+
+// session speaks just enough SMTP for net/smtp clients
+func (s *SMTPServer) session(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+	var mail Mail
+	reply("220 localhost ESMTP notifytest")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(verb, "EHLO"):
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case strings.HasPrefix(verb, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(verb, "AUTH PLAIN"):
+			mail.Auth = decodePlain(strings.TrimSpace(line[len("AUTH PLAIN"):]))
+			reply("235 2.7.0 Authentication successful")
+		case strings.HasPrefix(verb, "MAIL FROM:"):
+			mail.From = strings.Trim(line[len("MAIL FROM:"):], "<> ")
+			reply("250 OK")
+		case strings.HasPrefix(verb, "RCPT TO:"):
+			mail.To = append(mail.To, strings.Trim(line[len("RCPT TO:"):], "<> "))
+			reply("250 OK")
+		case verb == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			mail.Data = data.String()
+			s.mu.Lock()
+			s.mails = append(s.mails, mail)
+			s.mu.Unlock()
+			mail = Mail{Auth: mail.Auth}
+			reply("250 OK: queued")
+		case verb == "RSET":
+			mail = Mail{Auth: mail.Auth}
+			reply("250 OK")
+		case verb == "NOOP":
+			reply("250 OK")
+		case verb == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// decodePlain decodes an AUTH PLAIN initial response
+func decodePlain(encoded string) string {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return ""
+	}
+	return string(decoded)
+}
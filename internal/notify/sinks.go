@@ -0,0 +1,205 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// postJSON posts body as JSON and fails on any non-2xx response
+func postJSON(ctx context.Context, client *http.Client, url string, body interface{}) ([]byte, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return respBody, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
+
+// WebhookSink posts each event as JSON to a generic webhook
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink creates a sink posting to url
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{url: url, client: &http.Client{}}
+}
+
+// Name returns the sink name used in rules
+func (s *WebhookSink) Name() string { return SinkWebhook }
+
+// Send posts the event
+func (s *WebhookSink) Send(ctx context.Context, event Event) error {
+	_, err := postJSON(ctx, s.client, s.url, event)
+	return err
+}
+
+// SlackSink posts events to a Slack incoming webhook
+type SlackSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewSlackSink creates a sink posting to a Slack incoming webhook URL
+func NewSlackSink(url string) *SlackSink {
+	return &SlackSink{url: url, client: &http.Client{}}
+}
+
+// Name returns the sink name used in rules
+func (s *SlackSink) Name() string { return SinkSlack }
+
+// Send posts the event text
+func (s *SlackSink) Send(ctx context.Context, event Event) error {
+	_, err := postJSON(ctx, s.client, s.url, map[string]string{"text": event.Text()})
+	return err
+}
+
+// TelegramSink sends events through the Telegram Bot API
+type TelegramSink struct {
+	apiURL string
+	token  string
+	chatID string
+	client *http.Client
+}
+
+// NewTelegramSink creates a sink messaging chatID through the bot identified by token
+func NewTelegramSink(apiURL, token, chatID string) *TelegramSink {
+	return &TelegramSink{apiURL: strings.TrimRight(apiURL, "/"), token: token, chatID: chatID, client: &http.Client{}}
+}
+
+// Name returns the sink name used in rules
+func (s *TelegramSink) Name() string { return SinkTelegram }
+
+// Send calls sendMessage with the event text
+func (s *TelegramSink) Send(ctx context.Context, event Event) error {
+	url := fmt.Sprintf("%s/bot%s/sendMessage", s.apiURL, s.token)
+	body, err := postJSON(ctx, s.client, url, map[string]string{"chat_id": s.chatID, "text": event.Text()})
+	if err != nil {
+		// Never surface the bot token in logs
+		return fmt.Errorf("sendMessage failed: %s", strings.ReplaceAll(err.Error(), s.token, "***"))
+	}
+
+	var resp struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("invalid sendMessage response: %w", err)
+	}
+	if !resp.OK {
+		return fmt.Errorf("sendMessage failed: %s", resp.Description)
+	}
+	return nil
+}
+
+// SMTPSink emails events. STARTTLS is used whenever the server offers it, and PLAIN
+// authentication when a username is set.
+type SMTPSink struct {
+	addr     string
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+// NewSMTPSink creates a sink sending mail from from to each address in to through addr
+func NewSMTPSink(addr, username, password, from string, to []string) *SMTPSink {
+	return &SMTPSink{addr: addr, username: username, password: password, from: from, to: to}
+}
+
+// Name returns the sink name used in rules
+func (s *SMTPSink) Name() string { return SinkEmail }
+
+// Send delivers the event as a plain-text email
+func (s *SMTPSink) Send(ctx context.Context, event Event) error {
+	host, _, err := net.SplitHostPort(s.addr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address %q: %w", s.addr, err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, host)); err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.from); err != nil {
+		return err
+	}
+	for _, rcpt := range s.to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.message(event)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message builds the RFC 5322 message for an event
+func (s *SMTPSink) message(event Event) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&b, "Subject: [trading-system] %s\r\n", strings.ReplaceAll(event.Title, "\n", " "))
+	fmt.Fprintf(&b, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(event.Text(), "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,96 @@
+// Package pricing puts limit prices where the exchange accepts them: on the instrument's
+// tick grid and inside the day's circuit band. Prices only ever move in the order's favour,
+// down for buys and up for sells; a price that could only be fixed by moving it the other
+// way is rejected instead.
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// ErrOutsideBand is returned for limit prices outside the day's circuit band that the band
+// policy does not move inside it
+var ErrOutsideBand = errors.New("price outside circuit band")
+
+// Band is an instrument's circuit band for the day; a zero bound is unknown
+type Band struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+}
+
+// epsilon absorbs floating point error when deciding whether a price is already on the grid
+const epsilon = 1e-9
+
+// RoundToTick rounds price onto the tick grid, down for buys and up for sells. A zero tick
+// size leaves the price unchanged.
+func RoundToTick(price, tickSize float64, side string) float64 {
+	if tickSize <= 0 {
+		return price
+	}
+	ticks := price / tickSize
+	if isSell(side) {
+		ticks = math.Ceil(ticks - epsilon)
+	} else {
+		ticks = math.Floor(ticks + epsilon)
+	}
+	return math.Round(ticks*tickSize*10000) / 10000
+}
+
+// Normalize adjusts a LIMIT order's price to tickSize and band, recording each change in
+// order.PriceAdjustments. Under the adjust policy a buy above the band is lowered to its
+// upper limit and a sell below it raised to its lower limit; a buy below or a sell above the
+// band is rejected. The reject policy rejects every price outside the band, and off (or an
+// empty policy) ignores the band.
+func Normalize(order *models.Order, tickSize float64, band Band, policy string, at time.Time) error {
+	if !strings.EqualFold(order.OrderType, "LIMIT") || order.Price <= 0 {
+		return nil
+	}
+
+	if rounded := RoundToTick(order.Price, tickSize, order.Side); rounded != order.Price {
+		adjust(order, models.PriceAdjustmentTick, rounded, at)
+	}
+
+	if policy == "" || policy == config.PriceBandPolicyOff {
+		return nil
+	}
+	below := band.Lower > 0 && order.Price < band.Lower-epsilon
+	above := band.Upper > 0 && order.Price > band.Upper+epsilon
+	if !below && !above {
+		return nil
+	}
+
+	if policy == config.PriceBandPolicyAdjust {
+		switch {
+		case above && !isSell(order.Side):
+			adjust(order, models.PriceAdjustmentCircuit, band.Upper, at)
+			return nil
+		case below && isSell(order.Side):
+			adjust(order, models.PriceAdjustmentCircuit, band.Lower, at)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s %.2f is outside %.2f-%.2f", ErrOutsideBand, strings.ToLower(order.Side), order.Price, band.Lower, band.Upper)
+}
+
+// adjust changes the order's price and records why
+func adjust(order *models.Order, reason string, price float64, at time.Time) {
+	order.PriceAdjustments = append(order.PriceAdjustments, models.PriceAdjustment{
+		Reason: reason,
+		From:   order.Price,
+		To:     price,
+		At:     at,
+	})
+	order.Price = price
+}
+
+// isSell reports whether side is a sell
+func isSell(side string) bool {
+	return strings.EqualFold(side, "SELL")
+}
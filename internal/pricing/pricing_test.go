@@ -0,0 +1,83 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+func TestRoundToTick(t *testing.T) {
+	tests := []struct {
+		price, tick float64
+		side        string
+		want        float64
+	}{
+		{123.456, 0.05, "Buy", 123.45},
+		{123.456, 0.05, "Sell", 123.5},
+		{123.45, 0.05, "Sell", 123.45}, // Already on the grid
+		{100, 0.05, "Buy", 100},
+		{82.1234, 0.0025, "Buy", 82.1225},
+		{10.123, 0, "Sell", 10.123},
+	}
+	for _, tt := range tests {
+		if got := RoundToTick(tt.price, tt.tick, tt.side); got != tt.want {
+			t.Errorf("RoundToTick(%v, %v, %s) = %v, want %v", tt.price, tt.tick, tt.side, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	at := time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC)
+	band := Band{Lower: 90, Upper: 110}
+
+	tests := []struct {
+		side, policy string
+		price        float64
+		want         float64 // Price after normalising; 0 = rejected
+		adjustments  []string
+	}{
+		{"Buy", config.PriceBandPolicyAdjust, 100.03, 100, []string{models.PriceAdjustmentTick}},
+		{"Buy", config.PriceBandPolicyAdjust, 120.02, 110, []string{models.PriceAdjustmentTick, models.PriceAdjustmentCircuit}},
+		{"Buy", config.PriceBandPolicyAdjust, 80, 0, nil},
+		{"Sell", config.PriceBandPolicyAdjust, 80, 90, []string{models.PriceAdjustmentCircuit}},
+		{"Sell", config.PriceBandPolicyAdjust, 120, 0, nil},
+		{"Buy", config.PriceBandPolicyReject, 120, 0, nil},
+		{"Buy", config.PriceBandPolicyOff, 120, 120, nil},
+		{"Sell", "", 80.01, 80.05, []string{models.PriceAdjustmentTick}},
+	}
+	for _, tt := range tests {
+		order := models.Order{ID: "ORD", Side: tt.side, OrderType: "LIMIT", Price: tt.price}
+		err := Normalize(&order, 0.05, band, tt.policy, at)
+		if tt.want == 0 {
+			if !errors.Is(err, ErrOutsideBand) {
+				t.Errorf("%s %v (%s): err = %v, want ErrOutsideBand", tt.side, tt.price, tt.policy, err)
+			}
+			continue
+		}
+		if err != nil || order.Price != tt.want {
+			t.Errorf("%s %v (%s) = %v, %v; want %v", tt.side, tt.price, tt.policy, order.Price, err, tt.want)
+		}
+		var reasons []string
+		for _, adjustment := range order.PriceAdjustments {
+			reasons = append(reasons, adjustment.Reason)
+		}
+		if len(reasons) != len(tt.adjustments) {
+			t.Errorf("%s %v (%s) adjustments = %v, want %v", tt.side, tt.price, tt.policy, reasons, tt.adjustments)
+			continue
+		}
+		for i := range reasons {
+			if reasons[i] != tt.adjustments[i] {
+				t.Errorf("%s %v (%s) adjustments = %v, want %v", tt.side, tt.price, tt.policy, reasons, tt.adjustments)
+				break
+			}
+		}
+	}
+
+	market := models.Order{Side: "Buy", OrderType: "MARKET", Price: 120.02}
+	if err := Normalize(&market, 0.05, band, config.PriceBandPolicyReject, at); err != nil || len(market.PriceAdjustments) != 0 {
+		t.Errorf("market order normalised: %v, %+v", err, market.PriceAdjustments)
+	}
+}
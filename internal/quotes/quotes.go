@@ -0,0 +1,165 @@
+// Package quotes keeps the market data conditional orders are checked against: the last
+// price, the day's open and the volume traded so far, polled from the broker for the
+// instruments that have armed orders.
+package quotes
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+)
+
+// Defaults used when the configuration does not set them
+const (
+	DefaultPollInterval = time.Second
+	DefaultMaxAge       = 10 * time.Second
+)
+
+// unsubscribeAfter is how long an instrument stays subscribed after it was last asked about
+const unsubscribeAfter = time.Minute
+
+// Quote is an instrument's market data at a point in time
+type Quote struct {
+	LastPrice float64   `json:"last_price"`
+	Open      float64   `json:"open"`   // Day's opening price; 0 before the open is known
+	Volume    int64     `json:"volume"` // Traded so far today
+	At        time.Time `json:"at"`     // When the quote was fetched
+}
+
+// Source is implemented by anything that can quote instruments keyed EXCHANGE:SYMBOL, such as
+// the broker's quote API or a streaming feed. Instruments it cannot quote are left out.
+type Source interface {
+	Quotes(ctx context.Context, instruments []string) (map[string]Quote, error)
+}
+
+// subscription is an instrument the feed polls
+type subscription struct {
+	wantedAt time.Time // Last time the instrument was asked about
+	polled   bool      // Whether a poll has included it yet
+}
+
+// Feed polls a Source for every subscribed instrument at most once per poll interval and
+// serves the latest quotes in between
+type Feed struct {
+	source       Source
+	pollInterval time.Duration
+	maxAge       time.Duration
+	clock        clock.Clock
+	logger       *logger.Logger
+	mu           sync.Mutex
+	subscribed   map[string]*subscription
+	quotes       map[string]Quote
+	lastPoll     time.Time
+	lastErr      error
+}
+
+// NewFeed creates a feed polling source
+func NewFeed(cfg *config.Config, source Source, log *logger.Logger) *Feed {
+	pollInterval := cfg.Trigger.Quotes.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
+	maxAge := cfg.Trigger.Quotes.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	return &Feed{
+		source:       source,
+		pollInterval: pollInterval,
+		maxAge:       maxAge,
+		clock:        clock.Real{},
+		logger:       log,
+		subscribed:   make(map[string]*subscription),
+		quotes:       make(map[string]Quote),
+	}
+}
+
+// SetClock replaces the clock used to pace polls and age quotes
+func (f *Feed) SetClock(c clock.Clock) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clock = c
+}
+
+// Quotes subscribes to instruments and returns their latest quotes no older than the feed's
+// maximum age. The source is polled, for every subscribed instrument, when the poll interval
+// has passed or an instrument has not been polled yet. If that poll fails its error is
+// returned along with whatever fresh quotes the feed still holds.
+func (f *Feed) Quotes(ctx context.Context, instruments []string) (map[string]Quote, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := f.clock.Now()
+	due := now.Sub(f.lastPoll) >= f.pollInterval
+	for _, instrument := range instruments {
+		instrument = strings.ToUpper(instrument)
+		sub, ok := f.subscribed[instrument]
+		if !ok {
+			sub = &subscription{}
+			f.subscribed[instrument] = sub
+		}
+		sub.wantedAt = now
+		if !sub.polled {
+			due = true
+		}
+	}
+
+	var err error
+	if due {
+		err = f.poll(ctx, now)
+	}
+
+	result := make(map[string]Quote, len(instruments))
+	for _, instrument := range instruments {
+		instrument = strings.ToUpper(instrument)
+		if quote, ok := f.quotes[instrument]; ok && now.Sub(quote.At) <= f.maxAge {
+			result[instrument] = quote
+		}
+	}
+	return result, err
+}
+
+// poll fetches quotes for every subscribed instrument and drops instruments nobody has asked
+// about lately; callers must hold f.mu
+func (f *Feed) poll(ctx context.Context, now time.Time) error {
+	var instruments []string
+	for instrument, sub := range f.subscribed {
+		if now.Sub(sub.wantedAt) > unsubscribeAfter {
+			delete(f.subscribed, instrument)
+			delete(f.quotes, instrument)
+			continue
+		}
+		sub.polled = true
+		instruments = append(instruments, instrument)
+	}
+	f.lastPoll = now
+	if len(instruments) == 0 {
+		return nil
+	}
+
+	quotes, err := f.source.Quotes(ctx, instruments)
+	if err != nil {
+		// Log once per outage rather than on every poll
+		if f.lastErr == nil {
+			f.logger.Warn("⚠️  Quote poll for %d instruments failed: %v", len(instruments), err)
+		}
+		f.lastErr = err
+		return err
+	}
+	if f.lastErr != nil {
+		f.logger.Info("📡 Quote polling recovered")
+		f.lastErr = nil
+	}
+	for instrument, quote := range quotes {
+		if quote.At.IsZero() {
+			quote.At = now
+		}
+		f.quotes[strings.ToUpper(instrument)] = quote
+	}
+	return nil
+}
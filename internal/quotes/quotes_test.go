@@ -0,0 +1,80 @@
+package quotes
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+)
+
+// fakeSource quotes every instrument at price and records what it was asked for
+type fakeSource struct {
+	price float64
+	err   error
+	polls [][]string
+}
+
+func (f *fakeSource) Quotes(ctx context.Context, instruments []string) (map[string]Quote, error) {
+	f.polls = append(f.polls, instruments)
+	if f.err != nil {
+		return nil, f.err
+	}
+	result := make(map[string]Quote)
+	for _, instrument := range instruments {
+		result[instrument] = Quote{LastPrice: f.price}
+	}
+	return result, nil
+}
+
+func TestFeedPollsOncePerInterval(t *testing.T) {
+	log, err := logger.NewLogger("error", filepath.Join(t.TempDir(), "quotes.log"))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	cfg := &config.Config{}
+	cfg.Trigger.Quotes = config.QuotesConfig{PollInterval: time.Second, MaxAge: 5 * time.Second}
+	source := &fakeSource{price: 100}
+	feed := NewFeed(cfg, source, log)
+	start := time.Date(2024, 1, 15, 4, 0, 0, 0, time.UTC)
+	sim := clock.NewSimulated(start)
+	feed.SetClock(sim)
+	ctx := context.Background()
+
+	if quotes, err := feed.Quotes(ctx, []string{"nse:infy"}); err != nil || quotes["NSE:INFY"].LastPrice != 100 {
+		t.Fatalf("Quotes = %v, %v; want INFY at 100", quotes, err)
+	}
+	// Cached within the interval, but a new subscription is polled straight away
+	feed.Quotes(ctx, []string{"NSE:INFY"})
+	feed.Quotes(ctx, []string{"NSE:INFY", "NSE:TCS"})
+	if len(source.polls) != 2 || len(source.polls[1]) != 2 {
+		t.Fatalf("polls = %v, want INFY then INFY and TCS together", source.polls)
+	}
+
+	// A failed poll keeps quotes until they are too old to use
+	source.err = errors.New("connection refused")
+	sim.Set(start.Add(2 * time.Second))
+	if quotes, err := feed.Quotes(ctx, []string{"NSE:INFY"}); err == nil || len(quotes) != 1 {
+		t.Errorf("Quotes after failed poll = %v, %v; want the cached quote and the error", quotes, err)
+	}
+	sim.Set(start.Add(6 * time.Second))
+	if quotes, _ := feed.Quotes(ctx, []string{"NSE:INFY"}); len(quotes) != 0 {
+		t.Errorf("Quotes past max age = %v, want none", quotes)
+	}
+
+	// Instruments nobody asks about are dropped from later polls
+	source.err = nil
+	sim.Set(start.Add(2 * time.Minute))
+	feed.Quotes(ctx, []string{"NSE:INFY"})
+	sim.Set(start.Add(3*time.Minute + 2*time.Second))
+	feed.Quotes(ctx, []string{"NSE:INFY"})
+	if last := source.polls[len(source.polls)-1]; len(last) != 1 || last[0] != "NSE:INFY" {
+		t.Errorf("last poll = %v, want only INFY once TCS went unused", last)
+	}
+}
@@ -0,0 +1,115 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mach_five/trading-system/internal/models"
+	"google.golang.org/api/sheets/v4"
+)
+
+// writeGTTStatuses writes the status of each GTT row's GTTs to the GTT status column, so the
+// sheet shows what the broker holds. Only cells whose text changed are written, in one batch.
+func (r *SheetsReader) writeGTTStatuses(ctx context.Context, rangeStr string, rows [][]interface{}, orders []models.Order) {
+	if r.config.GTT.StatusColumn == "" || r.service == nil {
+		return
+	}
+	records, err := r.cache.GTTRecords(ctx)
+	if err != nil {
+		r.logger.Warn("⚠️  GTT statuses not written to the sheet: %v", err)
+		return
+	}
+	updates, err := gttStatusUpdates(rangeStr, r.config.GTT.StatusColumn, rows, orders, records)
+	if err != nil {
+		r.logger.Warn("⚠️  GTT statuses not written to the sheet: %v", err)
+		return
+	}
+	if len(updates) == 0 {
+		return
+	}
+
+	request := &sheets.BatchUpdateValuesRequest{ValueInputOption: "RAW", Data: updates}
+	if _, err := r.service.Spreadsheets.Values.BatchUpdate(r.sheetID, request).Context(ctx).Do(); err != nil {
+		r.logger.Warn("⚠️  Failed to write %d GTT statuses to %s: %v", len(updates), rangeStr, err)
+		r.logger.Warn("   💡 The service account needs edit access to the sheet, or set GTT_STATUS_COLUMN=off")
+		return
+	}
+	r.logger.Info("📝 Wrote %d GTT statuses to %s", len(updates), rangeStr)
+}
+
+// gttStatusUpdates returns the cells of the status column, in the sheet range rows were read
+// from, whose text differs from the GTT records of the rows' orders. A row split into several
+// orders lists each order's GTT; a GTT not placed yet is "pending".
+func gttStatusUpdates(rangeStr, column string, rows [][]interface{}, orders []models.Order,
+	records map[string]models.GTTRecord) ([]*sheets.ValueRange, error) {
+	tab, firstColumn, firstRow, err := parseRange(rangeStr)
+	if err != nil {
+		return nil, err
+	}
+	index := columnNumber(column) - columnNumber(firstColumn)
+	if index < 0 {
+		return nil, fmt.Errorf("GTT status column %s is left of range %s", column, rangeStr)
+	}
+
+	statuses := make(map[int][]string) // By SourceRow
+	for _, order := range orders {
+		if order.GTT == nil {
+			continue
+		}
+		status := "pending"
+		if record, ok := records[order.ID]; ok {
+			status = record.String()
+		}
+		statuses[order.SourceRow] = append(statuses[order.SourceRow], status)
+	}
+
+	sourceRows := make([]int, 0, len(statuses))
+	for sourceRow := range statuses {
+		sourceRows = append(sourceRows, sourceRow)
+	}
+	sort.Ints(sourceRows)
+
+	var updates []*sheets.ValueRange
+	for _, sourceRow := range sourceRows {
+		i := sourceRow - 3 // parseRows numbers rows from 3
+		if i < 0 || i >= len(rows) {
+			continue
+		}
+		status := strings.Join(statuses[sourceRow], "; ")
+		if index < len(rows[i]) && strings.TrimSpace(fmt.Sprintf("%v", rows[i][index])) == status {
+			continue
+		}
+		updates = append(updates, &sheets.ValueRange{
+			Range:  fmt.Sprintf("%s!%s%d", tab, column, firstRow+i),
+			Values: [][]interface{}{{status}},
+		})
+	}
+	return updates, nil
+}
+
+// parseRange splits an A1 range such as "to_buy!B3:T" into its tab, first column and first row
+func parseRange(rangeStr string) (string, string, int, error) {
+	tab, cells, ok := strings.Cut(rangeStr, "!")
+	if !ok {
+		return "", "", 0, fmt.Errorf("range %s has no sheet tab", rangeStr)
+	}
+	start, _, _ := strings.Cut(cells, ":")
+	column := strings.TrimRight(start, "0123456789")
+	row, err := strconv.Atoi(start[len(column):])
+	if column == "" || err != nil {
+		return "", "", 0, fmt.Errorf("range %s does not start at a cell", rangeStr)
+	}
+	return tab, strings.ToUpper(column), row, nil
+}
+
+// columnNumber returns the 1-based number of a column letter such as B or AA
+func columnNumber(column string) int {
+	number := 0
+	for _, letter := range strings.ToUpper(column) {
+		number = number*26 + int(letter-'A') + 1
+	}
+	return number
+}
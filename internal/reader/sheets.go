@@ -2,6 +2,7 @@ package reader
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -9,9 +10,20 @@ import (
 	"time"
 
 	"github.com/mach_five/trading-system/internal/cache"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/conditions"
 	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/derivatives"
+	"github.com/mach_five/trading-system/internal/instruments"
+	"github.com/mach_five/trading-system/internal/journal"
+	"github.com/mach_five/trading-system/internal/leader"
 	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/metrics"
 	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/pricing"
+	"github.com/mach_five/trading-system/internal/sizing"
+	"github.com/mach_five/trading-system/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
@@ -24,6 +36,11 @@ type SheetsReader struct {
 	logger  *logger.Logger
 	service *sheets.Service
 	sheetID string
+	clock   clock.Clock
+	elector *leader.Elector // Nil unless leader election is enabled
+	journal *journal.Journal // Records each order the first time it is cached; nil if unavailable
+	journaled map[string]bool // Order IDs already recorded as read by this process
+	instruments *instruments.Store // Validates symbols and tick sizes; nil if disabled
 }
 
 // NewSheetsReader creates a new Google Sheets reader
@@ -45,7 +62,12 @@ func NewSheetsReader(cfg *config.Config, cache *cache.RedisCache, log *logger.Lo
 
 	// Parse credentials
 	log.Debug("🔐 Parsing Google credentials JSON")
-	creds, err := google.CredentialsFromJSON(ctx, credData, sheets.SpreadsheetsReadonlyScope)
+	// Writing GTT statuses back to the sheet needs edit access
+	scope := sheets.SpreadsheetsReadonlyScope
+	if cfg.GTT.StatusColumn != "" {
+		scope = sheets.SpreadsheetsScope
+	}
+	creds, err := google.CredentialsFromJSON(ctx, credData, scope)
 	if err != nil {
 		log.Error("❌ Failed to parse Google credentials")
 		log.Error("   Path: %s", credentialsPath)
@@ -77,24 +99,96 @@ func NewSheetsReader(cfg *config.Config, cache *cache.RedisCache, log *logger.Lo
 	
 	log.Debug("📊 Using Google Sheet ID: %s", sheetID)
 
+	var elector *leader.Elector
+	if cfg.Leader.Enabled {
+		elector = leader.NewElector(cfg, cache, leader.RoleReader, log)
+	}
+
 	return &SheetsReader{
-		config:  cfg,
-		cache:   cache,
-		logger:  log,
-		service: srv,
-		sheetID: sheetID,
+		config:      cfg,
+		cache:       cache,
+		logger:      log,
+		service:     srv,
+		sheetID:     sheetID,
+		clock:       clock.Real{},
+		elector:     elector,
+		journal:     openJournal(cfg, log),
+		journaled:   make(map[string]bool),
+		instruments: openInstruments(cfg, cache, log),
 	}, nil
 }
 
+// openJournal opens the execution journal the reader records read orders in
+func openJournal(cfg *config.Config, log *logger.Logger) *journal.Journal {
+	readJournal, err := journal.Open(cfg.Journal.Path)
+	if err != nil {
+		log.Warn("Failed to open execution journal, read orders will not be journaled: %v", err)
+	}
+	return readJournal
+}
+
+// openInstruments creates the instrument store rows are validated against, or returns nil
+// if the instrument master is disabled
+func openInstruments(cfg *config.Config, cache *cache.RedisCache, log *logger.Logger) *instruments.Store {
+	if !cfg.Instruments.Enabled {
+		return nil
+	}
+	return instruments.NewStore(cfg, cache, log)
+}
+
+// Elector returns the reader's leader elector, or nil if leader election is disabled
+func (r *SheetsReader) Elector() *leader.Elector {
+	return r.elector
+}
+
+// SetClock replaces the clock used to decide which rows are still in the future
+func (r *SheetsReader) SetClock(c clock.Clock) {
+	r.clock = c
+	if r.instruments != nil {
+		r.instruments.SetClock(c)
+	}
+}
+
+// instrumentMaster returns the current instrument master, or nil if it is disabled or cannot
+// be loaded, in which case rows are cached without symbol validation or tick rounding
+func (r *SheetsReader) instrumentMaster(ctx context.Context) *instruments.Master {
+	if r.instruments == nil {
+		return nil
+	}
+	master, err := r.instruments.Master(ctx)
+	if err != nil {
+		r.logger.Warn("⚠️  Instrument master unavailable, symbols are not validated: %v", err)
+		return nil
+	}
+	return master
+}
+
 // Start starts the reader service (runs continuously)
 func (r *SheetsReader) Start(ctx context.Context) error {
 	r.logger.Info("Starting Google Sheets reader service")
+
+	shutdownTracing, err := tracing.Init(ctx, r.config, "trading-system-read", r.logger)
+	if err != nil {
+		r.logger.Warn("⚠️  Tracing disabled: %v", err)
+	} else {
+		defer shutdownTracing(context.Background())
+	}
+
+	if r.config.Metrics.Enabled {
+		go metrics.Serve(ctx, r.config.Metrics.ReaderAddr, r.logger)
+	}
+	
+	if r.elector != nil {
+		r.logger.Info("Leader election enabled (lease %v)", r.config.Leader.Lease)
+		r.elector.Campaign(ctx)
+		go r.elector.Run(ctx)
+	}
 	
 	ticker := time.NewTicker(r.config.GoogleSheets.RefreshInterval)
 	defer ticker.Stop()
 
 	// Initial read
-	if err := r.readAndCacheOrders(); err != nil {
+	if err := r.readAndCacheOrders(ctx); err != nil {
 		r.logger.Error("Initial read failed: %v", err)
 	}
 
@@ -104,11 +198,11 @@ func (r *SheetsReader) Start(ctx context.Context) error {
 			r.logger.Info("Stopping Google Sheets reader service")
 			return ctx.Err()
 		case <-ticker.C:
-			if err := r.readAndCacheOrders(); err != nil {
+			if err := r.readAndCacheOrders(ctx); err != nil {
 				r.logger.Error("Failed to read orders: %v", err)
 				// Retry with 0 delay as per requirements
 				time.Sleep(0)
-				if err := r.readAndCacheOrders(); err != nil {
+				if err := r.readAndCacheOrders(ctx); err != nil {
 					r.logger.Error("Retry failed: %v", err)
 				}
 			}
@@ -116,18 +210,29 @@ func (r *SheetsReader) Start(ctx context.Context) error {
 	}
 }
 
-// readAndCacheOrders reads orders from both sheets and caches them
-func (r *SheetsReader) readAndCacheOrders() error {
+// readAndCacheOrders reads orders from both sheets and caches them. Standby instances skip
+// the read and leave it to the leader.
+func (r *SheetsReader) readAndCacheOrders(ctx context.Context) error {
+	if r.elector != nil && !r.elector.IsLeader() {
+		r.logger.Debug("Standing by, %s reads the sheets", r.elector.Status().Holder)
+		return nil
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "reader.read_cycle")
+	defer span.End()
+
 	var allOrders []models.Order
+	complete := true // Both sheets were read, so GTT rows missing from them are really gone
 
 	// Read buy orders from to_buy sheet
 	r.logger.Debug("Reading buy orders from sheet: %s, range: %s", r.sheetID, r.config.GoogleSheets.BuyRange)
-	buyOrders, err := r.readSheet(r.config.GoogleSheets.BuyRange, "Buy")
+	buyOrders, err := r.readSheet(ctx, r.config.GoogleSheets.BuyRange, "Buy")
 	if err != nil {
 		r.logger.Error("❌ Failed to read buy orders: %v", err)
 		r.logger.Error("   Sheet ID: %s", r.sheetID)
 		r.logger.Error("   Range: %s", r.config.GoogleSheets.BuyRange)
 		r.logger.Error("   Full error details logged above")
+		complete = false
 	} else {
 		r.logger.Success("✅ Read %d buy orders from to_buy sheet", len(buyOrders))
 		allOrders = append(allOrders, buyOrders...)
@@ -135,12 +240,13 @@ func (r *SheetsReader) readAndCacheOrders() error {
 
 	// Read sell orders from to_sell sheet
 	r.logger.Debug("Reading sell orders from sheet: %s, range: %s", r.sheetID, r.config.GoogleSheets.SellRange)
-	sellOrders, err := r.readSheet(r.config.GoogleSheets.SellRange, "Sell")
+	sellOrders, err := r.readSheet(ctx, r.config.GoogleSheets.SellRange, "Sell")
 	if err != nil {
 		r.logger.Error("❌ Failed to read sell orders: %v", err)
 		r.logger.Error("   Sheet ID: %s", r.sheetID)
 		r.logger.Error("   Range: %s", r.config.GoogleSheets.SellRange)
 		r.logger.Error("   Full error details logged above")
+		complete = false
 	} else {
 		r.logger.Success("✅ Read %d sell orders from to_sell sheet", len(sellOrders))
 		allOrders = append(allOrders, sellOrders...)
@@ -152,16 +258,60 @@ func (r *SheetsReader) readAndCacheOrders() error {
 		"📈 Buy Orders":  fmt.Sprintf("%d", len(buyOrders)),
 		"📉 Sell Orders": fmt.Sprintf("%d", len(sellOrders)),
 		"📦 Total Orders": fmt.Sprintf("%d", len(allOrders)),
-		"🕐 Timestamp":   time.Now().Format("2006-01-02 15:04:05 IST"),
+		"🕐 Timestamp":   r.clock.Now().Format("2006-01-02 15:04:05 IST"),
 	})
 
-	// Cache all orders
-	for _, order := range allOrders {
-		expiryTime := order.ScheduledTime.Add(10 * time.Second)
-		if err := r.cache.StoreOrder(order, expiryTime); err != nil {
+	r.cacheOrders(ctx, allOrders)
+	if complete {
+		r.publishGTTs(ctx, allOrders)
+	} else {
+		r.logger.Warn("⚠️  GTT rows not synced this cycle, a sheet could not be read")
+	}
+	return nil
+}
+
+// publishGTTs hands the GTT rows read this cycle to the trigger, which places, modifies and
+// deletes GTTs at the broker to match them
+func (r *SheetsReader) publishGTTs(ctx context.Context, orders []models.Order) {
+	var gtts []models.Order
+	for _, order := range orders {
+		if order.GTT != nil {
+			gtts = append(gtts, order)
+		}
+	}
+	if err := r.cache.SetDesiredGTTs(ctx, gtts); err != nil {
+		r.logger.Error("Failed to store GTT rows: %v", err)
+	}
+}
+
+// cacheOrders stores parsed orders in the cache with their expiry windows
+func (r *SheetsReader) cacheOrders(ctx context.Context, orders []models.Order) {
+	for _, order := range orders {
+		if ctx.Err() != nil {
+			// Shutting down: the remaining orders are cached again on the next start
+			r.logger.Warn("⚠️  Stopped caching orders: %v", ctx.Err())
+			return
+		}
+		if order.GTT != nil {
+			// GTT rows are left at the broker by the trigger rather than queued
+			continue
+		}
+		expiryTime := cache.ExpiryFor(order, r.config.Trigger.Expiry)
+		storeCtx, storeSpan := tracing.Tracer().Start(tracing.Extract(ctx, order.TraceContext), "cache.store_order",
+			trace.WithAttributes(tracing.OrderAttributes(order)...))
+		if err := r.cache.StoreOrder(storeCtx, order, expiryTime); errors.Is(err, cache.ErrOrderCancelled) {
+			storeSpan.End()
+			r.logger.Debug("Order %s was cancelled via the admin API, not queuing it again", order.ID)
+			continue
+		} else if err != nil {
+			tracing.RecordError(storeSpan, err)
+			storeSpan.End()
 			r.logger.Error("Failed to cache order %s: %v", order.ID, err)
 			continue
 		}
+		storeSpan.End()
+		metrics.OrdersCached.Inc()
+		r.recordRead(order)
 		amoStatus := "Regular"
 		if order.IsAMO {
 			amoStatus = "AMO"
@@ -170,14 +320,33 @@ func (r *SheetsReader) readAndCacheOrders() error {
 			order.ID, order.Side, order.Exchange, order.Symbol, order.ScheduledTime.Format(time.RFC3339), amoStatus, expiryTime.Format(time.RFC3339))
 	}
 
-	return nil
+	if pending, err := r.cache.PendingCount(ctx); err == nil {
+		metrics.PendingOrders.Set(float64(pending))
+	}
+}
+
+// recordRead journals an order the first time this process caches it; the sheet is re-read
+// on every refresh, so later reads of the same order are not journaled again
+func (r *SheetsReader) recordRead(order models.Order) {
+	if r.journaled[order.ID] {
+		return
+	}
+	r.journaled[order.ID] = true
+	if err := r.journal.Record(models.JournalEntry{
+		Timestamp: r.clock.Now(),
+		Event:     models.JournalEventRead,
+		Broker:    r.config.Broker.Type,
+		Order:     &order,
+	}); err != nil {
+		r.logger.Warn("Failed to journal read of order %s: %v", order.ID, err)
+	}
 }
 
 // readSheet reads orders from a specific sheet range
-func (r *SheetsReader) readSheet(rangeStr, side string) ([]models.Order, error) {
+func (r *SheetsReader) readSheet(ctx context.Context, rangeStr, side string) ([]models.Order, error) {
 	r.logger.Debug("📖 Reading %s orders from sheet: %s, range: %s", side, r.sheetID, rangeStr)
 	
-	resp, err := r.service.Spreadsheets.Values.Get(r.sheetID, rangeStr).Do()
+	resp, err := r.service.Spreadsheets.Values.Get(r.sheetID, rangeStr).Context(ctx).Do()
 	if err != nil {
 		r.logger.Error("❌ Failed to read %s orders from Google Sheets", side)
 		r.logger.Error("   Sheet ID: %s", r.sheetID)
@@ -209,31 +378,50 @@ func (r *SheetsReader) readSheet(rangeStr, side string) ([]models.Order, error)
 	}
 
 	r.logger.Debug("Found %d rows in %s sheet", len(resp.Values), side)
-	orders, err := r.parseRows(resp.Values, side)
+	orders, err := r.parseRows(ctx, resp.Values, side)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse rows from %s: %w", rangeStr, err)
 	}
 
+	r.writeGTTStatuses(ctx, rangeStr, resp.Values, orders)
+
 	r.logger.Debug("Parsed %d valid orders from %d rows in %s sheet", len(orders), len(resp.Values), side)
+	metrics.OrdersRead.WithLabelValues(side).Add(float64(len(orders)))
 	return orders, nil
 }
 
 // parseRows parses sheet rows into Order objects
-// Column mapping (B through L):
+// Column mapping (B through T):
 // B: planned_buy_price (float) - Price
-// C: product (string) - Product type
-// D: Name (string) - Stock name
-// E: bse_code (string) - BSE code
+// C: product (string) - CNC, MIS or NRML; CNC for cash equities and NRML for futures and options when empty
+// D: Name (string) - Stock name, used to find the instrument when the symbol is not listed
+// E: bse_code (string) - BSE code, used to find the instrument when the symbol is not listed
 // F: symbol (string) - Trading symbol
 // G: execute_date (string) - Date (YYYY-MM-DD)
 // H: execute_time (string) - Time (HH:MM:SS or HH:MM)
-// I: Money Needed (float) - Money required (used to calculate quantity if quantity column not present)
+// I: Money Needed (float) - Money to size the row from, net of estimated charges, when quantity is not
+//    present; a percentage such as "5%" sizes buys from the funds available when they are due
 // J: Lots (int) - Number of orders to place
 // K: exchange (string) - Exchange (NSE, BSE, etc.)
 // L: quantity (int, optional) - Total quantity to distribute across lots
-// Note: If lots > 1, total quantity (q) is distributed as: floor(q/n) base quantity,
-//       with mod(q/n) orders getting floor(q/n) + 1 to ensure total quantity is used
-func (r *SheetsReader) parseRows(rows [][]interface{}, side string) ([]models.Order, error) {
+// M: expiry_seconds (int, optional) - Overrides the sheet's expiry window for this row
+// N: instrument_type (string, optional) - FUT, CE or PE makes the row a future or option on the
+//    underlying in F, traded on NFO (for NSE) or BFO (for BSE)
+// O: expiry (string, optional) - Contract expiry (YYYY-MM-DD); the nearest contract when empty
+// P: strike (float, options only) - Option strike price
+// Q: condition (string, optional) - Market condition such as "ltp >= 1500", "change% <= -2" or
+//    "volume >= 100000"; the order waits after its scheduled time until it holds
+// R: gtt (string, optional) - GTT or OCO leaves the order at the broker as a GTT until the
+//    execute date and time, which then say when it lapses rather than when it is placed
+// S: stop_loss (float, OCO only) - Stop-loss of an OCO sell; B is its target
+// T: GTT status (string) - Written back by the reader, see writeGTTStatuses
+// Note: Quantities are whole multiples of the instrument's lot size. If lots > 1, the total is
+//       split into equal numbers of lots with the first orders taking one extra lot each.
+//       Rows that size to less than one lot are skipped.
+// When the instrument master is enabled, rows for unknown instruments are skipped and prices
+// are rounded to the instrument's tick size, down for buys and up for sells.
+// Each parsed order carries the trace context of its own reader.parse_order span.
+func (r *SheetsReader) parseRows(ctx context.Context, rows [][]interface{}, side string) ([]models.Order, error) {
 	var orders []models.Order
 	// Get current time - we'll use IST for comparison
 	istLocation, err := time.LoadLocation("Asia/Kolkata")
@@ -241,7 +429,8 @@ func (r *SheetsReader) parseRows(rows [][]interface{}, side string) ([]models.Or
 		r.logger.Warn("Failed to load IST timezone: %v", err)
 		istLocation = time.UTC
 	}
-	now := time.Now().In(istLocation)
+	now := r.clock.Now().In(istLocation)
+	master := r.instrumentMaster(ctx)
 
 	for i, row := range rows {
 		// Need at least 10 columns (B through K, indexed 0-9)
@@ -267,13 +456,13 @@ func (r *SheetsReader) parseRows(rows [][]interface{}, side string) ([]models.Or
 			continue
 		}
 
-		// Column C (index 1): product - not used directly but logged
-		product := strings.TrimSpace(fmt.Sprintf("%v", row[1]))
+		// Column C (index 1): product - CNC, MIS or NRML; checked once the instrument is known
+		product := strings.ToUpper(strings.TrimSpace(fmt.Sprintf("%v", row[1])))
 
-		// Column D (index 2): Name - not used directly but logged
+		// Column D (index 2): Name - resolves the instrument when the symbol is not listed
 		name := strings.TrimSpace(fmt.Sprintf("%v", row[2]))
 
-		// Column E (index 3): bse_code - not used directly but logged
+		// Column E (index 3): bse_code - resolves the instrument when the symbol is not listed
 		bseCode := strings.TrimSpace(fmt.Sprintf("%v", row[3]))
 
 		// Column F (index 4): symbol
@@ -283,6 +472,15 @@ func (r *SheetsReader) parseRows(rows [][]interface{}, side string) ([]models.Or
 			continue
 		}
 
+		// Column K (index 9): exchange
+		exchange := strings.TrimSpace(fmt.Sprintf("%v", row[9]))
+		if exchange == "" {
+			r.logger.Debug("Row %d: empty exchange, defaulting to NSE", i+3)
+			exchange = "NSE" // Default to NSE if not specified
+		}
+		// Normalize exchange to uppercase
+		exchange = strings.ToUpper(exchange)
+
 		// Column G (index 5): execute_date
 		dateStr := strings.TrimSpace(fmt.Sprintf("%v", row[5]))
 		// Try multiple date formats
@@ -319,9 +517,105 @@ func (r *SheetsReader) parseRows(rows [][]interface{}, side string) ([]models.Or
 			continue
 		}
 
-		// Column I (index 7): Money Needed - used to calculate total quantity if quantity column not present
+		// Columns N-P (index 12-14): instrument_type, expiry and strike (optional) - a FUT, CE or PE
+		// row is a future or option on the underlying in column F, resolved from the instrument
+		// master on the exchange's derivatives segment (NFO for NSE, BFO for BSE). An empty
+		// expiry picks the nearest contract still trading on the execute date.
+		var contractType string
+		if len(row) > 12 {
+			contractType = strings.ToUpper(strings.TrimSpace(fmt.Sprintf("%v", row[12])))
+		}
+		if contractType != "" {
+			if !derivatives.IsDerivative(contractType) {
+				r.logger.Warn("Row %d: invalid instrument type '%s' (expected FUT, CE or PE), skipping", i+3, contractType)
+				continue
+			}
+			var contractExpiry time.Time
+			if len(row) > 13 {
+				if expiryStr := strings.TrimSpace(fmt.Sprintf("%v", row[13])); expiryStr != "" {
+					if contractExpiry, err = time.Parse("2006-01-02", expiryStr); err != nil {
+						r.logger.Warn("Row %d: invalid contract expiry '%s' (expected YYYY-MM-DD), skipping", i+3, expiryStr)
+						continue
+					}
+				}
+			}
+			var strike float64
+			if len(row) > 14 {
+				strike, _ = strconv.ParseFloat(strings.TrimSpace(fmt.Sprintf("%v", row[14])), 64)
+			}
+			if contractType != "FUT" && strike <= 0 {
+				r.logger.Warn("Row %d: %s %s option has no strike, skipping", i+3, symbol, contractType)
+				continue
+			}
+			exchange = derivatives.Segment(exchange)
+			if master == nil {
+				r.logger.Warn("❓ Row %d (%s): cannot resolve %s %s contract without the instrument master, skipping", i+3, side, symbol, contractType)
+				metrics.SheetRowsRejected.WithLabelValues("unknown_instrument").Inc()
+				continue
+			}
+			contract, ok := master.Contract(exchange, symbol, contractType, strike, contractExpiry, date)
+			if !ok {
+				r.logger.Warn("❓ Row %d (%s): no %s:%s %s contract (strike %v, expiry %s) in the %s instrument master, skipping",
+					i+3, side, exchange, symbol, contractType, strike, contractExpiry.Format("2006-01-02"), master.Day())
+				metrics.SheetRowsRejected.WithLabelValues("unknown_instrument").Inc()
+				continue
+			}
+			r.logger.Debug("Row %d: %s %s resolved to %s:%s expiring %s", i+3, symbol, contractType, exchange,
+				contract.Tradingsymbol, contract.Expiry.Format("2006-01-02"))
+			symbol = contract.Tradingsymbol
+		}
+
+		// Resolve the instrument; unknown instruments are rejected and the price is rounded
+		// onto the instrument's tick grid (down for buys, up for sells) before it sizes the order
+		var adjustments []models.PriceAdjustment
+		lotSize, instrumentType := 1, "EQ"
+		var expiry time.Time
+		var tickSize float64
+		if master != nil {
+			instrument, ok := master.Resolve(exchange, symbol, bseCode, name)
+			if !ok {
+				r.logger.Warn("❓ Row %d (%s): unknown instrument %s:%s (name %q, BSE code %q) in the %s instrument master, skipping",
+					i+3, side, exchange, symbol, name, bseCode, master.Day())
+				metrics.SheetRowsRejected.WithLabelValues("unknown_instrument").Inc()
+				continue
+			}
+			if instrument.Exchange != exchange || instrument.Tradingsymbol != symbol {
+				r.logger.Debug("Row %d: %s:%s resolved to %s:%s", i+3, exchange, symbol, instrument.Exchange, instrument.Tradingsymbol)
+			}
+			exchange, symbol = instrument.Exchange, instrument.Tradingsymbol
+			lotSize, instrumentType, expiry = instrument.LotSize, instrument.InstrumentType, instrument.Expiry
+			tickSize = instrument.TickSize
+			if rounded := pricing.RoundToTick(price, instrument.TickSize, side); rounded != price {
+				r.logger.Info("🔧 Row %d: %s price %v rounded to %v for %s (tick size %v)", i+3, side, price, rounded, symbol, instrument.TickSize)
+				adjustments = append(adjustments, models.PriceAdjustment{
+					Reason: models.PriceAdjustmentTick,
+					From:   price,
+					To:     rounded,
+					At:     now,
+				})
+				price = rounded
+			}
+		}
+
+		// Cash equities default to CNC and futures and options to NRML
+		if product == "" {
+			product = derivatives.DefaultProduct(instrumentType)
+		}
+		if err := derivatives.CheckProduct(product, instrumentType); err != nil {
+			r.logger.Warn("Row %d (%s): %v for %s, skipping", i+3, side, err, symbol)
+			metrics.SheetRowsRejected.WithLabelValues("invalid_product").Inc()
+			continue
+		}
+
+		// Column I (index 7): Money Needed - sizes the row when it has no quantity; a percentage
+		// such as "5%" sizes buys from the funds available when they are due
 		moneyNeededStr := strings.TrimSpace(fmt.Sprintf("%v", row[7]))
-		moneyNeeded, _ := strconv.ParseFloat(moneyNeededStr, 64)
+		var moneyNeeded, fundsPercent float64
+		if percentStr, ok := strings.CutSuffix(moneyNeededStr, "%"); ok {
+			fundsPercent, _ = strconv.ParseFloat(strings.TrimSpace(percentStr), 64)
+		} else {
+			moneyNeeded, _ = strconv.ParseFloat(moneyNeededStr, 64)
+		}
 
 		// Column J (index 8): Lots (number of orders to place)
 		lotsStr := strings.TrimSpace(fmt.Sprintf("%v", row[8]))
@@ -330,59 +624,88 @@ func (r *SheetsReader) parseRows(rows [][]interface{}, side string) ([]models.Or
 			r.logger.Warn("Row %d: invalid lots '%s', defaulting to 1", i+3, lotsStr)
 			lots = 1
 		}
-		
-		// Calculate total quantity (q)
-		// If there's a quantity column (index 10), use it; otherwise calculate from Money Needed / Price
-		var totalQuantity int
+
+		// Column L (index 10): Quantity (optional) - fixed total quantity, takes precedence over Money Needed
+		var fixedQuantity int
 		if len(row) > 10 {
-			// Column L (index 10): Quantity (if present)
 			quantityStr := strings.TrimSpace(fmt.Sprintf("%v", row[10]))
 			if quantityStr != "" {
 				if qty, err := strconv.Atoi(quantityStr); err == nil && qty > 0 {
-					totalQuantity = qty
+					fixedQuantity = qty
 				} else {
-					// Invalid quantity, calculate from Money Needed / Price
-					if price > 0 {
-						totalQuantity = int(moneyNeeded / price)
-					} else {
-						totalQuantity = 1 // Default if price is 0
-					}
+					r.logger.Warn("Row %d: invalid quantity '%s', sizing from Money Needed", i+3, quantityStr)
 				}
-			} else {
-				// No quantity column, calculate from Money Needed / Price
-				if price > 0 {
-					totalQuantity = int(moneyNeeded / price)
+			}
+		}
+
+		// Column M (index 11): per-row expiry window in seconds (optional)
+		var expiryWindow time.Duration
+		if len(row) > 11 {
+			expiryStr := strings.TrimSpace(fmt.Sprintf("%v", row[11]))
+			if expiryStr != "" {
+				if seconds, err := strconv.Atoi(expiryStr); err == nil && seconds > 0 {
+					expiryWindow = time.Duration(seconds) * time.Second
 				} else {
-					totalQuantity = 1 // Default if price is 0
+					r.logger.Warn("Row %d: invalid expiry_seconds '%s', using the sheet's expiry window", i+3, expiryStr)
 				}
 			}
-		} else {
-			// No quantity column, calculate from Money Needed / Price
-			if price > 0 {
-				totalQuantity = int(moneyNeeded / price)
-			} else {
-				totalQuantity = 1 // Default if price is 0
-			}
 		}
-		
-		// Ensure totalQuantity is at least 1
-		if totalQuantity <= 0 {
-			totalQuantity = 1
+
+		// Column Q (index 15): condition (optional) - e.g. "ltp >= 1500"; the order arms at its
+		// scheduled time and fires once the condition holds
+		var condition *models.Condition
+		if len(row) > 15 {
+			if conditionStr := strings.TrimSpace(fmt.Sprintf("%v", row[15])); conditionStr != "" {
+				parsedCondition, err := conditions.Parse(conditionStr)
+				if err != nil {
+					r.logger.Warn("Row %d (%s): %v, skipping", i+3, side, err)
+					metrics.SheetRowsRejected.WithLabelValues("invalid_condition").Inc()
+					continue
+				}
+				condition = &parsedCondition
+			}
 		}
-		
-		// Calculate quantity distribution across lots
-		// floor(q/n) for base quantity, mod(q/n) orders get +1
-		baseQuantity := totalQuantity / lots
-		remainder := totalQuantity % lots
 
-		// Column K (index 9): exchange
-		exchange := strings.TrimSpace(fmt.Sprintf("%v", row[9]))
-		if exchange == "" {
-			r.logger.Debug("Row %d: empty exchange, defaulting to NSE", i+3)
-			exchange = "NSE" // Default to NSE if not specified
+		// Columns R-S (index 16-17): gtt and stop_loss (optional) - GTT or OCO leaves the row at the
+		// broker as a GTT until its execute date and time instead of placing it then. An OCO row
+		// is a two-leg GTT on a holding: the price in B is the target and S the stop-loss.
+		var gtt *models.GTT
+		if len(row) > 16 {
+			gttStr := strings.ToUpper(strings.TrimSpace(fmt.Sprintf("%v", row[16])))
+			var stopLoss float64
+			if len(row) > 17 {
+				stopLoss, _ = strconv.ParseFloat(strings.TrimSpace(fmt.Sprintf("%v", row[17])), 64)
+			}
+			var gttErr string
+			switch gttStr {
+			case "":
+			case "GTT", "SINGLE":
+				gtt = &models.GTT{Type: models.GTTSingle}
+			case "OCO", "TWO-LEG":
+				gtt = &models.GTT{Type: models.GTTOCO, StopLoss: stopLoss}
+				if side != "Sell" {
+					gttErr = "OCO GTTs only sell"
+				} else if stopLoss <= 0 || stopLoss >= price {
+					gttErr = fmt.Sprintf("OCO stop-loss %v must be above 0 and below the target %v", stopLoss, price)
+				}
+			default:
+				gttErr = fmt.Sprintf("invalid GTT type '%s' (expected GTT or OCO)", gttStr)
+			}
+			switch {
+			case gttErr == "" && gtt != nil && condition != nil:
+				gttErr = "a GTT cannot also have a condition"
+			case gttErr == "" && gtt != nil && fundsPercent > 0:
+				gttErr = "a GTT cannot be sized from a percentage of funds"
+			}
+			if gttErr != "" {
+				r.logger.Warn("Row %d (%s): %s, skipping", i+3, side, gttErr)
+				metrics.SheetRowsRejected.WithLabelValues("invalid_gtt").Inc()
+				continue
+			}
+			if gtt != nil && gtt.StopLoss > 0 && tickSize > 0 {
+				gtt.StopLoss = pricing.RoundToTick(gtt.StopLoss, tickSize, side)
+			}
 		}
-		// Normalize exchange to uppercase
-		exchange = strings.ToUpper(exchange)
 
 		// Load IST timezone (Asia/Kolkata)
 		istLocation, err := time.LoadLocation("Asia/Kolkata")
@@ -400,7 +723,7 @@ func (r *SheetsReader) parseRows(rows [][]interface{}, side string) ([]models.Or
 		)
 
 		// Get current time in IST for comparison
-		nowIST := time.Now().In(istLocation)
+		nowIST := r.clock.Now().In(istLocation)
 
 		// Skip if scheduled time is in the past (in IST)
 		if scheduledTime.Before(nowIST) {
@@ -408,22 +731,58 @@ func (r *SheetsReader) parseRows(rows [][]interface{}, side string) ([]models.Or
 			continue
 		}
 
+		// Skip contracts that will have expired, or be past the expiry-day cutoff, when due
+		if err := derivatives.CheckExpiry(expiry, scheduledTime, r.config.Broker.Derivatives.ExpiryDayCutoff); err != nil {
+			r.logger.Warn("⌛ Row %d (%s): %s scheduled for %s IST: %v, skipping", i+3, side, symbol,
+				scheduledTime.Format("2006-01-02 15:04:05"), err)
+			metrics.SheetRowsRejected.WithLabelValues("contract_expired").Inc()
+			continue
+		}
+
 		// Determine if this order should be placed as AMO based on scheduled time
 		// Market hours: 9:00 AM - 3:30 PM IST (any day of the week)
-		isAMO := r.shouldUseAMO(scheduledTime)
+		isAMO := r.shouldUseAMO(scheduledTime) && gtt == nil
+
+		// Size the row, then split it across its orders in whole lots. Rows sized from a
+		// percentage of funds are split by percentage and sized when they are due.
+		request := sizing.Request{Side: side, Price: price, LotSize: lotSize, Charges: r.charges(product, instrumentType)}
+		switch {
+		case fixedQuantity > 0:
+			request.Strategy, request.Quantity = sizing.StrategyFixed, fixedQuantity
+		case fundsPercent > 0 && side == "Buy":
+			request.Strategy, request.Percent = sizing.StrategyFundsPercent, fundsPercent
+		case fundsPercent > 0:
+			r.logger.Warn("📐 Row %d (%s): Money Needed %s is a percentage of funds, which only sizes buys, skipping", i+3, side, moneyNeededStr)
+			metrics.SheetRowsRejected.WithLabelValues("unsized").Inc()
+			continue
+		case moneyNeeded > 0:
+			request.Strategy, request.Money = sizing.StrategyMoney, moneyNeeded
+		default:
+			r.logger.Warn("📐 Row %d (%s): no quantity or Money Needed to size %s from, skipping", i+3, side, symbol)
+			metrics.SheetRowsRejected.WithLabelValues("unsized").Inc()
+			continue
+		}
+
+		var decision sizing.Decision
+		quantities := make([]int, lots)
+		if request.Strategy != sizing.StrategyFundsPercent {
+			decision, err = sizing.Size(request)
+			if err != nil {
+				r.logger.Warn("📐 Row %d (%s): cannot size %s: %v, skipping", i+3, side, symbol, err)
+				metrics.SheetRowsRejected.WithLabelValues("unsized").Inc()
+				continue
+			}
+			quantities = sizing.Split(decision.Quantity, lots, lotSize)
+		}
 
 		// Create multiple orders based on lots value
-		// Distribute totalQuantity across lots orders:
-		// - baseQuantity = floor(totalQuantity / lots)
-		// - remainder orders get baseQuantity + 1
 		for orderNum := 1; orderNum <= lots; orderNum++ {
-			// Calculate quantity for this order
-			// First 'remainder' orders get baseQuantity + 1, rest get baseQuantity
-			orderQuantity := baseQuantity
-			if orderNum <= remainder {
-				orderQuantity = baseQuantity + 1
+			orderQuantity := quantities[orderNum-1]
+			if request.Strategy != sizing.StrategyFundsPercent && orderQuantity == 0 {
+				r.logger.Warn("📐 Row %d, Order %d/%d: %s leaves no lots for this order, skipping", i+3, orderNum, lots, decision)
+				continue
 			}
-			
+
 			// Generate unique order ID by appending order number
 			orderID := models.GenerateOrderID(symbol, scheduledTime)
 			if lots > 1 {
@@ -432,38 +791,80 @@ func (r *SheetsReader) parseRows(rows [][]interface{}, side string) ([]models.Or
 			}
 			
 			order := models.Order{
-				ID:            orderID,
-				Symbol:        symbol,
-				Exchange:      exchange,
-				Price:         price,
-				Quantity:      orderQuantity,
-				OrderType:     "LIMIT", // Default to LIMIT as we have a price
-				Side:          side,
-				ScheduledTime: scheduledTime,
-				CreatedAt:     now,
-				IsAMO:         isAMO,
+				ID:               orderID,
+				Symbol:           symbol,
+				Exchange:         exchange,
+				Price:            price,
+				Quantity:         orderQuantity,
+				OrderType:        "LIMIT", // Default to LIMIT as we have a price
+				Side:             side,
+				ScheduledTime:    scheduledTime,
+				CreatedAt:        now,
+				IsAMO:            isAMO,
+				ExpiryWindow:     expiryWindow,
+				SourceRow:        i + 3,
+				PriceAdjustments: append([]models.PriceAdjustment(nil), adjustments...),
+				LotSize:          lotSize,
+				Product:          product,
+			}
+			if derivatives.IsDerivative(instrumentType) {
+				order.InstrumentType, order.Expiry = instrumentType, expiry
+			}
+			if condition != nil {
+				orderCondition := *condition
+				order.Condition = &orderCondition
+			}
+			if gtt != nil {
+				orderGTT := *gtt
+				order.GTT = &orderGTT
 			}
+			if request.Strategy == sizing.StrategyFundsPercent {
+				order.FundsPercent = fundsPercent / float64(lots)
+			}
+
+			// Start the order's trace here so the trigger can continue it after reading from cache
+			orderCtx, span := tracing.Tracer().Start(ctx, "reader.parse_order",
+				trace.WithAttributes(tracing.OrderAttributes(order)...))
+			order.TraceContext = tracing.Inject(orderCtx)
+			span.End()
 
 			if isAMO {
 				r.logger.Debug("Row %d, Order %d/%d: Scheduled for %s IST (market closed) - marked as AMO", 
 					i+3, orderNum, lots, scheduledTime.Format("2006-01-02 15:04:05 IST"))
 			}
 
+			if order.FundsPercent > 0 {
+				r.logger.Info("📐 Row %d, Order %d/%d %s: %s %.2f%% of available funds, sized when due", i+3, orderNum, lots, order.ID,
+					sizing.StrategyFundsPercent, order.FundsPercent)
+			} else {
+				r.logger.Info("📐 Row %d, Order %d/%d %s: quantity %d of row %s", i+3, orderNum, lots, order.ID, orderQuantity, decision)
+			}
+
 			r.logger.Debug("Parsed order %d/%d: %s, Exchange: %s, Symbol: %s, Name: %s, BSE: %s, Product: %s, Money: %.2f, Quantity: %d, Lots: %d, Total Qty: %d", 
-				orderNum, lots, order.ID, exchange, symbol, name, bseCode, product, moneyNeeded, orderQuantity, lots, totalQuantity)
+				orderNum, lots, order.ID, exchange, symbol, name, bseCode, product, moneyNeeded, orderQuantity, lots, decision.Quantity)
 
 			orders = append(orders, order)
 		}
 		
 		if lots > 1 {
-			r.logger.Info("Row %d: Created %d orders (lots=%d, total qty=%d, base=%d, remainder=%d) for %s", 
-				i+3, lots, lots, totalQuantity, baseQuantity, remainder, symbol)
+			r.logger.Info("Row %d: Created %d orders (lots=%d, total qty=%d, lot size=%d) for %s", 
+				i+3, lots, lots, decision.Quantity, lotSize, symbol)
 		}
 	}
 
 	return orders, nil
 }
 
+// charges returns the charge schedule to leave room for when sizing, or nil when charges
+// are not estimated
+func (r *SheetsReader) charges(product, instrumentType string) *sizing.Charges {
+	if !r.config.Sizing.EstimateCharges {
+		return nil
+	}
+	charges := sizing.ChargesFor(product, instrumentType)
+	return &charges
+}
+
 // shouldUseAMO determines if an order should be placed as AMO based on scheduled time
 // Market hours: 9:00 AM - 3:30 PM IST (any day of the week)
 func (r *SheetsReader) shouldUseAMO(scheduledTime time.Time) bool {
@@ -0,0 +1,362 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/broker/kitetest"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+func newTestReader(t *testing.T, now time.Time) *SheetsReader {
+	t.Helper()
+	log, err := logger.NewLogger("error", filepath.Join(t.TempDir(), "reader.log"))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	r := NewSnapshotReader(&config.Config{}, nil, log)
+	r.SetClock(clock.NewSimulated(now))
+	return r
+}
+
+func mustIST(t *testing.T) *time.Location {
+	t.Helper()
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatalf("failed to load IST: %v", err)
+	}
+	return ist
+}
+
+// row builds a sheet row (columns B-L) for symbol at date/at with the given lots and quantity
+func row(symbol, date, at, lots, quantity string) []interface{} {
+	return []interface{}{"100", "CNC", symbol, "500000", symbol, date, at, "1000", lots, "NSE", quantity}
+}
+
+func TestParseRowsSkipsPastRowsAtFixedTime(t *testing.T) {
+	ist := mustIST(t)
+	r := newTestReader(t, time.Date(2024, 1, 15, 9, 0, 0, 0, ist))
+
+	orders, err := r.parseRows(context.Background(), [][]interface{}{
+		row("PAST", "2024-01-15", "08:59:59", "1", "10"),
+		row("NOW", "2024-01-15", "09:00:00", "1", "10"),
+		row("LATER", "2024-01-15", "09:00:01", "1", "10"),
+		row("YESTERDAY", "2024-01-14", "15:00:00", "1", "10"),
+	}, "Buy")
+	if err != nil {
+		t.Fatalf("parseRows: %v", err)
+	}
+
+	var symbols []string
+	for _, order := range orders {
+		symbols = append(symbols, order.Symbol)
+	}
+	if len(symbols) != 2 || symbols[0] != "NOW" || symbols[1] != "LATER" {
+		t.Fatalf("parsed symbols = %v, want [NOW LATER]", symbols)
+	}
+
+	want := time.Date(2024, 1, 15, 9, 0, 0, 0, ist)
+	if !orders[0].ScheduledTime.Equal(want) {
+		t.Errorf("ScheduledTime = %v, want %v", orders[0].ScheduledTime, want)
+	}
+	if !orders[0].CreatedAt.Equal(want) {
+		t.Errorf("CreatedAt = %v, want the simulated now %v", orders[0].CreatedAt, want)
+	}
+}
+
+func TestParseRowsMarksAMOAtMarketBoundaries(t *testing.T) {
+	ist := mustIST(t)
+	r := newTestReader(t, time.Date(2024, 1, 15, 0, 0, 0, 0, ist))
+
+	tests := []struct {
+		at    string
+		isAMO bool
+	}{
+		{"08:59:00", true},
+		{"09:00:00", false},
+		{"15:29:00", false},
+		{"15:30:00", true},
+		{"18:00:00", true},
+	}
+
+	for _, tt := range tests {
+		orders, err := r.parseRows(context.Background(), [][]interface{}{
+			row("INFY", "2024-01-15", tt.at, "1", "10"),
+		}, "Buy")
+		if err != nil {
+			t.Fatalf("parseRows(%s): %v", tt.at, err)
+		}
+		if len(orders) != 1 {
+			t.Fatalf("parseRows(%s) returned %d orders, want 1", tt.at, len(orders))
+		}
+		if orders[0].IsAMO != tt.isAMO {
+			t.Errorf("order at %s: IsAMO = %v, want %v", tt.at, orders[0].IsAMO, tt.isAMO)
+		}
+	}
+}
+
+func TestParseRowsSplitsQuantityAcrossLots(t *testing.T) {
+	ist := mustIST(t)
+	r := newTestReader(t, time.Date(2024, 1, 15, 9, 0, 0, 0, ist))
+
+	orders, err := r.parseRows(context.Background(), [][]interface{}{
+		row("INFY", "2024-01-15", "10:00", "3", "10"),
+	}, "Sell")
+	if err != nil {
+		t.Fatalf("parseRows: %v", err)
+	}
+
+	wantQuantities := []int{4, 3, 3}
+	if len(orders) != len(wantQuantities) {
+		t.Fatalf("got %d orders, want %d", len(orders), len(wantQuantities))
+	}
+	seen := make(map[string]bool)
+	for i, order := range orders {
+		if order.Quantity != wantQuantities[i] {
+			t.Errorf("lot %d quantity = %d, want %d", i+1, order.Quantity, wantQuantities[i])
+		}
+		if seen[order.ID] {
+			t.Errorf("duplicate order ID %s", order.ID)
+		}
+		seen[order.ID] = true
+	}
+}
+
+func TestParseRowsValidatesInstruments(t *testing.T) {
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+	server.AddInstrument(kitetest.Instrument{Token: 408065, ExchangeToken: "1594", Tradingsymbol: "INFY", Name: "INFOSYS",
+		TickSize: 0.05, LotSize: 1, InstrumentType: "EQ", Segment: "NSE", Exchange: "NSE"})
+	server.AddInstrument(kitetest.Instrument{Token: 128083204, ExchangeToken: "500325", Tradingsymbol: "RELIANCE",
+		Name: "RELIANCE INDUSTRIES", TickSize: 0.05, LotSize: 1, InstrumentType: "EQ", Segment: "BSE", Exchange: "BSE"})
+
+	log, err := logger.NewLogger("error", filepath.Join(t.TempDir(), "reader.log"))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+	cfg := &config.Config{}
+	server.Configure(cfg)
+	cfg.Instruments = config.InstrumentsConfig{Enabled: true, CacheDir: t.TempDir()}
+	r := NewSnapshotReader(cfg, nil, log)
+	r.SetClock(clock.NewSimulated(time.Date(2024, 1, 15, 9, 0, 0, 0, mustIST(t))))
+
+	infy := row("INFY", "2024-01-15", "10:00", "1", "10")
+	infy[0] = "1500.123"
+	reliance := []interface{}{"2500", "CNC", "Reliance", "500325", "RIL", "2024-01-15", "10:00", "1000", "1", "BSE", "4"}
+	orders, err := r.parseRows(context.Background(), [][]interface{}{
+		infy,
+		row("DELISTED", "2024-01-15", "10:00", "1", "10"),
+		reliance,
+	}, "Buy")
+	if err != nil {
+		t.Fatalf("parseRows: %v", err)
+	}
+	if len(orders) != 2 {
+		t.Fatalf("parsed %d orders, want INFY and RELIANCE without the unknown symbol", len(orders))
+	}
+	if orders[0].Symbol != "INFY" || orders[0].Price != 1500.1 {
+		t.Errorf("INFY order = %s at %v, want the buy rounded down to the 0.05 tick grid (1500.1)", orders[0].Symbol, orders[0].Price)
+	}
+	if adjustments := orders[0].PriceAdjustments; len(adjustments) != 1 || adjustments[0].From != 1500.123 {
+		t.Errorf("INFY price adjustments = %+v, want the tick rounding from 1500.123", adjustments)
+	}
+	if orders[1].Symbol != "RELIANCE" || orders[1].Exchange != "BSE" {
+		t.Errorf("BSE code row resolved to %s:%s, want BSE:RELIANCE", orders[1].Exchange, orders[1].Symbol)
+	}
+}
+
+func TestParseRowsSizesOrders(t *testing.T) {
+	r := newTestReader(t, time.Date(2024, 1, 15, 9, 0, 0, 0, mustIST(t)))
+	r.config.Sizing.EstimateCharges = true
+
+	percent := row("PCT", "2024-01-15", "10:00", "2", "")
+	percent[7] = "5%"
+	small := row("SMALL", "2024-01-15", "10:00", "1", "")
+	small[7] = "50"
+	orders, err := r.parseRows(context.Background(), [][]interface{}{
+		row("MONEY", "2024-01-15", "10:00", "2", ""),
+		percent,
+		small,
+	}, "Buy")
+	if err != nil {
+		t.Fatalf("parseRows: %v", err)
+	}
+
+	var got []string
+	for _, order := range orders {
+		got = append(got, fmt.Sprintf("%s:%d:%v", order.Symbol, order.Quantity, order.FundsPercent))
+	}
+	// 1000 buys 9 shares at 100 once charges are left for; the percentage row is sized when due
+	want := []string{"MONEY:5:0", "MONEY:4:0", "PCT:0:2.5", "PCT:0:2.5"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("orders = %v, want %v and SMALL skipped", got, want)
+	}
+}
+
+func TestParseRowsResolvesDerivatives(t *testing.T) {
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+	for _, instrument := range []kitetest.Instrument{
+		{Token: 13238786, Tradingsymbol: "NIFTY24JANFUT", Name: "NIFTY", Expiry: "2024-01-25", TickSize: 0.05, LotSize: 50,
+			InstrumentType: "FUT", Segment: "NFO-FUT", Exchange: "NFO"},
+		{Token: 13317890, Tradingsymbol: "NIFTY24FEBFUT", Name: "NIFTY", Expiry: "2024-02-29", TickSize: 0.05, LotSize: 50,
+			InstrumentType: "FUT", Segment: "NFO-FUT", Exchange: "NFO"},
+		{Token: 10560258, Tradingsymbol: "NIFTY24JAN21500CE", Name: "NIFTY", Expiry: "2024-01-25", Strike: 21500,
+			TickSize: 0.05, LotSize: 50, InstrumentType: "CE", Segment: "NFO-OPT", Exchange: "NFO"},
+	} {
+		server.AddInstrument(instrument)
+	}
+
+	log, err := logger.NewLogger("error", filepath.Join(t.TempDir(), "reader.log"))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+	cfg := &config.Config{}
+	server.Configure(cfg)
+	cfg.Instruments = config.InstrumentsConfig{Enabled: true, CacheDir: t.TempDir()}
+	cfg.Broker.Derivatives.ExpiryDayCutoff = "15:00"
+	r := NewSnapshotReader(cfg, nil, log)
+	r.SetClock(clock.NewSimulated(time.Date(2024, 1, 15, 9, 0, 0, 0, mustIST(t))))
+
+	// price, product, name, bse, underlying, date, time, money, lots, exchange, quantity, expiry_seconds, type, expiry, strike
+	contract := func(product, date, at, quantity, instrumentType, expiry, strike string) []interface{} {
+		return []interface{}{"100", product, "", "", "NIFTY", date, at, "", "2", "NSE", quantity, "", instrumentType, expiry, strike}
+	}
+	orders, err := r.parseRows(context.Background(), [][]interface{}{
+		contract("NRML", "2024-01-15", "10:00", "160", "FUT", "", ""),
+		contract("", "2024-01-15", "10:00", "60", "CE", "", "21500"),
+		contract("CNC", "2024-01-15", "10:00", "50", "CE", "", "21500"),
+		contract("NRML", "2024-01-25", "15:10", "100", "FUT", "2024-01-25", ""),
+		contract("NRML", "2024-01-26", "10:00", "50", "FUT", "", ""),
+	}, "Buy")
+	if err != nil {
+		t.Fatalf("parseRows: %v", err)
+	}
+
+	var got []string
+	for _, order := range orders {
+		got = append(got, fmt.Sprintf("%s:%s:%s:%d", order.Exchange, order.Symbol, order.Product, order.Quantity))
+	}
+	// 160 of the future is three lots, split 2+1; the option defaults to NRML and rounds down to
+	// one lot; the CNC option and the row past the expiry-day cutoff are skipped; after January
+	// expiry the nearest future is February's
+	want := []string{"NFO:NIFTY24JANFUT:NRML:100", "NFO:NIFTY24JANFUT:NRML:50", "NFO:NIFTY24JAN21500CE:NRML:50",
+		"NFO:NIFTY24FEBFUT:NRML:50"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("orders = %v, want %v", got, want)
+	}
+	if orders[0].LotSize != 50 || orders[0].InstrumentType != "FUT" || orders[0].Expiry.Format("2006-01-02") != "2024-01-25" {
+		t.Errorf("future order = %+v, want lot size 50, FUT expiring 2024-01-25", orders[0])
+	}
+}
+
+func TestParseRowsReadsConditions(t *testing.T) {
+	r := newTestReader(t, time.Date(2024, 1, 15, 9, 0, 0, 0, mustIST(t)))
+
+	withCondition := func(symbol, condition string) []interface{} {
+		return append(row(symbol, "2024-01-15", "10:00", "2", "10"), "", "", "", "", condition)
+	}
+	orders, err := r.parseRows(context.Background(), [][]interface{}{
+		withCondition("DIP", "change% <= -2%"),
+		withCondition("BAD", "ltp == 10"),
+		withCondition("PLAIN", ""),
+	}, "Buy")
+	if err != nil {
+		t.Fatalf("parseRows: %v", err)
+	}
+
+	var got []string
+	for _, order := range orders {
+		got = append(got, fmt.Sprintf("%s:%v", order.Symbol, order.Condition))
+	}
+	want := []string{"DIP:change_pct <= -2", "DIP:change_pct <= -2", "PLAIN:<nil>", "PLAIN:<nil>"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("orders = %v, want %v and BAD skipped", got, want)
+	}
+	if orders[0].Condition == orders[1].Condition {
+		t.Error("orders split from one row share a condition")
+	}
+}
+
+func TestParseRowsReadsGTTs(t *testing.T) {
+	r := newTestReader(t, time.Date(2024, 1, 15, 9, 0, 0, 0, mustIST(t)))
+
+	withGTT := func(symbol, gtt, stopLoss, condition string) []interface{} {
+		return append(row(symbol, "2024-01-31", "15:00", "1", "10"), "", "", "", "", condition, gtt, stopLoss)
+	}
+	buys, err := r.parseRows(context.Background(), [][]interface{}{
+		withGTT("DIP", "GTT", "", ""),
+		withGTT("BUYOCO", "OCO", "90", ""),
+		withGTT("BOTH", "GTT", "", "ltp <= 95"),
+	}, "Buy")
+	if err != nil {
+		t.Fatalf("parseRows: %v", err)
+	}
+	sells, err := r.parseRows(context.Background(), [][]interface{}{
+		withGTT("EXIT", "oco", "90", ""),
+		withGTT("BADSTOP", "OCO", "110", ""),
+	}, "Sell")
+	if err != nil {
+		t.Fatalf("parseRows: %v", err)
+	}
+
+	var got []string
+	for _, order := range append(buys, sells...) {
+		got = append(got, fmt.Sprintf("%s:%s:%v:%v", order.Symbol, order.GTT.Type, order.GTT.TriggerValues(order.Price), order.IsAMO))
+	}
+	want := []string{"DIP:single:[100]:false", "EXIT:two-leg:[90 100]:false"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("orders = %v, want %v and the invalid GTT rows skipped", got, want)
+	}
+}
+
+func TestGTTStatusUpdates(t *testing.T) {
+	gtt := &models.GTT{Type: models.GTTSingle}
+	orders := []models.Order{
+		{ID: "A-1", SourceRow: 3, GTT: gtt},
+		{ID: "A-2", SourceRow: 3, GTT: gtt},
+		{ID: "B", SourceRow: 4, GTT: gtt},
+		{ID: "C", SourceRow: 5, GTT: gtt},
+		{ID: "PLAIN", SourceRow: 6},
+	}
+	records := map[string]models.GTTRecord{
+		"A-1": {TriggerID: 1001, Status: models.GTTStatusActive},
+		"A-2": {TriggerID: 1002, Status: models.GTTStatusTriggered, OrderID: "240115000000001"},
+		"B":   {TriggerID: 1003, Status: models.GTTStatusActive},
+	}
+	rows := [][]interface{}{
+		{"100"},
+		{"100", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "", "active #1003"},
+		{"100"},
+		{"100"},
+	}
+
+	updates, err := gttStatusUpdates("to_buy!B5:T", "T", rows, orders, records)
+	if err != nil {
+		t.Fatalf("gttStatusUpdates: %v", err)
+	}
+	var got []string
+	for _, update := range updates {
+		got = append(got, fmt.Sprintf("%s=%v", update.Range, update.Values[0][0]))
+	}
+	// Row 4 already shows its status; sheet rows start at 5 in this range
+	want := []string{"to_buy!T5=active #1001; triggered #1002 order 240115000000001", "to_buy!T7=pending"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("updates = %v, want %v", got, want)
+	}
+
+	if _, err := gttStatusUpdates("to_buy!D3:T", "C", rows, orders, records); err == nil {
+		t.Error("gttStatusUpdates with a column left of the range succeeded, want an error")
+	}
+}
@@ -0,0 +1,83 @@
+package reader
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+
+	"github.com/mach_five/trading-system/internal/cache"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// NewSnapshotReader creates a reader for exported sheet snapshots. It parses CSV files
+// with the same parseRows logic as the live reader but never calls the Sheets API.
+func NewSnapshotReader(cfg *config.Config, cache *cache.RedisCache, log *logger.Logger) *SheetsReader {
+	return &SheetsReader{
+		config:      cfg,
+		cache:       cache,
+		logger:      log,
+		clock:       clock.Real{},
+		journal:     openJournal(cfg, log),
+		journaled:   make(map[string]bool),
+		instruments: openInstruments(cfg, cache, log),
+	}
+}
+
+// ReadSnapshot parses to_buy/to_sell CSV snapshots and caches the resulting orders.
+// Each CSV holds the sheet range starting at column B, exactly as the live reader sees it;
+// header rows are skipped by parseRows. Either path may be empty.
+func (r *SheetsReader) ReadSnapshot(ctx context.Context, buyPath, sellPath string) ([]models.Order, error) {
+	var allOrders []models.Order
+
+	for _, snapshot := range []struct{ path, side string }{{buyPath, "Buy"}, {sellPath, "Sell"}} {
+		if snapshot.path == "" {
+			continue
+		}
+
+		rows, err := readCSVRows(snapshot.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s snapshot: %w", snapshot.side, err)
+		}
+
+		orders, err := r.parseRows(ctx, rows, snapshot.side)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s snapshot: %w", snapshot.side, err)
+		}
+
+		r.logger.Info("📄 Parsed %d %s orders from %d rows in %s", len(orders), snapshot.side, len(rows), snapshot.path)
+		allOrders = append(allOrders, orders...)
+	}
+
+	r.cacheOrders(ctx, allOrders)
+	return allOrders, nil
+}
+
+// readCSVRows loads a CSV file into the row format returned by the Sheets API
+func readCSVRows(path string) ([][]interface{}, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1 // Sheets ranges omit trailing empty cells, so row lengths vary
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]interface{}, 0, len(records))
+	for _, record := range records {
+		row := make([]interface{}, len(record))
+		for i, cell := range record {
+			row[i] = cell
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
@@ -0,0 +1,167 @@
+package replay
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PriceEvent is one historical last traded price fed to the paper broker
+type PriceEvent struct {
+	Time     time.Time
+	Exchange string
+	Symbol   string
+	Price    float64
+}
+
+// priceTimeLayouts are the timestamp formats accepted in price CSVs. Layouts without
+// a zone are interpreted in IST, matching the order sheets.
+var priceTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+}
+
+// LoadPrices reads historical prices from CSV files and returns them in time order.
+// Each file needs a header row and is either tick data
+// (timestamp,exchange,symbol,price) or OHLC bars (timestamp,exchange,symbol,open,high,low,close).
+// Extra columns such as volume are ignored.
+func LoadPrices(paths []string, loc *time.Location) ([]PriceEvent, error) {
+	var events []PriceEvent
+	for _, path := range paths {
+		fileEvents, err := loadPriceFile(path, loc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		events = append(events, fileEvents...)
+	}
+
+	// Stable so the intra-bar order of OHLC events is preserved
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events, nil
+}
+
+// loadPriceFile parses a single tick or OHLC CSV file
+func loadPriceFile(path string, loc *time.Location) ([]PriceEvent, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"timestamp", "exchange", "symbol"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing %q column", required)
+		}
+	}
+
+	// OHLC bars are walked open -> nearer extreme -> farther extreme -> close, the
+	// usual assumption when only bar data is available
+	var priceColumns []string
+	isOHLC := hasColumn(columns, "open")
+	switch {
+	case isOHLC:
+		for _, name := range []string{"high", "low", "close"} {
+			if _, ok := columns[name]; !ok {
+				return nil, fmt.Errorf("OHLC file missing %q column", name)
+			}
+		}
+	case hasColumn(columns, "price"):
+		priceColumns = []string{"price"}
+	default:
+		return nil, fmt.Errorf("expected a price column (ticks) or open/high/low/close columns (bars)")
+	}
+
+	var events []PriceEvent
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		field := func(name string) string {
+			i := columns[name]
+			if i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		at, err := parsePriceTime(field("timestamp"), loc)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		values := make(map[string]float64, 4)
+		names := priceColumns
+		if isOHLC {
+			names = []string{"open", "high", "low", "close"}
+		}
+		for _, name := range names {
+			value, err := strconv.ParseFloat(field(name), 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s %q", line, name, field(name))
+			}
+			values[name] = value
+		}
+
+		sequence := names
+		if isOHLC {
+			if values["close"] >= values["open"] {
+				sequence = []string{"open", "low", "high", "close"}
+			} else {
+				sequence = []string{"open", "high", "low", "close"}
+			}
+		}
+
+		for _, name := range sequence {
+			events = append(events, PriceEvent{
+				Time:     at,
+				Exchange: strings.ToUpper(field("exchange")),
+				Symbol:   strings.ToUpper(field("symbol")),
+				Price:    values[name],
+			})
+		}
+	}
+	return events, nil
+}
+
+// parsePriceTime parses a price timestamp, defaulting to loc when no zone is given
+func parsePriceTime(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range priceTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised timestamp %q", value)
+}
+
+// hasColumn reports whether the header contains name
+func hasColumn(columns map[string]int, name string) bool {
+	_, ok := columns[name]
+	return ok
+}
@@ -0,0 +1,197 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/mach_five/trading-system/internal/broker"
+	"github.com/mach_five/trading-system/internal/cache"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/journal"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/reader"
+	"github.com/mach_five/trading-system/internal/trigger"
+)
+
+// Options describes one replay run
+type Options struct {
+	Date       time.Time // Trading day to replay; only its date in IST is used
+	BuyPath    string    // CSV snapshot of the to_buy sheet range
+	SellPath   string    // CSV snapshot of the to_sell sheet range
+	PricePaths []string  // Historical tick or OHLC CSV files
+	OutputDir  string    // Receives the journal, report.md and fills.csv
+}
+
+// Runner replays an order book snapshot against historical prices. It drives the real
+// reader parsing and trigger execution with a simulated clock, an in-memory Redis and
+// the paper broker, so nothing leaves the process.
+type Runner struct {
+	config  *config.Config
+	options Options
+	logger  *logger.Logger
+}
+
+// NewRunner creates a new replay runner. cfg is copied and adjusted for replay.
+func NewRunner(cfg *config.Config, opts Options, log *logger.Logger) *Runner {
+	replayCfg := *cfg
+	replayCfg.Broker.Type = "paper"
+	replayCfg.Broker.Paper.LatencyMs = 0
+	replayCfg.Broker.Paper.LatencyJitterMs = 0
+	replayCfg.Broker.Paper.PricesPath = ""
+	replayCfg.Broker.Paper.QuoteSource = config.PaperQuoteSourceOff // Prices come from the replayed day only
+	replayCfg.Broker.RateLimit = config.RateLimitConfig{RequestsPerSecond: 1000000, BurstSize: 1000000}
+	replayCfg.Trigger.WorkerPoolSize = 1 // Execute orders one at a time so seeded runs are reproducible
+	replayCfg.Metrics.Enabled = false
+	replayCfg.Admin.Enabled = false
+	replayCfg.Leader.Enabled = false         // A replay is the only instance on its in-memory Redis
+	replayCfg.Notify = config.NotifyConfig{} // Simulated executions must not page anyone
+	replayCfg.Instruments.Enabled = false    // Today's dump would neither match the replayed day nor stay offline
+	replayCfg.Journal.Path = filepath.Join(opts.OutputDir, "journal.jsonl")
+
+	return &Runner{
+		config:  &replayCfg,
+		options: opts,
+		logger:  log,
+	}
+}
+
+// Run replays the trading day and writes the fill report to the output directory
+func (r *Runner) Run(ctx context.Context) (*Report, error) {
+	istLocation, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		istLocation = time.UTC
+	}
+	date := r.options.Date.In(istLocation)
+	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, istLocation)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+
+	if err := os.MkdirAll(r.options.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	// Start each run with an empty journal
+	if err := os.Remove(r.config.Journal.Path); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to reset journal: %w", err)
+	}
+
+	events, err := LoadPrices(r.options.PricePaths, istLocation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load prices: %w", err)
+	}
+
+	redisServer, err := miniredis.Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start in-memory Redis: %w", err)
+	}
+	defer redisServer.Close()
+
+	sim := clock.NewSimulated(dayStart)
+
+	redisCache, err := cache.NewRedisCache(redisServer.Addr(), "", 0)
+	if err != nil {
+		return nil, err
+	}
+	defer redisCache.Close()
+	redisCache.SetClock(sim)
+
+	brokerMgr, err := broker.NewBrokerManager(r.config, r.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create paper broker: %w", err)
+	}
+	brokerMgr.SetClock(sim)
+	paper, ok := brokerMgr.Broker().(*broker.PaperBroker)
+	if !ok {
+		return nil, fmt.Errorf("replay requires the paper broker")
+	}
+
+	sheetsReader := reader.NewSnapshotReader(r.config, redisCache, r.logger)
+	sheetsReader.SetClock(sim)
+
+	orderTrigger := trigger.NewTrigger(r.config, redisCache, brokerMgr, r.logger)
+	orderTrigger.SetClock(sim)
+
+	r.logger.Section(fmt.Sprintf("⏪ Replaying %s", dayStart.Format("2006-01-02")))
+
+	parsed, err := sheetsReader.ReadSnapshot(ctx, r.options.BuyPath, r.options.SellPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+	for _, order := range parsed {
+		if !order.ScheduledTime.Before(dayStart) && order.ScheduledTime.Before(dayEnd) {
+			orders = append(orders, order)
+		}
+	}
+	if skipped := len(parsed) - len(orders); skipped > 0 {
+		r.logger.Warn("⚠️  Ignoring %d orders scheduled outside %s", skipped, dayStart.Format("2006-01-02"))
+	}
+
+	lastPrices := make(map[string]float64)
+	for _, at := range timeline(orders, events, dayStart, dayEnd) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Orders due at T are placed against the book as it stood before T's prices
+		sim.Set(at)
+		if err := orderTrigger.ExecuteDueOrders(ctx); err != nil {
+			r.logger.Error("❌ Replay cycle at %s failed: %v", at.Format("15:04:05"), err)
+		}
+
+		for len(events) > 0 && !events[0].Time.After(at) {
+			event := events[0]
+			events = events[1:]
+			if event.Time.Before(dayStart) {
+				continue
+			}
+			paper.UpdatePrice(event.Exchange, event.Symbol, event.Price, event.Time)
+			lastPrices[event.Exchange+":"+event.Symbol] = event.Price
+		}
+	}
+
+	entries, err := journal.ReadEntries(r.config.Journal.Path, dayStart, dayEnd)
+	if err != nil && !os.IsNotExist(err) {
+		r.logger.Warn("⚠️  Failed to read replay journal: %v", err)
+	}
+
+	report := buildReport(ctx, dayStart, orders, entries, paper, redisCache, lastPrices, r.config.Broker.Paper.InitialCash)
+	if err := report.WriteFiles(r.options.OutputDir); err != nil {
+		return nil, err
+	}
+
+	r.logger.Success("✅ Replay complete: %d filled, %d unfilled, %d rejected, %d expired, %d missed, P&L %.2f",
+		report.Count(StatusFilled), report.Count(StatusUnfilled), report.Count(StatusRejected),
+		report.Count(StatusExpired), report.Count(StatusMissed), report.TotalPnL())
+	return report, nil
+}
+
+// timeline returns the distinct order and price times within the day, in order
+func timeline(orders []models.Order, events []PriceEvent, dayStart, dayEnd time.Time) []time.Time {
+	seen := make(map[int64]bool)
+	var times []time.Time
+	add := func(t time.Time) {
+		if t.Before(dayStart) || !t.Before(dayEnd) || seen[t.UnixNano()] {
+			return
+		}
+		seen[t.UnixNano()] = true
+		times = append(times, t)
+	}
+
+	for _, order := range orders {
+		add(order.ScheduledTime)
+	}
+	for _, event := range events {
+		add(event.Time)
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	return times
+}
@@ -0,0 +1,272 @@
+package replay
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/broker"
+	"github.com/mach_five/trading-system/internal/cache"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// Order outcomes in a replay report
+const (
+	StatusFilled   = "filled"   // Order traded
+	StatusUnfilled = "unfilled" // Order accepted but still resting in the book at the end of the day
+	StatusRejected = "rejected" // Broker rejected the order
+	StatusExpired  = "expired"  // Order passed its expiry window and the late-order policy did not execute it
+	StatusMissed   = "missed"   // Order never reached the broker (halted, paused or never due)
+)
+
+// OrderOutcome is what happened to one planned order
+type OrderOutcome struct {
+	Order       models.Order
+	Status      string
+	ExecutionID string
+	FillPrice   float64
+	FilledAt    time.Time
+	SlippageBps float64 // Versus the planned price; positive means worse than planned
+	Reason      string  // Why the order was rejected or missed
+}
+
+// PositionSummary is an end-of-day paper position marked to the last replayed price
+type PositionSummary struct {
+	broker.PaperPosition
+	LastPrice     float64
+	UnrealisedPnL float64
+}
+
+// Report summarises a replayed trading day
+type Report struct {
+	Date          time.Time
+	Orders        []OrderOutcome
+	Positions     []PositionSummary
+	StartingCash  float64
+	EndingCash    float64
+	RealisedPnL   float64
+	UnrealisedPnL float64
+}
+
+// buildReport combines the journal, paper broker state and cache into per-order outcomes
+func buildReport(ctx context.Context, date time.Time, orders []models.Order, entries []models.JournalEntry, paper *broker.PaperBroker,
+	redisCache *cache.RedisCache, lastPrices map[string]float64, startingCash float64) *Report {
+	executions := make(map[string]models.ExecutionResult)
+	expirations := make(map[string]models.ExecutionResult)
+	for _, entry := range entries {
+		if entry.Result == nil {
+			continue
+		}
+		switch entry.Event {
+		case models.JournalEventExecution, models.JournalEventRejected:
+			executions[entry.Result.OrderID] = *entry.Result
+		case models.JournalEventExpired:
+			expirations[entry.Result.OrderID] = *entry.Result
+		}
+	}
+	fills := make(map[string]models.Fill)
+	for _, fill := range paper.Fills() {
+		fills[fill.OrderID] = fill
+	}
+	resting := make(map[string]bool)
+	for _, open := range paper.OpenOrders() {
+		resting[open.Order.ID] = true
+	}
+
+	report := &Report{
+		Date:         date,
+		StartingCash: startingCash,
+		EndingCash:   paper.Cash(),
+	}
+
+	for _, order := range orders {
+		outcome := OrderOutcome{Order: order}
+		result, executed := executions[order.ID]
+		fill, filled := fills[order.ID]
+		expired, isExpired := expirations[order.ID]
+
+		switch {
+		case filled:
+			outcome.Status = StatusFilled
+			outcome.ExecutionID = fill.ExecutionID
+			outcome.FillPrice = fill.Price
+			outcome.FilledAt = fill.FilledAt
+			outcome.SlippageBps = order.SlippageBps(fill.Price)
+		case executed && result.Success && resting[order.ID]:
+			outcome.Status = StatusUnfilled
+			outcome.ExecutionID = result.ExecutionID
+			outcome.Reason = "price never crossed the limit"
+		case executed:
+			outcome.Status = StatusRejected
+			outcome.Reason = result.ErrorMessage
+		case isExpired:
+			outcome.Status = StatusExpired
+			outcome.Reason = expired.ErrorMessage
+		default:
+			outcome.Status = StatusMissed
+			outcome.Reason = "never became due"
+			if _, err := redisCache.GetOrder(ctx, order.ID); err == nil {
+				outcome.Reason = "still queued at end of day (halted or paused)"
+			} else if !errors.Is(err, cache.ErrOrderNotFound) {
+				outcome.Reason = err.Error()
+			}
+		}
+		report.Orders = append(report.Orders, outcome)
+	}
+
+	for _, position := range paper.Positions() {
+		summary := PositionSummary{PaperPosition: position}
+		report.RealisedPnL += position.RealisedPnL
+		if lastPrice, ok := lastPrices[position.Exchange+":"+position.Symbol]; ok && position.Quantity != 0 {
+			summary.LastPrice = lastPrice
+			summary.UnrealisedPnL = (lastPrice - position.AveragePrice) * float64(position.Quantity)
+			report.UnrealisedPnL += summary.UnrealisedPnL
+		}
+		report.Positions = append(report.Positions, summary)
+	}
+
+	return report
+}
+
+// TotalPnL returns realised plus unrealised P&L
+func (r *Report) TotalPnL() float64 {
+	return r.RealisedPnL + r.UnrealisedPnL
+}
+
+// Count returns the number of orders with the given status
+func (r *Report) Count(status string) int {
+	count := 0
+	for _, outcome := range r.Orders {
+		if outcome.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// AverageSlippageBps returns the quantity-weighted slippage across filled orders
+func (r *Report) AverageSlippageBps() float64 {
+	var weighted float64
+	var quantity int
+	for _, outcome := range r.Orders {
+		if outcome.Status == StatusFilled {
+			weighted += outcome.SlippageBps * float64(outcome.Order.Quantity)
+			quantity += outcome.Order.Quantity
+		}
+	}
+	if quantity == 0 {
+		return 0
+	}
+	return weighted / float64(quantity)
+}
+
+// WriteFiles writes report.md and fills.csv into dir
+func (r *Report) WriteFiles(dir string) error {
+	for name, write := range map[string]func(io.Writer) error{
+		"report.md": r.WriteMarkdown,
+		"fills.csv": r.WriteCSV,
+	} {
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", name, err)
+		}
+		if err := write(file); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// WriteMarkdown renders the report as Markdown
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Replay report for %s\n\n", r.Date.Format("2006-01-02"))
+	b.WriteString("## Summary\n\n")
+	b.WriteString("| Metric | Value |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Orders planned | %d |\n", len(r.Orders))
+	fmt.Fprintf(&b, "| Filled | %d |\n", r.Count(StatusFilled))
+	fmt.Fprintf(&b, "| Unfilled | %d |\n", r.Count(StatusUnfilled))
+	fmt.Fprintf(&b, "| Rejected | %d |\n", r.Count(StatusRejected))
+	fmt.Fprintf(&b, "| Expired | %d |\n", r.Count(StatusExpired))
+	fmt.Fprintf(&b, "| Missed | %d |\n", r.Count(StatusMissed))
+	fmt.Fprintf(&b, "| Starting cash | %.2f |\n", r.StartingCash)
+	fmt.Fprintf(&b, "| Ending cash | %.2f |\n", r.EndingCash)
+	fmt.Fprintf(&b, "| Realised P&L | %.2f |\n", r.RealisedPnL)
+	fmt.Fprintf(&b, "| Unrealised P&L | %.2f |\n", r.UnrealisedPnL)
+	fmt.Fprintf(&b, "| Total P&L | %.2f |\n", r.TotalPnL())
+	fmt.Fprintf(&b, "| Avg slippage (bps, qty-weighted) | %.2f |\n\n", r.AverageSlippageBps())
+
+	b.WriteString("## Orders\n\n")
+	b.WriteString("| Order ID | Side | Symbol | Qty | Type | Scheduled | Planned | Status | Fill | Filled at | Slippage (bps) | Reason |\n")
+	b.WriteString("|---|---|---|---|---|---|---|---|---|---|---|---|\n")
+	for _, outcome := range r.Orders {
+		order := outcome.Order
+		fill, filledAt, slippage := "", "", ""
+		if outcome.Status == StatusFilled {
+			fill = fmt.Sprintf("%.2f", outcome.FillPrice)
+			filledAt = outcome.FilledAt.Format("15:04:05")
+			slippage = fmt.Sprintf("%.2f", outcome.SlippageBps)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %d | %s | %s | %.2f | %s | %s | %s | %s | %s |\n",
+			order.ID, order.Side, order.Symbol, order.Quantity, order.OrderType,
+			order.ScheduledTime.Format("15:04:05"), order.Price, outcome.Status,
+			fill, filledAt, slippage, strings.ReplaceAll(outcome.Reason, "|", "/"))
+	}
+
+	if len(r.Positions) > 0 {
+		b.WriteString("\n## Positions\n\n")
+		b.WriteString("| Instrument | Qty | Avg price | Last price | Realised P&L | Unrealised P&L |\n")
+		b.WriteString("|---|---|---|---|---|---|\n")
+		for _, position := range r.Positions {
+			fmt.Fprintf(&b, "| %s:%s | %d | %.2f | %.2f | %.2f | %.2f |\n",
+				position.Exchange, position.Symbol, position.Quantity, position.AveragePrice,
+				position.LastPrice, position.RealisedPnL, position.UnrealisedPnL)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCSV writes one row per planned order
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{
+		"order_id", "side", "exchange", "symbol", "quantity", "order_type", "scheduled_time",
+		"planned_price", "status", "execution_id", "fill_price", "filled_at", "slippage_bps", "reason",
+	}); err != nil {
+		return err
+	}
+
+	for _, outcome := range r.Orders {
+		order := outcome.Order
+		fillPrice, filledAt, slippage := "", "", ""
+		if outcome.Status == StatusFilled {
+			fillPrice = strconv.FormatFloat(outcome.FillPrice, 'f', 2, 64)
+			filledAt = outcome.FilledAt.Format(time.RFC3339)
+			slippage = strconv.FormatFloat(outcome.SlippageBps, 'f', 2, 64)
+		}
+		if err := writer.Write([]string{
+			order.ID, order.Side, order.Exchange, order.Symbol, strconv.Itoa(order.Quantity), order.OrderType,
+			order.ScheduledTime.Format(time.RFC3339), strconv.FormatFloat(order.Price, 'f', 2, 64),
+			outcome.Status, outcome.ExecutionID, fillPrice, filledAt, slippage, outcome.Reason,
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,138 @@
+package replay
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/mach_five/trading-system/internal/broker"
+	"github.com/mach_five/trading-system/internal/cache"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// newTestPaper returns a seeded paper broker with 100000 cash and a cache on miniredis
+func newTestPaper(t *testing.T) (*broker.PaperBroker, *cache.RedisCache) {
+	t.Helper()
+	dir := t.TempDir()
+	log, err := logger.NewLogger("error", filepath.Join(dir, "replay.log"))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	cfg := &config.Config{}
+	cfg.Broker.Paper.Seed = 1
+	cfg.Broker.Paper.InitialCash = 100000
+	cfg.Journal.Path = filepath.Join(dir, "journal.jsonl")
+	paper, err := broker.NewPaperBroker(cfg, log)
+	if err != nil {
+		t.Fatalf("NewPaperBroker: %v", err)
+	}
+
+	server := miniredis.RunT(t)
+	redisCache, err := cache.NewRedisCache(server.Addr(), "", 0)
+	if err != nil {
+		t.Fatalf("failed to connect to miniredis: %v", err)
+	}
+	t.Cleanup(func() { redisCache.Close() })
+	return paper, redisCache
+}
+
+func TestBuildReport(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	at := day.Add(9*time.Hour + 30*time.Minute)
+	paper, redisCache := newTestPaper(t)
+
+	order := func(id, side string, price float64, quantity int) models.Order {
+		return models.Order{ID: id, Symbol: "INFY", Exchange: "NSE", Side: side, OrderType: "LIMIT",
+			Price: price, Quantity: quantity, ScheduledTime: at}
+	}
+	orders := []models.Order{
+		order("BUY", "Buy", 101, 10),  // Fills on arrival at the last price, better than planned
+		order("SELL", "Sell", 108, 5), // Rests, then fills at its limit when the price rises
+		order("REST", "Buy", 90, 10),  // Never crossed
+		order("REJECTED", "Buy", 100, 10),
+		order("HELD", "Buy", 100, 10),
+		order("LATE", "Buy", 100, 10),
+		order("QUEUED", "Buy", 100, 10),
+		order("NEVER", "Buy", 100, 10),
+	}
+
+	var entries []models.JournalEntry
+	execute := func(o models.Order) {
+		t.Helper()
+		result, err := paper.ExecuteOrder(ctx, o)
+		if err != nil {
+			t.Fatalf("ExecuteOrder(%s): %v", o.ID, err)
+		}
+		entries = append(entries, models.JournalEntry{Event: models.JournalEventExecution, Result: &result})
+	}
+	paper.UpdatePrice("NSE", "INFY", 100, at)
+	execute(orders[0])
+	execute(orders[1])
+	paper.UpdatePrice("NSE", "INFY", 110, at.Add(time.Minute))
+	execute(orders[2])
+
+	entries = append(entries,
+		models.JournalEntry{Event: models.JournalEventExecution,
+			Result: &models.ExecutionResult{OrderID: "REJECTED", ErrorMessage: "paper broker rejected order: insufficient cash"}},
+		models.JournalEntry{Event: models.JournalEventRejected,
+			Result: &models.ExecutionResult{OrderID: "HELD", ErrorMessage: "insufficient funds"}},
+		models.JournalEntry{Event: models.JournalEventExpired,
+			Result: &models.ExecutionResult{OrderID: "LATE", ErrorMessage: "not executed within expiry window"}},
+		models.JournalEntry{Event: models.JournalEventRead, Order: &orders[7]},
+	)
+	if err := redisCache.StoreOrder(ctx, orders[6], time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("StoreOrder: %v", err)
+	}
+
+	report := buildReport(ctx, day, orders, entries, paper, redisCache, map[string]float64{"NSE:INFY": 120}, 100000)
+
+	tests := []struct {
+		id       string
+		status   string
+		reason   string
+		fill     float64
+		slippage float64
+	}{
+		{"BUY", StatusFilled, "", 100, -99.0099},
+		{"SELL", StatusFilled, "", 108, 0},
+		{"REST", StatusUnfilled, "never crossed", 0, 0},
+		{"REJECTED", StatusRejected, "insufficient cash", 0, 0},
+		{"HELD", StatusRejected, "insufficient funds", 0, 0},
+		{"LATE", StatusExpired, "expiry window", 0, 0},
+		{"QUEUED", StatusMissed, "still queued", 0, 0},
+		{"NEVER", StatusMissed, "never became due", 0, 0},
+	}
+	if len(report.Orders) != len(tests) {
+		t.Fatalf("report has %d orders, want %d", len(report.Orders), len(tests))
+	}
+	for i, tt := range tests {
+		got := report.Orders[i]
+		if got.Order.ID != tt.id || got.Status != tt.status || !strings.Contains(got.Reason, tt.reason) ||
+			got.FillPrice != tt.fill || math.Abs(got.SlippageBps-tt.slippage) > 0.001 {
+			t.Errorf("outcome %d = %s %s (%q) fill %.2f slippage %.4f; want %s %s (%q) fill %.2f slippage %.4f",
+				i, got.Order.ID, got.Status, got.Reason, got.FillPrice, got.SlippageBps,
+				tt.id, tt.status, tt.reason, tt.fill, tt.slippage)
+		}
+	}
+
+	// Bought 10 at 100 and sold 5 at 108, leaving 5 marked at 120
+	if report.RealisedPnL != 40 || report.UnrealisedPnL != 100 || report.TotalPnL() != 140 {
+		t.Errorf("P&L = %.2f realised + %.2f unrealised, want 40 + 100", report.RealisedPnL, report.UnrealisedPnL)
+	}
+	if report.EndingCash != 100000-1000+540 {
+		t.Errorf("EndingCash = %.2f, want 99540", report.EndingCash)
+	}
+	if got := report.AverageSlippageBps(); math.Abs(got-(-99.0099*10)/15) > 0.001 {
+		t.Errorf("AverageSlippageBps = %.4f, want the quantity-weighted mean of the fills", got)
+	}
+}
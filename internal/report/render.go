@@ -0,0 +1,291 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Output formats
+const (
+	FormatMarkdown = "md"
+	FormatCSV      = "csv"
+	FormatHTML     = "html"
+)
+
+// Formats lists every output format
+var Formats = []string{FormatMarkdown, FormatCSV, FormatHTML}
+
+// WriteFiles writes report.<format> into dir for each format and returns the paths written
+func (r *Report) WriteFiles(dir string, formats []string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	var paths []string
+	for _, format := range formats {
+		var write func(io.Writer) error
+		switch format {
+		case FormatMarkdown:
+			write = r.WriteMarkdown
+		case FormatCSV:
+			write = r.WriteCSV
+		case FormatHTML:
+			write = r.WriteHTML
+		default:
+			return paths, fmt.Errorf("unknown report format %q (supported: %s)", format, strings.Join(Formats, ", "))
+		}
+
+		path := filepath.Join(dir, "report."+format)
+		file, err := os.Create(path)
+		if err != nil {
+			return paths, fmt.Errorf("failed to create %s: %w", path, err)
+		}
+		if err := write(file); err != nil {
+			file.Close()
+			return paths, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		if err := file.Close(); err != nil {
+			return paths, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// summaryRow is one line of the summary table
+type summaryRow struct {
+	Metric string
+	Value  string
+}
+
+// summaryRows returns the summary table shared by the Markdown and HTML renderings
+func (r *Report) summaryRows() []summaryRow {
+	amo, regular := r.SubmittedCounts()
+	return []summaryRow{
+		{"Orders read", strconv.Itoa(r.ReadCount())},
+		{"Orders in journal", strconv.Itoa(len(r.Orders))},
+		{"Executed", strconv.Itoa(r.Count(StatusExecuted))},
+		{"Failed", strconv.Itoa(r.Count(StatusFailed))},
+		{"Expired", strconv.Itoa(r.Count(StatusExpired))},
+		{"Abandoned", strconv.Itoa(r.Count(StatusAbandoned))},
+		{"Not submitted", strconv.Itoa(r.Count(StatusNotSubmitted))},
+		{"Submitted as AMO", strconv.Itoa(amo)},
+		{"Submitted as regular", strconv.Itoa(regular)},
+		{"Filled", strconv.Itoa(r.FilledCount())},
+		{"Turnover", fmt.Sprintf("%.2f", r.Turnover())},
+		{"Avg slippage (bps, qty-weighted)", fmt.Sprintf("%.2f", r.AverageSlippageBps())},
+	}
+}
+
+// latencyRows returns the percentile table rows
+func (r *Report) latencyRows() [][]string {
+	row := func(name string, p Percentiles) []string {
+		return []string{name, strconv.Itoa(p.Count), formatMs(p.P50), formatMs(p.P90), formatMs(p.P99), formatMs(p.Max)}
+	}
+	return [][]string{
+		row("Scheduler delay", r.SchedulerDelay),
+		row("Total time", r.TotalTime),
+	}
+}
+
+// orderRow formats one order for the Markdown and HTML order tables
+func orderRow(o OrderOutcome) []string {
+	order := o.Order
+	orderType := "Regular"
+	if order.IsAMO {
+		orderType = "AMO"
+	}
+	fill, filledQty, slippage, delay := "", "", "", ""
+	if o.Filled() {
+		fill = fmt.Sprintf("%.2f", o.FillPrice)
+		filledQty = strconv.Itoa(o.FilledQuantity)
+		slippage = fmt.Sprintf("%.2f", o.SlippageBps)
+	}
+	if o.Attempts > 0 {
+		delay = formatMs(o.SchedulerDelay)
+	}
+	return []string{
+		order.ID, order.Side, order.Symbol, strconv.Itoa(order.Quantity), orderType,
+		formatClock(order.ScheduledTime), fmt.Sprintf("%.2f", order.Price), o.Status, strconv.Itoa(o.Attempts),
+		fill, filledQty, slippage, delay, o.Reason,
+	}
+}
+
+var orderHeaders = []string{
+	"Order ID", "Side", "Symbol", "Qty", "Type", "Scheduled", "Planned", "Status", "Attempts",
+	"Fill", "Filled qty", "Slippage (bps)", "Delay (ms)", "Reason",
+}
+
+var latencyHeaders = []string{"Measure", "Samples", "p50 (ms)", "p90 (ms)", "p99 (ms)", "Max (ms)"}
+
+// WriteMarkdown renders the report as Markdown
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	cell := func(value string) string { return strings.ReplaceAll(value, "|", "/") }
+	table := func(headers []string, rows [][]string) {
+		b.WriteString("| " + strings.Join(headers, " | ") + " |\n")
+		b.WriteString(strings.Repeat("|---", len(headers)) + "|\n")
+		for _, row := range rows {
+			cells := make([]string, len(row))
+			for i, value := range row {
+				cells[i] = cell(value)
+			}
+			b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "# Execution report for %s\n\n", r.Date.Format("2006-01-02"))
+
+	b.WriteString("## Summary\n\n")
+	var summary [][]string
+	for _, row := range r.summaryRows() {
+		summary = append(summary, []string{row.Metric, row.Value})
+	}
+	table([]string{"Metric", "Value"}, summary)
+
+	b.WriteString("## Latency\n\n")
+	table(latencyHeaders, r.latencyRows())
+
+	if len(r.Failures) > 0 {
+		b.WriteString("## Failures by reason\n\n")
+		var failures [][]string
+		for _, failure := range r.Failures {
+			failures = append(failures, []string{failure.Reason, strconv.Itoa(failure.Count), failure.Example})
+		}
+		table([]string{"Reason", "Orders", "Example"}, failures)
+	}
+
+	b.WriteString("## Orders\n\n")
+	var orders [][]string
+	for _, o := range r.Orders {
+		orders = append(orders, orderRow(o))
+	}
+	table(orderHeaders, orders)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCSV writes one row per order
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{
+		"order_id", "side", "exchange", "symbol", "quantity", "order_type", "is_amo", "scheduled_time",
+		"planned_price", "status", "read", "attempts", "execution_id", "filled_quantity", "fill_price", "filled_at",
+		"slippage_bps", "scheduler_delay_ms", "reason",
+	}); err != nil {
+		return err
+	}
+
+	for _, o := range r.Orders {
+		order := o.Order
+		fillPrice, filledAt, slippage, delay := "", "", "", ""
+		if o.Filled() {
+			fillPrice = strconv.FormatFloat(o.FillPrice, 'f', 2, 64)
+			filledAt = o.FilledAt.Format(time.RFC3339)
+			slippage = strconv.FormatFloat(o.SlippageBps, 'f', 2, 64)
+		}
+		if o.Attempts > 0 {
+			delay = formatMs(o.SchedulerDelay)
+		}
+		scheduled := ""
+		if !order.ScheduledTime.IsZero() {
+			scheduled = order.ScheduledTime.Format(time.RFC3339)
+		}
+		if err := writer.Write([]string{
+			order.ID, order.Side, order.Exchange, order.Symbol, strconv.Itoa(order.Quantity), order.OrderType,
+			strconv.FormatBool(order.IsAMO), scheduled, strconv.FormatFloat(order.Price, 'f', 2, 64),
+			o.Status, strconv.FormatBool(o.Read), strconv.Itoa(o.Attempts), o.ExecutionID,
+			strconv.Itoa(o.FilledQuantity), fillPrice, filledAt, slippage, delay, o.Reason,
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// htmlTemplate renders a standalone page; html/template escapes every value
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Execution report for {{.Date}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #f3f3f3; }
+tr.failed td, tr.abandoned td { background: #fdecea; }
+tr.expired td, tr.not_submitted td { background: #fff8e1; }
+</style>
+</head>
+<body>
+<h1>Execution report for {{.Date}}</h1>
+<h2>Summary</h2>
+<table>
+<tr><th>Metric</th><th>Value</th></tr>
+{{range .Summary}}<tr><td>{{.Metric}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>
+<h2>Latency</h2>
+<table>
+<tr>{{range .LatencyHeaders}}<th>{{.}}</th>{{end}}</tr>
+{{range .Latency}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{if .Failures}}<h2>Failures by reason</h2>
+<table>
+<tr><th>Reason</th><th>Orders</th><th>Example</th></tr>
+{{range .Failures}}<tr><td>{{.Reason}}</td><td>{{.Count}}</td><td>{{.Example}}</td></tr>
+{{end}}</table>
+{{end}}<h2>Orders</h2>
+<table>
+<tr>{{range .OrderHeaders}}<th>{{.}}</th>{{end}}</tr>
+{{range .Orders}}<tr class="{{.Status}}">{{range .Cells}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteHTML renders the report as a standalone HTML page
+func (r *Report) WriteHTML(w io.Writer) error {
+	type htmlOrder struct {
+		Status string
+		Cells  []string
+	}
+	orders := make([]htmlOrder, 0, len(r.Orders))
+	for _, o := range r.Orders {
+		orders = append(orders, htmlOrder{Status: o.Status, Cells: orderRow(o)})
+	}
+
+	return htmlTemplate.Execute(w, map[string]interface{}{
+		"Date":           r.Date.Format("2006-01-02"),
+		"Summary":        r.summaryRows(),
+		"LatencyHeaders": latencyHeaders,
+		"Latency":        r.latencyRows(),
+		"Failures":       r.Failures,
+		"OrderHeaders":   orderHeaders,
+		"Orders":         orders,
+	})
+}
+
+// formatMs formats a duration in milliseconds
+func formatMs(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 1, 64)
+}
+
+// formatClock formats a time of day, or nothing for the zero time
+func formatClock(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("15:04:05")
+}
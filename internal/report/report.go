@@ -0,0 +1,404 @@
+// Package report builds the end-of-day execution report from the execution journal:
+// orders read versus executed, fills against planned prices, failures by reason,
+// scheduler-delay percentiles and AMO versus regular counts.
+package report
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/journal"
+	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/notify"
+)
+
+// Order outcomes in an execution report
+const (
+	StatusExecuted     = "executed"      // Broker accepted the order
+	StatusFailed       = "failed"        // Broker call failed or the order was rejected
+	StatusExpired      = "expired"       // Order passed its expiry window without being executed
+	StatusAbandoned    = "abandoned"     // Claim lease ran out after submission; the broker outcome is unknown
+	StatusNotSubmitted = "not_submitted" // Read from the sheet but never handed to the broker
+)
+
+// OrderOutcome is the day's final state of one order
+type OrderOutcome struct {
+	Order          models.Order
+	Status         string
+	Read           bool // Recorded by the reader
+	Attempts       int  // Broker submissions journaled today
+	ExecutionID    string
+	FilledQuantity int     // From journaled fills; brokers that only acknowledge orders report none
+	FillPrice      float64 // Quantity-weighted across fills; 0 when nothing filled
+	FilledAt       time.Time
+	SlippageBps    float64 // Versus the planned price; positive means worse than planned
+	SchedulerDelay time.Duration
+	Reason         string // Error of the last failed attempt, expiry or abandonment
+}
+
+// Filled reports whether any quantity of the order traded at a known price
+func (o OrderOutcome) Filled() bool {
+	return o.FilledQuantity > 0 && o.FillPrice > 0
+}
+
+// FailureCount groups failed, expired and abandoned orders by reason
+type FailureCount struct {
+	Reason  string
+	Count   int
+	Example string // One of the error messages in the group
+}
+
+// Percentiles summarises a distribution of durations (nearest-rank)
+type Percentiles struct {
+	Count int
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// Report summarises one trading day
+type Report struct {
+	Date           time.Time // Start of the IST day
+	Orders         []OrderOutcome
+	Failures       []FailureCount
+	SchedulerDelay Percentiles // Across every broker submission, including retries
+	TotalTime      Percentiles // Claim to bookkeeping done, per submission
+}
+
+// Load reads the journal entries for the IST day containing date and builds its report
+func Load(journalPath string, date time.Time) (*Report, error) {
+	dayStart := StartOfDay(date)
+	entries, err := journal.ReadEntries(journalPath, dayStart, dayStart.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read execution journal: %w", err)
+	}
+	return Build(dayStart, entries), nil
+}
+
+// StartOfDay returns midnight IST of the day containing t
+func StartOfDay(t time.Time) time.Time {
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		ist = time.UTC
+	}
+	t = t.In(ist)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, ist)
+}
+
+// Build aggregates journal entries into per-order outcomes. Entries are applied in
+// order, so an order requeued after a failure ends with the outcome of its last attempt.
+func Build(date time.Time, entries []models.JournalEntry) *Report {
+	report := &Report{Date: date}
+	outcomes := make(map[string]*OrderOutcome)
+	outcome := func(entry models.JournalEntry) *OrderOutcome {
+		orderID := entryOrderID(entry)
+		o, ok := outcomes[orderID]
+		if !ok {
+			o = &OrderOutcome{Status: StatusNotSubmitted}
+			o.Order.ID = orderID
+			outcomes[orderID] = o
+		}
+		// Abandoned orders whose cache entry was gone carry only their ID
+		if entry.Order != nil && (entry.Order.Symbol != "" || o.Order.Symbol == "") {
+			o.Order = *entry.Order
+		}
+		return o
+	}
+
+	var delays, totals []time.Duration
+	for _, entry := range entries {
+		if entryOrderID(entry) == "" {
+			continue
+		}
+		switch entry.Event {
+		case models.JournalEventRead:
+			o := outcome(entry)
+			o.Read = true
+		case models.JournalEventExecution:
+			o := outcome(entry)
+			o.Attempts++
+			if entry.Metrics != nil {
+				o.SchedulerDelay = entry.Metrics.SchedulerDelay
+				delays = append(delays, entry.Metrics.SchedulerDelay)
+				totals = append(totals, entry.Metrics.TotalTime)
+			}
+			if entry.Result != nil && entry.Result.Success {
+				o.Status = StatusExecuted
+				o.ExecutionID = entry.Result.ExecutionID
+				o.Reason = ""
+			} else {
+				o.Status = StatusFailed
+				if entry.Result != nil {
+					o.Reason = entry.Result.ErrorMessage
+				}
+			}
+		case models.JournalEventRejected:
+			o := outcome(entry)
+			o.Status = StatusFailed
+			if entry.Result != nil {
+				o.Reason = entry.Result.ErrorMessage
+			}
+		case models.JournalEventFill:
+			o := outcome(entry)
+			addFill(o, *entry.Fill)
+		case models.JournalEventExpired, models.JournalEventAbandoned:
+			o := outcome(entry)
+			o.Status = StatusExpired
+			if entry.Event == models.JournalEventAbandoned {
+				o.Status = StatusAbandoned
+			}
+			if entry.Result != nil {
+				o.Reason = entry.Result.ErrorMessage
+			}
+		}
+	}
+
+	for _, o := range outcomes {
+		if o.Filled() {
+			o.SlippageBps = o.Order.SlippageBps(o.FillPrice)
+		}
+		report.Orders = append(report.Orders, *o)
+	}
+	sort.Slice(report.Orders, func(i, j int) bool {
+		a, b := report.Orders[i].Order, report.Orders[j].Order
+		if !a.ScheduledTime.Equal(b.ScheduledTime) {
+			return a.ScheduledTime.Before(b.ScheduledTime)
+		}
+		return a.ID < b.ID
+	})
+
+	report.Failures = groupFailures(report.Orders)
+	report.SchedulerDelay = percentiles(delays)
+	report.TotalTime = percentiles(totals)
+	return report
+}
+
+// entryOrderID returns the order a journal entry belongs to
+func entryOrderID(entry models.JournalEntry) string {
+	switch {
+	case entry.Order != nil && entry.Order.ID != "":
+		return entry.Order.ID
+	case entry.Result != nil && entry.Result.OrderID != "":
+		return entry.Result.OrderID
+	case entry.Fill != nil:
+		return entry.Fill.OrderID
+	}
+	return ""
+}
+
+// addFill folds a (possibly partial) fill into the order's volume-weighted fill price
+func addFill(o *OrderOutcome, fill models.Fill) {
+	if fill.Quantity <= 0 {
+		return
+	}
+	notional := o.FillPrice*float64(o.FilledQuantity) + fill.Price*float64(fill.Quantity)
+	o.FilledQuantity += fill.Quantity
+	o.FillPrice = notional / float64(o.FilledQuantity)
+	if fill.FilledAt.After(o.FilledAt) {
+		o.FilledAt = fill.FilledAt
+	}
+	if o.Order.Symbol == "" {
+		o.Order.Symbol, o.Order.Exchange, o.Order.Side = fill.Symbol, fill.Exchange, fill.Side
+	}
+}
+
+// failureReasons maps error message fragments to a failure reason, checked in order
+var failureReasons = []struct{ fragment, reason string }{
+	{"access token rejected", "token rejected"},
+	{"tokenexception", "token rejected"},
+	{"rate limit", "rate limited"},
+	{"deadline exceeded", "timeout"},
+	{"timeout", "timeout"},
+	{"outside circuit band", "price outside circuit band"},
+	{"contract has expired", "contract expired"},
+	{"expiry-day cutoff", "contract expired"},
+	{"product not allowed", "invalid product"},
+	{"not a multiple of its lot size", "invalid lot size"},
+	{"insufficient holdings", "insufficient holdings"},
+	{"insufficient position", "insufficient holdings"},
+	{"insufficient", "insufficient funds"},
+	{"margin", "insufficient funds"},
+	{"connection refused", "broker unreachable"},
+	{"no such host", "broker unreachable"},
+	{"inputexception", "rejected by broker"},
+	{"orderexception", "rejected by broker"},
+	{"rejected", "rejected by broker"},
+}
+
+// failureReason classifies why an order did not execute
+func failureReason(o OrderOutcome) string {
+	switch o.Status {
+	case StatusExpired:
+		return "expired"
+	case StatusAbandoned:
+		return "abandoned"
+	}
+	message := strings.ToLower(o.Reason)
+	for _, r := range failureReasons {
+		if strings.Contains(message, r.fragment) {
+			return r.reason
+		}
+	}
+	return "other"
+}
+
+// groupFailures counts failed, expired and abandoned orders by reason, most frequent first
+func groupFailures(orders []OrderOutcome) []FailureCount {
+	counts := make(map[string]*FailureCount)
+	for _, o := range orders {
+		switch o.Status {
+		case StatusFailed, StatusExpired, StatusAbandoned:
+		default:
+			continue
+		}
+		reason := failureReason(o)
+		if counts[reason] == nil {
+			counts[reason] = &FailureCount{Reason: reason, Example: o.Reason}
+		}
+		counts[reason].Count++
+	}
+
+	failures := make([]FailureCount, 0, len(counts))
+	for _, count := range counts {
+		failures = append(failures, *count)
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].Count != failures[j].Count {
+			return failures[i].Count > failures[j].Count
+		}
+		return failures[i].Reason < failures[j].Reason
+	})
+	return failures
+}
+
+// percentiles computes nearest-rank percentiles of values
+func percentiles(values []time.Duration) Percentiles {
+	if len(values) == 0 {
+		return Percentiles{}
+	}
+	sorted := append([]time.Duration(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := func(p float64) time.Duration {
+		return sorted[int(math.Ceil(p/100*float64(len(sorted))))-1]
+	}
+	return Percentiles{
+		Count: len(sorted),
+		P50:   rank(50),
+		P90:   rank(90),
+		P99:   rank(99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+// Count returns the number of orders with the given status
+func (r *Report) Count(status string) int {
+	count := 0
+	for _, o := range r.Orders {
+		if o.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// ReadCount returns the number of orders the reader recorded
+func (r *Report) ReadCount() int {
+	count := 0
+	for _, o := range r.Orders {
+		if o.Read {
+			count++
+		}
+	}
+	return count
+}
+
+// SubmittedCounts returns how many orders reached the broker as AMO and as regular orders
+func (r *Report) SubmittedCounts() (amo, regular int) {
+	for _, o := range r.Orders {
+		if o.Attempts == 0 {
+			continue
+		}
+		if o.Order.IsAMO {
+			amo++
+		} else {
+			regular++
+		}
+	}
+	return amo, regular
+}
+
+// FilledCount returns the number of orders with at least one fill
+func (r *Report) FilledCount() int {
+	count := 0
+	for _, o := range r.Orders {
+		if o.Filled() {
+			count++
+		}
+	}
+	return count
+}
+
+// Turnover returns the traded value across all fills
+func (r *Report) Turnover() float64 {
+	var turnover float64
+	for _, o := range r.Orders {
+		if o.Filled() {
+			turnover += o.FillPrice * float64(o.FilledQuantity)
+		}
+	}
+	return turnover
+}
+
+// AverageSlippageBps returns the quantity-weighted slippage across filled orders
+func (r *Report) AverageSlippageBps() float64 {
+	var weighted float64
+	var quantity int
+	for _, o := range r.Orders {
+		if o.Filled() {
+			weighted += o.SlippageBps * float64(o.FilledQuantity)
+			quantity += o.FilledQuantity
+		}
+	}
+	if quantity == 0 {
+		return 0
+	}
+	return weighted / float64(quantity)
+}
+
+// Event summarises the report as a daily_summary alert
+func (r *Report) Event() notify.Event {
+	amo, regular := r.SubmittedCounts()
+	date := r.Date.Format("2006-01-02")
+	fields := map[string]string{
+		"Read":            fmt.Sprintf("%d", r.ReadCount()),
+		"Executed":        fmt.Sprintf("%d", r.Count(StatusExecuted)),
+		"Failed":          fmt.Sprintf("%d", r.Count(StatusFailed)),
+		"Expired":         fmt.Sprintf("%d", r.Count(StatusExpired)),
+		"Abandoned":       fmt.Sprintf("%d", r.Count(StatusAbandoned)),
+		"Not submitted":   fmt.Sprintf("%d", r.Count(StatusNotSubmitted)),
+		"AMO / regular":   fmt.Sprintf("%d / %d", amo, regular),
+		"Filled":          fmt.Sprintf("%d", r.FilledCount()),
+		"Turnover":        fmt.Sprintf("%.2f", r.Turnover()),
+		"Avg slippage":    fmt.Sprintf("%.2f bps", r.AverageSlippageBps()),
+		"Scheduler delay": fmt.Sprintf("p50 %v, p99 %v", r.SchedulerDelay.P50, r.SchedulerDelay.P99),
+	}
+	if len(r.Failures) > 0 {
+		reasons := make([]string, 0, len(r.Failures))
+		for _, failure := range r.Failures {
+			reasons = append(reasons, fmt.Sprintf("%s %d", failure.Reason, failure.Count))
+		}
+		fields["Failures"] = strings.Join(reasons, ", ")
+	}
+
+	return notify.Event{
+		Type:    notify.EventDailySummary,
+		Title:   "Execution report for " + date,
+		Message: fmt.Sprintf("%d of %d orders executed", r.Count(StatusExecuted), len(r.Orders)),
+		Fields:  fields,
+		Key:     date,
+	}
+}
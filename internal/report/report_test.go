@@ -0,0 +1,172 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+func testDay(t *testing.T) time.Time {
+	t.Helper()
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatalf("failed to load IST: %v", err)
+	}
+	return time.Date(2024, 1, 15, 0, 0, 0, 0, ist)
+}
+
+// testEntries journals a day with a filled order (two partial fills), an AMO order that
+// was retried after a token failure, a rejection, an expiry and an order that never ran
+func testEntries(day time.Time) []models.JournalEntry {
+	at := func(h, m int) time.Time { return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+	order := func(id, side string, price float64, scheduled time.Time, amo bool) *models.Order {
+		return &models.Order{ID: id, Symbol: "SYM-" + id, Exchange: "NSE", Side: side, Price: price, Quantity: 10,
+			OrderType: "LIMIT", ScheduledTime: scheduled, IsAMO: amo}
+	}
+	execution := func(o *models.Order, ok bool, msg string, delay time.Duration) models.JournalEntry {
+		return models.JournalEntry{
+			Timestamp: o.ScheduledTime.Add(delay),
+			Event:     models.JournalEventExecution,
+			Order:     o,
+			Result:    &models.ExecutionResult{OrderID: o.ID, Success: ok, ExecutionID: "X-" + o.ID, ErrorMessage: msg},
+			Metrics:   &models.ProfilingMetrics{OrderID: o.ID, SchedulerDelay: delay, TotalTime: 2 * delay},
+		}
+	}
+
+	a := order("A", "Buy", 100, at(9, 30), false)
+	b := order("B", "Sell", 200, at(8, 0), true)
+	c := order("C", "Buy", 50, at(10, 0), false)
+	d := order("D", "Buy", 70, at(11, 0), false)
+	e := order("E", "Buy", 80, at(12, 0), false)
+
+	entries := []models.JournalEntry{}
+	for _, o := range []*models.Order{a, b, c, d, e} {
+		entries = append(entries, models.JournalEntry{Timestamp: at(7, 0), Event: models.JournalEventRead, Order: o})
+	}
+	entries = append(entries,
+		execution(b, false, "access token rejected: kite AMO API returned status 403", 4*time.Millisecond),
+		execution(b, true, "", 8*time.Millisecond),
+		models.JournalEntry{Event: models.JournalEventFill, Fill: &models.Fill{OrderID: "B", Side: "Sell", Quantity: 10, Price: 199}},
+		models.JournalEntry{Event: models.JournalEventFill, Fill: &models.Fill{OrderID: "A", Quantity: 4, Price: 100}},
+		models.JournalEntry{Event: models.JournalEventFill, Fill: &models.Fill{OrderID: "A", Quantity: 6, Price: 101, FilledAt: at(9, 31)}},
+		execution(a, true, "", 2*time.Millisecond),
+		execution(c, false, "kite API returned status 400: InputException: Invalid price | tick size", 1*time.Millisecond),
+		models.JournalEntry{Event: models.JournalEventExpired, Order: d,
+			Result: &models.ExecutionResult{OrderID: "D", ErrorMessage: "not executed within expiry window"}},
+	)
+	return entries
+}
+
+func TestBuildAggregatesTheDay(t *testing.T) {
+	day := testDay(t)
+	r := Build(day, testEntries(day))
+
+	if len(r.Orders) != 5 || r.ReadCount() != 5 {
+		t.Fatalf("orders = %d, read = %d, want 5 and 5", len(r.Orders), r.ReadCount())
+	}
+	if r.Orders[0].Order.ID != "B" {
+		t.Errorf("orders are not sorted by scheduled time: first is %s", r.Orders[0].Order.ID)
+	}
+	for status, want := range map[string]int{StatusExecuted: 2, StatusFailed: 1, StatusExpired: 1, StatusNotSubmitted: 1} {
+		if got := r.Count(status); got != want {
+			t.Errorf("Count(%s) = %d, want %d", status, got, want)
+		}
+	}
+	if amo, regular := r.SubmittedCounts(); amo != 1 || regular != 2 {
+		t.Errorf("SubmittedCounts = %d AMO, %d regular, want 1 and 2", amo, regular)
+	}
+
+	outcomes := make(map[string]OrderOutcome)
+	for _, o := range r.Orders {
+		outcomes[o.Order.ID] = o
+	}
+	a := outcomes["A"]
+	if a.FilledQuantity != 10 || a.FillPrice != 100.6 || !a.FilledAt.Equal(day.Add(9*time.Hour+31*time.Minute)) {
+		t.Errorf("A fill = %d @ %v at %v, want 10 @ 100.6 at 09:31", a.FilledQuantity, a.FillPrice, a.FilledAt)
+	}
+	if a.SlippageBps < 59.9 || a.SlippageBps > 60.1 {
+		t.Errorf("A slippage = %.2f bps, want 60", a.SlippageBps)
+	}
+	if b := outcomes["B"]; b.Status != StatusExecuted || b.Attempts != 2 || b.Reason != "" || b.SlippageBps != 50 {
+		t.Errorf("B = %+v, want executed on the second attempt with 50 bps slippage", b)
+	}
+
+	wantFailures := []FailureCount{
+		{Reason: "expired", Count: 1, Example: "not executed within expiry window"},
+		{Reason: "rejected by broker", Count: 1, Example: "kite API returned status 400: InputException: Invalid price | tick size"},
+	}
+	if len(r.Failures) != len(wantFailures) {
+		t.Fatalf("Failures = %+v, want %+v", r.Failures, wantFailures)
+	}
+	for i, want := range wantFailures {
+		if r.Failures[i] != want {
+			t.Errorf("Failures[%d] = %+v, want %+v", i, r.Failures[i], want)
+		}
+	}
+
+	// Delays 1, 2, 4 and 8 ms across the four submissions
+	if r.SchedulerDelay.Count != 4 || r.SchedulerDelay.P50 != 2*time.Millisecond || r.SchedulerDelay.P99 != 8*time.Millisecond {
+		t.Errorf("SchedulerDelay = %+v, want 4 samples, p50 2ms, p99 8ms", r.SchedulerDelay)
+	}
+	if r.TotalTime.Max != 16*time.Millisecond {
+		t.Errorf("TotalTime.Max = %v, want 16ms", r.TotalTime.Max)
+	}
+}
+
+func TestReportRendering(t *testing.T) {
+	day := testDay(t)
+	r := Build(day, testEntries(day))
+
+	var md bytes.Buffer
+	if err := r.WriteMarkdown(&md); err != nil {
+		t.Fatalf("WriteMarkdown: %v", err)
+	}
+	for _, want := range []string{
+		"# Execution report for 2024-01-15",
+		"| Orders read | 5 |",
+		"| Submitted as AMO | 1 |",
+		"| rejected by broker | 1 | kite API returned status 400: InputException: Invalid price / tick size |",
+		"| Scheduler delay | 4 | 2.0 | 8.0 | 8.0 | 8.0 |",
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("Markdown is missing %q:\n%s", want, md.String())
+		}
+	}
+
+	var csvOut bytes.Buffer
+	if err := r.WriteCSV(&csvOut); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	rows, err := csv.NewReader(&csvOut).ReadAll()
+	if err != nil {
+		t.Fatalf("CSV does not parse: %v", err)
+	}
+	if len(rows) != 6 || rows[1][0] != "B" || rows[1][9] != StatusExecuted || rows[1][11] != "2" {
+		t.Errorf("CSV rows = %v", rows)
+	}
+
+	var html bytes.Buffer
+	if err := r.WriteHTML(&html); err != nil {
+		t.Fatalf("WriteHTML: %v", err)
+	}
+	if !strings.Contains(html.String(), `<tr class="failed"><td>C</td>`) {
+		t.Errorf("HTML is missing the failed order row:\n%s", html.String())
+	}
+
+	paths, err := r.WriteFiles(t.TempDir(), Formats)
+	if err != nil || len(paths) != 3 {
+		t.Fatalf("WriteFiles = %v, %v", paths, err)
+	}
+	if _, err := r.WriteFiles(t.TempDir(), []string{"pdf"}); err == nil {
+		t.Error("WriteFiles accepted an unknown format")
+	}
+
+	event := r.Event()
+	if event.Fields["Executed"] != "2" || event.Fields["AMO / regular"] != "1 / 2" || event.Key != "2024-01-15" {
+		t.Errorf("Event = %+v", event)
+	}
+}
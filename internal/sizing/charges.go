@@ -0,0 +1,70 @@
+package sizing
+
+import (
+	"math"
+	"strings"
+)
+
+// Charges is a schedule of brokerage and statutory charges, as fractions of an order's value
+// unless noted, modelled on Zerodha's published rates
+type Charges struct {
+	BrokerageRate float64 // Brokerage as a fraction of value
+	BrokerageFlat float64 // Brokerage per order in rupees, used when BrokerageRate is 0
+	BrokerageCap  float64 // Maximum brokerage per order in rupees; 0 = no cap
+	STTBuyRate    float64 // Securities transaction tax on buys
+	STTSellRate   float64 // Securities transaction tax on sells
+	ExchangeRate  float64 // Exchange transaction charges
+	SEBIRate      float64 // SEBI turnover fee
+	StampRate     float64 // Stamp duty, charged on buys only
+	GSTRate       float64 // GST on brokerage, exchange and SEBI charges
+}
+
+// Charge schedules for each kind of order
+var (
+	DeliveryCharges = Charges{STTBuyRate: 0.001, STTSellRate: 0.001, ExchangeRate: 0.0000297, SEBIRate: 0.000001,
+		StampRate: 0.00015, GSTRate: 0.18}
+	IntradayCharges = Charges{BrokerageRate: 0.0003, BrokerageCap: 20, STTSellRate: 0.00025, ExchangeRate: 0.0000297,
+		SEBIRate: 0.000001, StampRate: 0.00003, GSTRate: 0.18}
+	FuturesCharges = Charges{BrokerageRate: 0.0003, BrokerageCap: 20, STTSellRate: 0.0002, ExchangeRate: 0.0000173,
+		SEBIRate: 0.000001, StampRate: 0.00002, GSTRate: 0.18}
+	OptionsCharges = Charges{BrokerageFlat: 20, STTSellRate: 0.001, ExchangeRate: 0.0003503, SEBIRate: 0.000001,
+		StampRate: 0.00003, GSTRate: 0.18}
+)
+
+// ChargesFor returns the schedule for a product (CNC, MIS, NRML) and instrument type
+// (EQ, FUT, CE, PE)
+func ChargesFor(product, instrumentType string) Charges {
+	switch strings.ToUpper(instrumentType) {
+	case "FUT":
+		return FuturesCharges
+	case "CE", "PE":
+		return OptionsCharges
+	}
+	if strings.EqualFold(product, "MIS") {
+		return IntradayCharges
+	}
+	return DeliveryCharges
+}
+
+// Estimate returns the charges on an order of the given value and side
+func (c Charges) Estimate(value float64, side string) float64 {
+	if value <= 0 {
+		return 0
+	}
+	brokerage := c.BrokerageFlat
+	if c.BrokerageRate > 0 {
+		brokerage = value * c.BrokerageRate
+	}
+	if c.BrokerageCap > 0 {
+		brokerage = math.Min(brokerage, c.BrokerageCap)
+	}
+	exchange := value * c.ExchangeRate
+	sebi := value * c.SEBIRate
+	total := brokerage + exchange + sebi + (brokerage+exchange+sebi)*c.GSTRate
+	if strings.EqualFold(side, "SELL") {
+		total += value * c.STTSellRate
+	} else {
+		total += value*c.STTBuyRate + value*c.StampRate
+	}
+	return math.Round(total*100) / 100
+}
@@ -0,0 +1,139 @@
+// Package sizing works out order quantities: a fixed quantity, as much as a sum of money
+// buys net of estimated charges, or a percentage of the available funds, rounded to whole
+// lots for derivatives.
+package sizing
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sizing strategies
+const (
+	StrategyFixed        = "fixed"         // Quantity given by the order source
+	StrategyMoney        = "money"         // As much as the money allotted buys, net of charges
+	StrategyFundsPercent = "funds_percent" // As much as a percentage of available funds buys, net of charges
+)
+
+// ErrTooSmall is returned when an order sizes to less than one lot
+var ErrTooSmall = errors.New("order sizes to less than one lot")
+
+// Request describes what an order should be sized from
+type Request struct {
+	Strategy string
+	Side     string
+	Price    float64
+	Quantity int      // Fixed quantity, for StrategyFixed
+	Money    float64  // Money allotted, for StrategyMoney
+	Percent  float64  // Percentage of Funds, for StrategyFundsPercent
+	Funds    float64  // Funds available, for StrategyFundsPercent
+	LotSize  int      // Quantities are whole multiples of this; 0 or 1 for cash equities
+	Charges  *Charges // Charges to leave room for; nil to ignore charges
+}
+
+// Decision is the outcome of sizing an order
+type Decision struct {
+	Strategy string
+	Quantity int
+	LotSize  int
+	Budget   float64 // Money the order had to fit in; 0 for fixed quantities
+	Value    float64 // Quantity × price
+	Charges  float64 // Estimated charges on Value
+}
+
+// String describes the decision for the logs
+func (d Decision) String() string {
+	lots := ""
+	if d.LotSize > 1 {
+		lots = fmt.Sprintf(" (%d lots of %d)", d.Quantity/d.LotSize, d.LotSize)
+	}
+	if d.Strategy == StrategyFixed {
+		return fmt.Sprintf("%s: %d%s = %.2f + %.2f charges", d.Strategy, d.Quantity, lots, d.Value, d.Charges)
+	}
+	return fmt.Sprintf("%s: %d%s = %.2f + %.2f charges within %.2f", d.Strategy, d.Quantity, lots, d.Value, d.Charges, d.Budget)
+}
+
+// Size works out an order's quantity
+func Size(req Request) (Decision, error) {
+	lotSize := req.LotSize
+	if lotSize < 1 {
+		lotSize = 1
+	}
+	decision := Decision{Strategy: req.Strategy, LotSize: lotSize}
+
+	switch req.Strategy {
+	case StrategyFixed:
+		if req.Quantity <= 0 {
+			return decision, fmt.Errorf("fixed quantity %d is not positive", req.Quantity)
+		}
+		decision.Quantity = req.Quantity / lotSize * lotSize
+
+	case StrategyMoney, StrategyFundsPercent:
+		budget := req.Money
+		if req.Strategy == StrategyFundsPercent {
+			budget = req.Funds * req.Percent / 100
+		}
+		if req.Price <= 0 {
+			return decision, fmt.Errorf("cannot size from money without a price")
+		}
+		decision.Budget = budget
+		decision.Quantity = fit(budget, req.Price, lotSize, req.Side, req.Charges) * lotSize
+
+	default:
+		return decision, fmt.Errorf("unknown sizing strategy %q", req.Strategy)
+	}
+
+	decision.Value = float64(decision.Quantity) * req.Price
+	if req.Charges != nil {
+		decision.Charges = req.Charges.Estimate(decision.Value, req.Side)
+	}
+	if decision.Quantity <= 0 {
+		return decision, fmt.Errorf("%w: %s", ErrTooSmall, decision)
+	}
+	return decision, nil
+}
+
+// fit returns the most lots whose value plus charges fits in budget
+func fit(budget, price float64, lotSize int, side string, charges *Charges) int {
+	unit := price * float64(lotSize)
+	cost := func(lots int) float64 {
+		value := float64(lots) * unit
+		if charges == nil {
+			return value
+		}
+		return value + charges.Estimate(value, side)
+	}
+
+	lots := int(budget / unit)
+	if charges != nil {
+		lots = int((budget - charges.Estimate(budget, side)) / unit)
+	}
+	for cost(lots+1) <= budget {
+		lots++
+	}
+	for lots > 0 && cost(lots) > budget {
+		lots--
+	}
+	return lots
+}
+
+// Split divides total across parts orders in whole lots: each order gets the same number of
+// lots and the first orders one more lot each until the remainder is used up. Orders left
+// with nothing get 0.
+func Split(total, parts, lotSize int) []int {
+	if lotSize < 1 {
+		lotSize = 1
+	}
+	if parts < 1 {
+		parts = 1
+	}
+	lots := total / lotSize
+	quantities := make([]int, parts)
+	for i := range quantities {
+		quantities[i] = lots / parts * lotSize
+		if i < lots%parts {
+			quantities[i] += lotSize
+		}
+	}
+	return quantities
+}
@@ -0,0 +1,68 @@
+package sizing
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSize(t *testing.T) {
+	delivery := DeliveryCharges
+	tests := []struct {
+		name string
+		req  Request
+		want int
+		err  error
+	}{
+		{"fixed", Request{Strategy: StrategyFixed, Price: 100, Quantity: 7}, 7, nil},
+		{"fixed rounds down to lots", Request{Strategy: StrategyFixed, Price: 100, Quantity: 130, LotSize: 50}, 100, nil},
+		{"fixed below one lot", Request{Strategy: StrategyFixed, Price: 100, Quantity: 30, LotSize: 50}, 0, ErrTooSmall},
+		{"money", Request{Strategy: StrategyMoney, Side: "Buy", Price: 100, Money: 1000}, 10, nil},
+		{"money net of charges", Request{Strategy: StrategyMoney, Side: "Buy", Price: 100, Money: 1000, Charges: &delivery}, 9, nil},
+		{"money in lots", Request{Strategy: StrategyMoney, Side: "Buy", Price: 100, Money: 12000, LotSize: 50}, 100, nil},
+		{"money below one lot", Request{Strategy: StrategyMoney, Side: "Buy", Price: 100, Money: 4000, LotSize: 50}, 0, ErrTooSmall},
+		{"funds percent", Request{Strategy: StrategyFundsPercent, Side: "Buy", Price: 100, Percent: 10, Funds: 50000}, 50, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := Size(tt.req)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Size() error = %v, want %v", err, tt.err)
+			}
+			if decision.Quantity != tt.want {
+				t.Errorf("Size() quantity = %d, want %d", decision.Quantity, tt.want)
+			}
+			if err == nil && decision.Value+decision.Charges > tt.req.Money && tt.req.Strategy == StrategyMoney {
+				t.Errorf("Size() = %s, over the money allotted", decision)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		total, parts, lotSize int
+		want                  []int
+	}{
+		{10, 3, 1, []int{4, 3, 3}},
+		{2, 3, 1, []int{1, 1, 0}},
+		{250, 2, 50, []int{150, 100}},
+		{120, 2, 50, []int{50, 50}},
+	}
+	for _, tt := range tests {
+		if got := Split(tt.total, tt.parts, tt.lotSize); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Split(%d, %d, %d) = %v, want %v", tt.total, tt.parts, tt.lotSize, got, tt.want)
+		}
+	}
+}
+
+func TestChargesEstimate(t *testing.T) {
+	// Delivery buy of 100000: STT 100, stamp 15, exchange 2.97, SEBI 0.10, GST on those 0.55
+	if got := DeliveryCharges.Estimate(100000, "Buy"); got != 118.62 {
+		t.Errorf("delivery buy charges = %v, want 118.62", got)
+	}
+	// Options brokerage is a flat 20 per order
+	if got := ChargesFor("NRML", "CE").Estimate(1000, "Buy"); got < 20 {
+		t.Errorf("option buy charges = %v, want at least the flat brokerage", got)
+	}
+}
@@ -0,0 +1,136 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/mach_five/trading-system"
+
+// propagator serialises span contexts into orders (W3C traceparent/tracestate)
+var propagator = propagation.TraceContext{}
+
+// Init configures the global tracer provider for a process and returns a shutdown
+// function that flushes pending spans. With the "none" exporter spans are no-ops.
+func Init(ctx context.Context, cfg *config.Config, serviceName string, log *logger.Logger) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+
+	var exporter sdktrace.SpanExporter
+	var closeFile func() error
+
+	switch strings.ToLower(cfg.Tracing.Exporter) {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Tracing.OTLPEndpoint)}
+		if cfg.Tracing.OTLPInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exp, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		exporter = exp
+		log.Info("🔭 Tracing enabled: exporting spans via OTLP to %s", cfg.Tracing.OTLPEndpoint)
+	case "file":
+		if err := os.MkdirAll(filepath.Dir(cfg.Tracing.FilePath), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create trace directory: %w", err)
+		}
+		file, err := os.OpenFile(cfg.Tracing.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace file: %w", err)
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to create file exporter: %w", err)
+		}
+		exporter = exp
+		closeFile = file.Close
+		log.Info("🔭 Tracing enabled: writing spans to %s", cfg.Tracing.FilePath)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter: %s (supported: none, otlp, file)", cfg.Tracing.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeFile != nil {
+			closeFile()
+		}
+		return err
+	}, nil
+}
+
+// Tracer returns the tracer used by all trading system packages
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Inject serialises the span context in ctx so it can travel with an order
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract restores a span context previously stored with Inject
+func Extract(ctx context.Context, traceContext map[string]string) context.Context {
+	if len(traceContext) == 0 {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier(traceContext))
+}
+
+// RecordError marks span as failed with err
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// OrderAttributes returns the common span attributes describing an order
+func OrderAttributes(order models.Order) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("order.id", order.ID),
+		attribute.String("order.symbol", order.Symbol),
+		attribute.String("order.exchange", order.Exchange),
+		attribute.String("order.side", order.Side),
+		attribute.Int("order.quantity", order.Quantity),
+		attribute.Float64("order.price", order.Price),
+		attribute.Bool("order.is_amo", order.IsAMO),
+	}
+}
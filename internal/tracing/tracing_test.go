@@ -0,0 +1,144 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// initFileTracing starts tracing with the file exporter and restores the global provider afterwards
+func initFileTracing(t *testing.T) (string, func(context.Context) error) {
+	t.Helper()
+	dir := t.TempDir()
+	log, err := logger.NewLogger("error", filepath.Join(dir, "tracing.log"))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	cfg := &config.Config{}
+	cfg.Tracing.Exporter = "file"
+	cfg.Tracing.FilePath = filepath.Join(dir, "traces", "traces.jsonl")
+	cfg.Tracing.SampleRatio = 1
+	shutdown, err := Init(context.Background(), cfg, "trading-system-test", log)
+	if err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	return cfg.Tracing.FilePath, shutdown
+}
+
+// exportedSpan is the part of a span written by the file exporter that the tests check
+type exportedSpan struct {
+	Name        string
+	SpanContext struct {
+		TraceID string
+		SpanID  string
+	}
+	Parent struct {
+		TraceID string
+		SpanID  string
+	}
+}
+
+// readSpans decodes every span in a trace file
+func readSpans(t *testing.T, path string) []exportedSpan {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open trace file: %v", err)
+	}
+	defer file.Close()
+
+	var spans []exportedSpan
+	decoder := json.NewDecoder(file)
+	for {
+		var span exportedSpan
+		if err := decoder.Decode(&span); errors.Is(err, io.EOF) {
+			return spans
+		} else if err != nil {
+			t.Fatalf("failed to decode span: %v", err)
+		}
+		spans = append(spans, span)
+	}
+}
+
+func TestTraceContextRoundTripsThroughOrder(t *testing.T) {
+	path, shutdown := initFileTracing(t)
+
+	ctx, parent := Tracer().Start(context.Background(), "reader.parse_order")
+	order := models.Order{ID: "ORDER", TraceContext: Inject(ctx)}
+	parent.End()
+	if order.TraceContext["traceparent"] == "" {
+		t.Fatalf("TraceContext = %v, want a traceparent", order.TraceContext)
+	}
+
+	// The order crosses the cache as JSON between the reader and the trigger
+	data, err := json.Marshal(order)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var cached models.Order
+	if err := json.Unmarshal(data, &cached); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	extracted := trace.SpanContextFromContext(Extract(context.Background(), cached.TraceContext))
+	if extracted.TraceID() != parent.SpanContext().TraceID() || extracted.SpanID() != parent.SpanContext().SpanID() ||
+		!extracted.IsRemote() || !extracted.IsSampled() {
+		t.Fatalf("extracted span context = %+v, want the reader's span %+v", extracted, parent.SpanContext())
+	}
+
+	_, child := Tracer().Start(Extract(context.Background(), cached.TraceContext), "trigger.execute_order")
+	child.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	spans := readSpans(t, path)
+	if len(spans) != 2 {
+		t.Fatalf("trace file has %d spans, want 2", len(spans))
+	}
+	traceID := parent.SpanContext().TraceID().String()
+	if spans[0].Name != "reader.parse_order" || spans[0].SpanContext.TraceID != traceID {
+		t.Errorf("first span = %+v, want the reader span in trace %s", spans[0], traceID)
+	}
+	if spans[1].Name != "trigger.execute_order" || spans[1].SpanContext.TraceID != traceID ||
+		spans[1].Parent.SpanID != parent.SpanContext().SpanID().String() {
+		t.Errorf("second span = %+v, want the trigger span as a child of the reader span", spans[1])
+	}
+}
+
+func TestTracingOff(t *testing.T) {
+	if got := Inject(context.Background()); got != nil {
+		t.Errorf("Inject without a span = %v, want nil so orders carry no trace context", got)
+	}
+	ctx := context.Background()
+	if got := Extract(ctx, nil); got != ctx {
+		t.Error("Extract without a trace context returned a different context")
+	}
+
+	log, err := logger.NewLogger("error", filepath.Join(t.TempDir(), "tracing.log"))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	defer log.Close()
+	cfg := &config.Config{}
+	cfg.Tracing.Exporter = "zipkin"
+	if _, err := Init(ctx, cfg, "trading-system-test", log); err == nil {
+		t.Error("Init accepted an unknown exporter")
+	}
+}
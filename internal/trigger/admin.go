@@ -0,0 +1,690 @@
+package trigger
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/broker"
+	"github.com/mach_five/trading-system/internal/cache"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// AdminServer exposes an authenticated HTTP API for operating the trigger process
+type AdminServer struct {
+	config  *config.Config
+	cache   *cache.RedisCache
+	trigger *Trigger
+	logger  *logger.Logger
+}
+
+// manualOrderRequest is the payload accepted by POST /api/orders
+type manualOrderRequest struct {
+	Symbol        string    `json:"symbol"`
+	Exchange      string    `json:"exchange"`
+	Price         float64   `json:"price"`
+	Quantity      int       `json:"quantity"`
+	OrderType     string    `json:"order_type"`
+	Side          string    `json:"side"`
+	ScheduledTime time.Time `json:"scheduled_time"`
+	IsAMO         *bool     `json:"is_amo"` // Derived from market hours when omitted
+	ExpirySeconds int       `json:"expiry_seconds"` // Overrides the configured expiry window when set
+}
+
+// NewAdminServer creates a new admin API server
+func NewAdminServer(cfg *config.Config, cache *cache.RedisCache, trigger *Trigger, log *logger.Logger) *AdminServer {
+	return &AdminServer{
+		config:  cfg,
+		cache:   cache,
+		trigger: trigger,
+		logger:  log,
+	}
+}
+
+// Handler returns the admin API routes wrapped in token authentication
+func (a *AdminServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/orders", a.handleOrders)
+	mux.HandleFunc("/api/orders/", a.handleOrder)
+	mux.HandleFunc("/api/execution", a.handleExecution)
+	mux.HandleFunc("/api/execution/pause", a.handlePause)
+	mux.HandleFunc("/api/execution/resume", a.handleResume)
+	mux.HandleFunc("/api/readiness", a.handleReadiness)
+	mux.HandleFunc("/api/portfolio", a.handlePortfolio)
+	mux.HandleFunc("/api/funds", a.handleFunds)
+	mux.HandleFunc("/api/halts", a.handleHalts)
+	mux.HandleFunc("/api/halts/", a.handleHalt)
+	mux.HandleFunc("/api/killswitch/trip", a.handleTrip)
+	mux.HandleFunc("/api/killswitch/reset", a.handleReset)
+	mux.HandleFunc("/api/audit", a.handleAudit)
+	mux.HandleFunc("/api/deadletters", a.handleDeadLetters)
+	mux.HandleFunc("/api/deadletters/", a.handleDeadLetter)
+	mux.HandleFunc("/api/quarantine", a.handleQuarantine)
+	mux.HandleFunc("/api/quarantine/", a.handleQuarantined)
+	mux.HandleFunc("/api/cache/migrate", a.handleMigrate)
+	mux.HandleFunc("/api/gtts", a.handleGTTs)
+	mux.HandleFunc("/api/gtts/sync", a.handleGTTSync)
+	return a.requireToken(mux)
+}
+
+// Serve runs the admin API until ctx is cancelled
+func (a *AdminServer) Serve(ctx context.Context) error {
+	if a.config.Admin.Token == "" {
+		a.logger.Error("❌ Admin API not started: ADMIN_TOKEN is not set")
+		return fmt.Errorf("admin token is required")
+	}
+
+	server := &http.Server{
+		Addr:              a.config.Admin.Addr,
+		Handler:           a.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	a.logger.Info("🛠️  Admin API listening on http://%s/api", a.config.Admin.Addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		a.logger.Error("❌ Admin API stopped: %v", err)
+		return err
+	}
+	return nil
+}
+
+// requireToken rejects requests without the configured bearer token
+func (a *AdminServer) requireToken(next http.Handler) http.Handler {
+	expected := []byte("Bearer " + a.config.Admin.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		provided := []byte(req.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(provided, expected) != 1 {
+			a.logger.Warn("🔒 Rejected admin request %s %s from %s: invalid token", req.Method, req.URL.Path, req.RemoteAddr)
+			writeError(w, http.StatusUnauthorized, "invalid or missing admin token")
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// handleOrders lists pending orders (GET) or adds a manual order (POST)
+func (a *AdminServer) handleOrders(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		entries, err := a.cache.ListPendingOrders(req.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, entries)
+	case http.MethodPost:
+		a.addManualOrder(w, req)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleOrder shows (GET) or cancels (DELETE) a single pending order
+func (a *AdminServer) handleOrder(w http.ResponseWriter, req *http.Request) {
+	orderID, err := url.PathUnescape(strings.TrimPrefix(req.URL.EscapedPath(), "/api/orders/"))
+	if err != nil || orderID == "" {
+		writeError(w, http.StatusBadRequest, "invalid order id")
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		entry, err := a.cache.GetOrder(req.Context(), orderID)
+		if errors.Is(err, cache.ErrOrderNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		} else if errors.Is(err, cache.ErrOrderQuarantined) {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, entry)
+	case http.MethodDelete:
+		entry, err := a.cache.GetOrder(req.Context(), orderID)
+		if errors.Is(err, cache.ErrOrderNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		} else if errors.Is(err, cache.ErrOrderQuarantined) {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if err := a.cache.CancelOrder(req.Context(), *entry); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		a.logger.Warn("🗑️  Order %s cancelled via admin API", orderID)
+		writeJSON(w, http.StatusOK, map[string]string{"status": "cancelled", "order_id": orderID})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// addManualOrder validates a manual order and stores it in the pending queue
+func (a *AdminServer) addManualOrder(w http.ResponseWriter, req *http.Request) {
+	var payload manualOrderRequest
+	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid order payload: %v", err))
+		return
+	}
+
+	order, err := payload.toOrder(a.trigger.clock.Now().In(a.trigger.istLocation))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	expiryTime := cache.ExpiryFor(order, a.config.Trigger.Expiry)
+	if err := a.cache.StoreOrder(req.Context(), order, expiryTime); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a.logger.Info("➕ Manual order %s added via admin API (%s %d %s @ %.2f, scheduled %s IST)",
+		order.ID, order.Side, order.Quantity, order.Symbol, order.Price, order.ScheduledTime.Format("2006-01-02 15:04:05"))
+	writeJSON(w, http.StatusCreated, models.OrderCacheEntry{Order: order, ExpiryTime: expiryTime, CreatedAt: order.CreatedAt})
+}
+
+// toOrder converts the payload into an order, applying the same defaults as the sheet reader
+func (p manualOrderRequest) toOrder(now time.Time) (models.Order, error) {
+	symbol := strings.ToUpper(strings.TrimSpace(p.Symbol))
+	if symbol == "" {
+		return models.Order{}, fmt.Errorf("symbol is required")
+	}
+	if p.Quantity <= 0 {
+		return models.Order{}, fmt.Errorf("quantity must be positive")
+	}
+
+	var side string
+	switch strings.ToUpper(p.Side) {
+	case "BUY":
+		side = "Buy"
+	case "SELL":
+		side = "Sell"
+	default:
+		return models.Order{}, fmt.Errorf("side must be Buy or Sell")
+	}
+
+	orderType := strings.ToUpper(p.OrderType)
+	if orderType == "" {
+		orderType = "LIMIT"
+	}
+	if orderType != "LIMIT" && orderType != "MARKET" {
+		return models.Order{}, fmt.Errorf("order_type must be LIMIT or MARKET")
+	}
+	if orderType == "LIMIT" && p.Price <= 0 {
+		return models.Order{}, fmt.Errorf("price is required for LIMIT orders")
+	}
+	if p.ExpirySeconds < 0 {
+		return models.Order{}, fmt.Errorf("expiry_seconds must not be negative")
+	}
+
+	exchange := strings.ToUpper(strings.TrimSpace(p.Exchange))
+	if exchange == "" {
+		exchange = "NSE"
+	}
+
+	scheduledTime := p.ScheduledTime
+	if scheduledTime.IsZero() {
+		scheduledTime = now
+	}
+	scheduledTime = scheduledTime.In(now.Location())
+
+	isAMO := broker.NewMarketHours().ShouldUseAMO(scheduledTime)
+	if p.IsAMO != nil {
+		isAMO = *p.IsAMO
+	}
+
+	return models.Order{
+		ID:            models.GenerateOrderID(symbol, scheduledTime) + "-manual",
+		Symbol:        symbol,
+		Exchange:      exchange,
+		Price:         p.Price,
+		Quantity:      p.Quantity,
+		OrderType:     orderType,
+		Side:          side,
+		ScheduledTime: scheduledTime,
+		CreatedAt:     now,
+		IsAMO:         isAMO,
+		ExpiryWindow:  time.Duration(p.ExpirySeconds) * time.Second,
+	}, nil
+}
+
+// handleExecution reports whether execution is paused
+func (a *AdminServer) handleExecution(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": a.trigger.IsPaused()})
+}
+
+// handlePause pauses order execution
+func (a *AdminServer) handlePause(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	a.trigger.Pause()
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": true})
+}
+
+// handleResume resumes order execution
+func (a *AdminServer) handleResume(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	a.trigger.Resume()
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": false})
+}
+
+// handleReadiness returns the last MaintainSystemReadiness result
+func (a *AdminServer) handleReadiness(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, a.trigger.LastReadiness())
+}
+
+// handlePortfolio returns the broker's cached holdings and positions; ?refresh=true fetches them first
+func (a *AdminServer) handlePortfolio(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	refresh, _ := strconv.ParseBool(req.URL.Query().Get("refresh"))
+	portfolio, err := a.trigger.Portfolio(req.Context(), refresh)
+	if errors.Is(err, broker.ErrPortfolioUnsupported) {
+		writeError(w, http.StatusNotImplemented, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, portfolio)
+}
+
+// handleFunds returns the broker's cached available margin; ?refresh=true fetches it first
+func (a *AdminServer) handleFunds(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	refresh, _ := strconv.ParseBool(req.URL.Query().Get("refresh"))
+	funds, err := a.trigger.Funds(req.Context(), refresh)
+	if errors.Is(err, broker.ErrFundsUnsupported) {
+		writeError(w, http.StatusNotImplemented, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, funds)
+}
+
+// requeueRequest is the payload accepted by POST /api/deadletters/{id}/requeue
+type requeueRequest struct {
+	ScheduledTime time.Time `json:"scheduled_time"` // Defaults to now
+	ExpirySeconds int       `json:"expiry_seconds"` // Overrides the order's expiry window when set
+	IsAMO         *bool     `json:"is_amo"`         // Derived from market hours when omitted
+}
+
+// haltRequest is the payload accepted by POST /api/halts and /api/killswitch/trip
+type haltRequest struct {
+	Scope  string `json:"scope"`
+	Target string `json:"target"`
+	Reason string `json:"reason"`
+}
+
+// handleHalts lists active halts (GET) or sets a halt (POST)
+func (a *AdminServer) handleHalts(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		halts, err := a.trigger.KillSwitch().Load(req.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, halts)
+	case http.MethodPost:
+		var payload haltRequest
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid halt payload: %v", err))
+			return
+		}
+		if err := a.trigger.KillSwitch().Halt(req.Context(), payload.Scope, payload.Target, payload.Reason, actor(req)); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]string{"status": "halted", "scope": payload.Scope, "target": payload.Target})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleHalt lifts a halt: DELETE /api/halts/{scope}[/{target}]
+func (a *AdminServer) handleHalt(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	scope, target, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/api/halts/"), "/")
+	if err := a.trigger.KillSwitch().Resume(req.Context(), scope, target, actor(req)); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "resumed", "scope": scope, "target": target})
+}
+
+// handleTrip activates the global kill switch
+func (a *AdminServer) handleTrip(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var payload haltRequest
+	if req.ContentLength != 0 {
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+			return
+		}
+	}
+	if payload.Reason == "" {
+		payload.Reason = "manual kill switch"
+	}
+
+	if err := a.trigger.KillSwitch().Trip(req.Context(), payload.Reason, actor(req)); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"tripped": true})
+}
+
+// handleReset deactivates the global kill switch
+func (a *AdminServer) handleReset(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if err := a.trigger.KillSwitch().Reset(req.Context(), actor(req)); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"tripped": false})
+}
+
+// handleAudit returns recent audit log entries (?limit=N, default 100)
+func (a *AdminServer) handleAudit(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	limit := int64(100)
+	if value := req.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := a.trigger.KillSwitch().AuditLog(req.Context(), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// actor identifies who made an admin request for the audit log
+func actor(req *http.Request) string {
+	if name := req.Header.Get("X-Actor"); name != "" {
+		return name
+	}
+	return "admin-api@" + req.RemoteAddr
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// handleQuarantine lists cache entries that could not be decoded
+func (a *AdminServer) handleQuarantine(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	entries, err := a.cache.ListQuarantined(req.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// handleQuarantined discards (DELETE) a quarantined entry once it has been dealt with
+func (a *AdminServer) handleQuarantined(w http.ResponseWriter, req *http.Request) {
+	orderID, err := url.PathUnescape(strings.TrimPrefix(req.URL.EscapedPath(), "/api/quarantine/"))
+	if err != nil || orderID == "" {
+		writeError(w, http.StatusBadRequest, "invalid order id")
+		return
+	}
+	if req.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	removed, err := a.cache.DiscardQuarantined(req.Context(), orderID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no quarantined entry for %s", orderID))
+		return
+	}
+	a.logger.Warn("🗑️  Quarantined order %s discarded via admin API", orderID)
+	writeJSON(w, http.StatusOK, map[string]string{"status": "discarded", "order_id": orderID})
+}
+
+// handleMigrate upgrades cached orders to the current schema version
+func (a *AdminServer) handleMigrate(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	migrated, quarantined, err := a.trigger.MigrateCache(req.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"schema_version": models.OrderCacheEntryVersion,
+		"migrated":       migrated,
+		"quarantined":    quarantined,
+	})
+}
+
+// handleGTTs lists the GTTs placed for order-source rows with their last known status
+func (a *AdminServer) handleGTTs(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	records, err := a.trigger.GTTs(req.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+// handleGTTSync reconciles GTTs with the order source and the broker now instead of at the
+// next sync interval, and lists them
+func (a *AdminServer) handleGTTSync(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if err := a.trigger.SyncGTTs(req.Context()); err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	records, err := a.trigger.GTTs(req.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+// handleDeadLetters lists orders in the dead-letter queue
+func (a *AdminServer) handleDeadLetters(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	letters, err := a.cache.ListDeadLetters(req.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if reason := req.URL.Query().Get("reason"); reason != "" {
+		filtered := letters[:0]
+		for _, letter := range letters {
+			if letter.Reason == reason {
+				filtered = append(filtered, letter)
+			}
+		}
+		letters = filtered
+	}
+	writeJSON(w, http.StatusOK, letters)
+}
+
+// handleDeadLetter shows (GET) or discards (DELETE) a dead letter, or requeues it
+// (POST /api/deadletters/{id}/requeue)
+func (a *AdminServer) handleDeadLetter(w http.ResponseWriter, req *http.Request) {
+	path := strings.TrimPrefix(req.URL.EscapedPath(), "/api/deadletters/")
+	requeue := strings.HasSuffix(path, "/requeue")
+	orderID, err := url.PathUnescape(strings.TrimSuffix(path, "/requeue"))
+	if err != nil || orderID == "" {
+		writeError(w, http.StatusBadRequest, "invalid order id")
+		return
+	}
+
+	switch {
+	case requeue && req.Method == http.MethodPost:
+		a.requeueDeadLetter(w, req, orderID)
+	case requeue:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	case req.Method == http.MethodGet:
+		letter, err := a.cache.GetDeadLetter(req.Context(), orderID)
+		if errors.Is(err, cache.ErrDeadLetterNotFound) {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, letter)
+	case req.Method == http.MethodDelete:
+		removed, err := a.cache.DiscardDeadLetter(req.Context(), orderID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !removed {
+			writeError(w, http.StatusNotFound, cache.ErrDeadLetterNotFound.Error())
+			return
+		}
+		a.logger.Warn("🗑️  Dead letter %s discarded via admin API (by: %s)", orderID, actor(req))
+		writeJSON(w, http.StatusOK, map[string]string{"status": "discarded", "order_id": orderID})
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// requeueDeadLetter puts a dead-lettered order back in the pending queue at a new scheduled time
+func (a *AdminServer) requeueDeadLetter(w http.ResponseWriter, req *http.Request, orderID string) {
+	var payload requeueRequest
+	if req.ContentLength != 0 {
+		if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid requeue payload: %v", err))
+			return
+		}
+	}
+	if payload.ExpirySeconds < 0 {
+		writeError(w, http.StatusBadRequest, "expiry_seconds must not be negative")
+		return
+	}
+
+	letter, err := a.cache.GetDeadLetter(req.Context(), orderID)
+	if errors.Is(err, cache.ErrDeadLetterNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	now := a.trigger.clock.Now().In(a.trigger.istLocation)
+	order := letter.Order
+	order.ScheduledTime = payload.ScheduledTime
+	if order.ScheduledTime.IsZero() {
+		order.ScheduledTime = now
+	}
+	order.ScheduledTime = order.ScheduledTime.In(now.Location())
+	order.IsAMO = broker.NewMarketHours().ShouldUseAMO(order.ScheduledTime)
+	if payload.IsAMO != nil {
+		order.IsAMO = *payload.IsAMO
+	}
+	if payload.ExpirySeconds > 0 {
+		order.ExpiryWindow = time.Duration(payload.ExpirySeconds) * time.Second
+	}
+
+	expiryTime := cache.ExpiryFor(order, a.config.Trigger.Expiry)
+	if err := a.cache.RequeueDeadLetter(req.Context(), order, expiryTime); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a.logger.Info("♻️  Dead letter %s requeued for %s IST after %d attempts (by: %s)",
+		orderID, order.ScheduledTime.Format("2006-01-02 15:04:05"), order.Attempts, actor(req))
+	writeJSON(w, http.StatusOK, models.OrderCacheEntry{Order: order, ExpiryTime: expiryTime, CreatedAt: now})
+}
@@ -0,0 +1,142 @@
+package trigger
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/config"
+)
+
+func TestAdminRoutesRequireToken(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, mustIST(t))
+	h := newTestHarness(t, now, func(cfg *config.Config) {
+		cfg.Admin.Token = "secret"
+	})
+	h.store(t, "ORDER", now.Add(time.Hour))
+	handler := NewAdminServer(h.config, h.cache, h.trigger, h.log).Handler()
+
+	routes := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodGet, "/api/orders", ""},
+		{http.MethodPost, "/api/orders", `{"symbol":"INFY","side":"Buy","price":100,"quantity":1}`},
+		{http.MethodGet, "/api/orders/ORDER", ""},
+		{http.MethodDelete, "/api/orders/ORDER", ""},
+		{http.MethodGet, "/api/execution", ""},
+		{http.MethodPost, "/api/execution/pause", ""},
+		{http.MethodPost, "/api/execution/resume", ""},
+		{http.MethodGet, "/api/readiness", ""},
+		{http.MethodGet, "/api/portfolio", ""},
+		{http.MethodGet, "/api/funds", ""},
+		{http.MethodGet, "/api/halts", ""},
+		{http.MethodPost, "/api/halts", `{"scope":"symbol","target":"INFY","reason":"test"}`},
+		{http.MethodDelete, "/api/halts/symbol/INFY", ""},
+		{http.MethodPost, "/api/killswitch/trip", ""},
+		{http.MethodPost, "/api/killswitch/reset", ""},
+		{http.MethodGet, "/api/audit", ""},
+		{http.MethodGet, "/api/deadletters", ""},
+		{http.MethodGet, "/api/deadletters/ORDER", ""},
+		{http.MethodDelete, "/api/deadletters/ORDER", ""},
+		{http.MethodPost, "/api/deadletters/ORDER/requeue", ""},
+		{http.MethodGet, "/api/quarantine", ""},
+		{http.MethodDelete, "/api/quarantine/ORDER", ""},
+		{http.MethodPost, "/api/cache/migrate", ""},
+		{http.MethodGet, "/api/gtts", ""},
+		{http.MethodPost, "/api/gtts/sync", ""},
+	}
+	credentials := []struct {
+		name          string
+		authorization string
+	}{
+		{"no token", ""},
+		{"wrong token", "Bearer wrong"},
+		{"empty bearer token", "Bearer "},
+		{"token without the bearer scheme", "secret"},
+		{"token with a prefix of the secret", "Bearer secre"},
+	}
+
+	for _, route := range routes {
+		for _, credential := range credentials {
+			req := httptest.NewRequest(route.method, route.path, strings.NewReader(route.body))
+			if credential.authorization != "" {
+				req.Header.Set("Authorization", credential.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s with %s returned %d, want 401", route.method, route.path, credential.name, rec.Code)
+			}
+		}
+	}
+
+	// None of the rejected requests changed anything
+	if h.trigger.IsPaused() {
+		t.Error("execution paused by an unauthenticated request")
+	}
+	if halts, err := h.trigger.KillSwitch().Load(ctx); err != nil || len(halts) != 0 {
+		t.Errorf("halts = %+v, %v; want none set by unauthenticated requests", halts, err)
+	}
+	if count, _ := h.cache.PendingCount(ctx); count != 1 {
+		t.Errorf("PendingCount = %d, want the order neither cancelled nor joined by a manual order", count)
+	}
+	if _, err := h.cache.GetOrder(ctx, "ORDER"); err != nil {
+		t.Errorf("GetOrder: %v, want the order still queued", err)
+	}
+
+	// The same request with the token is served
+	req := httptest.NewRequest(http.MethodPost, "/api/execution/pause", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !h.trigger.IsPaused() {
+		t.Errorf("pause with the token returned %d, paused %v", rec.Code, h.trigger.IsPaused())
+	}
+}
+
+func TestAdminServeRequiresToken(t *testing.T) {
+	h := newTestHarness(t, time.Date(2024, 1, 15, 9, 0, 0, 0, mustIST(t)), func(cfg *config.Config) {
+		cfg.Admin.Addr = "127.0.0.1:0"
+	})
+	done := make(chan error, 1)
+	go func() { done <- NewAdminServer(h.config, h.cache, h.trigger, h.log).Serve(context.Background()) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Serve started with no admin token")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve is listening with no admin token")
+	}
+}
+
+func TestAdminListensOnLocalhostByDefault(t *testing.T) {
+	brokerConfig := filepath.Join(t.TempDir(), "broker-config.json")
+	if err := os.WriteFile(brokerConfig, []byte(`{"type":"mock"}`), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	t.Setenv("BROKER_CONFIG_PATH", brokerConfig)
+	t.Setenv("ADMIN_ADDR", "")
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	host, _, err := net.SplitHostPort(cfg.Admin.Addr)
+	if err != nil {
+		t.Fatalf("admin address %q: %v", cfg.Admin.Addr, err)
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		t.Errorf("admin address = %q, want a loopback address by default", cfg.Admin.Addr)
+	}
+}
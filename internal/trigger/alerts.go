@@ -0,0 +1,128 @@
+package trigger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mach_five/trading-system/internal/broker"
+	"github.com/mach_five/trading-system/internal/journal"
+	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/notify"
+	"github.com/mach_five/trading-system/internal/report"
+)
+
+// Notifier returns the trigger's alert notifier
+func (t *Trigger) Notifier() *notify.Notifier {
+	return t.notifier
+}
+
+// notifyOrderSuccess alerts that an order was accepted by the broker
+func (t *Trigger) notifyOrderSuccess(order models.Order, result models.ExecutionResult) {
+	t.notifier.Notify(notify.Event{
+		Type:  notify.EventOrderSuccess,
+		Title: fmt.Sprintf("Order %s executed", order.ID),
+		Fields: map[string]string{
+			"Symbol":       order.Symbol,
+			"Side":         order.Side,
+			"Quantity":     fmt.Sprintf("%d", order.Quantity),
+			"Price":        fmt.Sprintf("%.2f", order.Price),
+			"Execution ID": result.ExecutionID,
+		},
+		Key:  fmt.Sprintf("%s#%d", order.ID, order.Attempts),
+		Time: t.clock.Now(),
+	})
+}
+
+// notifyOrderFailure alerts that an order did not execute and was dead-lettered for reason
+func (t *Trigger) notifyOrderFailure(order models.Order, reason, errMsg string) {
+	t.notifier.Notify(notify.Event{
+		Type:    notify.EventOrderFailure,
+		Title:   fmt.Sprintf("Order %s %s", order.ID, reason),
+		Message: errMsg,
+		Fields: map[string]string{
+			"Symbol":   order.Symbol,
+			"Side":     order.Side,
+			"Quantity": fmt.Sprintf("%d", order.Quantity),
+			"Attempts": fmt.Sprintf("%d", order.Attempts),
+		},
+		Key:  fmt.Sprintf("%s#%d:%s", order.ID, order.Attempts, reason),
+		Time: t.clock.Now(),
+	})
+}
+
+// notifyBrokerError alerts on a broker error that means the access token must be renewed
+func (t *Trigger) notifyBrokerError(err error) {
+	if !errors.Is(err, broker.ErrTokenRejected) {
+		return
+	}
+	t.notifier.Notify(notify.Event{
+		Type:    notify.EventTokenExpired,
+		Title:   fmt.Sprintf("%s access token rejected", t.config.Broker.Type),
+		Message: "Orders will fail until the access token is renewed. " + err.Error(),
+		Key:     t.config.Broker.Type,
+		Time:    t.clock.Now(),
+	})
+}
+
+// notifyHealthDegraded alerts that a component failed its health check
+func (t *Trigger) notifyHealthDegraded(component string, err error) {
+	t.notifier.Notify(notify.Event{
+		Type:    notify.EventHealthDegraded,
+		Title:   fmt.Sprintf("%s health check failed on %s", component, t.instanceID),
+		Message: err.Error(),
+		Key:     component,
+		Time:    t.clock.Now(),
+	})
+}
+
+// DailySummary summarises the executions journaled since the start of now's IST day, along
+// with the orders still waiting in the queues
+func (t *Trigger) DailySummary(ctx context.Context, now time.Time) (notify.Event, error) {
+	dayStart := report.StartOfDay(now)
+	entries, err := journal.ReadEntries(t.config.Journal.Path, dayStart, now.Add(time.Nanosecond))
+	if err != nil {
+		return notify.Event{}, fmt.Errorf("failed to read execution journal: %w", err)
+	}
+
+	event := report.Build(dayStart, entries).Event()
+	event.Title = fmt.Sprintf("Daily summary for %s (%s)", dayStart.Format("2006-01-02"), t.config.Broker.Type)
+	event.Time = now
+	if pending, err := t.cache.PendingCount(ctx); err == nil {
+		event.Fields["Pending orders"] = fmt.Sprintf("%d", pending)
+	}
+	if deadLetters, err := t.cache.DeadLetterCount(ctx); err == nil {
+		event.Fields["Dead letters"] = fmt.Sprintf("%d", deadLetters)
+	}
+	return event, nil
+}
+
+// sendDailySummary queues the daily summary once the configured time of day has passed.
+// Only the leader sends it, once per day per process.
+func (t *Trigger) sendDailySummary(ctx context.Context) {
+	at := t.config.Notify.DailySummaryAt
+	if at == "" || !t.notifier.Enabled() || !t.isLeader() {
+		return
+	}
+	sendAt, err := time.Parse("15:04", at)
+	if err != nil {
+		return
+	}
+
+	now := t.clock.Now().In(t.istLocation)
+	date := now.Format("2006-01-02")
+	due := time.Date(now.Year(), now.Month(), now.Day(), sendAt.Hour(), sendAt.Minute(), 0, 0, t.istLocation)
+	if now.Before(due) || t.lastSummaryDate == date {
+		return
+	}
+	t.lastSummaryDate = date
+
+	event, err := t.DailySummary(ctx, now)
+	if err != nil {
+		t.logger.Warn("Failed to build daily summary: %v", err)
+		return
+	}
+	t.logger.Info("📊 Sending daily summary for %s", date)
+	t.notifier.Notify(event)
+}
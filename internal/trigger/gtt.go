@@ -0,0 +1,219 @@
+package trigger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/mach_five/trading-system/internal/broker"
+	"github.com/mach_five/trading-system/internal/metrics"
+	"github.com/mach_five/trading-system/internal/models"
+)
+
+// SyncGTTs reconciles the GTTs held at the broker with the GTT rows the order source lists.
+// Status changes the broker reports are followed first, then rows without a GTT get one,
+// active GTTs whose row changed price, quantity or stop-loss are modified, and active GTTs
+// whose row is gone (or past its valid-until time) are deleted. Every change is journaled and
+// stored, and the reader writes the stored status back to the row.
+func (t *Trigger) SyncGTTs(ctx context.Context) error {
+	if t.paused.Load() || !t.isLeader() {
+		return nil
+	}
+
+	desired, err := t.cache.DesiredGTTs(ctx)
+	if err != nil {
+		return err
+	}
+	records, err := t.cache.GTTRecords(ctx)
+	if err != nil {
+		return err
+	}
+	if len(desired) == 0 && len(records) == 0 {
+		return nil
+	}
+
+	triggers, err := t.brokerManager.GTTs(ctx)
+	if err != nil {
+		if errors.Is(err, broker.ErrGTTUnsupported) {
+			t.logger.Warn("⚠️  %d GTT rows not placed: %v", len(desired), err)
+			return nil
+		}
+		t.notifyBrokerError(err)
+		return fmt.Errorf("failed to list GTTs: %w", err)
+	}
+	listed := make(map[int]models.GTTTrigger, len(triggers))
+	for _, trigger := range triggers {
+		listed[trigger.ID] = trigger
+	}
+
+	for _, orderID := range sortedKeys(records) {
+		if record := records[orderID]; !record.Terminal() {
+			records[orderID] = t.followGTT(ctx, record, listed)
+		}
+	}
+
+	// New GTTs are not placed, nor old ones modified, while a halt blocks their order
+	halts, haltsErr := t.killSwitch.Load(ctx)
+	if haltsErr != nil {
+		t.logger.Error("❌ Failed to read trading halts, GTTs are not placed or modified: %v", haltsErr)
+	}
+	for _, orderID := range sortedKeys(desired) {
+		order := desired[orderID]
+		record, placed := records[orderID]
+		if placed && (record.Terminal() || !gttChanged(record.Order, order)) {
+			continue
+		}
+		if haltsErr != nil {
+			continue
+		}
+		if halt, blocked := halts.Blocks(order); blocked {
+			t.logger.Debug("⛔ GTT for order %s held by %s halt (%s)", order.ID, halt.Key(), halt.Reason)
+			continue
+		}
+		if placed {
+			t.modifyGTT(ctx, record, order)
+		} else {
+			t.placeGTT(ctx, order)
+		}
+	}
+
+	for _, orderID := range sortedKeys(records) {
+		if _, wanted := desired[orderID]; wanted {
+			continue
+		}
+		if record := records[orderID]; !record.Terminal() && !t.deleteGTT(ctx, record) {
+			continue
+		}
+		if err := t.cache.DeleteGTTRecord(ctx, orderID); err != nil {
+			t.logger.Warn("Failed to forget GTT of order %s: %v", orderID, err)
+		}
+	}
+	return nil
+}
+
+// followGTT applies the status the broker lists for an active GTT. A GTT the broker no
+// longer lists was deleted outside the system.
+func (t *Trigger) followGTT(ctx context.Context, record models.GTTRecord, listed map[int]models.GTTTrigger) models.GTTRecord {
+	trigger, ok := listed[record.TriggerID]
+	if !ok {
+		trigger = models.GTTTrigger{ID: record.TriggerID, Status: models.GTTStatusDeleted}
+	}
+	if trigger.Status == record.Status {
+		return record
+	}
+
+	record.Status, record.OrderID, record.Error = trigger.Status, trigger.OrderID, trigger.RejectionReason
+	record.UpdatedAt = t.clock.Now()
+	t.logger.Info("🎯 GTT for order %s: %s", record.Order.ID, record)
+	t.saveGTT(ctx, record, models.JournalEventGTTStatus, trigger.Status)
+
+	switch {
+	case record.Status == models.GTTStatusTriggered && record.Error == "":
+		t.notifyOrderSuccess(record.Order, models.ExecutionResult{OrderID: record.Order.ID, Success: true, ExecutionID: record.OrderID})
+	case record.Status != models.GTTStatusDeleted:
+		t.notifyOrderFailure(record.Order, "GTT "+record.Status, record.Error)
+	}
+	return record
+}
+
+// placeGTT leaves a GTT for an order at the broker. Failures are retried on the next sync.
+func (t *Trigger) placeGTT(ctx context.Context, order models.Order) {
+	triggerID, err := t.brokerManager.PlaceGTT(ctx, order)
+	if err != nil {
+		t.logger.Error("❌ Failed to place GTT for order %s: %v", order.ID, err)
+		t.notifyBrokerError(err)
+		return
+	}
+	record := models.GTTRecord{
+		Order:     order,
+		TriggerID: triggerID,
+		Status:    models.GTTStatusActive,
+		UpdatedAt: t.clock.Now(),
+	}
+	t.logger.Success("✅ GTT #%d placed for order %s: %s %d %s at %v", triggerID, order.ID, order.Side,
+		order.Quantity, order.Symbol, order.GTT.TriggerValues(order.Price))
+	t.saveGTT(ctx, record, models.JournalEventGTTPlaced, "placed")
+}
+
+// modifyGTT changes an active GTT to match its row. Failures are kept on the record and
+// retried on the next sync.
+func (t *Trigger) modifyGTT(ctx context.Context, record models.GTTRecord, order models.Order) {
+	if err := t.brokerManager.ModifyGTT(ctx, record.TriggerID, order); err != nil {
+		t.logger.Error("❌ Failed to modify GTT #%d of order %s: %v", record.TriggerID, order.ID, err)
+		t.notifyBrokerError(err)
+		if record.Error != err.Error() {
+			record.Error = err.Error()
+			if err := t.cache.SaveGTTRecord(ctx, record); err != nil {
+				t.logger.Warn("Failed to store GTT of order %s: %v", order.ID, err)
+			}
+		}
+		return
+	}
+	record.Order, record.Error, record.UpdatedAt = order, "", t.clock.Now()
+	t.logger.Info("✏️  GTT #%d modified for order %s: %s %d %s at %v", record.TriggerID, order.ID, order.Side,
+		order.Quantity, order.Symbol, order.GTT.TriggerValues(order.Price))
+	t.saveGTT(ctx, record, models.JournalEventGTTModified, "modified")
+}
+
+// deleteGTT deletes an active GTT whose row is gone and reports whether it was deleted
+func (t *Trigger) deleteGTT(ctx context.Context, record models.GTTRecord) bool {
+	if err := t.brokerManager.DeleteGTT(ctx, record.TriggerID); err != nil {
+		t.logger.Error("❌ Failed to delete GTT #%d of order %s: %v", record.TriggerID, record.Order.ID, err)
+		t.notifyBrokerError(err)
+		return false
+	}
+	record.Status, record.UpdatedAt = models.GTTStatusDeleted, t.clock.Now()
+	t.logger.Info("🗑️  GTT #%d deleted, order %s is no longer in the order source", record.TriggerID, record.Order.ID)
+	t.saveGTT(ctx, record, models.JournalEventGTTDeleted, "deleted")
+	return true
+}
+
+// saveGTT stores a GTT record, journals the change and counts it under event
+func (t *Trigger) saveGTT(ctx context.Context, record models.GTTRecord, journalEvent, event string) {
+	metrics.GTTEvents.WithLabelValues(event).Inc()
+	if err := t.cache.SaveGTTRecord(ctx, record); err != nil {
+		t.logger.Warn("Failed to store GTT of order %s: %v", record.Order.ID, err)
+	}
+	if err := t.journal.Record(models.JournalEntry{
+		Timestamp: record.UpdatedAt,
+		Event:     journalEvent,
+		Broker:    t.config.Broker.Type,
+		Order:     &record.Order,
+		GTT:       &record,
+	}); err != nil {
+		t.logger.Warn("Failed to journal GTT of order %s: %v", record.Order.ID, err)
+	}
+}
+
+// GTTs returns the GTTs placed for order-source rows, by order ID
+func (t *Trigger) GTTs(ctx context.Context) ([]models.GTTRecord, error) {
+	records, err := t.cache.GTTRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]models.GTTRecord, 0, len(records))
+	for _, orderID := range sortedKeys(records) {
+		list = append(list, records[orderID])
+	}
+	return list, nil
+}
+
+// gttChanged reports whether a row's GTT order differs from the one its GTT was placed with.
+// A missing GTT on either side counts as a change.
+func gttChanged(placed, order models.Order) bool {
+	if placed.GTT == nil || order.GTT == nil {
+		return true
+	}
+	return placed.Price != order.Price || placed.Quantity != order.Quantity || *placed.GTT != *order.GTT
+}
+
+// sortedKeys returns a map's order IDs in order, so GTTs are synced in a stable order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
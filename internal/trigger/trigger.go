@@ -2,15 +2,28 @@ package trigger
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mach_five/trading-system/internal/broker"
 	"github.com/mach_five/trading-system/internal/cache"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/conditions"
 	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/journal"
+	"github.com/mach_five/trading-system/internal/killswitch"
+	"github.com/mach_five/trading-system/internal/leader"
 	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/metrics"
 	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/notify"
+	"github.com/mach_five/trading-system/internal/quotes"
+	"github.com/mach_five/trading-system/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Trigger handles order execution
@@ -18,13 +31,44 @@ type Trigger struct {
 	config              *config.Config
 	cache               *cache.RedisCache
 	brokerManager       *broker.BrokerManager
+	killSwitch          *killswitch.KillSwitch
+	elector             *leader.Elector // Nil unless leader election is enabled
+	marketHours         *broker.MarketHours // Decides whether late orders can be converted to AMO
+	journal             *journal.Journal // Execution journal shared with the paper broker; nil if unavailable
+	notifier            *notify.Notifier // Sends alerts; discards them when no sink is configured
+	quoteFeed           *quotes.Feed     // Quotes conditional orders are checked against
 	logger              *logger.Logger
 	workerPool          int
+	instanceID          string        // Owner recorded on claimed orders
+	claimLease          time.Duration // How long claimed orders are reserved for this instance
 	istLocation         *time.Location // Cached timezone location
+	clock               clock.Clock    // Source of "now" for scheduling decisions
 	healthCheckMu       sync.Mutex     // Mutex to ensure only one health check runs at a time
 	healthCheckInProgress bool         // Flag to track if health check is running
+	paused              atomic.Bool    // When set, due orders are left in the queue instead of executed
+	paperPollInProgress atomic.Bool    // Set while a paper quote poll is running
+	readinessMu         sync.RWMutex   // Protects lastReadiness
+	lastReadiness       ReadinessReport // Result of the most recent MaintainSystemReadiness run
+	lastSummaryDate     string          // IST date of the last daily summary sent; used by RunContinuous only
 }
 
+// ReadinessReport captures the outcome of a MaintainSystemReadiness run
+type ReadinessReport struct {
+	CheckedAt     time.Time `json:"checked_at"`
+	CacheHealthy  bool      `json:"cache_healthy"`
+	CacheError    string    `json:"cache_error,omitempty"`
+	BrokerHealthy bool      `json:"broker_healthy"`
+	BrokerError   string    `json:"broker_error,omitempty"`
+	PendingOrders int64     `json:"pending_orders"`
+	InFlightOrders int64    `json:"in_flight_orders"`
+	DeadLetterOrders int64  `json:"dead_letter_orders"`
+	QuarantinedOrders int64 `json:"quarantined_orders"`
+	Leadership    leader.Status `json:"leadership"`
+}
+
+// defaultClaimLease is used when the configuration does not set a claim lease
+const defaultClaimLease = 30 * time.Second
+
 // NewTrigger creates a new trigger instance
 func NewTrigger(cfg *config.Config, cache *cache.RedisCache, brokerMgr *broker.BrokerManager, log *logger.Logger) *Trigger {
 	// Load and cache timezone location once
@@ -34,38 +78,152 @@ func NewTrigger(cfg *config.Config, cache *cache.RedisCache, brokerMgr *broker.B
 		istLocation = time.UTC
 	}
 	
+	executionJournal, err := journal.Open(cfg.Journal.Path)
+	if err != nil {
+		log.Warn("Failed to open execution journal, executions will only be logged: %v", err)
+	}
+	
+	instanceID := cfg.Trigger.InstanceID
+	if instanceID == "" {
+		instanceID = "trigger"
+	}
+	claimLease := cfg.Trigger.ClaimLease
+	if claimLease <= 0 {
+		claimLease = defaultClaimLease
+	}
+	
+	var elector *leader.Elector
+	if cfg.Leader.Enabled {
+		elector = leader.NewElector(cfg, cache, leader.RoleTrigger, log)
+	}
+	
+	killSwitch := killswitch.NewKillSwitch(cfg, cache, log)
+	if notifier, ok := brokerMgr.Broker().(broker.FillNotifier); ok {
+		// Realised P&L counts toward the kill switch's daily loss limit
+		notifier.OnFill(func(fill models.Fill) {
+			if fill.RealisedPnL != 0 {
+				killSwitch.RecordPnL(context.Background(), fill.RealisedPnL)
+			}
+		})
+	}
+
 	return &Trigger{
 		config:        cfg,
 		cache:         cache,
 		brokerManager: brokerMgr,
+		killSwitch:    killSwitch,
+		elector:       elector,
+		marketHours:   broker.NewMarketHours(),
+		journal:       executionJournal,
+		notifier:      notify.NewNotifier(cfg, log),
+		quoteFeed:     quotes.NewFeed(cfg, brokerMgr, log),
 		logger:        log,
 		workerPool:    cfg.Trigger.WorkerPoolSize,
+		instanceID:    instanceID,
+		claimLease:    claimLease,
 		istLocation:   istLocation,
+		clock:         clock.Real{},
+	}
+}
+
+// SetClock replaces the clock used to decide which orders are due and to timestamp halts
+func (t *Trigger) SetClock(c clock.Clock) {
+	t.clock = c
+	t.killSwitch.SetClock(c)
+	t.notifier.SetClock(c)
+	t.quoteFeed.SetClock(c)
+	if t.elector != nil {
+		t.elector.SetClock(c)
+	}
+}
+
+// Elector returns the trigger's leader elector, or nil if leader election is disabled
+func (t *Trigger) Elector() *leader.Elector {
+	return t.elector
+}
+
+// isLeader reports whether this instance may dispatch orders
+func (t *Trigger) isLeader() bool {
+	return t.elector == nil || t.elector.IsLeader()
+}
+
+// fence returns the fencing token for claims, or no fence if leader election is disabled
+func (t *Trigger) fence() cache.Fence {
+	if t.elector == nil {
+		return cache.Fence{}
+	}
+	return t.elector.Fence()
+}
+
+// Leadership returns this instance's leader election status
+func (t *Trigger) Leadership() leader.Status {
+	if t.elector == nil {
+		return leader.Status{Role: leader.RoleTrigger, InstanceID: t.instanceID, Leader: true}
 	}
+	return t.elector.Status()
 }
 
 // ExecuteDueOrders executes all orders that are due for execution
 func (t *Trigger) ExecuteDueOrders(ctx context.Context) error {
+	// Leave orders in the queue while execution is paused via the admin API
+	if t.paused.Load() {
+		return nil
+	}
+
+	// Standby instances leave dispatching to the leader
+	if !t.isLeader() {
+		return nil
+	}
+
 	// Get current time in IST using cached location (optimized for 1ms polling)
-	now := time.Now().In(t.istLocation)
+	now := t.clock.Now().In(t.istLocation)
 	
-	// Get orders due for execution
-	orders, err := t.cache.GetOrdersDueForExecution(now)
+	// Claim due orders so no other trigger instance executes them
+	orders, late, err := t.cache.ClaimDueOrders(ctx, now, t.instanceID, t.claimLease, t.fence())
 	if err != nil {
-		t.logger.Error("❌ Failed to get orders due for execution")
+		t.logger.Error("❌ Failed to claim orders due for execution")
 		t.logger.Error("   Current time (IST): %s", now.Format("2006-01-02 15:04:05 IST"))
 		t.logger.Error("   Error: %v", err)
 		return fmt.Errorf("failed to get orders due for execution: %w", err)
 	}
 
+	// Orders that missed their expiry window are executed, converted or recorded as expired
+	if len(late) > 0 {
+		orders = append(orders, t.resolveLateOrders(ctx, late, now)...)
+	}
+
 	// Return silently if no orders are due (no logging - critical for 1ms polling)
 	if len(orders) == 0 {
 		return nil
 	}
+
+	// Honour the kill switch and per-symbol/per-side halts; halted orders stay queued
+	orders, err = t.filterHalted(ctx, orders)
+	if err != nil {
+		return err
+	}
+
+	// Hold armed orders back until their market condition holds
+	orders = t.filterConditions(ctx, orders)
+
+	// Size buys given as a percentage of funds, then hold back buys the account cannot fund
+	// instead of letting the broker reject them one by one
+	orders = t.sizeOrders(ctx, orders)
+	orders = t.checkFunds(ctx, orders)
+	if len(orders) == 0 {
+		return nil
+	}
+
+	// Fetch any circuit bands the readiness check has not, so workers only use cached ones
+	t.brokerManager.PrefetchBands(ctx, orders)
 	
 	startTime := time.Now()
 	t.logger.Debug("Checking for orders due at %s IST", now.Format("2006-01-02 15:04:05 IST"))
 
+	ctx, span := tracing.Tracer().Start(ctx, "trigger.execute_cycle",
+		trace.WithAttributes(attribute.Int("orders.due", len(orders))))
+	defer span.End()
+
 	// Only log when there are orders to execute
 	t.logger.Section("🚀 Order Execution Cycle Started")
 
@@ -111,6 +269,142 @@ func (t *Trigger) ExecuteDueOrders(ctx context.Context) error {
 	return nil
 }
 
+// sizeOrders sizes orders given as a percentage of funds and dead-letters those that cannot be sized
+func (t *Trigger) sizeOrders(ctx context.Context, orders []models.Order) []models.Order {
+	sized, rejected := t.brokerManager.SizeOrders(ctx, orders)
+	for _, rejection := range rejected {
+		t.rejectOrder(ctx, rejection.Order, rejection.Reason)
+	}
+	return sized
+}
+
+// checkFunds applies the buy funds policy to the batch and dead-letters the buys held back
+func (t *Trigger) checkFunds(ctx context.Context, orders []models.Order) []models.Order {
+	approved, rejected := t.brokerManager.CheckBuys(ctx, orders)
+	for _, rejection := range rejected {
+		t.rejectOrder(ctx, rejection.Order, rejection.Reason)
+	}
+	return approved
+}
+
+// rejectOrder records a claimed order that was held back before reaching the broker
+func (t *Trigger) rejectOrder(ctx context.Context, order models.Order, reason string) {
+	now := t.clock.Now()
+	metrics.OrdersFailed.Inc()
+	t.logger.Warn("💸 Order %s held back before execution: %s", order.ID, reason)
+	if err := t.journal.Record(models.JournalEntry{
+		Timestamp: now,
+		Event:     models.JournalEventRejected,
+		Broker:    t.config.Broker.Type,
+		Order:     &order,
+		Result: &models.ExecutionResult{
+			OrderID:      order.ID,
+			Success:      false,
+			ExecutedAt:   now,
+			ErrorMessage: reason,
+		},
+	}); err != nil {
+		t.logger.Warn("Failed to journal rejection of order %s: %v", order.ID, err)
+	}
+	t.deadLetter(ctx, order, models.DeadLetterRejected, reason, cache.ExpiryFor(order, t.config.Trigger.Expiry))
+}
+
+// filterHalted drops orders blocked by an active halt and returns their claims to the queue.
+// If the halts cannot be read the whole batch is held back, since executing through an unknown
+// kill-switch state is unsafe.
+func (t *Trigger) filterHalted(ctx context.Context, orders []models.Order) ([]models.Order, error) {
+	halts, err := t.killSwitch.Load(ctx)
+	if err != nil {
+		t.logger.Error("❌ Failed to read trading halts, holding %d due orders", len(orders))
+		t.logger.Error("   Error: %v", err)
+		for _, order := range orders {
+			t.releaseClaim(ctx, order.ID)
+		}
+		return nil, fmt.Errorf("failed to read trading halts: %w", err)
+	}
+	if len(halts) == 0 {
+		return orders, nil
+	}
+
+	allowed := orders[:0]
+	for _, order := range orders {
+		if halt, blocked := halts.Blocks(order); blocked {
+			// Debug only: halted orders are seen again on every poll until they expire or the halt is lifted
+			t.logger.Debug("⛔ Order %s held by %s halt (%s)", order.ID, halt.Key(), halt.Reason)
+			t.releaseClaim(ctx, order.ID)
+			continue
+		}
+		allowed = append(allowed, order)
+	}
+	return allowed, nil
+}
+
+// filterConditions drops orders whose market condition does not hold yet and returns their
+// claims to the queue, so they are checked again on every poll until they expire. An order
+// without a fresh quote for its instrument is held back too.
+func (t *Trigger) filterConditions(ctx context.Context, orders []models.Order) []models.Order {
+	var instruments []string
+	for _, order := range orders {
+		if order.Condition != nil {
+			instruments = append(instruments, broker.QuoteKey(order))
+		}
+	}
+	if len(instruments) == 0 {
+		return orders
+	}
+
+	// Poll failures are logged by the feed; orders wait for the next successful poll
+	latest, _ := t.quoteFeed.Quotes(ctx, instruments)
+	ready := orders[:0]
+	for _, order := range orders {
+		if order.Condition == nil {
+			ready = append(ready, order)
+			continue
+		}
+		quote, ok := latest[broker.QuoteKey(order)]
+		if !ok {
+			t.logger.Debug("🎯 Order %s waiting for a quote of %s to check %s", order.ID, broker.QuoteKey(order), order.Condition)
+			t.releaseClaim(ctx, order.ID)
+			continue
+		}
+		met, observed := conditions.Evaluate(*order.Condition, quote)
+		if !met {
+			// Debug only: armed orders are seen again on every poll until their condition holds
+			t.logger.Debug("🎯 Order %s armed, %s is %.2f (%s)", order.ID, order.Condition.Metric, observed, order.Condition)
+			t.releaseClaim(ctx, order.ID)
+			continue
+		}
+		metrics.OrdersConditionMet.Inc()
+		t.logger.Info("🎯 Order %s condition %s met: %s %.2f at %s IST", order.ID, order.Condition,
+			order.Condition.Metric, observed, quote.At.In(t.istLocation).Format("15:04:05"))
+		ready = append(ready, order)
+	}
+	return ready
+}
+
+// releaseClaim puts a claimed order that will not be executed now back in the queue
+func (t *Trigger) releaseClaim(ctx context.Context, orderID string) {
+	if _, err := t.cache.ReleaseClaim(ctx, orderID, t.instanceID); err != nil {
+		// The sweeper re-queues it once the lease ends
+		t.logger.Warn("Failed to release claim on order %s: %v", orderID, err)
+	}
+}
+
+// KillSwitch returns the trigger's kill switch
+func (t *Trigger) KillSwitch() *killswitch.KillSwitch {
+	return t.killSwitch
+}
+
+// Portfolio returns the broker's holdings and positions as cached by the broker layer
+func (t *Trigger) Portfolio(ctx context.Context, refresh bool) (models.Portfolio, error) {
+	return t.brokerManager.Portfolio(ctx, refresh)
+}
+
+// Funds returns the broker's available margin as cached by the broker layer
+func (t *Trigger) Funds(ctx context.Context, refresh bool) (models.Funds, error) {
+	return t.brokerManager.Funds(ctx, refresh)
+}
+
 // worker processes orders from the channel
 func (t *Trigger) worker(ctx context.Context, workerID int, orderChan <-chan models.Order, wg *sync.WaitGroup) {
 	defer wg.Done()
@@ -127,15 +421,23 @@ func (t *Trigger) worker(ctx context.Context, workerID int, orderChan <-chan mod
 }
 
 // executeOrder executes a single order with profiling
+// The order's span continues the trace started by the reader and links back to the execution cycle.
 func (t *Trigger) executeOrder(ctx context.Context, workerID int, order models.Order) {
+	spanOpts := []trace.SpanStartOption{trace.WithAttributes(tracing.OrderAttributes(order)...)}
+	if len(order.TraceContext) > 0 {
+		spanOpts = append(spanOpts, trace.WithLinks(trace.LinkFromContext(ctx)))
+	}
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, order.TraceContext), "trigger.execute_order", spanOpts...)
+	defer span.End()
+
 	metrics := models.ProfilingMetrics{
 		OrderID:       order.ID,
 		ScheduledTime: order.ScheduledTime,
-		StartedAt:     time.Now(),
+		StartedAt:     t.clock.Now(),
 	}
 
 	// Calculate scheduler delay
-	metrics.SchedulerDelay = time.Since(order.ScheduledTime)
+	metrics.SchedulerDelay = metrics.StartedAt.Sub(order.ScheduledTime)
 	if metrics.SchedulerDelay < 0 {
 		metrics.SchedulerDelay = 0
 	}
@@ -143,30 +445,24 @@ func (t *Trigger) executeOrder(ctx context.Context, workerID int, order models.O
 	t.logger.Info("👷 Worker %d processing order %s (⏱️  scheduler delay: %v)", 
 		workerID, order.ID, metrics.SchedulerDelay)
 
-	// Try to acquire lock to prevent duplicate execution
-	lockTTL := 30 * time.Second
-	acquired, err := t.cache.TryLock(order.ID, lockTTL)
+	// Confirm the claim is still ours before the order reaches the broker
+	submitted, err := t.cache.MarkSubmitted(ctx, order.ID, t.instanceID, t.claimLease, t.fence())
 	if err != nil {
-		t.logger.Error("❌ Failed to acquire lock for order %s", order.ID)
+		t.logger.Error("❌ Failed to confirm claim on order %s", order.ID)
 		t.logger.Error("   Order ID: %s", order.ID)
-		t.logger.Error("   Lock TTL: %v", lockTTL)
+		t.logger.Error("   Instance: %s", t.instanceID)
 		t.logger.Error("   Error: %v", err)
-		t.logger.Error("   Redis connection may be unstable")
-		t.removeOrder(order.ID, "lock acquisition failed")
+		t.logger.Error("   Redis connection may be unstable; the order is re-queued when its lease ends")
+		tracing.RecordError(span, err)
 		return
 	}
 
-	if !acquired {
-		t.logger.Warn("Order %s is already being processed by another worker", order.ID)
+	if !submitted {
+		t.logger.Warn("Claim on order %s expired or was taken over by another instance, skipping", order.ID)
 		return
 	}
 
-	defer func() {
-		// Release lock
-		if err := t.cache.ReleaseLock(order.ID); err != nil {
-			t.logger.Warn("Failed to release lock for order %s: %v", order.ID, err)
-		}
-	}()
+	order.Attempts++
 
 	// Profile cache lookup (already done, but track time)
 	cacheStart := time.Now()
@@ -176,12 +472,37 @@ func (t *Trigger) executeOrder(ctx context.Context, workerID int, order models.O
 	brokerStart := time.Now()
 	result, err := t.brokerManager.ExecuteOrder(ctx, order)
 	metrics.BrokerConnectTime = time.Since(brokerStart)
+
+	// The broker has seen the order, so finish the bookkeeping even if shutdown began meanwhile
+	ctx = context.WithoutCancel(ctx)
 	metrics.OrderExecutionTime = metrics.BrokerConnectTime // Combined for simplicity
 
+	if result.CappedQuantity > 0 {
+		t.logger.Warn("✂️  Order %s sell quantity capped from %d to %d by holdings", order.ID, order.Quantity, result.CappedQuantity)
+		order.Quantity = result.CappedQuantity
+	}
+	if len(result.PriceAdjustments) > 0 {
+		order.PriceAdjustments = append(order.PriceAdjustments, result.PriceAdjustments...)
+		order.Price = result.PriceAdjustments[len(result.PriceAdjustments)-1].To
+	}
+
+	// Orders held back by a local check never reached the broker, so they neither count
+	// toward the kill switch's failure streak nor raise a broker alert
+	if errors.Is(err, broker.ErrOrderRejected) {
+		tracing.RecordError(span, err)
+		t.rejectOrder(ctx, order, err.Error())
+		return
+	}
+
 	if err != nil {
-		metrics.CompletedAt = time.Now()
-		metrics.TotalTime = time.Since(metrics.StartedAt)
+		metrics.CompletedAt = t.clock.Now()
+		metrics.TotalTime = metrics.CompletedAt.Sub(metrics.StartedAt)
 		t.logProfilingMetrics(metrics, false, err.Error())
+		t.recordMetrics(metrics, false)
+		t.killSwitch.RecordExecution(ctx, false)
+		t.recordJournal(order, result, metrics)
+		tracing.RecordError(span, err)
+		t.notifyBrokerError(err)
 		t.logger.Error("❌ Order %s execution failed", order.ID)
 		t.logger.Error("   Order Details:")
 		t.logger.Error("     - ID: %s", order.ID)
@@ -192,20 +513,28 @@ func (t *Trigger) executeOrder(ctx context.Context, workerID int, order models.O
 		t.logger.Error("     - Scheduled Time: %s", order.ScheduledTime.Format("2006-01-02 15:04:05 IST"))
 		t.logger.Error("   Error: %v", err)
 		t.logger.Error("   Full error details logged by broker module above")
-		t.removeOrder(order.ID, err.Error())
+		t.deadLetter(ctx, order, models.DeadLetterFailed, err.Error(), cache.ExpiryFor(order, t.config.Trigger.Expiry))
 		return
 	}
 
 	// Profile cleanup
 	cleanupStart := time.Now()
-	t.removeOrder(order.ID, "")
+	if result.Success {
+		t.removeOrder(ctx, order.ID, "")
+	} else {
+		t.deadLetter(ctx, order, models.DeadLetterRejected, result.ErrorMessage, cache.ExpiryFor(order, t.config.Trigger.Expiry))
+	}
 	metrics.CleanupTime = time.Since(cleanupStart)
 
-	metrics.CompletedAt = time.Now()
-	metrics.TotalTime = time.Since(metrics.StartedAt)
+	metrics.CompletedAt = t.clock.Now()
+	metrics.TotalTime = metrics.CompletedAt.Sub(metrics.StartedAt)
 
 	t.logProfilingMetrics(metrics, result.Success, result.ErrorMessage)
+	t.recordMetrics(metrics, result.Success)
+	t.killSwitch.RecordExecution(ctx, result.Success)
+	t.recordJournal(order, result, metrics)
 	if result.Success {
+		t.notifyOrderSuccess(order, result)
 		t.logger.Success("✅ Order %s executed successfully", order.ID)
 		t.logger.TableSimple("Execution Details", map[string]string{
 			"Order ID":        order.ID,
@@ -221,9 +550,175 @@ func (t *Trigger) executeOrder(ctx context.Context, workerID int, order models.O
 	}
 }
 
+// resolveLateOrders applies the late-order policy to orders found past their expiry window.
+// It returns the orders that should still be executed; the rest are recorded as expired.
+func (t *Trigger) resolveLateOrders(ctx context.Context, late []models.OrderCacheEntry, now time.Time) []models.Order {
+	expiry := t.config.Trigger.Expiry
+	var orders []models.Order
+
+	for _, entry := range late {
+		order := entry.Order
+		if order.Symbol == "" {
+			t.recordExpired(ctx, entry, now, "order data expired from cache before execution")
+			continue
+		}
+
+		// An order whose condition never held is not sent late or as an AMO
+		if order.Condition != nil {
+			t.recordExpired(ctx, entry, now, fmt.Sprintf("condition %s not met within expiry window", order.Condition))
+			continue
+		}
+
+		lateBy := now.Sub(entry.ExpiryTime).Round(time.Millisecond)
+		var reason string
+		switch expiry.LatePolicy {
+		case config.LatePolicyExecute:
+			if lateBy <= expiry.LateGrace {
+				t.logger.Warn("⏰ Executing late order %s (%v past its expiry window, grace %v)", order.ID, lateBy, expiry.LateGrace)
+				orders = append(orders, order)
+				continue
+			}
+			reason = fmt.Sprintf("%v past expiry window, beyond late grace of %v", lateBy, expiry.LateGrace)
+		case config.LatePolicyAMO:
+			if t.marketHours.ShouldUseAMO(now) {
+				t.logger.Warn("⏰ Converting late order %s to AMO (%v past its expiry window)", order.ID, lateBy)
+				order.IsAMO = true
+				orders = append(orders, order)
+				continue
+			}
+			reason = fmt.Sprintf("%v past expiry window and market is open, cannot convert to AMO", lateBy)
+		default:
+			reason = fmt.Sprintf("not executed within expiry window (%v late)", lateBy)
+		}
+		t.recordExpired(ctx, entry, now, reason)
+	}
+	return orders
+}
+
+// recordExpired dead-letters an order that will not be executed and records it as expired
+func (t *Trigger) recordExpired(ctx context.Context, entry models.OrderCacheEntry, now time.Time, reason string) {
+	order := entry.Order
+	if order.Symbol == "" {
+		// Nothing left to requeue
+		t.removeOrder(ctx, order.ID, reason)
+	} else {
+		t.deadLetter(ctx, order, models.DeadLetterExpired, reason, entry.ExpiryTime)
+	}
+	metrics.OrdersExpired.Inc()
+	t.logger.Warn("⌛ Order %s expired without execution: %s", order.ID, reason)
+
+	if err := t.journal.Record(models.JournalEntry{
+		Timestamp: now,
+		Event:     models.JournalEventExpired,
+		Broker:    t.config.Broker.Type,
+		Order:     &order,
+		Result: &models.ExecutionResult{
+			OrderID:      order.ID,
+			Success:      false,
+			ExecutedAt:   now,
+			ErrorMessage: reason,
+		},
+	}); err != nil {
+		t.logger.Warn("Failed to journal expiry of order %s: %v", order.ID, err)
+	}
+}
+
+// SweepExpiredLeases re-queues claimed orders whose lease ran out before they reached the
+// broker, and records orders submitted by an instance that then went away as abandoned
+func (t *Trigger) SweepExpiredLeases(ctx context.Context) error {
+	if !t.isLeader() {
+		return nil
+	}
+
+	now := t.clock.Now()
+	requeued, abandoned, err := t.cache.SweepExpiredLeases(ctx, now)
+	if err != nil {
+		t.logger.Error("❌ Failed to sweep expired order claims: %v", err)
+		return err
+	}
+
+	for _, orderID := range requeued {
+		t.logger.Warn("♻️  Re-queued order %s after its claim lease expired", orderID)
+	}
+	for _, orderID := range abandoned {
+		t.recordAbandoned(ctx, orderID, now)
+	}
+	return nil
+}
+
+// recordAbandoned dead-letters an order whose broker outcome is unknown and records it for reconciliation
+func (t *Trigger) recordAbandoned(ctx context.Context, orderID string, now time.Time) {
+	reason := "claim lease expired after submission to the broker; check the broker order book"
+	order := models.Order{ID: orderID}
+	if entry, err := t.cache.GetOrder(ctx, orderID); err == nil {
+		order = entry.Order
+		order.Attempts++ // The cached copy predates the submission
+		t.deadLetter(ctx, order, models.DeadLetterAbandoned, reason, entry.ExpiryTime)
+	} else {
+		t.removeOrder(ctx, orderID, reason)
+	}
+
+	metrics.OrdersAbandoned.Inc()
+	t.logger.Error("🚨 Order %s abandoned by its trigger instance after submission", orderID)
+	t.logger.Error("   Symbol: %s, Side: %s, Quantity: %d", order.Symbol, order.Side, order.Quantity)
+	t.logger.Error("   The order may or may not have been placed; reconcile it with the broker")
+
+	if err := t.journal.Record(models.JournalEntry{
+		Timestamp: now,
+		Event:     models.JournalEventAbandoned,
+		Broker:    t.config.Broker.Type,
+		Order:     &order,
+		Result: &models.ExecutionResult{
+			OrderID:      orderID,
+			Success:      false,
+			ExecutedAt:   now,
+			ErrorMessage: reason,
+		},
+	}); err != nil {
+		t.logger.Warn("Failed to journal abandoned order %s: %v", orderID, err)
+	}
+}
+
+// MigrateCache rewrites cached orders from older schema versions and reports any entries
+// that had to be quarantined
+func (t *Trigger) MigrateCache(ctx context.Context) (int, []string, error) {
+	migrated, quarantined, err := t.cache.MigrateOrders(ctx)
+	if err != nil {
+		t.logger.Error("❌ Failed to migrate cached orders: %v", err)
+	}
+	if migrated > 0 {
+		t.logger.Info("🧬 Upgraded %d cached orders to schema version %d", migrated, models.OrderCacheEntryVersion)
+	}
+	for _, orderID := range quarantined {
+		t.logger.Error("☣️  Cached order %s could not be decoded and was quarantined", orderID)
+	}
+	return migrated, quarantined, err
+}
+
+// deadLetter moves an order that did not execute to the dead-letter queue, where it can be
+// inspected and requeued
+func (t *Trigger) deadLetter(ctx context.Context, order models.Order, reason, errMsg string, expiryTime time.Time) {
+	t.notifyOrderFailure(order, reason, errMsg)
+	err := t.cache.DeadLetterOrder(ctx, models.DeadLetter{
+		Order:          order,
+		Reason:         reason,
+		Error:          errMsg,
+		Attempts:       order.Attempts,
+		ExpiryTime:     expiryTime,
+		DeadLetteredAt: t.clock.Now(),
+	})
+	if err != nil {
+		// Left claimed; the sweeper re-queues or reports it once the lease ends
+		t.logger.Error("Failed to dead-letter order %s: %v", order.ID, err)
+		return
+	}
+	metrics.OrdersDeadLettered.WithLabelValues(reason).Inc()
+	t.logger.Warn("📮 Order %s moved to the dead-letter queue (%s, attempt %d): %s", order.ID, reason, order.Attempts, errMsg)
+}
+
 // removeOrder removes an order from cache
-func (t *Trigger) removeOrder(orderID, reason string) {
-	if err := t.cache.RemoveOrder(orderID); err != nil {
+func (t *Trigger) removeOrder(ctx context.Context, orderID, reason string) {
+	if err := t.cache.RemoveOrder(ctx, orderID); err != nil {
 		t.logger.Error("Failed to remove order %s from cache: %v", orderID, err)
 	} else {
 		if reason != "" {
@@ -234,6 +729,36 @@ func (t *Trigger) removeOrder(orderID, reason string) {
 	}
 }
 
+// recordMetrics exports an order's profiling metrics and outcome to Prometheus
+func (t *Trigger) recordMetrics(profile models.ProfilingMetrics, success bool) {
+	metrics.SchedulerDelay.Observe(profile.SchedulerDelay.Seconds())
+	metrics.OrderTotalTime.Observe(profile.TotalTime.Seconds())
+	if success {
+		metrics.OrdersExecuted.Inc()
+	} else {
+		metrics.OrdersFailed.Inc()
+	}
+}
+
+// recordJournal appends the execution outcome to the execution journal
+func (t *Trigger) recordJournal(order models.Order, result models.ExecutionResult, profile models.ProfilingMetrics) {
+	if result.OrderID == "" {
+		// Failures before the broker was reached (e.g. rate limiting) return an empty result
+		result.OrderID = order.ID
+		result.ExecutedAt = profile.CompletedAt
+	}
+	if err := t.journal.Record(models.JournalEntry{
+		Timestamp: t.clock.Now(),
+		Event:     models.JournalEventExecution,
+		Broker:    t.config.Broker.Type,
+		Order:     &order,
+		Result:    &result,
+		Metrics:   &profile,
+	}); err != nil {
+		t.logger.Warn("Failed to journal execution of order %s: %v", order.ID, err)
+	}
+}
+
 // logProfilingMetrics logs profiling metrics in tabular format
 func (t *Trigger) logProfilingMetrics(metrics models.ProfilingMetrics, success bool, errorMsg string) {
 	// Format times for display
@@ -280,19 +805,53 @@ func (t *Trigger) logProfilingMetrics(metrics models.ProfilingMetrics, success b
 
 // MaintainSystemReadiness ensures system is ready before execution
 func (t *Trigger) MaintainSystemReadiness(ctx context.Context) error {
+	report := ReadinessReport{CheckedAt: t.clock.Now()}
+	defer t.setLastReadiness(&report)
+
 	// Check cache health
-	if err := t.cache.HealthCheck(); err != nil {
+	if err := t.cache.HealthCheck(ctx); err != nil {
+		report.CacheError = err.Error()
+		metrics.SetHealth("cache", false)
+		t.notifyHealthDegraded("cache", err)
 		t.logger.Error("❌ Cache health check failed")
 		t.logger.Error("   Error: %v", err)
 		t.logger.Error("   Redis may be down or unreachable")
 		return fmt.Errorf("cache health check failed: %w", err)
 	}
 	t.logger.Debug("✅ Cache health check passed")
+	metrics.SetHealth("cache", true)
+	report.CacheHealthy = true
+
+	if pending, err := t.cache.PendingCount(ctx); err == nil {
+		metrics.PendingOrders.Set(float64(pending))
+		report.PendingOrders = pending
+	}
+	if inFlight, err := t.cache.InFlightCount(ctx); err == nil {
+		metrics.InFlightOrders.Set(float64(inFlight))
+		report.InFlightOrders = inFlight
+	}
+	if deadLetters, err := t.cache.DeadLetterCount(ctx); err == nil {
+		metrics.DeadLetterOrders.Set(float64(deadLetters))
+		report.DeadLetterOrders = deadLetters
+	}
+	if quarantined, err := t.cache.QuarantineCount(ctx); err == nil {
+		metrics.QuarantinedOrders.Set(float64(quarantined))
+		report.QuarantinedOrders = quarantined
+		if quarantined > 0 {
+			t.logger.Warn("☣️  %d cached orders are quarantined and will not execute; inspect them with admin-api.sh quarantine", quarantined)
+		}
+	}
 
 	// Check broker health
 	brokerHealthOk := true
 	if err := t.brokerManager.HealthCheck(ctx); err != nil {
 		brokerHealthOk = false
+		report.BrokerError = err.Error()
+		if errors.Is(err, broker.ErrTokenRejected) {
+			t.notifyBrokerError(err)
+		} else {
+			t.notifyHealthDegraded("broker", err)
+		}
 		t.logger.Error("❌ Broker health check failed")
 		t.logger.Error("   Error: %v", err)
 		t.logger.Error("   Broker may be unreachable or credentials invalid")
@@ -301,6 +860,20 @@ func (t *Trigger) MaintainSystemReadiness(ctx context.Context) error {
 		// return fmt.Errorf("broker health check failed: %w", err)
 	}
 
+	metrics.SetHealth("broker", brokerHealthOk)
+	report.BrokerHealthy = brokerHealthOk
+
+	// Fetch the day's circuit bands for queued limit orders ahead of their execution
+	if brokerHealthOk {
+		if pending, err := t.cache.ListPendingOrders(ctx); err == nil {
+			orders := make([]models.Order, len(pending))
+			for i, entry := range pending {
+				orders[i] = entry.Order
+			}
+			t.brokerManager.PrefetchBands(ctx, orders)
+		}
+	}
+
 	// Only log success when both checks pass
 	if brokerHealthOk {
 		t.logger.Info("✅ System readiness check passed (cache and broker healthy)")
@@ -311,6 +884,43 @@ func (t *Trigger) MaintainSystemReadiness(ctx context.Context) error {
 	return nil
 }
 
+// setLastReadiness stores the result of a readiness check for the admin API
+func (t *Trigger) setLastReadiness(report *ReadinessReport) {
+	t.readinessMu.Lock()
+	defer t.readinessMu.Unlock()
+	t.lastReadiness = *report
+}
+
+// LastReadiness returns the result of the most recent readiness check
+func (t *Trigger) LastReadiness() ReadinessReport {
+	t.readinessMu.RLock()
+	report := t.lastReadiness
+	t.readinessMu.RUnlock()
+
+	// Leadership changes between checks, so report it live
+	report.Leadership = t.Leadership()
+	return report
+}
+
+// Pause stops order execution; due orders stay in the queue until resumed or expired
+func (t *Trigger) Pause() {
+	if !t.paused.Swap(true) {
+		t.logger.Warn("⏸️  Order execution paused")
+	}
+}
+
+// Resume restarts order execution after Pause
+func (t *Trigger) Resume() {
+	if t.paused.Swap(false) {
+		t.logger.Info("▶️  Order execution resumed")
+	}
+}
+
+// IsPaused reports whether order execution is paused
+func (t *Trigger) IsPaused() bool {
+	return t.paused.Load()
+}
+
 // RunContinuous runs the trigger in a continuous loop, checking for orders at regular intervals
 func (t *Trigger) RunContinuous(ctx context.Context) error {
 	checkInterval := t.config.Trigger.CheckInterval
@@ -319,13 +929,70 @@ func (t *Trigger) RunContinuous(ctx context.Context) error {
 	t.logger.Info("🔄 Starting continuous trigger loop")
 	t.logger.Info("   Check interval: %v", checkInterval)
 	t.logger.Info("   Health check interval: %v", healthCheckInterval)
+	t.logger.Info("   Instance: %s (claim lease %v)", t.instanceID, t.claimLease)
+	
+	// The paper broker only sees the market move when it follows a live quote source
+	paper, _ := t.brokerManager.Broker().(*broker.PaperBroker)
+	if paper != nil && !paper.HasQuoteSource() {
+		if path := t.config.Broker.Paper.PricesPath; path != "" {
+			t.logger.Warn("⚠️  Paper broker fills only against the static prices in %s (PAPER_QUOTE_SOURCE=off); LIMIT orders that do not cross them rest unfilled", path)
+		} else {
+			t.logger.Warn("⚠️  Paper broker has no prices (prices_path unset, PAPER_QUOTE_SOURCE=off); every order rests unfilled")
+		}
+	}
 	
+	if t.config.Metrics.Enabled {
+		go metrics.Serve(ctx, t.config.Metrics.TriggerAddr, t.logger)
+	}
+	
+	shutdownTracing, err := tracing.Init(ctx, t.config, "trading-system-trigger", t.logger)
+	if err != nil {
+		t.logger.Warn("⚠️  Tracing disabled: %v", err)
+	} else {
+		defer shutdownTracing(context.Background())
+	}
+	
+	if t.notifier.Enabled() {
+		go t.notifier.Run(ctx)
+	}
+	
+	if t.config.Admin.Enabled {
+		go NewAdminServer(t.config, t.cache, t, t.logger).Serve(ctx)
+	}
+	
+	if t.elector != nil {
+		t.logger.Info("   Leader election: enabled (lease %v)", t.config.Leader.Lease)
+		t.elector.Campaign(ctx)
+		go t.elector.Run(ctx)
+	}
+	
+	// Upgrade entries written by an older reader before they are claimed
+	t.MigrateCache(ctx)
+
 	checkTicker := time.NewTicker(checkInterval)
 	defer checkTicker.Stop()
 	
 	healthCheckTicker := time.NewTicker(healthCheckInterval)
 	defer healthCheckTicker.Stop()
 	
+	sweepTicker := time.NewTicker(t.config.Trigger.SweepInterval)
+	defer sweepTicker.Stop()
+	
+	gttTicker := time.NewTicker(t.config.GTT.SyncInterval)
+	defer gttTicker.Stop()
+	
+	// Left nil (never firing) unless the paper broker follows a quote source
+	var paperQuotes <-chan time.Time
+	if paper != nil && paper.HasQuoteSource() {
+		interval := time.Duration(t.config.Broker.Paper.QuoteIntervalMs) * time.Millisecond
+		if interval <= 0 {
+			interval = time.Second
+		}
+		paperTicker := time.NewTicker(interval)
+		defer paperTicker.Stop()
+		paperQuotes = paperTicker.C
+	}
+	
 	lastHealthCheck := time.Now()
 	
 	// Run initial health check
@@ -341,12 +1008,34 @@ func (t *Trigger) RunContinuous(ctx context.Context) error {
 			
 		case <-checkTicker.C:
 			// Check for due orders
-			if err := t.ExecuteDueOrders(ctx); err != nil {
+			if err := t.ExecuteDueOrders(ctx); err != nil && ctx.Err() == nil {
 				t.logger.Error("❌ Error executing due orders: %v", err)
 				// Continue running even if there's an error
 			}
 			
+		case <-sweepTicker.C:
+			// Recover orders claimed by instances that stopped before finishing them
+			t.SweepExpiredLeases(ctx)
+			
+		case <-paperQuotes:
+			// Move the paper broker's prices in the background so a slow quote source does not delay orders
+			if t.paperPollInProgress.CompareAndSwap(false, true) {
+				go func() {
+					defer t.paperPollInProgress.Store(false)
+					t.PollPaperQuotes(ctx, paper)
+				}()
+			}
+			
+		case <-gttTicker.C:
+			// Place, modify and delete GTTs to match the order source and follow their status
+			if err := t.SyncGTTs(ctx); err != nil && ctx.Err() == nil {
+				t.logger.Error("❌ Error syncing GTTs: %v", err)
+			}
+			
 		case <-healthCheckTicker.C:
+			// Send the daily summary once its time of day has passed
+			t.sendDailySummary(ctx)
+			
 			// Run periodic health checks (ensure only one runs at a time)
 			if time.Since(lastHealthCheck) >= healthCheckInterval {
 				// Check if health check is already running
@@ -380,6 +1069,27 @@ func (t *Trigger) RunContinuous(ctx context.Context) error {
 	}
 }
 
+// PollPaperQuotes feeds the paper broker live prices for queued orders' instruments and its
+// resting orders, filling those the market has crossed
+func (t *Trigger) PollPaperQuotes(ctx context.Context, paper *broker.PaperBroker) {
+	var instruments []string
+	pending, err := t.cache.ListPendingOrders(ctx)
+	if err != nil {
+		t.logger.Debug("Failed to list pending orders for paper quotes: %v", err)
+	}
+	for _, entry := range pending {
+		instruments = append(instruments, entry.Order.Exchange+":"+entry.Order.Symbol)
+	}
+	
+	fills, err := paper.PollQuotes(ctx, instruments)
+	if err != nil {
+		return
+	}
+	if len(fills) > 0 {
+		t.logger.Info("📝 %d resting paper orders filled on new quotes", len(fills))
+	}
+}
+
 // truncateString truncates a string to max length
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -0,0 +1,1015 @@
+package trigger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/mach_five/trading-system/internal/broker"
+	"github.com/mach_five/trading-system/internal/broker/kitetest"
+	"github.com/mach_five/trading-system/internal/cache"
+	"github.com/mach_five/trading-system/internal/clock"
+	"github.com/mach_five/trading-system/internal/config"
+	"github.com/mach_five/trading-system/internal/journal"
+	"github.com/mach_five/trading-system/internal/logger"
+	"github.com/mach_five/trading-system/internal/models"
+	"github.com/mach_five/trading-system/internal/notify"
+	"github.com/mach_five/trading-system/internal/notify/notifytest"
+	"github.com/mach_five/trading-system/internal/reader"
+)
+
+type testHarness struct {
+	trigger *Trigger
+	cache   *cache.RedisCache
+	brokers *broker.BrokerManager
+	paper   *broker.PaperBroker
+	clock   *clock.Simulated
+	config  *config.Config
+	log     *logger.Logger
+}
+
+// newTestHarness wires a trigger to miniredis and a seeded paper broker on a simulated clock.
+// configure may swap the broker, e.g. for the Kite stand-in.
+func newTestHarness(t *testing.T, now time.Time, configure ...func(*config.Config)) *testHarness {
+	t.Helper()
+	dir := t.TempDir()
+
+	log, err := logger.NewLogger("error", filepath.Join(dir, "trigger.log"))
+	if err != nil {
+		t.Fatalf("failed to create logger: %v", err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	cfg := &config.Config{}
+	cfg.Broker.Type = "paper"
+	cfg.Broker.Paper.Seed = 1
+	cfg.Broker.Paper.InitialCash = 1000000
+	cfg.Broker.RateLimit = config.RateLimitConfig{RequestsPerSecond: 1000, BurstSize: 1000}
+	cfg.Trigger.WorkerPoolSize = 1
+	cfg.Trigger.InstanceID = "replica-1"
+	cfg.Journal.Path = filepath.Join(dir, "journal.jsonl")
+	for _, apply := range configure {
+		apply(cfg)
+	}
+
+	server := miniredis.RunT(t)
+	redisCache, err := cache.NewRedisCache(server.Addr(), "", 0)
+	if err != nil {
+		t.Fatalf("failed to connect to miniredis: %v", err)
+	}
+	t.Cleanup(func() { redisCache.Close() })
+
+	brokerMgr, err := broker.NewBrokerManager(cfg, log)
+	if err != nil {
+		t.Fatalf("failed to create broker: %v", err)
+	}
+
+	sim := clock.NewSimulated(now)
+	redisCache.SetClock(sim)
+	brokerMgr.SetClock(sim)
+	trig := NewTrigger(cfg, redisCache, brokerMgr, log)
+	trig.SetClock(sim)
+
+	paper, _ := brokerMgr.Broker().(*broker.PaperBroker)
+	return &testHarness{
+		trigger: trig,
+		cache:   redisCache,
+		brokers: brokerMgr,
+		paper:   paper,
+		clock:   sim,
+		config:  cfg,
+		log:     log,
+	}
+}
+
+func (h *testHarness) store(t *testing.T, id string, scheduled time.Time) {
+	t.Helper()
+	order := models.Order{
+		ID:            id,
+		Symbol:        "INFY",
+		Exchange:      "NSE",
+		Price:         100,
+		Quantity:      10,
+		OrderType:     "LIMIT",
+		Side:          "Buy",
+		ScheduledTime: scheduled,
+	}
+	if err := h.cache.StoreOrder(context.Background(), order, scheduled.Add(cache.DefaultExpiryWindow)); err != nil {
+		t.Fatalf("StoreOrder: %v", err)
+	}
+}
+
+func mustIST(t *testing.T) *time.Location {
+	t.Helper()
+	ist, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatalf("failed to load IST: %v", err)
+	}
+	return ist
+}
+
+func TestExecuteDueOrdersWaitsForScheduledTime(t *testing.T) {
+	ctx := context.Background()
+	scheduled := time.Date(2024, 1, 15, 9, 30, 0, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-time.Minute))
+	h.store(t, "A", scheduled)
+
+	h.clock.Set(scheduled.Add(-time.Millisecond))
+	if err := h.trigger.ExecuteDueOrders(context.Background()); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if len(h.paper.OpenOrders()) != 0 {
+		t.Fatal("order executed before its scheduled time")
+	}
+
+	h.clock.Set(scheduled)
+	if err := h.trigger.ExecuteDueOrders(context.Background()); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if open := h.paper.OpenOrders(); len(open) != 1 || open[0].Order.ID != "A" {
+		t.Fatalf("open paper orders = %v, want order A", open)
+	}
+	if _, err := h.cache.GetOrder(ctx, "A"); !errors.Is(err, cache.ErrOrderNotFound) {
+		t.Errorf("executed order still cached (err = %v)", err)
+	}
+
+	entries, err := journal.ReadEntries(h.config.Journal.Path, scheduled, scheduled.Add(time.Second))
+	if err != nil {
+		t.Fatalf("ReadEntries: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Event != models.JournalEventExecution {
+		t.Fatalf("journal entries at simulated time = %v, want one execution", entries)
+	}
+	if entries[0].Metrics.SchedulerDelay != 0 {
+		t.Errorf("SchedulerDelay = %v, want 0 on a stopped clock", entries[0].Metrics.SchedulerDelay)
+	}
+}
+
+func TestExecuteDueOrdersSkipsExpiredOrders(t *testing.T) {
+	ctx := context.Background()
+	scheduled := time.Date(2024, 1, 15, 15, 29, 0, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-time.Minute))
+	h.store(t, "LATE", scheduled)
+
+	// The trigger was not polled during the order's expiry window
+	h.clock.Set(scheduled.Add(cache.DefaultExpiryWindow + time.Second))
+	if err := h.trigger.ExecuteDueOrders(context.Background()); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if len(h.paper.OpenOrders()) != 0 || len(h.paper.Fills()) != 0 {
+		t.Fatal("expired order reached the broker")
+	}
+	if count, _ := h.cache.PendingCount(ctx); count != 0 {
+		t.Errorf("PendingCount = %d, want expired order removed", count)
+	}
+
+	entries, err := journal.ReadEntries(h.config.Journal.Path, scheduled, h.clock.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("ReadEntries: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Event != models.JournalEventExpired || entries[0].Result.ErrorMessage == "" {
+		t.Fatalf("journal entries = %v, want one expired entry with a reason", entries)
+	}
+	if letter, err := h.cache.GetDeadLetter(ctx, "LATE"); err != nil || letter.Reason != models.DeadLetterExpired || letter.Attempts != 0 {
+		t.Errorf("dead letter = %+v, %v; want an expired order that never reached the broker", letter, err)
+	}
+}
+
+func TestExecuteDueOrdersLatePolicyExecuteWithinGrace(t *testing.T) {
+	ctx := context.Background()
+	scheduled := time.Date(2024, 1, 15, 10, 0, 0, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-5*time.Minute), func(cfg *config.Config) {
+		cfg.Trigger.Expiry.LatePolicy = config.LatePolicyExecute
+		cfg.Trigger.Expiry.LateGrace = time.Minute
+	})
+	h.store(t, "GRACE", scheduled)
+	h.store(t, "TOO-LATE", scheduled.Add(-2*time.Minute))
+
+	h.clock.Set(scheduled.Add(cache.DefaultExpiryWindow + 30*time.Second))
+	if err := h.trigger.ExecuteDueOrders(context.Background()); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if open := h.paper.OpenOrders(); len(open) != 1 || open[0].Order.ID != "GRACE" {
+		t.Fatalf("open paper orders = %v, want only the order within grace", open)
+	}
+	if count, _ := h.cache.PendingCount(ctx); count != 0 {
+		t.Errorf("PendingCount = %d, want both late orders resolved", count)
+	}
+}
+
+func TestExecuteDueOrdersLatePolicyAMOAfterClose(t *testing.T) {
+	scheduled := time.Date(2024, 1, 15, 15, 29, 55, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-time.Minute), func(cfg *config.Config) {
+		cfg.Trigger.Expiry.LatePolicy = config.LatePolicyAMO
+	})
+	h.store(t, "CLOSE", scheduled)
+
+	h.clock.Set(time.Date(2024, 1, 15, 15, 31, 0, 0, mustIST(t)))
+	if err := h.trigger.ExecuteDueOrders(context.Background()); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	open := h.paper.OpenOrders()
+	if len(open) != 1 || !open[0].Order.IsAMO {
+		t.Fatalf("open paper orders = %v, want the late order placed as AMO", open)
+	}
+}
+
+func TestExecuteDueOrdersLatePolicyAMODuringMarketHours(t *testing.T) {
+	scheduled := time.Date(2024, 1, 15, 11, 0, 0, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-time.Minute), func(cfg *config.Config) {
+		cfg.Trigger.Expiry.LatePolicy = config.LatePolicyAMO
+	})
+	h.store(t, "MIDDAY", scheduled)
+
+	h.clock.Set(scheduled.Add(time.Minute))
+	if err := h.trigger.ExecuteDueOrders(context.Background()); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if len(h.paper.OpenOrders()) != 0 {
+		t.Fatal("late order executed while the market is open under the amo policy")
+	}
+}
+
+func TestExecuteDueOrdersAcrossWeekend(t *testing.T) {
+	ist := mustIST(t)
+	friday := time.Date(2024, 1, 12, 16, 0, 0, 0, ist)
+	monday := time.Date(2024, 1, 15, 9, 0, 0, 0, ist)
+	h := newTestHarness(t, friday)
+	h.store(t, "MONDAY", monday)
+
+	// Poll through the weekend an hour at a time; nothing is due until Monday's open
+	for h.clock.Now().Before(monday) {
+		if err := h.trigger.ExecuteDueOrders(context.Background()); err != nil {
+			t.Fatalf("ExecuteDueOrders: %v", err)
+		}
+		if len(h.paper.OpenOrders()) != 0 {
+			t.Fatalf("order executed early at %s", h.clock.Now().Format(time.RFC3339))
+		}
+		h.clock.Advance(time.Hour)
+	}
+
+	h.clock.Set(monday)
+	if err := h.trigger.ExecuteDueOrders(context.Background()); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if len(h.paper.OpenOrders()) != 1 {
+		t.Fatal("order not executed at Monday's open")
+	}
+}
+
+func TestExecuteDueOrdersAcrossReplicas(t *testing.T) {
+	scheduled := time.Date(2024, 1, 15, 9, 30, 0, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-time.Minute))
+	ids := []string{"A", "B", "C", "D", "E", "F", "G", "H"}
+	for _, id := range ids {
+		h.store(t, id, scheduled)
+	}
+
+	// A second replica sharing the same Redis and broker
+	replicaConfig := *h.config
+	replicaConfig.Trigger.InstanceID = "replica-2"
+	replica := NewTrigger(&replicaConfig, h.cache, h.brokers, h.log)
+	replica.SetClock(h.clock)
+
+	h.clock.Set(scheduled)
+	var wg sync.WaitGroup
+	for _, trig := range []*Trigger{h.trigger, replica} {
+		wg.Add(1)
+		go func(trig *Trigger) {
+			defer wg.Done()
+			if err := trig.ExecuteDueOrders(context.Background()); err != nil {
+				t.Errorf("ExecuteDueOrders: %v", err)
+			}
+		}(trig)
+	}
+	wg.Wait()
+
+	placed := make(map[string]int)
+	for _, open := range h.paper.OpenOrders() {
+		placed[open.Order.ID]++
+	}
+	for _, id := range ids {
+		if placed[id] != 1 {
+			t.Errorf("order %s placed %d times, want exactly once", id, placed[id])
+		}
+	}
+}
+
+func TestOnlyLeaderDispatchesOrders(t *testing.T) {
+	ctx := context.Background()
+	scheduled := time.Date(2024, 1, 15, 9, 30, 0, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-time.Minute), func(cfg *config.Config) {
+		cfg.Leader = config.LeaderConfig{Enabled: true, InstanceID: "vm-a", Lease: 10 * time.Second, RenewInterval: 3 * time.Second}
+	})
+	h.store(t, "A", scheduled)
+
+	standbyConfig := *h.config
+	standbyConfig.Leader.InstanceID = "vm-b"
+	standby := NewTrigger(&standbyConfig, h.cache, h.brokers, h.log)
+	standby.SetClock(h.clock)
+
+	h.clock.Set(scheduled)
+	h.trigger.Elector().Campaign(ctx)
+	standby.Elector().Campaign(ctx)
+
+	if err := standby.ExecuteDueOrders(context.Background()); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if len(h.paper.OpenOrders()) != 0 {
+		t.Fatal("standby dispatched an order")
+	}
+	if status := standby.LastReadiness().Leadership; status.Leader || status.Holder != "vm-a" {
+		t.Errorf("standby leadership = %+v, want standby behind vm-a", status)
+	}
+
+	if err := h.trigger.ExecuteDueOrders(context.Background()); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if len(h.paper.OpenOrders()) != 1 {
+		t.Fatal("leader did not dispatch the due order")
+	}
+	if status := h.trigger.LastReadiness().Leadership; !status.Leader || status.Token == 0 {
+		t.Errorf("leader leadership = %+v, want leader with a fencing token", status)
+	}
+}
+
+func TestReadinessReportUsesTriggerClock(t *testing.T) {
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, mustIST(t))
+	h := newTestHarness(t, now)
+	h.store(t, "ORDER", now.Add(time.Hour))
+
+	if err := h.trigger.MaintainSystemReadiness(context.Background()); err != nil {
+		t.Fatalf("MaintainSystemReadiness: %v", err)
+	}
+	report := h.trigger.LastReadiness()
+	if !report.CheckedAt.Equal(now) || !report.CacheHealthy || !report.BrokerHealthy || report.PendingOrders != 1 {
+		t.Errorf("readiness = %+v, want a healthy check at %s with 1 pending order", report, now)
+	}
+}
+
+func TestHaltedOrdersReturnToQueue(t *testing.T) {
+	ctx := context.Background()
+	scheduled := time.Date(2024, 1, 15, 9, 30, 0, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-time.Minute))
+	h.store(t, "A", scheduled)
+	if err := h.trigger.KillSwitch().Trip(ctx, "test", "tester"); err != nil {
+		t.Fatalf("Trip: %v", err)
+	}
+
+	h.clock.Set(scheduled)
+	if err := h.trigger.ExecuteDueOrders(context.Background()); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if pending, _ := h.cache.PendingCount(ctx); pending != 1 {
+		t.Errorf("PendingCount = %d, want the halted order back in the queue", pending)
+	}
+	if inFlight, _ := h.cache.InFlightCount(ctx); inFlight != 0 {
+		t.Errorf("InFlightCount = %d, want the halted order's claim released", inFlight)
+	}
+}
+
+func TestUnfundedBuysAreHeldBackInSheetOrder(t *testing.T) {
+	ctx := context.Background()
+	scheduled := time.Date(2024, 1, 15, 9, 30, 0, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-time.Minute), func(cfg *config.Config) {
+		cfg.Broker.Paper.InitialCash = 1500
+		cfg.Broker.Funds = config.FundsConfig{BuyPolicy: config.FundsPolicyPriority, RefreshInterval: time.Minute}
+	})
+	for id, row := range map[string]int{"A": 4, "B": 3} {
+		order := models.Order{ID: id, Symbol: "INFY", Exchange: "NSE", Price: 100, Quantity: 10,
+			OrderType: "LIMIT", Side: "Buy", ScheduledTime: scheduled, SourceRow: row}
+		if err := h.cache.StoreOrder(ctx, order, scheduled.Add(cache.DefaultExpiryWindow)); err != nil {
+			t.Fatalf("StoreOrder: %v", err)
+		}
+	}
+
+	h.clock.Set(scheduled)
+	if err := h.trigger.ExecuteDueOrders(ctx); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if open := h.paper.OpenOrders(); len(open) != 1 || open[0].Order.ID != "B" {
+		t.Fatalf("open paper orders = %v, want only B, the earlier sheet row", open)
+	}
+
+	letter, err := h.cache.GetDeadLetter(ctx, "A")
+	if err != nil || letter.Reason != models.DeadLetterRejected || letter.Attempts != 0 ||
+		!strings.Contains(letter.Error, "insufficient funds") {
+		t.Fatalf("dead letter = %+v, %v; want A rejected for insufficient funds before reaching the broker", letter, err)
+	}
+	entries, err := journal.ReadEntries(h.config.Journal.Path, scheduled, scheduled.Add(time.Second))
+	if err != nil {
+		t.Fatalf("ReadEntries: %v", err)
+	}
+	events := make(map[string]string)
+	for _, entry := range entries {
+		events[entry.Order.ID] = entry.Event
+	}
+	if events["A"] != models.JournalEventRejected || events["B"] != models.JournalEventExecution {
+		t.Errorf("journal events = %v, want A rejected and B executed", events)
+	}
+}
+
+func TestFundsPercentBuysAreSizedWhenDue(t *testing.T) {
+	ctx := context.Background()
+	scheduled := time.Date(2024, 1, 15, 9, 30, 0, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-time.Minute), func(cfg *config.Config) {
+		cfg.Broker.Paper.InitialCash = 10000
+	})
+	for id, percent := range map[string]float64{"QUARTER": 25, "TINY": 0.5} {
+		order := models.Order{ID: id, Symbol: "INFY", Exchange: "NSE", Price: 100, OrderType: "LIMIT",
+			Side: "Buy", ScheduledTime: scheduled, FundsPercent: percent}
+		if err := h.cache.StoreOrder(ctx, order, scheduled.Add(cache.DefaultExpiryWindow)); err != nil {
+			t.Fatalf("StoreOrder: %v", err)
+		}
+	}
+
+	h.clock.Set(scheduled)
+	if err := h.trigger.ExecuteDueOrders(ctx); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if open := h.paper.OpenOrders(); len(open) != 1 || open[0].Order.ID != "QUARTER" || open[0].Order.Quantity != 25 {
+		t.Fatalf("open paper orders = %v, want QUARTER for 25 shares, a quarter of 10000 at 100", open)
+	}
+	letter, err := h.cache.GetDeadLetter(ctx, "TINY")
+	if err != nil || letter.Reason != models.DeadLetterRejected || !strings.Contains(letter.Error, "less than one lot") {
+		t.Fatalf("dead letter = %+v, %v; want TINY rejected for sizing to less than one lot", letter, err)
+	}
+}
+
+func TestSweepExpiredLeasesRecordsAbandonedOrders(t *testing.T) {
+	ctx := context.Background()
+	scheduled := time.Date(2024, 1, 15, 9, 30, 0, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-time.Minute))
+	h.store(t, "REQUEUE", scheduled)
+	h.store(t, "ABANDON", scheduled)
+
+	// Another instance claims both orders, submits one and stops
+	if _, _, err := h.cache.ClaimDueOrders(ctx, scheduled, "crashed", time.Second, cache.Fence{}); err != nil {
+		t.Fatalf("ClaimDueOrders: %v", err)
+	}
+	if ok, _ := h.cache.MarkSubmitted(ctx, "ABANDON", "crashed", time.Second, cache.Fence{}); !ok {
+		t.Fatal("MarkSubmitted failed for the owner")
+	}
+
+	h.clock.Set(scheduled.Add(2 * time.Second))
+	if err := h.trigger.SweepExpiredLeases(ctx); err != nil {
+		t.Fatalf("SweepExpiredLeases: %v", err)
+	}
+	if err := h.trigger.ExecuteDueOrders(context.Background()); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+
+	if open := h.paper.OpenOrders(); len(open) != 1 || open[0].Order.ID != "REQUEUE" {
+		t.Fatalf("open paper orders = %v, want only the re-queued order", open)
+	}
+	if _, err := h.cache.GetOrder(ctx, "ABANDON"); !errors.Is(err, cache.ErrOrderNotFound) {
+		t.Errorf("abandoned order still cached (err = %v)", err)
+	}
+
+	entries, err := journal.ReadEntries(h.config.Journal.Path, scheduled, h.clock.Now().Add(time.Second))
+	if err != nil {
+		t.Fatalf("ReadEntries: %v", err)
+	}
+	abandoned := 0
+	for _, entry := range entries {
+		if entry.Event == models.JournalEventAbandoned && entry.Order.Symbol == "INFY" {
+			abandoned++
+		}
+	}
+	if abandoned != 1 {
+		t.Errorf("journal has %d abandoned entries with order details, want 1", abandoned)
+	}
+}
+
+func TestKillSwitchUsesTriggerClock(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, mustIST(t))
+	h := newTestHarness(t, now)
+
+	if err := h.trigger.KillSwitch().Trip(ctx, "test", "tester"); err != nil {
+		t.Fatalf("Trip: %v", err)
+	}
+	halts, err := h.trigger.KillSwitch().Load(ctx)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if halt := halts[models.HaltScopeGlobal]; !halt.Since.Equal(now) {
+		t.Errorf("halt Since = %v, want simulated now %v", halt.Since, now)
+	}
+}
+
+func TestRealisedLossTripsKillSwitch(t *testing.T) {
+	ctx := context.Background()
+	scheduled := time.Date(2024, 1, 15, 9, 30, 0, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-time.Minute), func(cfg *config.Config) {
+		cfg.KillSwitch.DailyLossLimit = 400
+	})
+	h.paper.UpdatePrice("NSE", "INFY", 100, scheduled.Add(-time.Minute))
+	h.store(t, "BUY", scheduled)
+	h.clock.Set(scheduled)
+	if err := h.trigger.ExecuteDueOrders(ctx); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+
+	// Selling the 10 shares 50 lower realises a loss of 500
+	h.paper.UpdatePrice("NSE", "INFY", 50, scheduled)
+	sellAt := scheduled.Add(time.Minute)
+	sell := models.Order{ID: "SELL", Symbol: "INFY", Exchange: "NSE", Price: 50, Quantity: 10,
+		OrderType: "LIMIT", Side: "Sell", ScheduledTime: sellAt}
+	if err := h.cache.StoreOrder(ctx, sell, sellAt.Add(cache.DefaultExpiryWindow)); err != nil {
+		t.Fatalf("StoreOrder: %v", err)
+	}
+	h.clock.Set(sellAt)
+	if err := h.trigger.ExecuteDueOrders(ctx); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if fills := h.paper.Fills(); len(fills) != 2 || fills[1].RealisedPnL != -500 {
+		t.Fatalf("fills = %+v, want the sell to realise -500", fills)
+	}
+
+	halts, err := h.trigger.KillSwitch().Load(ctx)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !halts.Tripped() {
+		t.Error("kill switch not tripped after a realised loss beyond the daily limit")
+	}
+}
+
+func TestLocallyRejectedOrdersDoNotTripKillSwitch(t *testing.T) {
+	ctx := context.Background()
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+
+	scheduled := time.Date(2024, 1, 15, 9, 30, 0, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-time.Minute), server.Configure, func(cfg *config.Config) {
+		cfg.Broker.Portfolio = config.PortfolioConfig{SellPolicy: config.SellPolicyReject, RefreshInterval: time.Minute}
+		cfg.KillSwitch.MaxConsecutiveFailures = 1
+	})
+	order := models.Order{ID: "S", Symbol: "INFY", Exchange: "NSE", Price: 100, Quantity: 10,
+		OrderType: "LIMIT", Side: "Sell", ScheduledTime: scheduled}
+	if err := h.cache.StoreOrder(ctx, order, scheduled.Add(cache.DefaultExpiryWindow)); err != nil {
+		t.Fatalf("StoreOrder: %v", err)
+	}
+
+	h.clock.Set(scheduled)
+	if err := h.trigger.ExecuteDueOrders(ctx); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if len(server.Orders()) != 0 {
+		t.Fatalf("placed orders = %d, want the unheld sell kept from the broker", len(server.Orders()))
+	}
+
+	letter, err := h.cache.GetDeadLetter(ctx, "S")
+	if err != nil || letter.Reason != models.DeadLetterRejected || !strings.Contains(letter.Error, "insufficient holdings") {
+		t.Fatalf("dead letter = %+v, %v; want S rejected for insufficient holdings", letter, err)
+	}
+	halts, err := h.trigger.KillSwitch().Load(ctx)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if halts.Tripped() {
+		t.Errorf("kill switch tripped by a local rejection: %+v", halts)
+	}
+}
+
+func TestCancelledOrdersStayCancelledAfterReaderRefresh(t *testing.T) {
+	ctx := context.Background()
+	scheduled := time.Date(2024, 1, 15, 9, 30, 0, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-time.Hour), func(cfg *config.Config) {
+		cfg.Admin.Token = "secret"
+	})
+	snapshot := filepath.Join(t.TempDir(), "to_buy.csv")
+	writeRow := func(price string) {
+		t.Helper()
+		row := price + ",CNC,INFY,500000,INFY,2024-01-15,09:30:00,1000,1,NSE,10\n"
+		if err := os.WriteFile(snapshot, []byte(row), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	sheets := reader.NewSnapshotReader(h.config, h.cache, h.log)
+	sheets.SetClock(h.clock)
+	refresh := func() string {
+		t.Helper()
+		orders, err := sheets.ReadSnapshot(ctx, snapshot, "")
+		if err != nil || len(orders) != 1 {
+			t.Fatalf("ReadSnapshot = %v, %v; want one order", orders, err)
+		}
+		return orders[0].ID
+	}
+
+	writeRow("100")
+	orderID := refresh()
+	req := httptest.NewRequest(http.MethodDelete, "/api/orders/"+orderID, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	NewAdminServer(h.config, h.cache, h.trigger, h.log).Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cancel returned %d: %s", rec.Code, rec.Body.String())
+	}
+
+	refresh()
+	if count, _ := h.cache.PendingCount(ctx); count != 0 {
+		t.Fatalf("PendingCount = %d after a refresh, want the cancelled order kept out of the queue", count)
+	}
+
+	// Editing the row makes it a new version of the order, which is queued again
+	writeRow("99")
+	refresh()
+	if count, _ := h.cache.PendingCount(ctx); count != 1 {
+		t.Errorf("PendingCount = %d after the row was edited, want the new version queued", count)
+	}
+}
+
+func TestExecuteDueOrdersThroughKiteStandIn(t *testing.T) {
+	ctx := context.Background()
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+
+	scheduled := time.Date(2024, 1, 15, 9, 30, 0, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-time.Minute), server.Configure)
+	h.store(t, "A", scheduled)
+	h.store(t, "B", scheduled)
+	server.FailNext(kitetest.PathRegularOrder, kitetest.Failure{Status: 400, ErrorType: "InputException", Message: "Invalid price"})
+
+	h.clock.Set(scheduled)
+	if err := h.trigger.ExecuteDueOrders(context.Background()); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+
+	if got := server.Requests(kitetest.PathRegularOrder); got != 2 {
+		t.Fatalf("regular order requests = %d, want 2", got)
+	}
+	if len(server.Orders()) != 1 {
+		t.Errorf("placed orders = %d, want 1 after one injected failure", len(server.Orders()))
+	}
+	if count, _ := h.cache.PendingCount(ctx); count != 0 {
+		t.Errorf("PendingCount = %d, want both orders removed after execution", count)
+	}
+
+	entries, err := journal.ReadEntries(h.config.Journal.Path, scheduled, scheduled.Add(time.Second))
+	if err != nil {
+		t.Fatalf("ReadEntries: %v", err)
+	}
+	succeeded := 0
+	for _, entry := range entries {
+		if entry.Result.Success {
+			succeeded++
+		}
+	}
+	if len(entries) != 2 || succeeded != 1 {
+		t.Errorf("journal has %d executions (%d successful), want 2 (1 successful)", len(entries), succeeded)
+	}
+}
+
+func TestConditionalOrdersFireWhenConditionHolds(t *testing.T) {
+	ctx := context.Background()
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+	server.SetQuote("NSE", "INFY", 1500)
+	server.SetSession("NSE", "INFY", 1550, 250000)
+
+	scheduled := time.Date(2024, 1, 15, 9, 30, 0, 0, mustIST(t))
+	h := newTestHarness(t, scheduled.Add(-time.Minute), server.Configure, func(cfg *config.Config) {
+		cfg.Trigger.Quotes = config.QuotesConfig{PollInterval: time.Second, MaxAge: 10 * time.Second}
+	})
+	for id, condition := range map[string]models.Condition{
+		"BREAKOUT": {Metric: models.ConditionLTP, Operator: ">=", Value: 1600},
+		"DIP":      {Metric: models.ConditionChangePct, Operator: "<=", Value: -2},
+		"NEVER":    {Metric: models.ConditionVolume, Operator: ">=", Value: 1e9},
+	} {
+		condition := condition
+		order := models.Order{ID: id, Symbol: "INFY", Exchange: "NSE", Price: 1500, Quantity: 1, OrderType: "LIMIT",
+			Side: "Buy", ScheduledTime: scheduled, Condition: &condition}
+		if err := h.cache.StoreOrder(ctx, order, scheduled.Add(time.Minute)); err != nil {
+			t.Fatalf("StoreOrder: %v", err)
+		}
+	}
+
+	// 1500 is 3.2% below the 1550 open: only DIP fires
+	h.clock.Set(scheduled)
+	if err := h.trigger.ExecuteDueOrders(ctx); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if orders := server.Orders(); len(orders) != 1 {
+		t.Fatalf("placed %d orders, want only DIP", len(orders))
+	}
+	if count, _ := h.cache.PendingCount(ctx); count != 2 {
+		t.Errorf("PendingCount = %d, want BREAKOUT and NEVER still armed", count)
+	}
+
+	// Within the poll interval the cached quote is reused
+	server.SetQuote("NSE", "INFY", 1605)
+	h.clock.Set(scheduled.Add(500 * time.Millisecond))
+	if err := h.trigger.ExecuteDueOrders(ctx); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if orders := server.Orders(); len(orders) != 1 {
+		t.Fatalf("placed %d orders before the next poll, want 1", len(orders))
+	}
+
+	h.clock.Set(scheduled.Add(2 * time.Second))
+	if err := h.trigger.ExecuteDueOrders(ctx); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if orders := server.Orders(); len(orders) != 2 {
+		t.Fatalf("placed %d orders after LTP crossed 1600, want 2", len(orders))
+	}
+	if got := server.Requests(kitetest.PathQuote); got != 2 {
+		t.Errorf("quote requests = %d, want one per poll interval", got)
+	}
+
+	// An order whose condition never holds expires instead of going out late
+	h.clock.Set(scheduled.Add(2 * time.Minute))
+	if err := h.trigger.ExecuteDueOrders(ctx); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	letter, err := h.cache.GetDeadLetter(ctx, "NEVER")
+	if err != nil || letter.Reason != models.DeadLetterExpired || !strings.Contains(letter.Error, "condition volume >= 1000000000 not met") {
+		t.Fatalf("dead letter = %+v, %v; want NEVER expired with its condition unmet", letter, err)
+	}
+}
+
+func TestRejectedTokenOrdersAreDeadLetteredAndRequeued(t *testing.T) {
+	ctx := context.Background()
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+
+	scheduled := time.Date(2024, 1, 15, 9, 30, 0, 0, mustIST(t))
+	alerts := notifytest.NewServer()
+	t.Cleanup(alerts.Close)
+	h := newTestHarness(t, scheduled.Add(-time.Minute), server.Configure, withWebhookAlerts(alerts), func(cfg *config.Config) {
+		cfg.Admin.Token = "secret"
+	})
+	startNotifier(t, h)
+	h.store(t, "A", scheduled)
+
+	// A stale token is rejected with 403 until it is refreshed
+	server.SetAccessToken("refreshed-token")
+	h.clock.Set(scheduled)
+	if err := h.trigger.ExecuteDueOrders(ctx); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+
+	letter, err := h.cache.GetDeadLetter(ctx, "A")
+	if err != nil {
+		t.Fatalf("GetDeadLetter: %v", err)
+	}
+	if letter.Reason != models.DeadLetterFailed || letter.Attempts != 1 || letter.Error == "" {
+		t.Fatalf("dead letter = %+v, want a failed first attempt with the broker error", letter)
+	}
+	if !letter.DeadLetteredAt.Equal(scheduled) {
+		t.Errorf("DeadLetteredAt = %v, want %v", letter.DeadLetteredAt, scheduled)
+	}
+	events := waitForAlerts(t, alerts, 2)
+	if types := alertTypes(events); !types[notify.EventTokenExpired] || !types[notify.EventOrderFailure] {
+		t.Errorf("alerts = %+v, want token_expired and order_failure", events)
+	}
+
+	// Requeue through the admin API once the token is accepted again
+	server.SetAccessToken(h.config.Broker.APISecret)
+	retryAt := scheduled.Add(5 * time.Minute)
+	body := strings.NewReader(`{"scheduled_time":"` + retryAt.Format(time.RFC3339) + `"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/deadletters/A/requeue", body)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	NewAdminServer(h.config, h.cache, h.trigger, h.log).Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("requeue returned %d: %s", rec.Code, rec.Body.String())
+	}
+	if count, _ := h.cache.DeadLetterCount(ctx); count != 0 {
+		t.Errorf("DeadLetterCount = %d after requeue, want 0", count)
+	}
+
+	h.clock.Set(retryAt)
+	if err := h.trigger.ExecuteDueOrders(ctx); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	if len(server.Orders()) != 1 {
+		t.Fatalf("placed orders = %d, want the requeued order placed", len(server.Orders()))
+	}
+
+	entries, err := journal.ReadEntries(h.config.Journal.Path, retryAt, retryAt.Add(time.Second))
+	if err != nil {
+		t.Fatalf("ReadEntries: %v", err)
+	}
+	if len(entries) != 1 || !entries[0].Result.Success || entries[0].Order.Attempts != 2 {
+		t.Errorf("journal = %+v, want one successful second attempt", entries)
+	}
+}
+
+// withWebhookAlerts routes every alert type to the webhook sink of a notification stand-in
+func withWebhookAlerts(server *notifytest.Server) func(*config.Config) {
+	return func(cfg *config.Config) {
+		cfg.Notify.WebhookURL = server.WebhookURL()
+		cfg.Notify.Rules = make(map[string][]string)
+		for _, eventType := range notify.EventTypes {
+			cfg.Notify.Rules[eventType] = []string{notify.AllSinks}
+		}
+		cfg.Notify.DedupWindow = 10 * time.Minute
+		cfg.Notify.Timeout = 5 * time.Second
+		cfg.Notify.DailySummaryAt = "15:45"
+	}
+}
+
+// startNotifier delivers the trigger's alerts until the test ends
+func startNotifier(t *testing.T, h *testHarness) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		h.trigger.Notifier().Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// waitForAlerts waits until the webhook has received n alerts and returns them
+func waitForAlerts(t *testing.T, server *notifytest.Server, n int) []notify.Event {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		requests := server.Requests(notifytest.PathWebhook)
+		if len(requests) >= n {
+			events := make([]notify.Event, len(requests))
+			for i, req := range requests {
+				if err := req.JSON(&events[i]); err != nil {
+					t.Fatalf("webhook body is not an event: %v", err)
+				}
+			}
+			return events
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("webhook received %d alerts, want %d", len(requests), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func alertTypes(events []notify.Event) map[string]bool {
+	types := make(map[string]bool)
+	for _, event := range events {
+		types[event.Type] = true
+	}
+	return types
+}
+
+func TestOrderAlertsAndDailySummary(t *testing.T) {
+	ctx := context.Background()
+	alerts := notifytest.NewServer()
+	t.Cleanup(alerts.Close)
+
+	ist := mustIST(t)
+	scheduled := time.Date(2024, 1, 15, 9, 30, 0, 0, ist)
+	h := newTestHarness(t, scheduled.Add(-time.Minute), withWebhookAlerts(alerts), func(cfg *config.Config) {
+		cfg.Broker.Paper.RejectSymbols = []string{"INFY"}
+	})
+	startNotifier(t, h)
+
+	accepted := models.Order{ID: "A", Symbol: "TCS", Exchange: "NSE", Price: 100, Quantity: 10, OrderType: "LIMIT", Side: "Buy", ScheduledTime: scheduled}
+	if err := h.cache.StoreOrder(ctx, accepted, scheduled.Add(cache.DefaultExpiryWindow)); err != nil {
+		t.Fatalf("StoreOrder: %v", err)
+	}
+	h.store(t, "B", scheduled) // The paper broker rejects INFY
+
+	h.clock.Set(scheduled)
+	if err := h.trigger.ExecuteDueOrders(ctx); err != nil {
+		t.Fatalf("ExecuteDueOrders: %v", err)
+	}
+	events := waitForAlerts(t, alerts, 2)
+	titles := map[string]string{}
+	for _, event := range events {
+		titles[event.Type] = event.Title
+	}
+	if titles[notify.EventOrderSuccess] != "Order A executed" || titles[notify.EventOrderFailure] != "Order B failed" {
+		t.Fatalf("alerts = %+v, want A executed and B failed", events)
+	}
+
+	// The summary waits for its time of day and is sent once
+	h.clock.Set(time.Date(2024, 1, 15, 15, 44, 0, 0, ist))
+	h.trigger.sendDailySummary(ctx)
+	h.clock.Set(time.Date(2024, 1, 15, 15, 46, 0, 0, ist))
+	h.trigger.sendDailySummary(ctx)
+	h.trigger.sendDailySummary(ctx)
+
+	events = waitForAlerts(t, alerts, 3)
+	time.Sleep(50 * time.Millisecond)
+	if got := len(alerts.Requests(notifytest.PathWebhook)); got != 3 {
+		t.Fatalf("webhook received %d alerts, want one daily summary after the order alerts", got)
+	}
+	summary := events[2]
+	if summary.Type != notify.EventDailySummary || summary.Fields["Executed"] != "1" || summary.Fields["Failed"] != "1" || summary.Fields["Dead letters"] != "1" {
+		t.Errorf("daily summary = %+v", summary)
+	}
+}
+
+func TestSyncGTTsFollowsOrderSource(t *testing.T) {
+	ctx := context.Background()
+	server := kitetest.NewServer()
+	t.Cleanup(server.Close)
+	server.SetQuote("NSE", "INFY", 1500)
+	server.SetQuote("NSE", "TCS", 3500)
+
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, mustIST(t))
+	h := newTestHarness(t, now, server.Configure)
+	validUntil := time.Date(2024, 1, 31, 15, 0, 0, 0, mustIST(t))
+	dip := models.Order{ID: "INFY-GTT", Symbol: "INFY", Exchange: "NSE", Price: 1400, Quantity: 10, OrderType: "LIMIT",
+		Side: "Buy", ScheduledTime: validUntil, GTT: &models.GTT{Type: models.GTTSingle}}
+	exit := models.Order{ID: "TCS-OCO", Symbol: "TCS", Exchange: "NSE", Price: 3800, Quantity: 5, OrderType: "LIMIT",
+		Side: "Sell", ScheduledTime: validUntil, GTT: &models.GTT{Type: models.GTTOCO, StopLoss: 3300}}
+	sync := func(desired ...models.Order) {
+		t.Helper()
+		if err := h.cache.SetDesiredGTTs(ctx, desired); err != nil {
+			t.Fatalf("SetDesiredGTTs: %v", err)
+		}
+		if err := h.trigger.SyncGTTs(ctx); err != nil {
+			t.Fatalf("SyncGTTs: %v", err)
+		}
+	}
+
+	sync(dip, exit)
+	gtts := server.GTTs()
+	if len(gtts) != 2 || gtts[0].Type != "single" || gtts[1].Type != "two-leg" {
+		t.Fatalf("GTTs = %+v, want a single and a two-leg GTT", gtts)
+	}
+	if values := gtts[1].Condition.TriggerValues; len(values) != 2 || values[0] != 3300 || values[1] != 3800 ||
+		gtts[1].Orders[0].TransactionType != "SELL" || gtts[1].Condition.LastPrice != 3500 {
+		t.Errorf("OCO GTT = %+v, want a stop-loss at 3300 and a target at 3800 on a SELL", gtts[1])
+	}
+
+	// A changed row modifies its GTT; an unchanged one is left alone
+	dip.Price = 1380
+	sync(dip, exit)
+	if values := server.GTTs()[0].Condition.TriggerValues; len(values) != 1 || values[0] != 1380 {
+		t.Errorf("INFY trigger values = %v, want [1380] after the row changed", values)
+	}
+
+	// The target leg fires at the broker; the status is followed once
+	server.TriggerGTT(gtts[1].ID, 1, "")
+	sync(dip, exit)
+	sync(dip, exit)
+	records, err := h.trigger.GTTs(ctx)
+	if err != nil {
+		t.Fatalf("GTTs: %v", err)
+	}
+	if len(records) != 2 || records[1].Status != models.GTTStatusTriggered || records[1].OrderID != server.Orders()[0].OrderID {
+		t.Errorf("records = %+v, want TCS-OCO triggered with the order the broker placed", records)
+	}
+
+	// A row removed from the order source has its active GTT deleted and is forgotten
+	sync(exit)
+	if status := server.GTTs()[0].Status; status != "deleted" {
+		t.Errorf("INFY GTT status = %s, want deleted", status)
+	}
+	if records, _ := h.trigger.GTTs(ctx); len(records) != 1 || records[0].Order.ID != "TCS-OCO" {
+		t.Errorf("records = %+v, want only TCS-OCO left", records)
+	}
+
+	entries, err := journal.ReadEntries(h.config.Journal.Path, now, now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("ReadEntries: %v", err)
+	}
+	var events []string
+	for _, entry := range entries {
+		events = append(events, entry.Order.ID+":"+entry.Event)
+	}
+	want := []string{"INFY-GTT:gtt_placed", "TCS-OCO:gtt_placed", "INFY-GTT:gtt_modified", "TCS-OCO:gtt_status", "INFY-GTT:gtt_deleted"}
+	if strings.Join(events, " ") != strings.Join(want, " ") {
+		t.Errorf("journal = %v, want %v", events, want)
+	}
+}
+
+func TestGTTChanged(t *testing.T) {
+	gtt := &models.GTT{Type: models.GTTSingle}
+	placed := models.Order{Price: 100, Quantity: 10, GTT: gtt}
+	tests := []struct {
+		name  string
+		order models.Order
+		want  bool
+	}{
+		{"same", models.Order{Price: 100, Quantity: 10, GTT: &models.GTT{Type: models.GTTSingle}}, false},
+		{"price", models.Order{Price: 101, Quantity: 10, GTT: gtt}, true},
+		{"stop loss", models.Order{Price: 100, Quantity: 10, GTT: &models.GTT{Type: models.GTTOCO, StopLoss: 90}}, true},
+		{"no GTT", models.Order{Price: 100, Quantity: 10}, true},
+	}
+	for _, tt := range tests {
+		if got := gttChanged(placed, tt.order); got != tt.want {
+			t.Errorf("%s: gttChanged = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if !gttChanged(models.Order{Price: 100, Quantity: 10}, placed) {
+		t.Error("gttChanged with no placed GTT = false, want true")
+	}
+}
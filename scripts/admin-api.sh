@@ -0,0 +1,129 @@
+#!/bin/bash
+
+# Call the trigger service admin API
+# Usage: ./admin-api.sh <command> [args]
+#
+# Commands:
+#   list                 List pending orders
+#   show <order_id>      Show a single pending order
+#   cancel <order_id>    Cancel (remove) a pending order
+#   add <json>           Add a manual order, e.g. '{"symbol":"INFY","side":"Buy","quantity":1,"price":1500}'
+#   pause | resume       Pause or resume order execution
+#   status               Show whether execution is paused
+#   readiness            Show the last system readiness check
+#   halts                List active trading halts
+#   halt <scope> <target> [reason]   Halt a symbol or side (scope: symbol|side)
+#   unhalt <scope> [target]          Lift a halt (scope: global|symbol|side)
+#   kill [reason]        Trip the global kill switch
+#   unkill               Reset the global kill switch
+#   audit [limit]        Show recent audit log entries
+#   dlq [reason]         List dead-lettered orders (reason: failed|rejected|expired|abandoned)
+#   dlq-show <order_id>  Show a dead-lettered order with its error and attempt count
+#   dlq-requeue <order_id> [scheduled_time]  Requeue a dead letter (RFC3339 time, default now)
+#   dlq-requeue-all [reason] [scheduled_time]  Requeue every dead letter with the given reason (default failed)
+#   dlq-discard <order_id>   Delete a dead letter
+#   quarantine           List cached orders that could not be decoded
+#   discard <order_id>   Delete a quarantined entry
+#   migrate              Upgrade cached orders to the current schema version
+
+set -e
+
+ADMIN_ADDR="${ADMIN_ADDR:-127.0.0.1:8081}"
+BASE_URL="http://$ADMIN_ADDR/api"
+
+if [ -z "$ADMIN_TOKEN" ]; then
+    echo "❌ Error: ADMIN_TOKEN is not set"
+    exit 1
+fi
+
+call() {
+    local method=$1
+    local path=$2
+    shift 2
+    curl -sS -X "$method" -H "Authorization: Bearer $ADMIN_TOKEN" "$@" "$BASE_URL$path" | (jq . 2>/dev/null || cat)
+}
+
+urlencode() {
+    jq -rn --arg v "$1" '$v|@uri'
+}
+
+case "$1" in
+    list)
+        call GET /orders
+        ;;
+    show)
+        call GET "/orders/$(urlencode "$2")"
+        ;;
+    cancel)
+        call DELETE "/orders/$(urlencode "$2")"
+        ;;
+    add)
+        call POST /orders -H "Content-Type: application/json" -d "$2"
+        ;;
+    pause)
+        call POST /execution/pause
+        ;;
+    resume)
+        call POST /execution/resume
+        ;;
+    status)
+        call GET /execution
+        ;;
+    readiness)
+        call GET /readiness
+        ;;
+    halts)
+        call GET /halts
+        ;;
+    halt)
+        call POST /halts -H "Content-Type: application/json" \
+            -d "$(jq -n --arg s "$2" --arg t "$3" --arg r "${4:-manual halt}" '{scope:$s,target:$t,reason:$r}')"
+        ;;
+    unhalt)
+        call DELETE "/halts/$2${3:+/$(urlencode "$3")}"
+        ;;
+    kill)
+        call POST /killswitch/trip -H "Content-Type: application/json" \
+            -d "$(jq -n --arg r "${2:-manual kill switch}" '{reason:$r}')"
+        ;;
+    unkill)
+        call POST /killswitch/reset
+        ;;
+    audit)
+        call GET "/audit?limit=${2:-100}"
+        ;;
+    dlq)
+        call GET "/deadletters${2:+?reason=$(urlencode "$2")}"
+        ;;
+    dlq-show)
+        call GET "/deadletters/$(urlencode "$2")"
+        ;;
+    dlq-requeue)
+        call POST "/deadletters/$(urlencode "$2")/requeue" -H "Content-Type: application/json" \
+            -d "$(jq -n --arg t "$3" 'if $t == "" then {} else {scheduled_time:$t} end')"
+        ;;
+    dlq-requeue-all)
+        ids=$(curl -sS -H "Authorization: Bearer $ADMIN_TOKEN" "$BASE_URL/deadletters?reason=$(urlencode "${2:-failed}")" | jq -r '.[].order.id')
+        for id in $ids; do
+            echo "♻️  $id"
+            call POST "/deadletters/$(urlencode "$id")/requeue" -H "Content-Type: application/json" \
+                -d "$(jq -n --arg t "$3" 'if $t == "" then {} else {scheduled_time:$t} end')"
+        done
+        ;;
+    dlq-discard)
+        call DELETE "/deadletters/$(urlencode "$2")"
+        ;;
+    quarantine)
+        call GET /quarantine
+        ;;
+    discard)
+        call DELETE "/quarantine/$(urlencode "$2")"
+        ;;
+    migrate)
+        call POST /cache/migrate
+        ;;
+    *)
+        echo "Usage: $0 {list|show <id>|cancel <id>|add <json>|pause|resume|status|readiness|halts|halt|unhalt|kill|unkill|audit|dlq|dlq-show <id>|dlq-requeue <id> [time]|dlq-requeue-all [reason] [time]|dlq-discard <id>|quarantine|discard <id>|migrate}"
+        exit 1
+        ;;
+esac